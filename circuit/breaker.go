@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sony/gobreaker"
 )
 
-// BreakerType defines the type of the used breaker: consecutive, rate or disabled.
+// BreakerType defines the type of the used breaker: consecutive, rate, slow or disabled.
 type BreakerType int
 
 func (b *BreakerType) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -21,10 +25,12 @@ func (b *BreakerType) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		*b = ConsecutiveFailures
 	case "rate":
 		*b = FailureRate
+	case "slow":
+		*b = SlowCalls
 	case "disabled":
 		*b = BreakerDisabled
 	default:
-		return fmt.Errorf("invalid breaker type %v (allowed values are: consecutive, rate or disabled)", value)
+		return fmt.Errorf("invalid breaker type %v (allowed values are: consecutive, rate, slow or disabled)", value)
 	}
 
 	return nil
@@ -35,6 +41,7 @@ const (
 	ConsecutiveFailures
 	FailureRate
 	BreakerDisabled
+	SlowCalls
 )
 
 // BreakerSettings contains the settings for individual circuit breakers.
@@ -49,10 +56,31 @@ type BreakerSettings struct {
 	Timeout          time.Duration `yaml:"timeout"`
 	HalfOpenRequests int           `yaml:"half-open-requests"`
 	IdleTTL          time.Duration `yaml:"idle-ttl"`
+
+	// SlowCallThreshold and SlowCallRate are used only by the slow call breakers.
+	SlowCallThreshold time.Duration `yaml:"slow-call-threshold"`
+	SlowCallRate      int           `yaml:"slow-call-rate"`
+}
+
+// StateChange describes a state transition of a circuit breaker. The
+// states are closed, open and half-open.
+type StateChange struct {
+	Settings BreakerSettings
+	From     string
+	To       string
+
+	// Shared is true when the transition was caused by the state shared
+	// across the skipper instances.
+	Shared bool
+
+	// Manual is true when the breaker was tripped or reset manually.
+	Manual bool
 }
 
 type breakerImplementation interface {
 	Allow() (func(bool), bool)
+	State() gobreaker.State
+	Counts() gobreaker.Counts
 }
 
 type voidBreaker struct{}
@@ -61,9 +89,16 @@ type voidBreaker struct{}
 //
 // Use the Get() method of the Registry to request fully initialized breakers.
 type Breaker struct {
-	settings BreakerSettings
-	ts       time.Time
-	impl     breakerImplementation
+	settings      BreakerSettings
+	ts            time.Time
+	shared        *sharedState
+	onStateChange func(StateChange)
+
+	// guards the fields below, but it is not held while calling the implementation
+	mx             sync.Mutex
+	impl           breakerImplementation
+	tripped        bool
+	lastTransition time.Time
 }
 
 func (to BreakerSettings) mergeSettings(from BreakerSettings) BreakerSettings {
@@ -78,6 +113,12 @@ func (to BreakerSettings) mergeSettings(from BreakerSettings) BreakerSettings {
 			to.Window = from.Window
 			to.Failures = from.Failures
 		}
+
+		if from.Type == SlowCalls {
+			to.Window = from.Window
+			to.SlowCallThreshold = from.SlowCallThreshold
+			to.SlowCallRate = from.SlowCallRate
+		}
 	}
 
 	if to.Timeout == 0 {
@@ -106,6 +147,8 @@ func (s BreakerSettings) String() string {
 		ss = append(ss, "type=consecutive")
 	case FailureRate:
 		ss = append(ss, "type=rate")
+	case SlowCalls:
+		ss = append(ss, "type=slow")
 	case BreakerDisabled:
 		return "disabled"
 	default:
@@ -116,10 +159,18 @@ func (s BreakerSettings) String() string {
 		ss = append(ss, "host="+s.Host)
 	}
 
-	if s.Type == FailureRate && s.Window > 0 {
+	if (s.Type == FailureRate || s.Type == SlowCalls) && s.Window > 0 {
 		ss = append(ss, "window="+strconv.Itoa(s.Window))
 	}
 
+	if s.Type == SlowCalls && s.SlowCallThreshold > 0 {
+		ss = append(ss, "slow-call-threshold="+s.SlowCallThreshold.String())
+	}
+
+	if s.Type == SlowCalls && s.SlowCallRate > 0 {
+		ss = append(ss, "slow-call-rate="+strconv.Itoa(s.SlowCallRate))
+	}
+
 	if s.Failures > 0 {
 		ss = append(ss, "failures="+strconv.Itoa(s.Failures))
 	}
@@ -143,28 +194,168 @@ func (b voidBreaker) Allow() (func(bool), bool) {
 	return func(bool) {}, true
 }
 
+func (b voidBreaker) State() gobreaker.State {
+	return gobreaker.StateClosed
+}
+
+func (b voidBreaker) Counts() gobreaker.Counts {
+	return gobreaker.Counts{}
+}
+
 func newBreaker(s BreakerSettings) *Breaker {
-	var impl breakerImplementation
-	switch s.Type {
+	b := &Breaker{settings: s, lastTransition: time.Now()}
+	b.impl = b.newImplementation()
+	return b
+}
+
+func (b *Breaker) newImplementation() breakerImplementation {
+	switch b.settings.Type {
 	case ConsecutiveFailures:
-		impl = newConsecutive(s)
-	case FailureRate:
-		impl = newRate(s)
+		return newConsecutive(b.settings, b.stateChanged)
+	case FailureRate, SlowCalls:
+		return newRate(b.settings, b.stateChanged)
 	default:
-		impl = voidBreaker{}
+		return voidBreaker{}
+	}
+}
+
+func (b *Breaker) getImpl() (breakerImplementation, bool) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.impl, b.tripped
+}
+
+func (b *Breaker) notify(c StateChange) {
+	b.mx.Lock()
+	b.lastTransition = time.Now()
+	b.mx.Unlock()
+
+	if b.onStateChange != nil {
+		b.onStateChange(c)
 	}
+}
 
-	return &Breaker{
-		settings: s,
-		impl:     impl,
+func (b *Breaker) stateChanged(from, to gobreaker.State) {
+	log.Infof("circuit breaker %v went from %v to %v", b.settings.Host, from.String(), to.String())
+	if to == gobreaker.StateOpen && b.shared != nil {
+		b.shared.localOpened()
 	}
+
+	b.notify(StateChange{Settings: b.settings, From: from.String(), To: to.String()})
 }
 
 // Allow returns true if the breaker is in the closed state and a callback function for reporting the outcome of
 // the operation. The callback expects true values if the outcome of the request was successful. Allow may not
 // return a callback function when the state is open.
+//
+// When the breaker state is shared across the skipper instances, the breaker is also considered open while it
+// is open in the shared state.
+//
+// For the slow call breakers, the callback function also measures the time since calling Allow, and it counts
+// the successful outcomes as failures, when the measured time reaches the slow call threshold.
 func (b *Breaker) Allow() (func(bool), bool) {
-	return b.impl.Allow()
+	impl, tripped := b.getImpl()
+	if tripped {
+		return nil, false
+	}
+
+	if b.shared != nil && b.shared.isOpen(time.Now()) {
+		return nil, false
+	}
+
+	done, ok := impl.Allow()
+	if !ok {
+		return nil, false
+	}
+
+	if b.shared != nil {
+		implDone := done
+		done = func(success bool) {
+			b.shared.record(success)
+			implDone(success)
+		}
+	}
+
+	if b.settings.Type == SlowCalls {
+		done = measureSlowCalls(b.settings, done)
+	}
+
+	return done, true
+}
+
+// State returns the current state of the breaker: closed, open or half-open.
+func (b *Breaker) State() string {
+	impl, tripped := b.getImpl()
+	if tripped || b.shared != nil && b.shared.isOpen(time.Now()) {
+		return gobreaker.StateOpen.String()
+	}
+
+	return impl.State().String()
+}
+
+// Trip opens the breaker manually, until it is reset. When the breaker state is shared across the skipper
+// instances, it affects only the current instance.
+func (b *Breaker) Trip() {
+	from := b.State()
+	b.mx.Lock()
+	b.tripped = true
+	b.mx.Unlock()
+
+	log.Infof("circuit breaker %v tripped manually", b.settings.Host)
+	b.notify(StateChange{Settings: b.settings, From: from, To: gobreaker.StateOpen.String(), Manual: true})
+}
+
+// Reset closes the breaker manually, and resets its failure counts. When the breaker state is shared across the
+// skipper instances, the shared open state is cleared only in the current instance, until the next
+// synchronization.
+func (b *Breaker) Reset() {
+	from := b.State()
+	b.mx.Lock()
+	b.tripped = false
+	b.impl = b.newImplementation()
+	b.mx.Unlock()
+
+	if b.shared != nil {
+		b.shared.reset()
+	}
+
+	log.Infof("circuit breaker %v reset manually", b.settings.Host)
+	b.notify(StateChange{Settings: b.settings, From: from, To: gobreaker.StateClosed.String(), Manual: true})
+}
+
+// Info returns the current state and failure counts of the breaker.
+func (b *Breaker) Info() BreakerInfo {
+	impl, tripped := b.getImpl()
+	counts := impl.Counts()
+
+	b.mx.Lock()
+	lastTransition := b.lastTransition
+	b.mx.Unlock()
+
+	info := BreakerInfo{
+		ID:                  breakerID(b.settings),
+		Host:                b.settings.Host,
+		Settings:            b.settings.String(),
+		State:               b.State(),
+		Tripped:             tripped,
+		Requests:            int(counts.Requests),
+		Failures:            int(counts.TotalFailures),
+		ConsecutiveFailures: int(counts.ConsecutiveFailures),
+		LastTransition:      lastTransition,
+	}
+
+	if b.shared != nil {
+		if openUntil, ok := b.shared.openUntilTime(); ok {
+			info.SharedOpenUntil = &openUntil
+		}
+	}
+
+	return info
+}
+
+// Settings returns the settings of the breaker.
+func (b *Breaker) Settings() BreakerSettings {
+	return b.settings
 }
 
 func (b *Breaker) idle(now time.Time) bool {
@@ -127,6 +127,66 @@ func TestRateBreaker(t *testing.T) {
 	})
 }
 
+func TestSlowCallBreaker(t *testing.T) {
+	s := BreakerSettings{
+		Type:              SlowCalls,
+		Window:            4,
+		SlowCallThreshold: 3 * time.Millisecond,
+		SlowCallRate:      50,
+		Timeout:           time.Minute,
+	}
+
+	slow := func(b *Breaker) func() {
+		return func() {
+			done, ok := b.Allow()
+			if !ok {
+				t.Error("breaker is unexpectedly open")
+				return
+			}
+
+			time.Sleep(s.SlowCallThreshold)
+			done(true)
+		}
+	}
+
+	t.Run("doesn't open before the window is full", func(t *testing.T) {
+		b := newBreaker(s)
+		times(3, slow(b))
+		checkClosed(t, b)
+	})
+
+	t.Run("doesn't open below the rate", func(t *testing.T) {
+		b := newBreaker(s)
+		times(3, succeed(t, b))
+		times(1, slow(b))
+		checkClosed(t, b)
+	})
+
+	t.Run("opens on reaching the rate of slow calls", func(t *testing.T) {
+		b := newBreaker(s)
+		times(2, succeed(t, b))
+		times(2, slow(b))
+		checkOpen(t, b)
+	})
+
+	t.Run("counts the failures as slow calls", func(t *testing.T) {
+		b := newBreaker(s)
+		times(2, succeed(t, b))
+		times(1, slow(b))
+		times(1, fail(t, b))
+		checkOpen(t, b)
+	})
+
+	t.Run("default rate", func(t *testing.T) {
+		s := s
+		s.SlowCallRate = 0
+		b := newBreaker(s)
+		times(2, succeed(t, b))
+		times(2, fail(t, b))
+		checkOpen(t, b)
+	})
+}
+
 // no checks, used for race detector
 func TestRateBreakerFuzzy(t *testing.T) {
 	if testing.Short() {
@@ -203,3 +263,22 @@ func TestSettingsString(t *testing.T) {
 		t.Logf("expected: %s", expect)
 	}
 }
+
+func TestSlowCallSettingsString(t *testing.T) {
+	s := BreakerSettings{
+		Type:              SlowCalls,
+		Host:              "www.example.org",
+		Window:            100,
+		SlowCallThreshold: 300 * time.Millisecond,
+		SlowCallRate:      60,
+		Timeout:           time.Minute,
+	}
+
+	ss := s.String()
+	expect := "type=slow,host=www.example.org,window=100,slow-call-threshold=300ms,slow-call-rate=60,timeout=1m0s"
+	if ss != expect {
+		t.Error("invalid breaker settings string")
+		t.Logf("got     : %s", ss)
+		t.Logf("expected: %s", expect)
+	}
+}
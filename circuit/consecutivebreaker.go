@@ -1,7 +1,6 @@
 package circuit
 
 import (
-	log "github.com/sirupsen/logrus"
 	"github.com/sony/gobreaker"
 )
 
@@ -10,7 +9,7 @@ type consecutiveBreaker struct {
 	gb       *gobreaker.TwoStepCircuitBreaker
 }
 
-func newConsecutive(s BreakerSettings) *consecutiveBreaker {
+func newConsecutive(s BreakerSettings, onStateChange func(from, to gobreaker.State)) *consecutiveBreaker {
 	b := &consecutiveBreaker{
 		settings: s,
 	}
@@ -20,8 +19,8 @@ func newConsecutive(s BreakerSettings) *consecutiveBreaker {
 		MaxRequests: uint32(s.HalfOpenRequests),
 		Timeout:     s.Timeout,
 		ReadyToTrip: b.readyToTrip,
-		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			log.Infof("circuit breaker %v went from %v to %v", name, from.String(), to.String())
+		OnStateChange: func(_ string, from gobreaker.State, to gobreaker.State) {
+			onStateChange(from, to)
 		},
 	})
 
@@ -43,3 +42,11 @@ func (b *consecutiveBreaker) Allow() (func(bool), bool) {
 	}
 	return done, true
 }
+
+func (b *consecutiveBreaker) State() gobreaker.State {
+	return b.gb.State()
+}
+
+func (b *consecutiveBreaker) Counts() gobreaker.Counts {
+	return b.gb.Counts()
+}
@@ -65,11 +65,11 @@ route based values happens with filters in the route definitions.
 
 Settings - Type
 
-It can be ConsecutiveFailures, FailureRate or Disabled, where the first two values select which breaker to use,
-while the Disabled value can override a global or host configuration disabling the circuit breaker for the
-specific host or route.
+It can be ConsecutiveFailures, FailureRate, SlowCalls or Disabled, where the first three values select which
+breaker to use, while the Disabled value can override a global or host configuration disabling the circuit
+breaker for the specific host or route.
 
-Command line name: type. Possible command line values: consecutive, rate, disabled.
+Command line name: type. Possible command line values: consecutive, rate, slow, disabled.
 
 Settings - Host
 
@@ -80,7 +80,7 @@ Command line name: host.
 
 Settings - Window
 
-The window value sets the size of the sliding counter window of the failure rate breaker.
+The window value sets the size of the sliding counter window of the failure rate and the slow call breakers.
 
 Command line name: window. Possible command line values: any positive integer.
 
@@ -90,6 +90,22 @@ The failures value sets the max failure count for both the "consecutive" and "ra
 
 Command line name: failures. Possible command line values: any positive integer.
 
+Settings - Slow Call Threshold
+
+The slow call breaker counts the requests as failed, when the backend response takes at least the slow call
+threshold. The response time is measured from checking the breaker until receiving the response headers. When
+not set, only the failures are counted.
+
+Command line name: slow-call-threshold. Possible command line values: a duration string, e.g. 300ms.
+
+Settings - Slow Call Rate
+
+The slow call breaker opens when the percentage of the slow or failed requests within the sliding window
+reaches the slow call rate. It opens only after the window was filled, to avoid opening after the first few
+slow requests. When not set, it defaults to 50.
+
+Command line name: slow-call-rate. Possible command line values: an integer between 1 and 100.
+
 Settings - Timeout
 
 With the timeout we can set how long the breaker should stay open, before becoming half-open.
@@ -112,7 +128,8 @@ string, e.g. 15m30s.
 
 Filters
 
-The following circuit breaker filters are supported: consecutiveBreaker(), rateBreaker() and disableBreaker().
+The following circuit breaker filters are supported: consecutiveBreaker(), rateBreaker(), slowCallBreaker() and
+disableBreaker().
 
 The consecutiveBreaker filter expects one mandatory parameter: the number of consecutive failures to open. It
 accepts the following optional arguments: timeout, half-open requests, idle-ttl.
@@ -124,6 +141,12 @@ of the sliding window. It accepts the following optional arguments: timeout, hal
 
 	rateBreaker(30, 300, "1m", 12, "30m")
 
+The slowCallBreaker filter expects three mandatory parameters: the slow call threshold, the slow call rate and
+the size of the sliding window. It accepts the following optional arguments: timeout, half-open requests,
+idle-ttl.
+
+	slowCallBreaker("300ms", 50, 100, "1m", 12, "30m")
+
 The disableBreaker filter doesn't expect any arguments, and it disables the circuit breaker, if any, for the
 route that it appears in.
 
@@ -133,12 +156,60 @@ Proxy Usage
 
 The proxy, when circuit breakers are configured, uses them for backend connections. It checks the breaker for
 the current backend host if it's closed before making backend requests. It reports the outcome of the request to
-the breaker, considering connection failures and backend responses with status code >=500 as failures. When the
+the breaker, considering connection failures and backend responses with status code >=500 as failures, and in
+case of the slow call breakers, also the responses slower than the slow call threshold. When the
 breaker is open, the proxy doesn't try to make backend requests, and returns a response with a status code of
 503 and appending a header to the response:
 
 	X-Circuit-Open: true
 
+Shared State
+
+By default, every skipper instance counts the failures of its own requests, and it opens its breakers
+independently from the other instances. Optionally, the failure counts and the open state of the breakers can be
+shared across the skipper instances, using the redis or the swim based swarm:
+
+	skipper -enable-swarm -swarm-redis-urls=redis1:6379,redis2:6379 \
+		-breaker type=consecutive,failures=30 \
+		-enable-shared-breakers
+
+When enabled, the instances report the outcome of their requests to the shared store periodically, defined by
+the -breaker-sync-interval flag, 1s by default, and a breaker goes open in all the instances for the breaker
+timeout, when the failures counted by all the instances reach the configured failures, or when the breaker of
+any of the instances goes open locally. For the consecutive breakers, the shared failure count is reset when any
+of the instances reports a success, and for the rate breakers, the shared counts are reset when the number of the
+counted requests reaches the window size. These are approximations of the local breakers, due to the periodic
+reporting. When the shared store is not available, the breakers work based on the local failure counts.
+
+The state transitions of the breakers are counted in the circuit.transitions.<state> metrics, and the
+transitions caused by the shared state in the circuit.shared.transitions.<state> metrics, where the state can be
+closed, open or half-open. The failed synchronizations with the shared store are counted in the
+circuit.shared.errors metric. The breakers are synchronized within the sync interval, and the passes not
+finished within the interval are counted in the circuit.shared.timeouts metric. The outcomes of the breakers
+skipped this way are reported with the next pass. To receive the state transitions as events, set the OnStateChange field of the
+Options when creating the registry with NewRegistryWithOptions.
+
+Admin API and Metrics
+
+The active circuit breakers can be inspected on the support listener, defined by the -support-listener flag,
+under the /breakers path. Operating the breakers manually needs to be enabled with the
+-enable-breaker-admin-write flag. The support listener doesn't authenticate the requests, so it should be
+enabled only when the listener is not accessible for untrusted clients:
+
+	# list the active breakers, optionally filtered by the backend host
+	curl localhost:9911/breakers?host=api.example.org
+
+	# trip a breaker manually, it stays open until it is reset
+	curl -X POST localhost:9911/breakers/<id>/trip
+
+	# close a breaker manually, and reset its failure counts
+	curl -X POST localhost:9911/breakers/<id>/reset
+
+The listed breakers contain their id, settings, state, failure counts and the time of the last state
+transition. The manual transitions are counted in the circuit.manual.transitions.<state> metrics, and the number
+of the active breakers in each state is reported periodically in the circuit.breakers.closed,
+circuit.breakers.open and circuit.breakers.half-open gauges.
+
 Registry
 
 The active circuit breakers are stored in a registry. They are created on-demand, for the requested settings.
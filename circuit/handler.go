@@ -0,0 +1,132 @@
+package circuit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BreakerHandlerPrefix is the path prefix of the BreakerHandler.
+const BreakerHandlerPrefix = "/breakers"
+
+// BreakerHandlerOptions defines the behavior of the admin handler of the circuit breakers.
+type BreakerHandlerOptions struct {
+
+	// EnableWrite enables tripping and resetting the breakers. The handler doesn't authenticate the
+	// requests, so it should be enabled only when the listener is not accessible for untrusted clients.
+	EnableWrite bool
+}
+
+type breakerHandler struct {
+	registry *Registry
+	options  BreakerHandlerOptions
+}
+
+// NewBreakerHandler returns the read-only admin handler of the circuit breakers. It serves:
+//
+//	GET /breakers, optionally filtered by the host query parameter, listing the active breakers
+//	GET /breakers/<id>, returning a single breaker
+func NewBreakerHandler(r *Registry) http.Handler {
+	return NewBreakerHandlerWithOptions(r, BreakerHandlerOptions{})
+}
+
+// NewBreakerHandlerWithOptions returns the admin handler of the circuit breakers. When EnableWrite is set,
+// besides the endpoints of NewBreakerHandler, it serves:
+//
+//	POST /breakers/<id>/trip, opening a breaker manually until it is reset
+//	POST /breakers/<id>/reset, closing a breaker and resetting its failure counts
+//
+// Otherwise, these requests are rejected with 403 Forbidden.
+func NewBreakerHandlerWithOptions(r *Registry, o BreakerHandlerOptions) http.Handler {
+	return &breakerHandler{registry: r, options: o}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed to write the circuit breaker response: %v", err)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+func (h *breakerHandler) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, "GET, HEAD")
+		return
+	}
+
+	host := r.URL.Query().Get("host")
+	infos := []BreakerInfo{}
+	for _, info := range h.registry.Breakers() {
+		if host == "" || info.Host == host {
+			infos = append(infos, info)
+		}
+	}
+
+	writeJSON(w, infos)
+}
+
+func (h *breakerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.Trim(strings.TrimPrefix(r.URL.Path, BreakerHandlerPrefix), "/")
+	if !strings.HasPrefix(r.URL.Path, BreakerHandlerPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if p == "" {
+		h.list(w, r)
+		return
+	}
+
+	parts := strings.Split(p, "/")
+	if len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	b, ok := h.registry.Breaker(parts[0])
+	if !ok {
+		http.Error(w, "circuit breaker not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, "GET, HEAD")
+			return
+		}
+
+		writeJSON(w, b.Info())
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, "POST")
+		return
+	}
+
+	if parts[1] != "trip" && parts[1] != "reset" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !h.options.EnableWrite {
+		http.Error(w, "changing the circuit breakers is disabled", http.StatusForbidden)
+		return
+	}
+
+	switch parts[1] {
+	case "trip":
+		b.Trip()
+	case "reset":
+		b.Reset()
+	}
+
+	writeJSON(w, b.Info())
+}
@@ -0,0 +1,153 @@
+package circuit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/metrics/metricstest"
+)
+
+func TestBreakerHandler(t *testing.T) {
+	m := &metricstest.MockMetrics{}
+	r := NewRegistryWithOptions(Options{
+		Settings: []BreakerSettings{{Type: ConsecutiveFailures, Failures: 3, Timeout: time.Minute}},
+		Metrics:  m,
+	})
+	defer r.Close()
+
+	foo := r.Get(BreakerSettings{Host: "foo"})
+	bar := r.Get(BreakerSettings{Host: "bar", Type: FailureRate, Failures: 3, Window: 10})
+	times(2, fail(t, foo))
+	times(2, succeed(t, bar))
+	failOnce(t, bar)
+
+	h := NewBreakerHandlerWithOptions(r, BreakerHandlerOptions{EnableWrite: true})
+	request := func(method, path string) *httptest.ResponseRecorder {
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, httptest.NewRequest(method, path, nil))
+		return rsp
+	}
+
+	decode := func(t *testing.T, rsp *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		if rsp.Code != http.StatusOK {
+			t.Fatalf("invalid status: %d", rsp.Code)
+		}
+
+		if err := json.Unmarshal(rsp.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var infos []BreakerInfo
+	decode(t, request("GET", "/breakers"), &infos)
+	if len(infos) != 2 || infos[0].Host != "bar" || infos[1].Host != "foo" {
+		t.Fatalf("invalid breakers: %v", infos)
+	}
+
+	if infos[0].State != "closed" || infos[0].Requests != 3 || infos[0].Failures != 1 || infos[0].ConsecutiveFailures != 1 {
+		t.Errorf("invalid rate breaker: %+v", infos[0])
+	}
+
+	if infos[1].State != "closed" || infos[1].Failures != 2 || infos[1].ConsecutiveFailures != 2 ||
+		infos[1].Settings != foo.Settings().String() || infos[1].LastTransition.IsZero() {
+		t.Errorf("invalid consecutive breaker: %+v", infos[1])
+	}
+
+	decode(t, request("GET", "/breakers?host=foo"), &infos)
+	if len(infos) != 1 || infos[0].Host != "foo" {
+		t.Fatalf("failed to filter by host: %v", infos)
+	}
+
+	id := infos[0].ID
+	var info BreakerInfo
+	decode(t, request("POST", "/breakers/"+id+"/trip"), &info)
+	if info.State != "open" || !info.Tripped {
+		t.Errorf("failed to trip: %+v", info)
+	}
+
+	checkOpen(t, foo)
+
+	r.updateGauges()
+	if v, ok := m.Gauge("circuit.breakers.open"); !ok || v != 1 {
+		t.Errorf("invalid open gauge: %v", v)
+	}
+
+	if v, ok := m.Gauge("circuit.breakers.closed"); !ok || v != 1 {
+		t.Errorf("invalid closed gauge: %v", v)
+	}
+
+	info = BreakerInfo{}
+	decode(t, request("POST", "/breakers/"+id+"/reset"), &info)
+	if info.State != "closed" || info.Tripped || info.Failures != 0 {
+		t.Errorf("failed to reset: %+v", info)
+	}
+
+	// the reset breaker doesn't remember the previous failures
+	failOnce(t, foo)
+	checkClosed(t, foo)
+
+	info = BreakerInfo{}
+	decode(t, request("GET", "/breakers/"+id), &info)
+	if info.Host != "foo" || info.Failures != 1 {
+		t.Errorf("invalid breaker: %+v", info)
+	}
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["circuit.manual.transitions.open"] != 1 || c["circuit.manual.transitions.closed"] != 1 {
+			t.Errorf("invalid counters: %v", c)
+		}
+	})
+
+	for _, ti := range []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/breakers/unknown", http.StatusNotFound},
+		{"POST", "/breakers/" + id + "/unknown", http.StatusNotFound},
+		{"POST", "/breakers/" + id + "/trip/now", http.StatusNotFound},
+		{"GET", "/breakers/" + id + "/trip", http.StatusMethodNotAllowed},
+		{"DELETE", "/breakers/" + id, http.StatusMethodNotAllowed},
+		{"POST", "/breakers", http.StatusMethodNotAllowed},
+	} {
+		if rsp := request(ti.method, ti.path); rsp.Code != ti.status {
+			t.Errorf("invalid status for %s %s, expected: %d, got: %d", ti.method, ti.path, ti.status, rsp.Code)
+		}
+	}
+}
+
+func TestBreakerHandlerReadOnly(t *testing.T) {
+	r := NewRegistry(BreakerSettings{Type: ConsecutiveFailures, Failures: 3, Timeout: time.Minute})
+	defer r.Close()
+
+	foo := r.Get(BreakerSettings{Host: "foo"})
+	infos := r.Breakers()
+	if len(infos) != 1 {
+		t.Fatalf("invalid breakers: %v", infos)
+	}
+
+	h := NewBreakerHandler(r)
+	for _, ti := range []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/breakers", http.StatusOK},
+		{"GET", "/breakers/" + infos[0].ID, http.StatusOK},
+		{"POST", "/breakers/" + infos[0].ID + "/trip", http.StatusForbidden},
+		{"POST", "/breakers/" + infos[0].ID + "/reset", http.StatusForbidden},
+		{"POST", "/breakers/" + infos[0].ID + "/unknown", http.StatusNotFound},
+	} {
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, httptest.NewRequest(ti.method, ti.path, nil))
+		if rsp.Code != ti.status {
+			t.Errorf("invalid status for %s %s, expected: %d, got: %d", ti.method, ti.path, ti.status, rsp.Code)
+		}
+	}
+
+	checkClosed(t, foo)
+}
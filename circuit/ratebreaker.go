@@ -1,7 +1,6 @@
 package circuit
 
 import (
-	log "github.com/sirupsen/logrus"
 	"sync"
 
 	"github.com/sony/gobreaker"
@@ -19,7 +18,7 @@ type rateBreaker struct {
 	gb       *gobreaker.TwoStepCircuitBreaker
 }
 
-func newRate(s BreakerSettings) *rateBreaker {
+func newRate(s BreakerSettings, onStateChange func(from, to gobreaker.State)) *rateBreaker {
 	b := &rateBreaker{
 		settings: s,
 		mx:       &sync.Mutex{},
@@ -30,8 +29,8 @@ func newRate(s BreakerSettings) *rateBreaker {
 		MaxRequests: uint32(s.HalfOpenRequests),
 		Timeout:     s.Timeout,
 		ReadyToTrip: func(gobreaker.Counts) bool { return b.readyToTrip() },
-		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			log.Infof("circuit breaker %v went from %v to %v", name, from.String(), to.String())
+		OnStateChange: func(_ string, from gobreaker.State, to gobreaker.State) {
+			onStateChange(from, to)
 		},
 	})
 
@@ -46,6 +45,10 @@ func (b *rateBreaker) readyToTrip() bool {
 		return false
 	}
 
+	if b.settings.Type == SlowCalls {
+		return slowCallRateReached(b.settings, b.sampler.count, b.sampler.filled)
+	}
+
 	return b.sampler.count >= b.settings.Failures
 }
 
@@ -76,3 +79,22 @@ func (b *rateBreaker) Allow() (func(bool), bool) {
 		done(success)
 	}, true
 }
+
+func (b *rateBreaker) State() gobreaker.State {
+	return b.gb.State()
+}
+
+// returns the counts of gobreaker, where the total failures are the failures in the sliding window
+func (b *rateBreaker) Counts() gobreaker.Counts {
+	c := b.gb.Counts()
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.sampler == nil {
+		c.TotalFailures = 0
+	} else {
+		c.TotalFailures = uint32(b.sampler.count)
+	}
+
+	return c
+}
@@ -0,0 +1,123 @@
+package circuit
+
+import (
+	"context"
+	"time"
+
+	"github.com/zalando/skipper/net"
+)
+
+// the counts and the open state of a breaker are stored with the same hash tag, to be in the same shard
+//
+// KEYS[1]: counts, KEYS[2]: open until in milliseconds
+// ARGV: failures, successes, consecutive failures, opened, type, failure threshold, window, timeout in
+// milliseconds, idle ttl in milliseconds, now in milliseconds, slow call rate
+const redisSyncScript = `
+local now = tonumber(ARGV[10])
+local openUntil = tonumber(redis.call('GET', KEYS[2]) or '0')
+if openUntil > now then
+	return openUntil
+end
+
+local failures = tonumber(ARGV[1])
+local threshold = tonumber(ARGV[6])
+local window = tonumber(ARGV[7])
+local total = 0
+if ARGV[5] == 'rate' or ARGV[5] == 'slow' then
+	total = tonumber(redis.call('HGET', KEYS[1], 'total') or '0')
+	if total >= window then
+		redis.call('DEL', KEYS[1])
+	end
+
+	total = redis.call('HINCRBY', KEYS[1], 'total', failures + tonumber(ARGV[2]))
+	failures = redis.call('HINCRBY', KEYS[1], 'failures', failures)
+elseif tonumber(ARGV[2]) > 0 then
+	failures = tonumber(ARGV[3])
+	redis.call('HSET', KEYS[1], 'failures', failures)
+else
+	failures = redis.call('HINCRBY', KEYS[1], 'failures', failures)
+end
+
+local tripped = failures > 0 and failures >= threshold
+if ARGV[5] == 'slow' then
+	tripped = failures > 0 and total >= math.max(window, 1) and failures * 100 >= tonumber(ARGV[11]) * total
+end
+
+redis.call('PEXPIRE', KEYS[1], ARGV[9])
+if ARGV[4] == '1' or tripped then
+	openUntil = now + tonumber(ARGV[8])
+	redis.call('SET', KEYS[2], openUntil, 'PX', ARGV[8])
+	redis.call('DEL', KEYS[1])
+	return openUntil
+end
+
+return 0
+`
+
+type redisStore struct {
+	ring   *net.RedisRingClient
+	script *net.RedisScript
+}
+
+// NewRedisStore creates a shared store for the circuit breakers, using the redis ring client of the swarm.
+func NewRedisStore(ring *net.RedisRingClient) SharedStore {
+	return &redisStore{
+		ring:   ring,
+		script: ring.NewScript(redisSyncScript),
+	}
+}
+
+func boolArg(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func typeArg(t BreakerType) string {
+	switch t {
+	case FailureRate:
+		return "rate"
+	case SlowCalls:
+		return "slow"
+	default:
+		return "consecutive"
+	}
+}
+
+func (s *redisStore) Sync(ctx context.Context, key string, settings BreakerSettings, o Outcomes) (time.Time, error) {
+	idleTTL := settings.IdleTTL
+	if idleTTL <= 0 {
+		idleTTL = DefaultIdleTTL
+	}
+
+	tag := "circuit.{" + key + "}."
+	now := time.Now()
+	res, err := s.ring.RunScript(
+		ctx,
+		s.script,
+		[]string{tag + "counts", tag + "open"},
+		o.Failures,
+		o.Successes,
+		o.ConsecutiveFailures,
+		boolArg(o.Opened),
+		typeArg(settings.Type),
+		settings.Failures,
+		settings.Window,
+		timeout(settings).Milliseconds(),
+		idleTTL.Milliseconds(),
+		now.UnixNano()/int64(time.Millisecond),
+		slowCallRate(settings),
+	)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	openUntil, _ := res.(int64)
+	if openUntil == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(0, openUntil*int64(time.Millisecond)), nil
+}
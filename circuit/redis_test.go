@@ -0,0 +1,65 @@
+package circuit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/net"
+	"github.com/zalando/skipper/net/redistest"
+)
+
+func TestRedisStore(t *testing.T) {
+	redisAddr, done := redistest.NewTestRedis(t)
+	defer done()
+
+	ring := net.NewRedisRingClient(&net.RedisOptions{Addrs: []string{redisAddr}})
+	defer ring.Close()
+
+	s := BreakerSettings{Type: ConsecutiveFailures, Host: "foo", Failures: 4, Timeout: time.Minute}
+	store := NewRedisStore(ring)
+
+	for i := 0; i < 2; i++ {
+		openUntil, err := store.Sync(context.Background(), s.String(), s, Outcomes{Failures: 1, ConsecutiveFailures: i + 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !openUntil.IsZero() {
+			t.Fatalf("unexpected open state: %v", openUntil)
+		}
+	}
+
+	// a success from another instance resets the count
+	if _, err := store.Sync(context.Background(), s.String(), s, Outcomes{Failures: 1, Successes: 1, ConsecutiveFailures: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	openUntil, err := store.Sync(context.Background(), s.String(), s, Outcomes{Failures: 2, ConsecutiveFailures: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !openUntil.IsZero() {
+		t.Fatalf("unexpected open state: %v", openUntil)
+	}
+
+	openUntil, err = store.Sync(context.Background(), s.String(), s, Outcomes{Failures: 1, ConsecutiveFailures: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !openUntil.After(time.Now()) {
+		t.Fatalf("failed to open: %v", openUntil)
+	}
+
+	// other instances receive the open state
+	next, err := store.Sync(context.Background(), s.String(), s, Outcomes{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !next.Equal(openUntil) {
+		t.Errorf("invalid open state, expected: %v, got: %v", openUntil, next)
+	}
+}
@@ -1,11 +1,80 @@
 package circuit
 
 import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
 	"sync"
 	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando/skipper/metrics"
+)
+
+const (
+	DefaultIdleTTL = time.Hour
+
+	// DefaultSyncInterval is the default interval of synchronizing the breakers with the shared store.
+	DefaultSyncInterval = time.Second
+
+	// DefaultMetricsInterval is the default interval of updating the breaker state gauges.
+	DefaultMetricsInterval = 10 * time.Second
 )
 
-const DefaultIdleTTL = time.Hour
+// Options is used to initialize a registry with NewRegistryWithOptions.
+type Options struct {
+
+	// Settings are the default and the host specific breaker settings, see NewRegistry.
+	Settings []BreakerSettings
+
+	// SharedStore, when set, enables sharing the failure counts and the open state of the breakers across the
+	// skipper instances. When the store is not available, the breakers work based on the local failure counts.
+	SharedStore SharedStore
+
+	// SyncInterval is the interval of synchronizing the breakers with the shared store. Defaults to
+	// DefaultSyncInterval.
+	SyncInterval time.Duration
+
+	// Metrics is used to count the state transitions of the breakers, and to measure the number of breakers in
+	// each state. Defaults to metrics.Default.
+	Metrics metrics.Metrics
+
+	// MetricsInterval is the interval of updating the breaker state gauges, when started with
+	// StartMetricsCollection. Defaults to DefaultMetricsInterval.
+	MetricsInterval time.Duration
+
+	// OnStateChange, when set, is called on every state transition of the breakers. It must not block, and it
+	// must not call the breakers.
+	OnStateChange func(StateChange)
+}
+
+// BreakerInfo contains the settings, the current state and the failure counts of a breaker.
+type BreakerInfo struct {
+
+	// ID identifies the breaker in the registry.
+	ID string `json:"id"`
+
+	Host     string `json:"host"`
+	Settings string `json:"settings"`
+
+	// State is one of closed, open or half-open.
+	State string `json:"state"`
+
+	// Tripped is true when the breaker was opened manually.
+	Tripped bool `json:"tripped,omitempty"`
+
+	// Requests, Failures and ConsecutiveFailures are the counts since the last state transition. For the rate
+	// breakers, Failures is the number of failures in the sliding window.
+	Requests            int `json:"requests"`
+	Failures            int `json:"failures"`
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+
+	LastTransition time.Time `json:"lastTransition"`
+
+	// SharedOpenUntil is set when the breaker is open across the skipper instances.
+	SharedOpenUntil *time.Time `json:"sharedOpenUntil,omitempty"`
+}
 
 // Registry objects hold the active circuit breakers, ensure synchronized access to them, apply default settings
 // and recycle the idle breakers.
@@ -14,17 +83,30 @@ type Registry struct {
 	hostSettings map[string]BreakerSettings
 	lookup       map[BreakerSettings]*Breaker
 	mx           *sync.Mutex
+
+	store           SharedStore
+	metrics         metrics.Metrics
+	metricsInterval time.Duration
+	onStateChange   func(StateChange)
+	quit            chan struct{}
+	once            sync.Once
 }
 
 // NewRegistry initializes a registry with the provided default settings. Settings with an empty Host field are
 // considered as defaults. Settings with the same Host field are merged together.
 func NewRegistry(settings ...BreakerSettings) *Registry {
+	return NewRegistryWithOptions(Options{Settings: settings})
+}
+
+// NewRegistryWithOptions initializes a registry with the provided options. When a shared store is set, or the
+// metrics collection is started, the registry needs to be closed with Close.
+func NewRegistryWithOptions(o Options) *Registry {
 	var (
 		defaults     BreakerSettings
 		hostSettings []BreakerSettings
 	)
 
-	for _, s := range settings {
+	for _, s := range o.Settings {
 		if s.Host == "" {
 			defaults = defaults.mergeSettings(s)
 			continue
@@ -46,12 +128,35 @@ func NewRegistry(settings ...BreakerSettings) *Registry {
 		}
 	}
 
-	return &Registry{
-		defaults:     defaults,
-		hostSettings: hs,
-		lookup:       make(map[BreakerSettings]*Breaker),
-		mx:           &sync.Mutex{},
+	if o.Metrics == nil {
+		o.Metrics = metrics.Default
+	}
+
+	if o.MetricsInterval <= 0 {
+		o.MetricsInterval = DefaultMetricsInterval
+	}
+
+	r := &Registry{
+		defaults:        defaults,
+		hostSettings:    hs,
+		lookup:          make(map[BreakerSettings]*Breaker),
+		mx:              &sync.Mutex{},
+		store:           o.SharedStore,
+		metrics:         o.Metrics,
+		metricsInterval: o.MetricsInterval,
+		onStateChange:   o.OnStateChange,
+		quit:            make(chan struct{}),
+	}
+
+	if r.store != nil {
+		if o.SyncInterval <= 0 {
+			o.SyncInterval = DefaultSyncInterval
+		}
+
+		go r.syncShared(o.SyncInterval)
 	}
+
+	return r
 }
 
 func (r *Registry) mergeDefaults(s BreakerSettings) BreakerSettings {
@@ -84,6 +189,11 @@ func (r *Registry) get(s BreakerSettings) *Breaker {
 
 		// create a new one
 		b = newBreaker(s)
+		b.onStateChange = r.stateChanged
+		if r.store != nil {
+			b.shared = newSharedState(s)
+		}
+
 		r.lookup[s] = b
 	}
 
@@ -113,3 +223,161 @@ func (r *Registry) Get(s BreakerSettings) *Breaker {
 
 	return r.get(s)
 }
+
+func (r *Registry) stateChanged(c StateChange) {
+	switch {
+	case c.Shared:
+		r.metrics.IncCounter("circuit.shared.transitions." + c.To)
+	case c.Manual:
+		r.metrics.IncCounter("circuit.manual.transitions." + c.To)
+	default:
+		r.metrics.IncCounter("circuit.transitions." + c.To)
+	}
+
+	if r.onStateChange != nil {
+		r.onStateChange(c)
+	}
+}
+
+func (r *Registry) syncShared(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// a slow store doesn't delay the next pass, the breakers not synchronized within the interval
+			// keep their outcomes until then
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			r.sync(ctx, time.Now())
+			cancel()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// sync reports the local outcomes of the breakers to the shared store, and applies the shared open state. It
+// stops when the context is done.
+func (r *Registry) sync(ctx context.Context, now time.Time) {
+	r.mx.Lock()
+	breakers := make([]*Breaker, 0, len(r.lookup))
+	for _, b := range r.lookup {
+		breakers = append(breakers, b)
+	}
+	r.mx.Unlock()
+
+	for _, b := range breakers {
+		if b.shared == nil {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			log.Debugf("Circuit breaker synchronization timed out: %v", ctx.Err())
+			r.metrics.IncCounter("circuit.shared.timeouts")
+			return
+		}
+
+		openUntil, err := r.store.Sync(ctx, b.shared.key, b.settings, b.shared.takeOutcomes())
+		if err != nil {
+			// the local breaker keeps working, and the shared open state expires with its timeout
+			log.Debugf("Failed to sync circuit breaker %s: %v", b.shared.key, err)
+			r.metrics.IncCounter("circuit.shared.errors")
+			continue
+		}
+
+		impl, _ := b.getImpl()
+		local := impl.State().String()
+		wasOpen := b.shared.setOpenUntil(now, openUntil)
+		isOpen := openUntil.After(now)
+		switch {
+		case !wasOpen && isOpen:
+			log.Infof("circuit breaker %v went open across the skipper instances", b.settings.Host)
+			r.stateChanged(StateChange{Settings: b.settings, From: local, To: "open", Shared: true})
+		case wasOpen && !isOpen:
+			log.Infof("circuit breaker %v is not open anymore across the skipper instances", b.settings.Host)
+			r.stateChanged(StateChange{Settings: b.settings, From: "open", To: local, Shared: true})
+		}
+	}
+}
+
+// Close stops the synchronization with the shared store and the metrics collection.
+func (r *Registry) Close() {
+	r.once.Do(func() { close(r.quit) })
+}
+
+func breakerID(s BreakerSettings) string {
+	h := fnv.New64a()
+	h.Write([]byte(s.String()))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func (r *Registry) breakers() []*Breaker {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	breakers := make([]*Breaker, 0, len(r.lookup))
+	for _, b := range r.lookup {
+		breakers = append(breakers, b)
+	}
+
+	return breakers
+}
+
+// Breakers returns the current state of the active breakers, ordered by host and ID.
+func (r *Registry) Breakers() []BreakerInfo {
+	var infos []BreakerInfo
+	for _, b := range r.breakers() {
+		infos = append(infos, b.Info())
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Host != infos[j].Host {
+			return infos[i].Host < infos[j].Host
+		}
+
+		return infos[i].ID < infos[j].ID
+	})
+
+	return infos
+}
+
+// Breaker returns an active breaker by its ID.
+func (r *Registry) Breaker(id string) (*Breaker, bool) {
+	for _, b := range r.breakers() {
+		if breakerID(b.settings) == id {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *Registry) updateGauges() {
+	counts := map[string]int{"closed": 0, "open": 0, "half-open": 0}
+	for _, b := range r.breakers() {
+		counts[b.State()]++
+	}
+
+	for state, n := range counts {
+		r.metrics.UpdateGauge("circuit.breakers."+state, float64(n))
+	}
+}
+
+// StartMetricsCollection starts updating the gauges of the number of breakers in each state, the
+// circuit.breakers.closed, circuit.breakers.open and circuit.breakers.half-open. It stops when the registry is
+// closed.
+func (r *Registry) StartMetricsCollection() {
+	go func() {
+		ticker := time.NewTicker(r.metricsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.updateGauges()
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,155 @@
+package circuit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// the default open timeout of the breakers, the same as the default of gobreaker
+const defaultTimeout = 60 * time.Second
+
+// Outcomes contains the outcomes of the requests counted by a breaker of a single skipper instance since the
+// last synchronization with the shared store.
+type Outcomes struct {
+	Failures  int
+	Successes int
+
+	// ConsecutiveFailures is the number of the failures since the last success.
+	ConsecutiveFailures int
+
+	// Opened is true, when the local breaker went open since the last synchronization.
+	Opened bool
+}
+
+// SharedStore is used to share the failure counts and the open state of the circuit breakers across the skipper
+// instances.
+type SharedStore interface {
+
+	// Sync adds the local outcomes of a breaker to the shared failure counts, and returns the time until the
+	// breaker is open across the skipper instances. The returned time is in the past, or zero, when the shared
+	// breaker is closed. The key identifies the breaker, and it is the same in every skipper instance for the
+	// same breaker settings. The context is shared by all the breakers synchronized in the same pass, and
+	// its deadline bounds the duration of the pass.
+	Sync(ctx context.Context, key string, s BreakerSettings, o Outcomes) (time.Time, error)
+}
+
+// sharedState holds the local outcomes of a breaker not synchronized yet with the shared store, and the shared
+// open state received from the store.
+type sharedState struct {
+	key       string
+	mx        sync.Mutex
+	outcomes  Outcomes
+	openUntil time.Time
+
+	// the open state applied with the last synchronization
+	open bool
+}
+
+// sharedCounts implements the failure counting across the skipper instances. For the consecutive breakers, the
+// shared count is reset when any of the instances reports a success, and for the rate and the slow call
+// breakers, the counts are reset when the number of the counted requests reaches the window size. These are
+// approximations of the local breakers, due to the batched reporting of the outcomes.
+type sharedCounts struct {
+	failures int
+	total    int
+}
+
+func timeout(s BreakerSettings) time.Duration {
+	if s.Timeout <= 0 {
+		return defaultTimeout
+	}
+
+	return s.Timeout
+}
+
+func newSharedState(s BreakerSettings) *sharedState {
+	return &sharedState{key: s.String()}
+}
+
+func (s *sharedState) record(success bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if success {
+		s.outcomes.Successes++
+		s.outcomes.ConsecutiveFailures = 0
+		return
+	}
+
+	s.outcomes.Failures++
+	s.outcomes.ConsecutiveFailures++
+}
+
+func (s *sharedState) localOpened() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.outcomes.Opened = true
+}
+
+func (s *sharedState) isOpen(now time.Time) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.openUntil.After(now)
+}
+
+func (s *sharedState) openUntilTime() (time.Time, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.openUntil, s.openUntil.After(time.Now())
+}
+
+func (s *sharedState) reset() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.outcomes = Outcomes{}
+	s.openUntil = time.Time{}
+	s.open = false
+}
+
+// returns the outcomes since the last call, and resets them, except for the consecutive failures
+func (s *sharedState) takeOutcomes() Outcomes {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	o := s.outcomes
+	s.outcomes = Outcomes{ConsecutiveFailures: o.ConsecutiveFailures}
+	return o
+}
+
+// sets the open state received from the shared store, and returns whether it was open with the previous
+// synchronization
+func (s *sharedState) setOpenUntil(now, t time.Time) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	wasOpen := s.open
+	s.openUntil = t
+	s.open = t.After(now)
+	return wasOpen
+}
+
+func (c *sharedCounts) add(s BreakerSettings, o Outcomes) {
+	if s.Type == FailureRate || s.Type == SlowCalls {
+		if c.total >= s.Window {
+			*c = sharedCounts{}
+		}
+
+		c.failures += o.Failures
+		c.total += o.Failures + o.Successes
+		return
+	}
+
+	if o.Successes > 0 {
+		c.failures = o.ConsecutiveFailures
+		return
+	}
+
+	c.failures += o.Failures
+}
+
+func tripped(s BreakerSettings, c sharedCounts) bool {
+	if s.Type == SlowCalls {
+		return slowCallRateReached(s, c.failures, c.total)
+	}
+
+	return c.failures > 0 && c.failures >= s.Failures
+}
@@ -0,0 +1,289 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/metrics/metricstest"
+)
+
+// fakeSwarm shares the values between the instances of the same test
+type fakeSwarm struct {
+	mx     *sync.Mutex
+	values map[string]map[string]interface{}
+	node   string
+}
+
+func newFakeSwarm(nodes ...string) []Swarmer {
+	mx := &sync.Mutex{}
+	values := make(map[string]map[string]interface{})
+	var s []Swarmer
+	for _, n := range nodes {
+		s = append(s, &fakeSwarm{mx: mx, values: values, node: n})
+	}
+
+	return s
+}
+
+func (s *fakeSwarm) ShareValue(key string, value interface{}) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.values[key] == nil {
+		s.values[key] = make(map[string]interface{})
+	}
+
+	s.values[key][s.node] = value
+	return nil
+}
+
+func (s *fakeSwarm) Values(key string) map[string]interface{} {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	v := make(map[string]interface{})
+	for n, vi := range s.values[key] {
+		v[n] = vi
+	}
+
+	return v
+}
+
+type failingStore struct{}
+
+func (failingStore) Sync(context.Context, string, BreakerSettings, Outcomes) (time.Time, error) {
+	return time.Time{}, errors.New("test error")
+}
+
+// blocks until the synchronization pass times out
+type slowStore struct{}
+
+func (slowStore) Sync(ctx context.Context, _ string, _ BreakerSettings, _ Outcomes) (time.Time, error) {
+	<-ctx.Done()
+	return time.Time{}, ctx.Err()
+}
+
+func TestSharedCounts(t *testing.T) {
+	t.Run("consecutive", func(t *testing.T) {
+		s := BreakerSettings{Type: ConsecutiveFailures, Failures: 5}
+		var c sharedCounts
+		c.add(s, Outcomes{Failures: 3, ConsecutiveFailures: 3})
+		c.add(s, Outcomes{Failures: 1, ConsecutiveFailures: 4})
+		if c.failures != 4 || tripped(s, c) {
+			t.Fatalf("invalid failures: %d", c.failures)
+		}
+
+		c.add(s, Outcomes{Failures: 2, Successes: 1, ConsecutiveFailures: 1})
+		if c.failures != 1 {
+			t.Fatalf("failed to reset on success: %d", c.failures)
+		}
+
+		c.add(s, Outcomes{Failures: 4, ConsecutiveFailures: 5})
+		if !tripped(s, c) {
+			t.Fatalf("failed to trip: %d", c.failures)
+		}
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		s := BreakerSettings{Type: FailureRate, Failures: 3, Window: 10}
+		var c sharedCounts
+		c.add(s, Outcomes{Failures: 2, Successes: 8})
+		if c.failures != 2 || tripped(s, c) {
+			t.Fatalf("invalid failures: %d", c.failures)
+		}
+
+		c.add(s, Outcomes{Failures: 2, Successes: 3})
+		if c.failures != 2 || c.total != 5 {
+			t.Fatalf("failed to reset the window: %d/%d", c.failures, c.total)
+		}
+
+		c.add(s, Outcomes{Failures: 1})
+		if !tripped(s, c) {
+			t.Fatalf("failed to trip: %d", c.failures)
+		}
+	})
+
+	t.Run("slow calls", func(t *testing.T) {
+		s := BreakerSettings{Type: SlowCalls, Window: 10, SlowCallRate: 40}
+		var c sharedCounts
+		c.add(s, Outcomes{Failures: 3, Successes: 2})
+		if tripped(s, c) {
+			t.Fatal("unexpected trip before the window is full")
+		}
+
+		c.add(s, Outcomes{Failures: 1, Successes: 4})
+		if !tripped(s, c) {
+			t.Fatalf("failed to trip: %d/%d", c.failures, c.total)
+		}
+
+		c.add(s, Outcomes{Failures: 3, Successes: 7})
+		if tripped(s, c) {
+			t.Fatalf("unexpected trip: %d/%d", c.failures, c.total)
+		}
+	})
+
+	t.Run("no failures", func(t *testing.T) {
+		if tripped(BreakerSettings{Type: ConsecutiveFailures}, sharedCounts{}) {
+			t.Error("unexpected trip")
+		}
+	})
+}
+
+func TestSharedBreakers(t *testing.T) {
+	settings := BreakerSettings{
+		Type:             ConsecutiveFailures,
+		Failures:         4,
+		Timeout:          time.Minute,
+		HalfOpenRequests: 1,
+	}
+
+	swarms := newFakeSwarm("node1", "node2")
+	m := &metricstest.MockMetrics{}
+	var (
+		mx      sync.Mutex
+		changes []StateChange
+	)
+
+	var registries []*Registry
+	for _, s := range swarms {
+		r := NewRegistryWithOptions(Options{
+			Settings:     []BreakerSettings{settings},
+			SharedStore:  NewSwarmStore(s),
+			SyncInterval: time.Hour,
+			Metrics:      m,
+			OnStateChange: func(c StateChange) {
+				mx.Lock()
+				defer mx.Unlock()
+				changes = append(changes, c)
+			},
+		})
+		defer r.Close()
+		registries = append(registries, r)
+	}
+
+	b1 := registries[0].Get(BreakerSettings{Host: "foo"})
+	b2 := registries[1].Get(BreakerSettings{Host: "foo"})
+
+	// not enough failures locally to open
+	times(2, fail(t, b1))
+	times(2, fail(t, b2))
+	checkClosed(t, b1)
+	checkClosed(t, b2)
+
+	now := time.Now()
+	registries[0].sync(context.Background(), now)
+	registries[1].sync(context.Background(), now)
+
+	// the first instance sees the shared open state only with the next sync
+	registries[0].sync(context.Background(), now)
+
+	checkOpen(t, b1)
+	checkOpen(t, b2)
+	if b1.State() != "open" {
+		t.Errorf("invalid state: %s", b1.State())
+	}
+
+	mx.Lock()
+	if len(changes) != 2 || !changes[0].Shared || changes[0].To != "open" || changes[0].Settings.Host != "foo" {
+		t.Errorf("invalid state changes: %v", changes)
+	}
+	mx.Unlock()
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["circuit.shared.transitions.open"] != 2 {
+			t.Errorf("invalid counters: %v", c)
+		}
+	})
+
+	// the shared open state expires
+	registries[1].sync(context.Background(), now.Add(2*time.Minute))
+
+	mx.Lock()
+	defer mx.Unlock()
+	if len(changes) != 3 || !changes[2].Shared || changes[2].From != "open" || changes[2].To != "closed" {
+		t.Errorf("invalid state changes: %v", changes)
+	}
+}
+
+func TestSharedBreakersLocalOpen(t *testing.T) {
+	settings := BreakerSettings{
+		Type:     ConsecutiveFailures,
+		Failures: 2,
+		Timeout:  time.Minute,
+	}
+
+	swarms := newFakeSwarm("node1", "node2")
+	r1 := NewRegistryWithOptions(Options{Settings: []BreakerSettings{settings}, SharedStore: NewSwarmStore(swarms[0]), SyncInterval: time.Hour})
+	defer r1.Close()
+	r2 := NewRegistryWithOptions(Options{Settings: []BreakerSettings{settings}, SharedStore: NewSwarmStore(swarms[1]), SyncInterval: time.Hour})
+	defer r2.Close()
+
+	b1 := r1.Get(BreakerSettings{Host: "foo"})
+	b2 := r2.Get(BreakerSettings{Host: "foo"})
+	times(2, fail(t, b1))
+	checkOpen(t, b1)
+	checkClosed(t, b2)
+
+	now := time.Now()
+	r1.sync(context.Background(), now)
+	r2.sync(context.Background(), now)
+	checkOpen(t, b2)
+}
+
+func TestSharedBreakersLocalFallback(t *testing.T) {
+	m := &metricstest.MockMetrics{}
+	r := NewRegistryWithOptions(Options{
+		Settings:     []BreakerSettings{{Type: ConsecutiveFailures, Failures: 3}},
+		SharedStore:  failingStore{},
+		SyncInterval: time.Hour,
+		Metrics:      m,
+	})
+	defer r.Close()
+
+	b := r.Get(BreakerSettings{Host: "foo"})
+	times(2, fail(t, b))
+	r.sync(context.Background(), time.Now())
+	checkClosed(t, b)
+
+	failOnce(t, b)
+	checkOpen(t, b)
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["circuit.shared.errors"] != 1 || c["circuit.transitions.open"] != 1 {
+			t.Errorf("invalid counters: %v", c)
+		}
+	})
+}
+
+func TestSharedBreakersSyncTimeout(t *testing.T) {
+	m := &metricstest.MockMetrics{}
+	r := NewRegistryWithOptions(Options{
+		Settings:     []BreakerSettings{{Type: ConsecutiveFailures, Failures: 3}},
+		SharedStore:  slowStore{},
+		SyncInterval: time.Hour,
+		Metrics:      m,
+	})
+	defer r.Close()
+
+	foo := r.Get(BreakerSettings{Host: "foo"})
+	bar := r.Get(BreakerSettings{Host: "bar"})
+	failOnce(t, foo)
+	failOnce(t, bar)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	r.sync(ctx, time.Now())
+
+	// one of the breakers was synchronized when the pass timed out, the other one keeps its outcomes
+	pending := foo.shared.takeOutcomes().Failures + bar.shared.takeOutcomes().Failures
+	if pending != 1 {
+		t.Errorf("invalid pending failures: %d", pending)
+	}
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["circuit.shared.timeouts"] != 1 || c["circuit.shared.errors"] != 1 {
+			t.Errorf("invalid counters: %v", c)
+		}
+	})
+}
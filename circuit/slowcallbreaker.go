@@ -0,0 +1,38 @@
+package circuit
+
+import "time"
+
+// DefaultSlowCallRate is the percentage of the slow and failed calls in the sliding window, at which the slow
+// call breakers open, when it is not set.
+const DefaultSlowCallRate = 50
+
+// The slow call breakers use the same sliding window as the rate breakers, but instead of a fixed number of
+// failures, they open when the percentage of the slow or failed calls in the window reaches the slow call rate.
+// They decide only when the window is full, to avoid opening after the first few slow calls.
+
+func slowCallRate(s BreakerSettings) int {
+	if s.SlowCallRate <= 0 {
+		return DefaultSlowCallRate
+	}
+
+	return s.SlowCallRate
+}
+
+func slowCallRateReached(s BreakerSettings, failures, total int) bool {
+	window := s.Window
+	if window <= 0 {
+		window = 1
+	}
+
+	return failures > 0 && total >= window && failures*100 >= slowCallRate(s)*total
+}
+
+// measures the time between the call of Allow and the reported outcome, and reports the slow calls as failures.
+// When the slow call threshold is not set, only the failures are counted.
+func measureSlowCalls(s BreakerSettings, done func(bool)) func(bool) {
+	start := time.Now()
+	return func(success bool) {
+		slow := s.SlowCallThreshold > 0 && time.Since(start) >= s.SlowCallThreshold
+		done(success && !slow)
+	}
+}
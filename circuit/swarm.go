@@ -0,0 +1,134 @@
+package circuit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const swarmPrefix = "circuit."
+
+// Swarmer is used to share values with the other skipper instances. It is implemented by the swarm package.
+type Swarmer interface {
+	ShareValue(string, interface{}) error
+	Values(string) map[string]interface{}
+}
+
+type swarmCounts struct {
+	sharedCounts
+	openUntil time.Time
+	ts        time.Time
+}
+
+type swarmStore struct {
+	swarm   Swarmer
+	mx      sync.Mutex
+	counts  map[string]*swarmCounts
+	cleanup time.Time
+}
+
+// NewSwarmStore creates a shared store for the circuit breakers, using the swim based swarm. Every instance
+// shares its own failure counts, and a breaker goes open when the sum of the failure counts of all the instances
+// reaches the configured failures.
+func NewSwarmStore(swarm Swarmer) SharedStore {
+	return &swarmStore{
+		swarm:  swarm,
+		counts: make(map[string]*swarmCounts),
+	}
+}
+
+// drops the counts of the breakers not synchronized for longer than the default idle TTL
+func (s *swarmStore) dropIdle(now time.Time) {
+	if now.Sub(s.cleanup) < DefaultIdleTTL {
+		return
+	}
+
+	s.cleanup = now
+	for key, c := range s.counts {
+		if now.Sub(c.ts) > DefaultIdleTTL {
+			delete(s.counts, key)
+		}
+	}
+}
+
+func (s *swarmStore) update(key string, settings BreakerSettings, o Outcomes, now time.Time) *swarmCounts {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.dropIdle(now)
+	c, ok := s.counts[key]
+	if !ok {
+		c = &swarmCounts{}
+		s.counts[key] = c
+	}
+
+	c.ts = now
+	c.add(settings, o)
+	if o.Opened || tripped(settings, c.sharedCounts) {
+		c.openUntil = now.Add(timeout(settings))
+		c.sharedCounts = sharedCounts{}
+	}
+
+	cc := *c
+	return &cc
+}
+
+func (s *swarmStore) open(key string, until time.Time) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if c, ok := s.counts[key]; ok {
+		c.openUntil = until
+		c.sharedCounts = sharedCounts{}
+	}
+}
+
+func sumValues(swarm Swarmer, key string) int {
+	var sum int64
+	for _, v := range swarm.Values(key) {
+		if i, ok := v.(int64); ok {
+			sum += i
+		}
+	}
+
+	return int(sum)
+}
+
+func (s *swarmStore) Sync(_ context.Context, key string, settings BreakerSettings, o Outcomes) (time.Time, error) {
+	now := time.Now()
+	c := s.update(key, settings, o, now)
+
+	failuresKey := swarmPrefix + key + ".failures"
+	totalKey := swarmPrefix + key + ".total"
+	openKey := swarmPrefix + key + ".open"
+	if err := s.swarm.ShareValue(failuresKey, int64(c.failures)); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.swarm.ShareValue(totalKey, int64(c.total)); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.swarm.ShareValue(openKey, c.openUntil.UnixNano()); err != nil {
+		return time.Time{}, err
+	}
+
+	openUntil := c.openUntil
+	for _, v := range s.swarm.Values(openKey) {
+		if t, ok := v.(int64); ok && t > openUntil.UnixNano() {
+			openUntil = time.Unix(0, t)
+		}
+	}
+
+	if openUntil.After(now) {
+		return openUntil, nil
+	}
+
+	if !tripped(settings, sharedCounts{failures: sumValues(s.swarm, failuresKey), total: sumValues(s.swarm, totalKey)}) {
+		return openUntil, nil
+	}
+
+	// the shared open state is propagated to the other instances with the next synchronization
+	openUntil = now.Add(timeout(settings))
+	s.open(key, openUntil)
+	return openUntil, nil
+}
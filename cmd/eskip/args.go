@@ -40,7 +40,13 @@ const (
 	appendFileFlag     = "append-file"
 	prettyFlag         = "pretty"
 	indentStrFlag      = "indent"
+	expandFlag         = "expand"
 	jsonFlag           = "json"
+	methodFlag         = "method"
+	hostFlag           = "host"
+	pathFlag           = "path"
+	headerFlag         = "header"
+	remoteAddrFlag     = "remote-addr"
 
 	defaultEtcdUrls     = "http://127.0.0.1:2379,http://127.0.0.1:4001"
 	defaultEtcdPrefix   = "/skipper"
@@ -76,13 +82,22 @@ var (
 	appendFileArg     string
 	pretty            bool
 	indentStr         string
+	expand            bool
 	printJson         bool
+	explainMethod     string
+	explainHost       string
+	explainPath       string
+	explainHeaders    *headerFlags
+	explainRemoteAddr string
 )
 
 var (
 	// used to prevent automatic stdin detection during tests:
 	isTest = false
 
+	// set for the commands accepting multiple files
+	multipleFiles = false
+
 	nowrite = &noopWriter{}
 	flags   *flag.FlagSet
 )
@@ -111,7 +126,15 @@ func initFlags() {
 
 	flags.BoolVar(&pretty, prettyFlag, false, prettyUsage)
 	flags.StringVar(&indentStr, indentStrFlag, "  ", indentStrUsage)
+	flags.BoolVar(&expand, expandFlag, false, expandUsage)
 	flags.BoolVar(&printJson, jsonFlag, false, jsonUsage)
+
+	explainHeaders = &headerFlags{}
+	flags.StringVar(&explainMethod, methodFlag, "GET", methodUsage)
+	flags.StringVar(&explainHost, hostFlag, "", hostUsage)
+	flags.StringVar(&explainPath, pathFlag, "/", pathUsage)
+	flags.Var(explainHeaders, headerFlag, headerUsage)
+	flags.StringVar(&explainRemoteAddr, remoteAddrFlag, "", remoteAddrUsage)
 }
 
 func init() {
@@ -193,20 +216,40 @@ func processInnkeeperArgs(innkeeperUrl, oauthToken string) (*medium, error) {
 		oauthToken: oauthToken}, nil
 }
 
-// returns file type medium if a positional parameter is defined.
-func processFileArg() (*medium, error) {
+func isRemote(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// returns file type media if positional parameters are defined, or remote
+// type media for http and https URLs, e.g. the /routes endpoint of a
+// running skipper or routesrv. More than one positional parameter is
+// accepted only when multipleFiles is set.
+func processFileArgs() ([]*medium, error) {
 	nonFlagArgs := flags.Args()
-	if len(nonFlagArgs) > 1 {
+	if len(nonFlagArgs) > 1 && !multipleFiles {
 		return nil, invalidNumberOfArgs
 	}
 
-	if len(nonFlagArgs) == 0 {
-		return nil, nil
+	var media []*medium
+	for _, arg := range nonFlagArgs {
+		if isRemote(arg) {
+			urls, err := stringsToUrls(arg)
+			if err != nil {
+				return nil, err
+			}
+
+			media = append(media, &medium{
+				typ:  remote,
+				urls: urls})
+			continue
+		}
+
+		media = append(media, &medium{
+			typ:  file,
+			path: arg})
 	}
 
-	return &medium{
-		typ:  file,
-		path: nonFlagArgs[0]}, nil
+	return media, nil
 }
 
 // if pretty print then check that indent matches pattern
@@ -293,7 +336,7 @@ func processArgs() ([]*medium, error) {
 			ids: strings.Split(inlineRouteIds, ",")})
 	}
 
-	fileArg, err := processFileArg()
+	fileArgs, err := processFileArgs()
 	if err != nil {
 		return nil, err
 	}
@@ -303,8 +346,8 @@ func processArgs() ([]*medium, error) {
 		return nil, err
 	}
 
-	if fileArg != nil {
-		media = append(media, fileArg)
+	if len(fileArgs) > 0 {
+		media = append(media, fileArgs...)
 	} else {
 		stdinArg := processStdin()
 
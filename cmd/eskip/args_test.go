@@ -204,3 +204,22 @@ func TestProcessArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessMultipleFileArgs(t *testing.T) {
+	multipleFiles = true
+	defer func() { multipleFiles = false }()
+
+	preserveArgs([]string{"file1", "file2"}, func() {
+		media, err := processArgs()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(media) != 2 {
+			t.Fatalf("invalid media: %v", media)
+		}
+
+		checkMedium(t, &medium{typ: file, path: "file1"}, media[0], 0, 0)
+		checkMedium(t, &medium{typ: file, path: "file2"}, media[1], 0, 1)
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+var differentRoutes = errors.New("routes differ")
+
+type changedRoute struct {
+	ID   string       `json:"id"`
+	From *eskip.Route `json:"from"`
+	To   *eskip.Route `json:"to"`
+}
+
+type routesDiff struct {
+	Added   []*eskip.Route `json:"added"`
+	Removed []*eskip.Route `json:"removed"`
+	Changed []changedRoute `json:"changed"`
+}
+
+func (d *routesDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffRoutes compares two sets of routes by their ids, using semantic
+// equality, that ignores e.g. the order of the predicates and the
+// formatting of the arguments.
+func diffRoutes(from, to []*eskip.Route) *routesDiff {
+	fromByID, toByID := mapRoutes(from), mapRoutes(to)
+	d := &routesDiff{
+		Added:   []*eskip.Route{},
+		Removed: []*eskip.Route{},
+		Changed: []changedRoute{},
+	}
+
+	for id, r := range fromByID {
+		if tr, ok := toByID[id]; !ok {
+			d.Removed = append(d.Removed, r)
+		} else if !eskip.Eq(r, tr) {
+			d.Changed = append(d.Changed, changedRoute{ID: id, From: r, To: tr})
+		}
+	}
+
+	for id, r := range toByID {
+		if _, ok := fromByID[id]; !ok {
+			d.Added = append(d.Added, r)
+		}
+	}
+
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].Id < d.Added[j].Id })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].Id < d.Removed[j].Id })
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].ID < d.Changed[j].ID })
+	return d
+}
+
+// prints a route definition in canonical form, with each line prefixed
+func printDiffRoute(prefix string, r *eskip.Route) {
+	c := eskip.Canonical(r)
+	def := fmt.Sprintf("%s: %s;", r.Id, c.Print(eskip.PrettyPrintInfo{Pretty: pretty, IndentStr: indentStr}))
+	for _, line := range strings.Split(def, "\n") {
+		fmt.Fprintf(stdout, "%s%s\n", prefix, line)
+	}
+}
+
+type diffEntry struct {
+	id       string
+	from, to *eskip.Route
+}
+
+func printUnifiedDiff(fromName, toName string, d *routesDiff) {
+	var entries []diffEntry
+	for _, r := range d.Removed {
+		entries = append(entries, diffEntry{id: r.Id, from: r})
+	}
+
+	for _, r := range d.Added {
+		entries = append(entries, diffEntry{id: r.Id, to: r})
+	}
+
+	for _, c := range d.Changed {
+		entries = append(entries, diffEntry{id: c.ID, from: c.From, to: c.To})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+
+	fmt.Fprintf(stdout, "--- %s\n", fromName)
+	fmt.Fprintf(stdout, "+++ %s\n", toName)
+	for _, e := range entries {
+		if e.from != nil {
+			printDiffRoute("-", e.from)
+		}
+
+		if e.to != nil {
+			printDiffRoute("+", e.to)
+		}
+	}
+}
+
+// command executed for diff.
+func diffCmd(a cmdArgs) error {
+	if len(a.allMedia) != 2 {
+		return invalidNumberOfArgs
+	}
+
+	from, err := loadRoutesChecked(a.allMedia[0])
+	if err != nil {
+		return err
+	}
+
+	to, err := loadRoutesChecked(a.allMedia[1])
+	if err != nil {
+		return err
+	}
+
+	d := diffRoutes(from, to)
+	if printJson {
+		enc := json.NewEncoder(stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	} else if !d.empty() {
+		printUnifiedDiff(mediumName(a.allMedia[0]), mediumName(a.allMedia[1]), d)
+	}
+
+	if !d.empty() {
+		return differentRoutes
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestDiff(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		from, to string
+		expected string
+	}{{
+		msg:  "equal",
+		from: `r1: Path("/foo") && Method("GET") -> setPath("/bar") -> "https://www.example.org"`,
+		to: `r1: Method("GET")
+			&& Path("/foo")
+			-> setPath( "/bar" )
+			-> "https://www.example.org"`,
+	}, {
+		msg:  "added, removed and changed",
+		from: `r1: Path("/foo") -> <shunt>; r2: * -> <shunt>; r3: * -> "https://www.example.org"`,
+		to:   `r1: Path("/foo") -> status(404) -> <shunt>; r3: * -> "https://www.example.org"; r4: * -> <loopback>`,
+		expected: `--- inline
++++ inline
+-r1: Path("/foo") -> <shunt>;
++r1: Path("/foo") -> status(404) -> <shunt>;
+-r2: * -> <shunt>;
++r4: * -> <loopback>;
+`,
+	}} {
+		t.Run(ti.msg, func(t *testing.T) {
+			preserveOut := stdout
+			defer func() { stdout = preserveOut }()
+			buf := &bytes.Buffer{}
+			stdout = buf
+
+			err := diffCmd(cmdArgs{allMedia: []*medium{{typ: inline, eskip: ti.from}, {typ: inline, eskip: ti.to}}})
+			if ti.expected == "" && err != nil || ti.expected != "" && err != differentRoutes {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if buf.String() != ti.expected {
+				t.Errorf("invalid output, expected: %s, got: %s", ti.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	preserveOut := stdout
+	preserveJson := printJson
+	defer func() {
+		stdout = preserveOut
+		printJson = preserveJson
+	}()
+
+	buf := &bytes.Buffer{}
+	stdout = buf
+	printJson = true
+
+	err := diffCmd(cmdArgs{allMedia: []*medium{
+		{typ: inline, eskip: `r1: * -> <shunt>; r2: * -> <shunt>`},
+		{typ: inline, eskip: `r1: * -> status(404) -> <shunt>; r3: * -> <shunt>`},
+	}})
+	if err != differentRoutes {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var d routesDiff
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Added) != 1 || d.Added[0].Id != "r3" ||
+		len(d.Removed) != 1 || d.Removed[0].Id != "r2" ||
+		len(d.Changed) != 1 || d.Changed[0].ID != "r1" || len(d.Changed[0].To.Filters) != 1 {
+		t.Errorf("invalid diff: %s", buf.String())
+	}
+}
+
+func TestRemoteReader(t *testing.T) {
+	var routes []*eskip.Route
+	for i := 0; i < remoteRoutesLimit+3; i++ {
+		routes = append(routes, &eskip.Route{Id: "r" + strconv.Itoa(i), BackendType: eskip.ShuntBackend})
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/routes" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := offset + limit
+		if end > len(routes) {
+			end = len(routes)
+		}
+
+		w.Header().Set("X-Count", strconv.Itoa(len(routes)))
+		eskip.Fprint(w, eskip.PrettyPrintInfo{}, routes[offset:end]...)
+	}))
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := newRemoteReader(u).LoadAndParseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded) != len(routes) {
+		t.Fatalf("failed to load all routes, expected: %d, got: %d", len(routes), len(loaded))
+	}
+
+	for i, r := range loaded {
+		if r.Id != routes[i].Id {
+			t.Errorf("invalid route, expected: %s, got: %s", routes[i].Id, r.Id)
+		}
+	}
+}
@@ -42,6 +42,22 @@ Sync routes from an eskip file to etcd:
 
     eskip reset routes.eskip
 
+Explain which route matches a request:
+
+    eskip explain -method POST -path /api -header 'X-Foo: bar' routes.eskip
+
+Lint multiple eskip files:
+
+    eskip lint routes1.eskip routes2.eskip
+
+Compare the routes of a file with the routes of a running skipper:
+
+    eskip diff routes.eskip http://localhost:9911/routes
+
+Test the routes with request and response fixtures:
+
+    eskip test routes.eskip cases.yaml
+
 Delete routes from etcd:
 
     eskip delete -ids route1,route2,route3
@@ -84,11 +100,17 @@ const (
 	appendFileUsage     = "append filters from a file to each patched route"
 	prettyUsage         = "prints routes in a more readable format"
 	indentStrUsage      = "indent string used in pretty printing. Must match regexp \\s"
+	expandUsage         = "print: prints the routes with the named predicate sets and filter chains expanded"
 	jsonUsage           = "prints routes as JSON"
+	methodUsage         = "explain: method of the request"
+	hostUsage           = "explain: host of the request"
+	pathUsage           = "explain: path of the request, optionally with query"
+	headerUsage         = "explain: header of the request in the format of 'Name: value', can be repeated"
+	remoteAddrUsage     = "explain: remote address of the request"
 
 	// command line help (1):
 	help1 = `Usage: eskip <command> [media flags] [--] [file]
-Commands: check|print|upsert|reset|delete|patch
+Commands: check|print|upsert|reset|delete|patch|explain|lint|diff|test
 Verify, print, update or delete Skipper routes.
 See more: https://github.com/zalando/skipper
 
@@ -101,6 +123,8 @@ etcd          endpoint(s) of an etcd cluster. See more about etcd:
 stdin         standard input when not tty, expecting routes but ignored if a file is provided
 file          a file containing routes
 inline        routes as command line parameter
+remote        the /routes endpoint of a running skipper or routesrv, as an
+              http or https URL (only for diff)
 inline ids    a list of route ids (only for delete)
 prepend       a chain of filters to be prepended to the filter chain in
               each route
@@ -126,7 +150,9 @@ check    verifies the syntax of routes. Accepts one input medium
          Example:
          eskip check -etcd-urls http://etcd.example.org
 
-print    same as check, but also prints the routes.
+print    same as check, but also prints the routes. The references to
+         the named predicate sets and filter chains are preserved,
+         unless the -expand flag is set.
 
 upsert   insert/update routes from input to output. Expects one input
          medium of the following types: stdin, file, inline.
@@ -147,6 +173,67 @@ patch    takes a list of routes as input from any media except of inline
 		 route. Example:
 		 eskip patch -append 'filter1() -> filter2()'
 
+explain  loads the routes from an input medium, like check, and explains
+         which route would match a request described by the -method,
+         -host, -path, -header and -remote-addr flags, and why the other
+         candidate routes were rejected. Prints JSON with -json. Example:
+         eskip explain -method POST -host www.example.org -path /api \
+             -header 'X-Foo: bar' routes.eskip
+
+lint     loads the routes from one or more input media, e.g. multiple
+         files, with the builtin filters and predicates of skipper, and
+         reports the routes that are invalid or can never match:
+         unknown predicates and filters, invalid filter arguments,
+         duplicate ids across the inputs, shadowed routes, conflicting
+         Host regexps, loopback cycles and load balanced routes without
+         endpoints. Prints JSON with -json. Fails, when errors were
+         found, while warnings don't fail. Example:
+         eskip lint -json routes1.eskip routes2.eskip
+
+diff     compares the routes of two input media, files or the URLs of
+         the /routes endpoint of a running skipper or routesrv, and
+         prints the added, removed and changed routes. The routes are
+         compared by their id, ignoring formatting differences and the
+         order of the predicates. Prints JSON with -json. Fails, when
+         the routes differ. Example:
+         eskip diff routes.eskip https://skipper.example.org:9911/routes
+
+test     runs test cases against the routes, with the builtin filters
+         and predicates of skipper, and a stub replacing the network
+         backends. The routes can be loaded from any input medium, while
+         the test cases are loaded from the last file, in YAML format:
+
+           - name: api
+             request:
+               method: GET
+               url: https://api.example.org/foo?q=1
+               headers:
+                 X-Foo: bar
+             backend:
+               status: 200
+               headers:
+                 Content-Type: application/json
+               body: '{}'
+             expect:
+               route: api
+               backend: https://api.internal/bar
+               requestHeaders:
+                 X-Foo: bar
+               status: 200
+               responseHeaders:
+                 Content-Type: application/json
+
+         The backend section defines the response of the stub. In the
+         expect section, the fields that are not set are not checked,
+         while the backend URL is compared only up to the path and the
+         query set in the expected value. The expected route is the one
+         that handled the request, with loopback routes the first one.
+         The filters requiring runtime
+         configuration, e.g. oauthTokeninfoAnyScope, don't do anything.
+         Prints JSON with -json. Fails, when any test case failed.
+         Example:
+         eskip test routes.eskip cases.yaml
+
 version  print eskip version
 `
 )
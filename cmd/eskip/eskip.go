@@ -27,13 +27,17 @@ type (
 )
 
 const (
-	check  command = "check"
-	print  command = "print"
-	upsert command = "upsert"
-	reset  command = "reset"
-	delete command = "delete"
-	patch  command = "patch"
-	ver    command = "version"
+	check   command = "check"
+	print   command = "print"
+	upsert  command = "upsert"
+	reset   command = "reset"
+	delete  command = "delete"
+	patch   command = "patch"
+	explain command = "explain"
+	lint    command = "lint"
+	diff    command = "diff"
+	test    command = "test"
+	ver     command = "version"
 )
 
 var (
@@ -43,13 +47,20 @@ var (
 
 // map command string to command function
 var commands = map[command]commandFunc{
-	check:  checkCmd,
-	print:  printCmd,
-	upsert: upsertCmd,
-	reset:  resetCmd,
-	delete: deleteCmd,
-	patch:  patchCmd,
-	ver:    versionCmd}
+	check:   checkCmd,
+	print:   printCmd,
+	upsert:  upsertCmd,
+	reset:   resetCmd,
+	delete:  deleteCmd,
+	patch:   patchCmd,
+	explain: explainCmd,
+	lint:    lintCmd,
+	diff:    diffCmd,
+	test:    testCmd,
+	ver:     versionCmd}
+
+// commands accepting multiple files as input
+var multipleFileCommands = map[command]bool{lint: true, diff: true, test: true}
 
 var (
 	missingCommand = errors.New("missing command")
@@ -131,6 +142,8 @@ func main() {
 		exit(nil)
 	}
 
+	multipleFiles = multipleFileCommands[cmd]
+
 	// process arguments, not checking if they make any sense:
 	media, err := processArgs()
 	if err != nil {
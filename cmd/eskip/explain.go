@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+var invalidHeader = errors.New("invalid header, expected format: 'Name: value'")
+
+// headerFlags collects the repeated -header flags.
+type headerFlags struct {
+	values []string
+}
+
+func (h *headerFlags) String() string {
+	return strings.Join(h.values, ", ")
+}
+
+func (h *headerFlags) Set(v string) error {
+	if !strings.Contains(v, ":") {
+		return invalidHeader
+	}
+
+	h.values = append(h.values, v)
+	return nil
+}
+
+func (h *headerFlags) header() http.Header {
+	if len(h.values) == 0 {
+		return nil
+	}
+
+	header := make(http.Header)
+	for _, v := range h.values {
+		kv := strings.SplitN(v, ":", 2)
+		header.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+
+	return header
+}
+
+func explainRoutes(routes []*eskip.Route, er routing.ExplainRequest) (*routing.MatchExplanation, error) {
+	rt := newOfflineRouting(routes)
+	defer rt.Close()
+	return rt.Explain(er)
+}
+
+func printExplanation(e *routing.MatchExplanation) {
+	if e.RouteID == "" {
+		fmt.Fprintln(stdout, "no route matched")
+	} else {
+		fmt.Fprintf(stdout, "matched route: %s\n", e.RouteID)
+		keys := make([]string, 0, len(e.Params))
+		for k := range e.Params {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(stdout, "  param %s: %s\n", k, e.Params[k])
+		}
+	}
+
+	if len(e.Candidates) == 0 {
+		return
+	}
+
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "candidates:")
+	for _, c := range e.Candidates {
+		if c.Matched {
+			fmt.Fprintf(stdout, "  %s: matched\n", c.RouteID)
+			continue
+		}
+
+		fmt.Fprintf(stdout, "  %s: rejected by %s, %s\n", c.RouteID, c.RejectedBy, c.Reason)
+	}
+}
+
+// command executed for explain.
+func explainCmd(a cmdArgs) error {
+	routes, err := loadRoutesChecked(a.in)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRepeatedRouteIds(routes); err != nil {
+		return err
+	}
+
+	e, err := explainRoutes(routes, routing.ExplainRequest{
+		Method:     explainMethod,
+		Host:       explainHost,
+		Path:       explainPath,
+		Headers:    explainHeaders.header(),
+		RemoteAddr: explainRemoteAddr,
+	})
+	if err != nil {
+		return err
+	}
+
+	if printJson {
+		enc := json.NewEncoder(stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(e)
+	}
+
+	printExplanation(e)
+	return nil
+}
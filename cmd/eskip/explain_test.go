@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+const explainTestRoutes = `
+	api: Path("/api/:id") && Method("POST") -> "https://api.example.org";
+	cookie: PathSubtree("/") && Cookie("foo", "bar") -> <shunt>;
+	catchAll: * -> <shunt>;
+`
+
+func TestExplain(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		eskip    string
+		method   string
+		path     string
+		headers  []string
+		err      bool
+		expected string
+	}{{
+		msg:   "invalid routes",
+		eskip: "not an eskip document",
+		err:   true,
+	}, {
+		msg:    "matching route with params",
+		eskip:  explainTestRoutes,
+		method: "POST",
+		path:   "/api/42",
+		expected: `matched route: api
+  param id: 42
+
+candidates:
+  api: matched
+`,
+	}, {
+		msg:    "rejected candidates",
+		eskip:  explainTestRoutes,
+		method: "GET",
+		path:   "/api/42",
+		expected: `matched route: catchAll
+
+candidates:
+  api: rejected by Method("POST"), method is "GET"
+  cookie: rejected by Cookie("foo", "bar"), predicate not matched
+  catchAll: matched
+`,
+	}, {
+		msg:     "custom predicate",
+		eskip:   explainTestRoutes,
+		method:  "GET",
+		path:    "/foo",
+		headers: []string{"Cookie: foo=bar"},
+		expected: `matched route: cookie
+  param *: /foo
+
+candidates:
+  cookie: matched
+`,
+	}, {
+		msg:      "no match",
+		eskip:    `Path("/foo") -> <shunt>`,
+		path:     "/bar",
+		expected: "no route matched\n",
+	}} {
+		t.Run(ti.msg, func(t *testing.T) {
+			preserveOut := stdout
+			defer func() { stdout = preserveOut }()
+			buf := &bytes.Buffer{}
+			stdout = buf
+
+			explainMethod, explainPath = ti.method, ti.path
+			explainHeaders = &headerFlags{}
+			for _, h := range ti.headers {
+				if err := explainHeaders.Set(h); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := explainCmd(cmdArgs{in: &medium{typ: inline, eskip: ti.eskip}})
+			if ti.err {
+				if err == nil {
+					t.Error("failed to fail")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != ti.expected {
+				t.Errorf("invalid output, expected:\n%s\ngot:\n%s", ti.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestInvalidHeaderFlag(t *testing.T) {
+	if err := (&headerFlags{}).Set("X-Foo"); err != invalidHeader {
+		t.Error("failed to fail")
+	}
+}
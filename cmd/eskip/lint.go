@@ -0,0 +1,725 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+
+	checkParseError       = "parse-error"
+	checkDuplicateID      = "duplicate-id"
+	checkUnknownPredicate = "unknown-predicate"
+	checkUnknownFilter    = "unknown-filter"
+	checkInvalidRoute     = "invalid-route"
+	checkMissingBackend   = "missing-backend"
+	checkShadowedRoute    = "shadowed-route"
+	checkHostConflict     = "host-conflict"
+	checkLoopbackCycle    = "loopback-cycle"
+
+	// the default max number of loopbacks in skipper
+	maxLintLoopbacks = 9
+
+	// max number of sample strings generated from a regexp
+	maxRegexpSamples = 16
+)
+
+var lintErrors = errors.New("lint errors found")
+
+// predicates handled by the routing itself, without predicate specs
+var routingPredicates = map[string]bool{
+	predicates.PathName:         true,
+	predicates.PathSubtreeName:  true,
+	predicates.PathRegexpName:   true,
+	predicates.HostName:         true,
+	predicates.MethodName:       true,
+	predicates.HeaderName:       true,
+	predicates.HeaderRegexpName: true,
+	predicates.WeightName:       true,
+}
+
+// filters not changing the request, when following the loopbacks
+var loopbackNeutralFilters = map[string]bool{
+	"status":          true,
+	"tracingTag":      true,
+	"tracingSpanName": true,
+	"stateBagToTag":   true,
+	"logHeader":       true,
+	"preserveHost":    true,
+	"flowId":          true,
+}
+
+type lintIssue struct {
+	Source   string `json:"source"`
+	RouteID  string `json:"route_id,omitempty"`
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// the conditions of a route, relevant when comparing the routes with the
+// same tree predicate
+type routeConditions struct {
+	tree   string
+	hosts  []string
+	other  []string
+	weight int
+}
+
+type lintRoute struct {
+	source     string
+	route      *eskip.Route
+	conditions routeConditions
+	valid      bool
+}
+
+type linter struct {
+	options    routing.Options
+	predicates map[string]bool
+	routes     []*lintRoute
+	issues     []lintIssue
+}
+
+func mediumName(m *medium) string {
+	switch m.typ {
+	case file:
+		return m.path
+	case stdin:
+		return "stdin"
+	case inline:
+		return "inline"
+	case etcd:
+		return "etcd"
+	case innkeeper:
+		return "innkeeper"
+	case remote:
+		return m.urls[0].String()
+	default:
+		return "unknown"
+	}
+}
+
+func newLinter() *linter {
+	l := &linter{options: offlineRoutingOptions(), predicates: make(map[string]bool)}
+	for name := range routingPredicates {
+		l.predicates[name] = true
+	}
+
+	for _, spec := range l.options.Predicates {
+		l.predicates[spec.Name()] = true
+	}
+
+	return l
+}
+
+func (l *linter) report(source, routeID, check, severity, format string, args ...interface{}) {
+	l.issues = append(l.issues, lintIssue{
+		Source:   source,
+		RouteID:  routeID,
+		Check:    check,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) load(m *medium) {
+	source := mediumName(m)
+	lr, err := loadRoutes(m)
+	if err != nil {
+		l.report(source, "", checkParseError, lintError, "%v", err)
+	}
+
+	for _, r := range lr.routes {
+		if perr, ok := lr.parseErrors[r.Id]; ok {
+			l.report(source, r.Id, checkParseError, lintError, "%v", perr)
+			continue
+		}
+
+		l.routes = append(l.routes, &lintRoute{source: source, route: r})
+	}
+}
+
+func (l *linter) checkDuplicateIDs() {
+	sources := make(map[string]string)
+	for _, lr := range l.routes {
+		if s, ok := sources[lr.route.Id]; ok {
+			l.report(lr.source, lr.route.Id, checkDuplicateID, lintError, "route id already defined in %s", s)
+			continue
+		}
+
+		sources[lr.route.Id] = lr.source
+	}
+}
+
+func checkLBEndpoints(r *eskip.Route) error {
+	if len(r.LBEndpoints) == 0 {
+		return errors.New("load balanced route without endpoints")
+	}
+
+	for _, ep := range r.LBEndpoints {
+		u, err := url.ParseRequestURI(ep)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid endpoint: %s", ep)
+		}
+	}
+
+	return nil
+}
+
+// checks the route the same way as the routing does, and reports the unknown
+// predicates and filters separately
+func (l *linter) checkRoute(lr *lintRoute) {
+	r := lr.route
+	unknown := false
+	for _, p := range r.Predicates {
+		if !l.predicates[p.Name] {
+			l.report(lr.source, r.Id, checkUnknownPredicate, lintError, "predicate %q not found", p.Name)
+			unknown = true
+		}
+	}
+
+	for _, f := range r.Filters {
+		if _, ok := l.options.FilterRegistry[f.Name]; !ok {
+			l.report(lr.source, r.Id, checkUnknownFilter, lintError, "filter %q not found", f.Name)
+			unknown = true
+		}
+	}
+
+	if unknown {
+		return
+	}
+
+	if err := routing.ValidateRoute(l.options, r); err != nil {
+		l.report(lr.source, r.Id, checkInvalidRoute, lintError, "%v", err)
+		return
+	}
+
+	if r.BackendType == eskip.LBBackend {
+		if err := checkLBEndpoints(r); err != nil {
+			l.report(lr.source, r.Id, checkMissingBackend, lintError, "%v", err)
+			return
+		}
+	}
+
+	lr.valid = true
+	lr.conditions = conditions(r)
+}
+
+func conditions(r *eskip.Route) routeConditions {
+	var c routeConditions
+	for _, p := range eskip.Canonical(r).Predicates {
+		switch p.Name {
+		case predicates.PathName, predicates.PathSubtreeName:
+			c.tree = p.String()
+			continue
+		case predicates.WeightName:
+			if len(p.Args) == 1 {
+				if w, ok := p.Args[0].(float64); ok {
+					c.weight += int(w)
+				}
+			}
+
+			continue
+		case predicates.HostName:
+			if len(p.Args) == 1 {
+				if h, ok := p.Args[0].(string); ok {
+					c.hosts = append(c.hosts, h)
+				}
+			}
+		default:
+			c.other = append(c.other, p.String())
+		}
+
+		c.weight++
+	}
+
+	sort.Strings(c.hosts)
+	sort.Strings(c.other)
+	return c
+}
+
+// tells if every item of a is contained in b, counting the repetitions
+func subset(a, b []string) bool {
+	count := make(map[string]int)
+	for _, s := range b {
+		count[s]++
+	}
+
+	for _, s := range a {
+		if count[s] == 0 {
+			return false
+		}
+
+		count[s]--
+	}
+
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	return len(a) == len(b) && subset(a, b)
+}
+
+// shadows tells if every request matching b is matched by a, while a takes
+// precedence, or with equal conditions, when the order is undefined
+func shadows(a, b routeConditions) bool {
+	if a.tree != b.tree || a.weight < b.weight {
+		return false
+	}
+
+	return subset(a.hosts, b.hosts) && subset(a.other, b.other)
+}
+
+func (l *linter) validRoutes() []*lintRoute {
+	var valid []*lintRoute
+	for _, lr := range l.routes {
+		if lr.valid {
+			valid = append(valid, lr)
+		}
+	}
+
+	return valid
+}
+
+func (l *linter) checkShadowedRoutes(routes []*lintRoute) {
+	for i, b := range routes {
+		for j, a := range routes {
+			if i == j || !shadows(a.conditions, b.conditions) {
+				continue
+			}
+
+			// with equal conditions, only the later route is reported
+			if j > i && shadows(b.conditions, a.conditions) {
+				continue
+			}
+
+			l.report(b.source, b.route.Id, checkShadowedRoute, lintWarning,
+				"route can never match, because route %s matches the same requests with higher or equal priority", a.route.Id)
+			break
+		}
+	}
+}
+
+func regexpSamples(re *syntax.Regexp) []string {
+	limit := func(s []string) []string {
+		if len(s) > maxRegexpSamples {
+			return s[:maxRegexpSamples]
+		}
+
+		return s
+	}
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return nil
+		}
+
+		return []string{string(re.Rune[0])}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return []string{"a"}
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexpSamples(re.Sub[0])
+	case syntax.OpStar, syntax.OpQuest:
+		return []string{""}
+	case syntax.OpRepeat:
+		samples := []string{""}
+		for i := 0; i < re.Min; i++ {
+			samples = concatSamples(samples, regexpSamples(re.Sub[0]))
+		}
+
+		return limit(samples)
+	case syntax.OpConcat:
+		samples := []string{""}
+		for _, sub := range re.Sub {
+			samples = limit(concatSamples(samples, regexpSamples(sub)))
+		}
+
+		return samples
+	case syntax.OpAlternate:
+		var samples []string
+		for _, sub := range re.Sub {
+			samples = append(samples, regexpSamples(sub)...)
+		}
+
+		return limit(samples)
+	default:
+		// empty matches, anchors and word boundaries
+		return []string{""}
+	}
+}
+
+func concatSamples(left, right []string) []string {
+	var samples []string
+	for _, l := range left {
+		for _, r := range right {
+			samples = append(samples, l+r)
+		}
+	}
+
+	return samples
+}
+
+func matchAll(rxs []*regexp.Regexp, s string) bool {
+	for _, rx := range rxs {
+		if !rx.MatchString(s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hostSamples returns host names matching all the host regexps
+func hostSamples(hosts []string) []string {
+	rxs := make([]*regexp.Regexp, 0, len(hosts))
+	for _, h := range hosts {
+		rx, err := regexp.Compile(h)
+		if err != nil {
+			return nil
+		}
+
+		rxs = append(rxs, rx)
+	}
+
+	var samples []string
+	for _, h := range hosts {
+		re, err := syntax.Parse(h, syntax.Perl)
+		if err != nil {
+			return nil
+		}
+
+		for _, s := range regexpSamples(re.Simplify()) {
+			if s != "" && matchAll(rxs, s) {
+				samples = append(samples, s)
+			}
+		}
+	}
+
+	return samples
+}
+
+// hostConflict returns a host matched by both routes, when the routes
+// differ only in their host regexps
+func hostConflict(a, b routeConditions) (string, bool) {
+	if a.tree != b.tree || a.weight != b.weight || len(a.hosts) == 0 || len(b.hosts) == 0 ||
+		!equalStrings(a.other, b.other) || equalStrings(a.hosts, b.hosts) {
+		return "", false
+	}
+
+	arxs, brxs := compileAll(a.hosts), compileAll(b.hosts)
+	for _, s := range append(hostSamples(a.hosts), hostSamples(b.hosts)...) {
+		if matchAll(arxs, s) && matchAll(brxs, s) {
+			return s, true
+		}
+	}
+
+	return "", false
+}
+
+func compileAll(exps []string) []*regexp.Regexp {
+	rxs := make([]*regexp.Regexp, 0, len(exps))
+	for _, e := range exps {
+		if rx, err := regexp.Compile(e); err == nil {
+			rxs = append(rxs, rx)
+		}
+	}
+
+	return rxs
+}
+
+func (l *linter) checkHostConflicts(routes []*lintRoute) {
+	for i, b := range routes {
+		for _, a := range routes[:i] {
+			if h, ok := hostConflict(a.conditions, b.conditions); ok {
+				l.report(b.source, b.route.Id, checkHostConflict, lintWarning,
+					"host regexps conflict with route %s, both match the host %s", a.route.Id, h)
+				break
+			}
+		}
+	}
+}
+
+var pathWildcard = regexp.MustCompile(`^[:*]`)
+
+// sampleRequest creates a request matching the predicates of the routing,
+// the custom predicates are not considered
+func sampleRequest(r *eskip.Route) *http.Request {
+	req := &http.Request{Method: "GET", URL: &url.URL{Path: "/"}, Header: make(http.Header)}
+	var hosts []string
+	for _, p := range eskip.Canonical(r).Predicates {
+		args := make([]string, len(p.Args))
+		for i, a := range p.Args {
+			s, ok := a.(string)
+			if !ok {
+				continue
+			}
+
+			args[i] = s
+		}
+
+		switch {
+		case (p.Name == predicates.PathName || p.Name == predicates.PathSubtreeName) && len(args) == 1:
+			segments := strings.Split(args[0], "/")
+			for i, s := range segments {
+				if pathWildcard.MatchString(s) {
+					segments[i] = "x"
+				}
+			}
+
+			req.URL.Path = strings.Join(segments, "/")
+		case p.Name == predicates.MethodName && len(args) == 1:
+			req.Method = args[0]
+		case p.Name == predicates.HostName && len(args) == 1:
+			hosts = append(hosts, args[0])
+		case p.Name == predicates.HeaderName && len(args) == 2:
+			req.Header.Set(args[0], args[1])
+		case p.Name == predicates.HeaderRegexpName && len(args) == 2:
+			re, err := syntax.Parse(args[1], syntax.Perl)
+			if err != nil {
+				return nil
+			}
+
+			samples := regexpSamples(re.Simplify())
+			if len(samples) == 0 {
+				return nil
+			}
+
+			req.Header.Set(args[0], samples[0])
+		}
+	}
+
+	if len(hosts) > 0 {
+		samples := hostSamples(hosts)
+		if len(samples) == 0 {
+			return nil
+		}
+
+		req.Host = samples[0]
+	}
+
+	return req
+}
+
+func stringArgs(f *eskip.Filter, n int) ([]string, bool) {
+	if len(f.Args) != n {
+		return nil, false
+	}
+
+	args := make([]string, n)
+	for i, a := range f.Args {
+		s, ok := a.(string)
+		if !ok || strings.Contains(s, "${") {
+			return nil, false
+		}
+
+		args[i] = s
+	}
+
+	return args, true
+}
+
+// applyLoopbackFilters applies the effect of the filters on the request
+// matching, and returns false when the effect of a filter is not known
+func applyLoopbackFilters(r *eskip.Route, req *http.Request) bool {
+	for _, f := range r.Filters {
+		switch f.Name {
+		case "setPath":
+			args, ok := stringArgs(f, 1)
+			if !ok {
+				return false
+			}
+
+			req.URL.Path = args[0]
+		case "modPath":
+			args, ok := stringArgs(f, 2)
+			if !ok {
+				return false
+			}
+
+			rx, err := regexp.Compile(args[0])
+			if err != nil {
+				return false
+			}
+
+			req.URL.Path = rx.ReplaceAllString(req.URL.Path, args[1])
+		case "setRequestHeader", "appendRequestHeader":
+			args, ok := stringArgs(f, 2)
+			if !ok {
+				return false
+			}
+
+			if f.Name == "setRequestHeader" {
+				req.Header.Set(args[0], args[1])
+			} else {
+				req.Header.Add(args[0], args[1])
+			}
+		case "dropRequestHeader":
+			args, ok := stringArgs(f, 1)
+			if !ok {
+				return false
+			}
+
+			req.Header.Del(args[0])
+		default:
+			if !loopbackNeutralFilters[f.Name] && !strings.Contains(f.Name, "Response") {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// checkLoopbackCycles follows the loopback routes with a request matching
+// them, and reports the loops that always lead back to the same route
+func (l *linter) checkLoopbackCycles(routes []*lintRoute) {
+	var (
+		defs    []*eskip.Route
+		sources = make(map[string]string)
+	)
+
+	for _, lr := range routes {
+		defs = append(defs, lr.route)
+		sources[lr.route.Id] = lr.source
+	}
+
+	rt := newOfflineRouting(defs)
+	defer rt.Close()
+
+	reported := make(map[string]bool)
+	for _, lr := range routes {
+		if lr.route.BackendType != eskip.LoopBackend {
+			continue
+		}
+
+		req := sampleRequest(lr.route)
+		if req == nil {
+			continue
+		}
+
+		if r, _ := rt.Route(req); r == nil || r.Id != lr.route.Id {
+			continue
+		}
+
+		path := []string{lr.route.Id}
+		current := lr.route
+		for i := 0; i < maxLintLoopbacks; i++ {
+			if !applyLoopbackFilters(current, req) {
+				break
+			}
+
+			next, _ := rt.Route(req)
+			if next == nil || next.BackendType != eskip.LoopBackend {
+				break
+			}
+
+			cycleStart := -1
+			for j, id := range path {
+				if id == next.Id {
+					cycleStart = j
+					break
+				}
+			}
+
+			if cycleStart >= 0 {
+				cycle := append(path[cycleStart:], next.Id)
+				key := append([]string(nil), cycle[1:]...)
+				sort.Strings(key)
+				if k := strings.Join(key, ","); !reported[k] {
+					reported[k] = true
+					l.report(sources[cycle[0]], cycle[0], checkLoopbackCycle, lintError,
+						"loopback cycle: %s", strings.Join(cycle, " -> "))
+				}
+
+				break
+			}
+
+			path = append(path, next.Id)
+			current = &next.Route
+		}
+	}
+}
+
+func (l *linter) run() []lintIssue {
+	l.checkDuplicateIDs()
+	seen := make(map[string]bool)
+	for _, lr := range l.routes {
+		if seen[lr.route.Id] {
+			continue
+		}
+
+		seen[lr.route.Id] = true
+		l.checkRoute(lr)
+	}
+
+	valid := l.validRoutes()
+	l.checkShadowedRoutes(valid)
+	l.checkHostConflicts(valid)
+	l.checkLoopbackCycles(valid)
+	return l.issues
+}
+
+func printLintIssues(issues []lintIssue) error {
+	if printJson {
+		if issues == nil {
+			issues = []lintIssue{}
+		}
+
+		enc := json.NewEncoder(stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(issues)
+	}
+
+	for _, i := range issues {
+		if i.RouteID == "" {
+			fmt.Fprintf(stdout, "%s: %s: %s: %s\n", i.Source, i.Severity, i.Check, i.Message)
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s: %s: %s: %s: %s\n", i.Source, i.RouteID, i.Severity, i.Check, i.Message)
+	}
+
+	return nil
+}
+
+// command executed for lint.
+func lintCmd(a cmdArgs) error {
+	media := a.allMedia
+	if len(media) == 0 && a.in != nil {
+		media = []*medium{a.in}
+	}
+
+	l := newLinter()
+	for _, m := range media {
+		l.load(m)
+	}
+
+	issues := l.run()
+	if err := printLintIssues(issues); err != nil {
+		return err
+	}
+
+	for _, i := range issues {
+		if i.Severity == lintError {
+			return lintErrors
+		}
+	}
+
+	return nil
+}
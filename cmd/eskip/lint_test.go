@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestLint(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		routes   []string
+		err      bool
+		expected []lintIssue
+	}{{
+		msg:    "valid routes",
+		routes: []string{`r1: Path("/foo") -> setPath("/bar") -> "https://www.example.org"; r2: * -> <shunt>`},
+	}, {
+		msg:    "parse error",
+		routes: []string{`not an eskip document`},
+		err:    true,
+		expected: []lintIssue{
+			{Check: checkParseError, Severity: lintError},
+		},
+	}, {
+		msg:    "duplicate ids across inputs",
+		routes: []string{`r1: * -> <shunt>`, `r1: Path("/foo") -> <shunt>`},
+		err:    true,
+		expected: []lintIssue{
+			{RouteID: "r1", Check: checkDuplicateID, Severity: lintError},
+		},
+	}, {
+		msg:    "unknown predicate",
+		routes: []string{`r1: Foo() -> <shunt>`},
+		err:    true,
+		expected: []lintIssue{
+			{RouteID: "r1", Check: checkUnknownPredicate, Severity: lintError, Message: `predicate "Foo" not found`},
+		},
+	}, {
+		msg:    "unknown filter",
+		routes: []string{`r1: * -> foo() -> <shunt>`},
+		err:    true,
+		expected: []lintIssue{
+			{RouteID: "r1", Check: checkUnknownFilter, Severity: lintError, Message: `filter "foo" not found`},
+		},
+	}, {
+		msg:    "invalid filter arguments",
+		routes: []string{`r1: * -> setPath(42) -> <shunt>`},
+		err:    true,
+		expected: []lintIssue{
+			{RouteID: "r1", Check: checkInvalidRoute, Severity: lintError},
+		},
+	}, {
+		msg:    "runtime configured filters are accepted",
+		routes: []string{`r1: * -> oauthTokeninfoAnyScope("read") -> clusterClientRatelimit("foo", 10, "1m") -> <shunt>`},
+	}, {
+		msg: "shadowed route",
+		routes: []string{`
+			r1: Path("/foo") && Method("GET") -> <shunt>;
+			r2: Path("/foo") && Method("GET") -> "https://www.example.org";
+			r3: Path("/foo") && Method("GET") && Header("X-Foo", "bar") -> <shunt>;
+			r4: Path("/foo") && Header("X-Foo", "baz") && Weight(3) -> <shunt>;
+			r5: Path("/foo") && Header("X-Foo", "baz") && Method("POST") -> <shunt>;
+		`},
+		expected: []lintIssue{
+			{RouteID: "r2", Check: checkShadowedRoute, Severity: lintWarning, Message: "route can never match, because route r1 matches the same requests with higher or equal priority"},
+			{RouteID: "r5", Check: checkShadowedRoute, Severity: lintWarning, Message: "route can never match, because route r4 matches the same requests with higher or equal priority"},
+		},
+	}, {
+		msg: "conflicting host regexps",
+		routes: []string{`
+			r1: Path("/foo") && Host(/^(www|api)[.]example[.]org$/) -> <shunt>;
+			r2: Path("/foo") && Host(/^api[.]example[.]org$/) -> <shunt>;
+			r3: Path("/foo") && Host(/^www[.]example[.]com$/) -> <shunt>;
+		`},
+		expected: []lintIssue{
+			{RouteID: "r2", Check: checkHostConflict, Severity: lintWarning, Message: "host regexps conflict with route r1, both match the host api.example.org"},
+		},
+	}, {
+		msg: "loopback cycle",
+		routes: []string{`
+			r1: Path("/foo") -> setPath("/bar") -> <loopback>;
+			r2: Path("/bar") -> setRequestHeader("X-Foo", "bar") -> modPath("^/bar", "/foo") -> <loopback>;
+			r3: Path("/baz") -> <loopback>;
+			r4: Path("/qux") -> setPath("/quux") -> <loopback>;
+			r5: Path("/quux") -> <shunt>;
+		`},
+		err: true,
+		expected: []lintIssue{
+			{RouteID: "r1", Check: checkLoopbackCycle, Severity: lintError, Message: "loopback cycle: r1 -> r2 -> r1"},
+			{RouteID: "r3", Check: checkLoopbackCycle, Severity: lintError, Message: "loopback cycle: r3 -> r3"},
+		},
+	}} {
+		t.Run(ti.msg, func(t *testing.T) {
+			preserveOut := stdout
+			preserveJson := printJson
+			defer func() {
+				stdout = preserveOut
+				printJson = preserveJson
+			}()
+
+			buf := &bytes.Buffer{}
+			stdout = buf
+			printJson = true
+
+			var media []*medium
+			for _, r := range ti.routes {
+				media = append(media, &medium{typ: inline, eskip: r})
+			}
+
+			err := lintCmd(cmdArgs{allMedia: media})
+			if ti.err && err != lintErrors || !ti.err && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			var issues []lintIssue
+			if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(issues) != len(ti.expected) {
+				t.Fatalf("invalid issues, expected: %v, got: %v", ti.expected, issues)
+			}
+
+			for i, expected := range ti.expected {
+				got := issues[i]
+				if got.Source != "inline" || got.RouteID != expected.RouteID || got.Check != expected.Check ||
+					got.Severity != expected.Severity || expected.Message != "" && got.Message != expected.Message {
+					t.Errorf("invalid issue, expected: %v, got: %v", expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLintMissingBackend(t *testing.T) {
+	l := newLinter()
+	for _, r := range []*eskip.Route{{
+		Id:          "r1",
+		BackendType: eskip.LBBackend,
+	}, {
+		Id:          "r2",
+		BackendType: eskip.LBBackend,
+		LBEndpoints: []string{"https://www.example.org", "www.example.org"},
+	}} {
+		l.routes = append(l.routes, &lintRoute{source: "test", route: r})
+	}
+
+	issues := l.run()
+	if len(issues) != 2 {
+		t.Fatalf("invalid issues: %v", issues)
+	}
+
+	for _, i := range issues {
+		if i.Check != checkMissingBackend {
+			t.Errorf("invalid issue: %v", i)
+		}
+	}
+}
+
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	f1, f2 := filepath.Join(dir, "routes1.eskip"), filepath.Join(dir, "routes2.eskip")
+	if err := os.WriteFile(f1, []byte(`r1: * -> <shunt>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(f2, []byte(`r1: Path("/foo") -> <shunt>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	preserveOut := stdout
+	defer func() { stdout = preserveOut }()
+	buf := &bytes.Buffer{}
+	stdout = buf
+
+	err := lintCmd(cmdArgs{allMedia: []*medium{{typ: file, path: f1}, {typ: file, path: f2}}})
+	if err != lintErrors {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expected := f2 + ": r1: error: duplicate-id: route id already defined in " + f1 + "\n"
+	if buf.String() != expected {
+		t.Errorf("invalid output, expected: %s, got: %s", expected, buf.String())
+	}
+}
@@ -284,3 +284,41 @@ func TestPatch(t *testing.T) {
 		}
 	}
 }
+
+func TestPrintReferences(t *testing.T) {
+	doc := `@auth = oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m"); r0: Path("/foo") -> @auth -> <shunt>`
+	for _, ti := range []struct {
+		msg      string
+		expand   bool
+		expected string
+	}{{
+		msg: "preserved",
+		expected: `@auth = oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m");
+r0: Path("/foo") -> @auth -> <shunt>;`,
+	}, {
+		msg:      "expanded",
+		expand:   true,
+		expected: `r0: Path("/foo") -> oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m") -> <shunt>;`,
+	}} {
+		t.Run(ti.msg, func(t *testing.T) {
+			preserveOut := stdout
+			preserveExpand := expand
+			defer func() {
+				stdout = preserveOut
+				expand = preserveExpand
+			}()
+
+			buf := &bytes.Buffer{}
+			stdout = buf
+			expand = ti.expand
+
+			if err := printCmd(cmdArgs{in: &medium{typ: inline, eskip: doc}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != ti.expected {
+				t.Errorf("invalid output, expected: %s, got: %s", ti.expected, buf.String())
+			}
+		})
+	}
+}
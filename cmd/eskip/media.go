@@ -35,15 +35,20 @@ const (
 	patchPrependFile
 	patchAppend
 	patchAppendFile
+	remote
 )
 
 var commandToValidations = map[command]validateSelectFunc{
-	check:  validateSelectRead,
-	print:  validateSelectRead,
-	upsert: validateSelectWrite,
-	reset:  validateSelectWrite,
-	delete: validateSelectDelete,
-	patch:  validateSelectPatch}
+	check:   validateSelectRead,
+	print:   validateSelectRead,
+	upsert:  validateSelectWrite,
+	reset:   validateSelectWrite,
+	delete:  validateSelectDelete,
+	patch:   validateSelectPatch,
+	explain: validateSelectRead,
+	lint:    validateSelectLint,
+	diff:    validateSelectDiff,
+	test:    validateSelectTest}
 
 type medium struct {
 	typ          mediaType
@@ -151,6 +156,75 @@ func validateSelectDelete(media []*medium) (a cmdArgs, err error) {
 	return
 }
 
+// validate media for lint, accepting multiple inputs, e.g. files.
+func validateSelectLint(media []*medium) (a cmdArgs, err error) {
+	for _, m := range media {
+		switch m.typ {
+		case inlineIds, patchPrepend, patchPrependFile, patchAppend, patchAppendFile:
+			err = invalidInputType
+			return
+		}
+	}
+
+	if len(media) > 0 {
+		a.in = media[0]
+	}
+
+	return
+}
+
+// validate media for diff, expecting exactly two inputs.
+func validateSelectDiff(media []*medium) (a cmdArgs, err error) {
+	if len(media) < 2 {
+		err = missingInput
+		return
+	}
+
+	if len(media) > 2 {
+		err = tooManyInputs
+		return
+	}
+
+	for _, m := range media {
+		switch m.typ {
+		case inlineIds, patchPrepend, patchPrependFile, patchAppend, patchAppendFile:
+			err = invalidInputType
+			return
+		}
+	}
+
+	a.in = media[0]
+	return
+}
+
+// validate media for test, expecting the routes, and the test cases in
+// the last file.
+func validateSelectTest(media []*medium) (a cmdArgs, err error) {
+	if len(media) < 2 {
+		err = missingInput
+		return
+	}
+
+	if len(media) > 2 {
+		err = tooManyInputs
+		return
+	}
+
+	switch media[0].typ {
+	case inlineIds, patchPrepend, patchPrependFile, patchAppend, patchAppendFile:
+		err = invalidInputType
+		return
+	}
+
+	if media[1].typ != file {
+		err = invalidInputType
+		return
+	}
+
+	a.in = media[0]
+	return
+}
+
 func validateSelectPatch(media []*medium) (a cmdArgs, err error) {
 	for _, m := range media {
 		switch m.typ {
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/loadbalancer"
+	"github.com/zalando/skipper/predicates/auth"
+	"github.com/zalando/skipper/predicates/clientcert"
+	"github.com/zalando/skipper/predicates/cookie"
+	"github.com/zalando/skipper/predicates/cron"
+	"github.com/zalando/skipper/predicates/forwarded"
+	"github.com/zalando/skipper/predicates/host"
+	"github.com/zalando/skipper/predicates/interval"
+	"github.com/zalando/skipper/predicates/methods"
+	"github.com/zalando/skipper/predicates/primitive"
+	"github.com/zalando/skipper/predicates/query"
+	"github.com/zalando/skipper/predicates/source"
+	"github.com/zalando/skipper/predicates/tee"
+	"github.com/zalando/skipper/predicates/traffic"
+	"github.com/zalando/skipper/routing"
+)
+
+// the filters that skipper registers only with runtime configuration,
+// e.g. with the address of the tokeninfo service or a ratelimit registry.
+// Offline, their arguments are not checked.
+var runtimeFilters = []string{
+	filters.WebhookName,
+	filters.ExternalAuthzName,
+	filters.OpaAuthorizeRequestName,
+	filters.OAuthTokeninfoAnyScopeName,
+	filters.OAuthTokeninfoAllScopeName,
+	filters.OAuthTokeninfoAnyKVName,
+	filters.OAuthTokeninfoAllKVName,
+	filters.OAuthTokenintrospectionAnyClaimsName,
+	filters.OAuthTokenintrospectionAllClaimsName,
+	filters.OAuthTokenintrospectionAnyKVName,
+	filters.OAuthTokenintrospectionAllKVName,
+	filters.SecureOAuthTokenintrospectionAnyClaimsName,
+	filters.SecureOAuthTokenintrospectionAllClaimsName,
+	filters.SecureOAuthTokenintrospectionAnyKVName,
+	filters.SecureOAuthTokenintrospectionAllKVName,
+	filters.SignJwtName,
+	filters.TokenExchangeName,
+	filters.ApiKeyName,
+	filters.OAuthGrantName,
+	filters.GrantCallbackName,
+	filters.GrantLogoutName,
+	filters.GrantClaimsQueryName,
+	filters.JwtValidationName,
+	filters.OAuthOidcUserInfoName,
+	filters.OAuthOidcAnyClaimsName,
+	filters.OAuthOidcAllClaimsName,
+	filters.OidcClaimsQueryName,
+	filters.ClientRatelimitName,
+	filters.RatelimitName,
+	filters.ClusterClientRatelimitName,
+	filters.ClusterRatelimitName,
+	filters.ClusterLeakyBucketRatelimitName,
+	filters.ClusterClientQuotaName,
+	filters.BackendRateLimitName,
+	filters.DisableRatelimitName,
+	filters.AuditLogName,
+	filters.ApiUsageMonitoringName,
+	filters.BearerInjectorName,
+	filters.BackendTLSName,
+}
+
+type runtimeFilterSpec struct {
+	name string
+}
+
+type runtimeFilter struct{}
+
+func (s runtimeFilterSpec) Name() string { return s.name }
+
+func (s runtimeFilterSpec) CreateFilter([]interface{}) (filters.Filter, error) {
+	return runtimeFilter{}, nil
+}
+
+func (runtimeFilter) Request(filters.FilterContext)  {}
+func (runtimeFilter) Response(filters.FilterContext) {}
+
+// staticDataClient serves the loaded routes to the routing, without updates.
+type staticDataClient struct {
+	routes []*eskip.Route
+}
+
+func (c *staticDataClient) LoadAll() ([]*eskip.Route, error)              { return c.routes, nil }
+func (c *staticDataClient) LoadUpdate() ([]*eskip.Route, []string, error) { return nil, nil, nil }
+
+// the builtin filters, and the filters requiring runtime configuration
+func offlineFilters() filters.Registry {
+	fr := builtin.MakeRegistry()
+	for _, name := range runtimeFilters {
+		if _, ok := fr[name]; !ok {
+			fr.Register(runtimeFilterSpec{name: name})
+		}
+	}
+
+	return fr
+}
+
+// the predicates available by default in skipper, that don't require
+// configuration
+func offlinePredicates() []routing.PredicateSpec {
+	return []routing.PredicateSpec{
+		source.New(),
+		source.NewFromLast(),
+		source.NewClientIP(),
+		interval.NewBetween(),
+		interval.NewBefore(),
+		interval.NewAfter(),
+		cron.New(),
+		cookie.New(),
+		query.New(),
+		traffic.New(),
+		primitive.NewTrue(),
+		primitive.NewFalse(),
+		primitive.NewShutdown(),
+		auth.NewJWTPayloadAllKV(),
+		auth.NewJWTPayloadAnyKV(),
+		auth.NewJWTPayloadAllKVRegexp(),
+		auth.NewJWTPayloadAnyKVRegexp(),
+		methods.New(),
+		tee.New(),
+		forwarded.NewForwardedHost(),
+		forwarded.NewForwardedProto(),
+		host.NewAny(),
+		clientcert.NewSubject(),
+		clientcert.NewSAN(),
+	}
+}
+
+func offlineRoutingOptions() routing.Options {
+	return routing.Options{
+		FilterRegistry: offlineFilters(),
+		Predicates:     offlinePredicates(),
+		PostProcessors: []routing.PostProcessor{loadbalancer.NewAlgorithmProvider()},
+	}
+}
+
+// newOfflineRouting creates a routing with a fixed set of routes, and
+// waits until the routes are loaded.
+func newOfflineRouting(routes []*eskip.Route) *routing.Routing {
+	return newOfflineRoutingWithOptions(offlineRoutingOptions(), routes)
+}
+
+func newOfflineRoutingWithOptions(o routing.Options, routes []*eskip.Route) *routing.Routing {
+	// only the invalid routes are reported
+	log.SetLevel(log.WarnLevel)
+
+	o.DataClients = []routing.DataClient{&staticDataClient{routes: routes}}
+	o.PollTimeout = time.Hour
+	o.SignalFirstLoad = true
+	rt := routing.New(o)
+	<-rt.FirstLoad()
+	return rt
+}
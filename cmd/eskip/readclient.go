@@ -1,8 +1,14 @@
 package main
 
 import (
+	"crypto/tls"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/zalando/skipper/eskip"
 	"github.com/zalando/skipper/eskipfile"
@@ -26,6 +32,13 @@ type idsReader struct {
 	ids []string
 }
 
+// remoteReader loads the routes from the /routes endpoint of a running
+// skipper or routesrv.
+type remoteReader struct {
+	url    *url.URL
+	client *http.Client
+}
+
 func createReadClient(m *medium) (readClient, error) {
 	// no output, no client
 	if m == nil {
@@ -55,6 +68,9 @@ func createReadClient(m *medium) (readClient, error) {
 	case inlineIds:
 		return &idsReader{ids: m.ids}, nil
 
+	case remote:
+		return newRemoteReader(m.urls[0]), nil
+
 	default:
 		return nil, invalidInputType
 	}
@@ -111,6 +127,80 @@ func (r *idsReader) LoadAndParseAll() ([]*eskip.RouteInfo, error) {
 	return routeInfos, nil
 }
 
+// the page size used when loading the routes from skipper
+const remoteRoutesLimit = 1024
+
+func newRemoteReader(u *url.URL) *remoteReader {
+	ru := *u
+	if ru.Path == "" || ru.Path == "/" {
+		ru.Path = "/routes"
+	}
+
+	return &remoteReader{
+		url: &ru,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure}},
+		},
+	}
+}
+
+// loads a page of routes, and returns the total number of routes, when
+// the response contains it
+func (r *remoteReader) loadPage(offset int) ([]*eskip.Route, int, error) {
+	u := *r.url
+	q := u.Query()
+	q.Set("offset", strconv.Itoa(offset))
+	q.Set("limit", strconv.Itoa(remoteRoutesLimit))
+	u.RawQuery = q.Encode()
+
+	rsp, err := r.client.Get(u.String())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to load routes from %s: %s", r.url, rsp.Status)
+	}
+
+	doc, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	routes, err := eskip.Parse(string(doc))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count := -1
+	if c := rsp.Header.Get("X-Count"); c != "" {
+		if count, err = strconv.Atoi(c); err != nil {
+			return nil, 0, fmt.Errorf("invalid route count from %s: %s", r.url, c)
+		}
+	}
+
+	return routes, count, nil
+}
+
+// skipper returns the routes paginated, and the total number of routes
+// in the X-Count header, while routesrv returns all the routes at once.
+func (r *remoteReader) LoadAndParseAll() ([]*eskip.RouteInfo, error) {
+	var all []*eskip.Route
+	for {
+		routes, count, err := r.loadPage(len(all))
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, routes...)
+		if count < 0 || len(routes) == 0 || len(all) >= count {
+			return routesToRouteInfos(all), nil
+		}
+	}
+}
+
 func routesToRouteInfos(routes []*eskip.Route) (routeInfos []*eskip.RouteInfo) {
 	for _, route := range routes {
 		routeInfos = append(routeInfos, &eskip.RouteInfo{Route: *route})
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/proxy"
+	"github.com/zalando/skipper/routing"
+)
+
+var (
+	noTestCases = errors.New("no test cases found")
+	testsFailed = errors.New("route tests failed")
+)
+
+type testRequest struct {
+	Method     string            `yaml:"method"`
+	URL        string            `yaml:"url"`
+	Headers    map[string]string `yaml:"headers"`
+	Body       string            `yaml:"body"`
+	RemoteAddr string            `yaml:"remoteAddr"`
+}
+
+// the response of the backend stub
+type testBackend struct {
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
+// the fields left empty are not checked
+type testExpectation struct {
+	Route           string            `yaml:"route"`
+	Backend         string            `yaml:"backend"`
+	RequestHeaders  map[string]string `yaml:"requestHeaders"`
+	Status          int               `yaml:"status"`
+	ResponseHeaders map[string]string `yaml:"responseHeaders"`
+	Body            *string           `yaml:"body"`
+}
+
+type testCase struct {
+	Name    string          `yaml:"name"`
+	Request testRequest     `yaml:"request"`
+	Backend testBackend     `yaml:"backend"`
+	Expect  testExpectation `yaml:"expect"`
+}
+
+type testResult struct {
+	Name     string   `json:"name"`
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// backendStub replaces the network backends of the proxy, records the
+// outgoing requests, and responds with the backend response defined by
+// the current test case.
+type backendStub struct {
+	mu       sync.Mutex
+	response testBackend
+	requests []*http.Request
+}
+
+func (s *backendStub) reset(response testBackend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.response = response
+	s.requests = nil
+}
+
+func (s *backendStub) firstRequest() *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return nil
+	}
+
+	return s.requests[0]
+}
+
+func (s *backendStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+
+	status := s.response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := make(http.Header)
+	for k, v := range s.response.Headers {
+		header.Set(k, v)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(s.response.Body)),
+		ContentLength: int64(len(s.response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// the filter prepended to the tested routes, recording the id of the
+// route that handled the proxied request
+const testRouteFilterName = "eskipTestRoute"
+
+type testRouteKey struct{}
+
+// testRoute holds the id of the first route that handled the request of
+// a test case. It is passed to the filter in the request context.
+type testRoute struct {
+	id string
+}
+
+type testRouteSpec struct{}
+
+type testRouteFilter struct {
+	id string
+}
+
+func (testRouteSpec) Name() string { return testRouteFilterName }
+
+func (testRouteSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) != 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	id, ok := args[0].(string)
+	if !ok {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return testRouteFilter{id: id}, nil
+}
+
+// with loopback routes, the request is handled by multiple routes, and
+// the first one is recorded
+func (f testRouteFilter) Request(ctx filters.FilterContext) {
+	if r, ok := ctx.Request().Context().Value(testRouteKey{}).(*testRoute); ok && r.id == "" {
+		r.id = f.id
+	}
+}
+
+func (testRouteFilter) Response(filters.FilterContext) {}
+
+// testRoutePreProcessor prepends the filter recording the route id to
+// each route.
+type testRoutePreProcessor struct{}
+
+func (testRoutePreProcessor) Do(routes []*eskip.Route) []*eskip.Route {
+	result := make([]*eskip.Route, len(routes))
+	for i, r := range routes {
+		rc := *r
+		rc.Filters = append([]*eskip.Filter{{Name: testRouteFilterName, Args: []interface{}{r.Id}}}, r.Filters...)
+		result[i] = &rc
+	}
+
+	return result
+}
+
+func loadTestCases(path string) ([]testCase, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []testCase
+	if err := yaml.Unmarshal(b, &cases); err != nil {
+		return nil, fmt.Errorf("failed to parse test cases from %s: %w", path, err)
+	}
+
+	if len(cases) == 0 {
+		return nil, noTestCases
+	}
+
+	for i := range cases {
+		if cases[i].Name == "" {
+			cases[i].Name = fmt.Sprintf("case %d", i+1)
+		}
+	}
+
+	return cases, nil
+}
+
+func (r testRequest) httpRequest() *http.Request {
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	target := r.URL
+	if target == "" {
+		target = "/"
+	}
+
+	req := httptest.NewRequest(method, target, strings.NewReader(r.Body))
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if h := req.Header.Get("Host"); h != "" {
+		req.Host = h
+	}
+
+	if r.RemoteAddr != "" {
+		req.RemoteAddr = r.RemoteAddr
+	}
+
+	return req
+}
+
+// compares the scheme and the host, and the path and the query only when
+// they are set in the expected URL.
+func checkBackendURL(expected string, got *url.URL) error {
+	u, err := url.Parse(expected)
+	if err != nil {
+		return fmt.Errorf("invalid expected backend: %w", err)
+	}
+
+	if u.Scheme != got.Scheme || u.Host != got.Host ||
+		u.Path != "" && u.Path != got.Path ||
+		u.RawQuery != "" && u.RawQuery != got.RawQuery {
+		return fmt.Errorf("backend: expected %s, got %s", expected, got)
+	}
+
+	return nil
+}
+
+func checkHeaders(kind string, expected map[string]string, got http.Header) []string {
+	var names []string
+	for k := range expected {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	var failures []string
+	for _, k := range names {
+		if v := got.Get(k); v != expected[k] {
+			failures = append(failures, fmt.Sprintf("%s header %s: expected %q, got %q", kind, k, expected[k], v))
+		}
+	}
+
+	return failures
+}
+
+func runTestCase(p *proxy.Proxy, stub *backendStub, c testCase) testResult {
+	result := testResult{Name: c.Name}
+	fail := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	stub.reset(c.Backend)
+	route := &testRoute{}
+	req := c.Request.httpRequest()
+	req = req.WithContext(context.WithValue(req.Context(), testRouteKey{}, route))
+	rsp := httptest.NewRecorder()
+	p.ServeHTTP(rsp, req)
+
+	if c.Expect.Route != "" && route.id != c.Expect.Route {
+		routeID := route.id
+		if routeID == "" {
+			routeID = "no match"
+		}
+
+		fail("route: expected %s, got %s", c.Expect.Route, routeID)
+	}
+
+	if c.Expect.Backend != "" || len(c.Expect.RequestHeaders) > 0 {
+		if breq := stub.firstRequest(); breq == nil {
+			fail("backend: no request was forwarded")
+		} else {
+			if c.Expect.Backend != "" {
+				if err := checkBackendURL(c.Expect.Backend, breq.URL); err != nil {
+					fail("%v", err)
+				}
+			}
+
+			result.Failures = append(result.Failures, checkHeaders("request", c.Expect.RequestHeaders, breq.Header)...)
+		}
+	}
+
+	if c.Expect.Status != 0 && rsp.Code != c.Expect.Status {
+		fail("status: expected %d, got %d", c.Expect.Status, rsp.Code)
+	}
+
+	result.Failures = append(result.Failures, checkHeaders("response", c.Expect.ResponseHeaders, rsp.Header())...)
+
+	if c.Expect.Body != nil && rsp.Body.String() != *c.Expect.Body {
+		fail("body: expected %q, got %q", *c.Expect.Body, rsp.Body.String())
+	}
+
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// runRouteTests executes the test cases against a routing and a proxy
+// created with the builtin filters and predicates of skipper, where the
+// network backends are replaced by a stub.
+func runRouteTests(routes []*eskip.Route, cases []testCase) []testResult {
+	o := offlineRoutingOptions()
+	o.FilterRegistry.Register(testRouteSpec{})
+	o.PreProcessors = []routing.PreProcessor{testRoutePreProcessor{}}
+	rt := newOfflineRoutingWithOptions(o, routes)
+	defer rt.Close()
+
+	// the invalid routes were already reported, while the errors of the
+	// proxy, e.g. when no route matched, are reported as test failures
+	log.SetLevel(log.FatalLevel)
+
+	stub := &backendStub{}
+	p := proxy.WithParams(proxy.Params{
+		Routing:              rt,
+		CloseIdleConnsPeriod: -time.Second,
+		CustomHttpRoundTripperWrap: func(http.RoundTripper) http.RoundTripper {
+			return stub
+		},
+	})
+	defer p.Close()
+
+	var results []testResult
+	for _, c := range cases {
+		results = append(results, runTestCase(p, stub, c))
+	}
+
+	return results
+}
+
+func printTestResults(results []testResult) error {
+	if printJson {
+		enc := json.NewEncoder(stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(results)
+	}
+
+	var passed, failed int
+	for _, r := range results {
+		if r.Passed {
+			passed++
+			fmt.Fprintf(stdout, "PASS: %s\n", r.Name)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(stdout, "FAIL: %s\n", r.Name)
+		for _, f := range r.Failures {
+			fmt.Fprintf(stdout, "  %s\n", f)
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d passed, %d failed\n", passed, failed)
+	return nil
+}
+
+// command executed for test.
+func testCmd(a cmdArgs) error {
+	if len(a.allMedia) != 2 {
+		return invalidNumberOfArgs
+	}
+
+	routes, err := loadRoutesChecked(a.in)
+	if err != nil {
+		return err
+	}
+
+	cases, err := loadTestCases(a.allMedia[1].path)
+	if err != nil {
+		return err
+	}
+
+	results := runRouteTests(routes, cases)
+	if err := printTestResults(results); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if !r.Passed {
+			return testsFailed
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testRoutes = `
+	api: Host(/^api[.]example[.]org$/) && Path("/foo")
+		-> setPath("/bar")
+		-> setRequestHeader("X-Foo", "bar")
+		-> setResponseHeader("X-Route", "api")
+		-> "https://api.internal";
+	lb: Path("/lb") -> <roundRobin, "http://10.0.0.1:8080", "http://10.0.0.2:8080">;
+	auth: Path("/auth") -> oauthTokeninfoAnyScope("read") -> "https://auth.internal";
+	static: Path("/static") -> inlineContent("hello") -> <shunt>;
+	notFound: * -> status(404) -> <shunt>;
+`
+
+const testCasesDoc = `
+- name: api
+  request:
+    url: https://api.example.org/foo?q=1
+    headers:
+      X-Baz: qux
+  backend:
+    status: 201
+    headers:
+      Content-Type: application/json
+  expect:
+    route: api
+    backend: https://api.internal/bar?q=1
+    requestHeaders:
+      X-Foo: bar
+      X-Baz: qux
+    status: 201
+    responseHeaders:
+      X-Route: api
+      Content-Type: application/json
+- name: load balanced
+  request:
+    url: /lb
+  expect:
+    route: lb
+    status: 200
+- name: runtime configured filter
+  request:
+    url: /auth
+  expect:
+    route: auth
+    backend: https://auth.internal/auth
+- request:
+    url: /static
+  expect:
+    route: static
+    status: 200
+    body: hello
+- name: failing
+  request:
+    method: POST
+    url: https://www.example.org/foo
+  expect:
+    route: api
+    backend: https://api.internal
+    status: 200
+    responseHeaders:
+      X-Route: api
+`
+
+func TestRouteTests(t *testing.T) {
+	dir := t.TempDir()
+	casesFile := filepath.Join(dir, "cases.yaml")
+	if err := os.WriteFile(casesFile, []byte(testCasesDoc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	preserveOut := stdout
+	preserveJson := printJson
+	defer func() {
+		stdout = preserveOut
+		printJson = preserveJson
+	}()
+
+	buf := &bytes.Buffer{}
+	stdout = buf
+	printJson = true
+
+	err := testCmd(cmdArgs{
+		in:       &medium{typ: inline, eskip: testRoutes},
+		allMedia: []*medium{{typ: inline, eskip: testRoutes}, {typ: file, path: casesFile}},
+	})
+	if err != testsFailed {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var results []testResult
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 5 {
+		t.Fatalf("invalid results: %v", results)
+	}
+
+	for _, r := range results[:4] {
+		if !r.Passed {
+			t.Errorf("test case failed: %s: %v", r.Name, r.Failures)
+		}
+	}
+
+	if results[3].Name != "case 4" {
+		t.Errorf("invalid default name: %s", results[3].Name)
+	}
+
+	expected := []string{
+		"route: expected api, got notFound",
+		"backend: no request was forwarded",
+		"status: expected 200, got 404",
+		`response header X-Route: expected "api", got ""`,
+	}
+
+	failing := results[4]
+	if failing.Passed || len(failing.Failures) != len(expected) {
+		t.Fatalf("invalid failures, expected: %v, got: %v", expected, failing.Failures)
+	}
+
+	for i, f := range expected {
+		if failing.Failures[i] != f {
+			t.Errorf("invalid failure, expected: %s, got: %s", f, failing.Failures[i])
+		}
+	}
+}
+
+func TestRouteTestsNoCases(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "cases.yaml")
+	if err := os.WriteFile(f, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadTestCases(f); err != noTestCases {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
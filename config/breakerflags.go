@@ -11,10 +11,12 @@ import (
 
 const breakerUsage = `set global or host specific circuit breakers, e.g. -breaker type=rate,host=www.example.org,window=300s,failures=30
 	possible breaker properties:
-	type: consecutive/rate/disabled (defaults to consecutive)
+	type: consecutive/rate/slow/disabled (defaults to consecutive)
 	host: a host name that overrides the global for a host
 	failures: the number of failures for consecutive or rate breakers
-	window: the size of the sliding window for the rate breaker
+	window: the size of the sliding window for the rate and slow breakers
+	slow-call-threshold: duration string, the response time from which the slow breaker counts a request as failed
+	slow-call-rate: the percentage of the slow or failed requests in the window, at which the slow breaker opens
 	timeout: duration string or milliseconds while the breaker stays open
 	half-open-requests: the number of requests in half-open state to succeed before getting closed again
 	idle-ttl: duration string or milliseconds after the breaker is considered idle and reset
@@ -24,7 +26,7 @@ const enableBreakersUsage = `enable breakers to be set from filters without prov
 
 type breakerFlags []circuit.BreakerSettings
 
-var errInvalidBreakerConfig = errors.New("invalid breaker config (allowed values are: consecutive, rate or disabled)")
+var errInvalidBreakerConfig = errors.New("invalid breaker config (allowed values are: consecutive, rate, slow or disabled)")
 
 func (b breakerFlags) String() string {
 	s := make([]string, len(b))
@@ -52,6 +54,8 @@ func (b *breakerFlags) Set(value string) error {
 				s.Type = circuit.ConsecutiveFailures
 			case "rate":
 				s.Type = circuit.FailureRate
+			case "slow":
+				s.Type = circuit.SlowCalls
 			case "disabled":
 				s.Type = circuit.BreakerDisabled
 			default:
@@ -94,6 +98,20 @@ func (b *breakerFlags) Set(value string) error {
 			}
 
 			s.IdleTTL = d
+		case "slow-call-threshold":
+			d, err := time.ParseDuration(kv[1])
+			if err != nil {
+				return err
+			}
+
+			s.SlowCallThreshold = d
+		case "slow-call-rate":
+			i, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return err
+			}
+
+			s.SlowCallRate = i
 		default:
 			return errInvalidBreakerConfig
 		}
@@ -89,6 +89,24 @@ func Test_breakerFlags_Set(t *testing.T) {
 				IdleTTL:          5 * time.Second,
 			},
 		},
+		{
+			name:    "test breaker settings slow calls",
+			args:    "type=slow,host=example.com,window=100,slow-call-threshold=300ms,slow-call-rate=40,timeout=3s",
+			wantErr: false,
+			want: circuit.BreakerSettings{
+				Type:              circuit.SlowCalls,
+				Host:              "example.com",
+				Window:            100,
+				SlowCallThreshold: 300 * time.Millisecond,
+				SlowCallRate:      40,
+				Timeout:           3 * time.Second,
+			},
+		},
+		{
+			name:    "test breaker settings with wrong slow call rate",
+			args:    "type=slow,host=example.com,slow-call-rate=40%",
+			wantErr: true,
+		},
 		{
 			name:    "test breaker settings disabled",
 			args:    "type=disabled,host=example.com,timeout=3s,half-open-requests=3,idle-ttl=5s",
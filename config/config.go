@@ -17,11 +17,14 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/zalando/skipper"
+	"github.com/zalando/skipper/circuit"
 	"github.com/zalando/skipper/dataclients/kubernetes"
 	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/logging"
 	"github.com/zalando/skipper/net"
 	"github.com/zalando/skipper/proxy"
 	routesrv "github.com/zalando/skipper/routesrv"
+	"github.com/zalando/skipper/scheduler"
 	"github.com/zalando/skipper/swarm"
 )
 
@@ -40,6 +43,10 @@ type Config struct {
 	DevMode                         bool           `yaml:"dev-mode"`
 	SupportListener                 string         `yaml:"support-listener"`
 	DebugListener                   string         `yaml:"debug-listener"`
+	DebugTraceHeader                string         `yaml:"debug-trace-header"`
+	DebugTraceTokens                *listFlag      `yaml:"debug-trace-tokens"`
+	DebugTraceSigningKeyFile        string         `yaml:"debug-trace-signing-key-file"`
+	DebugTraceStoreSize             int            `yaml:"debug-trace-store-size"`
 	CertPathTLS                     string         `yaml:"tls-cert"`
 	KeyPathTLS                      string         `yaml:"tls-key"`
 	StatusChecks                    *listFlag      `yaml:"status-checks"`
@@ -54,9 +61,16 @@ type Config struct {
 	MaxAuditBody                    int            `yaml:"max-audit-body"`
 	EnableBreakers                  bool           `yaml:"enable-breakers"`
 	Breakers                        breakerFlags   `yaml:"breaker"`
+	EnableSharedBreakers            bool           `yaml:"enable-shared-breakers"`
+	BreakerSyncInterval             time.Duration  `yaml:"breaker-sync-interval"`
+	EnableBreakerAdminWrite         bool           `yaml:"enable-breaker-admin-write"`
+	EnableQuotaAdminReset           bool           `yaml:"enable-quota-admin-reset"`
 	EnableRatelimiters              bool           `yaml:"enable-ratelimits"`
 	Ratelimits                      ratelimitFlags `yaml:"ratelimits"`
 	EnableRouteLIFOMetrics          bool           `yaml:"enable-route-lifo-metrics"`
+	LoadSheddingMaxInflight         int            `yaml:"load-shedding-max-inflight"`
+	LoadSheddingMaxCPU              float64        `yaml:"load-shedding-max-cpu"`
+	LoadSheddingRetryAfter          time.Duration  `yaml:"load-shedding-retry-after"`
 	MetricsFlavour                  *listFlag      `yaml:"metrics-flavour"`
 	FilterPlugins                   *pluginFlag    `yaml:"filter-plugin"`
 	PredicatePlugins                *pluginFlag    `yaml:"predicate-plugin"`
@@ -108,6 +122,13 @@ type Config struct {
 	AccessLogDisabled                   bool      `yaml:"access-log-disabled"`
 	AccessLogJSONEnabled                bool      `yaml:"access-log-json-enabled"`
 	AccessLogStripQuery                 bool      `yaml:"access-log-strip-query"`
+	AccessLogFormat                     string    `yaml:"access-log-format"`
+	AccessLogSampleRate                 float64   `yaml:"access-log-sample-rate"`
+	AccessLogRedactHeaders              *listFlag `yaml:"access-log-redact-headers"`
+	AccessLogRedactQueryParams          *listFlag `yaml:"access-log-redact-query-params"`
+	ApplicationLogSinks                 *listFlag `yaml:"application-log-sinks"`
+	AccessLogSinks                      *listFlag `yaml:"access-log-sinks"`
+	LogSinkBufferSize                   int       `yaml:"log-sink-buffer-size"`
 	SuppressRouteUpdateLogs             bool      `yaml:"suppress-route-update-logs"`
 
 	// route sources:
@@ -191,6 +212,9 @@ type Config struct {
 	Oauth2TokeninfoSubjectKey       string        `yaml:"oauth2-tokeninfo-subject-key"`
 	Oauth2TokenCookieName           string        `yaml:"oauth2-token-cookie-name"`
 	WebhookTimeout                  time.Duration `yaml:"webhook-timeout"`
+	OpenPolicyAgentBundleSource     string        `yaml:"open-policy-agent-bundle-source"`
+	OpenPolicyAgentReloadInterval   time.Duration `yaml:"open-policy-agent-reload-interval"`
+	OpenPolicyAgentDecisionLogs     bool          `yaml:"open-policy-agent-decision-logs"`
 	OidcSecretsFile                 string        `yaml:"oidc-secrets-file"`
 	OidcDistributedClaimsTimeout    time.Duration `yaml:"oidc-distributed-claims-timeout"`
 	CredentialPaths                 *listFlag     `yaml:"credentials-paths"`
@@ -204,9 +228,23 @@ type Config struct {
 	// TLS version
 	TLSMinVersion string `yaml:"tls-min-version"`
 
+	// TLS client authentication
+	TLSClientAuthString string             `yaml:"tls-client-auth"`
+	TLSClientAuth       tls.ClientAuthType `yaml:"-"`
+	TLSClientCAFile     string             `yaml:"tls-client-ca"`
+
 	// TLS Config
 	KubernetesEnableTLS bool `yaml:"kubernetes-enable-tls"`
 
+	// ACME
+	ACMEAllowedHosts               *listFlag     `yaml:"acme-allowed-hosts"`
+	ACMEDirectoryURL               string        `yaml:"acme-directory-url"`
+	ACMEEmail                      string        `yaml:"acme-email"`
+	ACMECacheDir                   string        `yaml:"acme-cache-dir"`
+	ACMEKubernetesSecretsNamespace string        `yaml:"acme-kubernetes-secrets-namespace"`
+	ACMERenewBefore                time.Duration `yaml:"acme-renew-before"`
+	ACMECheckInterval              time.Duration `yaml:"acme-check-interval"`
+
 	// API Monitoring
 	ApiUsageMonitoringEnable                       bool   `yaml:"enable-api-usage-monitoring"`
 	ApiUsageMonitoringRealmKeys                    string `yaml:"api-usage-monitoring-realm-keys"`
@@ -239,15 +277,26 @@ type Config struct {
 	// swarm:
 	EnableSwarm bool `yaml:"enable-swarm"`
 	// redis based
-	SwarmRedisURLs          *listFlag     `yaml:"swarm-redis-urls"`
-	SwarmRedisPassword      string        `yaml:"swarm-redis-password"`
-	SwarmRedisHashAlgorithm string        `yaml:"swarm-redis-hash-algorithm"`
-	SwarmRedisDialTimeout   time.Duration `yaml:"swarm-redis-dial-timeout"`
-	SwarmRedisReadTimeout   time.Duration `yaml:"swarm-redis-read-timeout"`
-	SwarmRedisWriteTimeout  time.Duration `yaml:"swarm-redis-write-timeout"`
-	SwarmRedisPoolTimeout   time.Duration `yaml:"swarm-redis-pool-timeout"`
-	SwarmRedisMinConns      int           `yaml:"swarm-redis-min-conns"`
-	SwarmRedisMaxConns      int           `yaml:"swarm-redis-max-conns"`
+	SwarmRedisURLs                  *listFlag     `yaml:"swarm-redis-urls"`
+	SwarmRedisPassword              string        `yaml:"swarm-redis-password"`
+	SwarmRedisHashAlgorithm         string        `yaml:"swarm-redis-hash-algorithm"`
+	SwarmRedisDialTimeout           time.Duration `yaml:"swarm-redis-dial-timeout"`
+	SwarmRedisReadTimeout           time.Duration `yaml:"swarm-redis-read-timeout"`
+	SwarmRedisWriteTimeout          time.Duration `yaml:"swarm-redis-write-timeout"`
+	SwarmRedisPoolTimeout           time.Duration `yaml:"swarm-redis-pool-timeout"`
+	SwarmRedisMinConns              int           `yaml:"swarm-redis-min-conns"`
+	SwarmRedisMaxConns              int           `yaml:"swarm-redis-max-conns"`
+	SwarmRedisSRV                   string        `yaml:"swarm-redis-srv"`
+	SwarmRedisRemote                string        `yaml:"swarm-redis-remote"`
+	SwarmRedisUpdateInterval        time.Duration `yaml:"swarm-redis-update-interval"`
+	KubernetesRedisServiceNamespace string        `yaml:"kubernetes-redis-service-namespace"`
+	KubernetesRedisServiceName      string        `yaml:"kubernetes-redis-service-name"`
+	SwarmRedisUsername              string        `yaml:"swarm-redis-username"`
+	SwarmRedisTLS                   bool          `yaml:"swarm-redis-tls"`
+	SwarmRedisTLSCAFile             string        `yaml:"swarm-redis-tls-ca-file"`
+	SwarmRedisMode                  string        `yaml:"swarm-redis-mode"`
+	SwarmRedisSentinelMasterName    string        `yaml:"swarm-redis-sentinel-master-name"`
+	SwarmRedisSentinelPassword      string        `yaml:"swarm-redis-sentinel-password"`
 	// swim based
 	SwarmKubernetesNamespace          string        `yaml:"swarm-namespace"`
 	SwarmKubernetesLabelSelectorKey   string        `yaml:"swarm-label-selector-key"`
@@ -266,13 +315,15 @@ const (
 	defaultMinTLSVersion = "1.2"
 
 	// environment keys:
-	redisPasswordEnv = "SWARM_REDIS_PASSWORD"
+	redisPasswordEnv         = "SWARM_REDIS_PASSWORD"
+	redisSentinelPasswordEnv = "SWARM_REDIS_SENTINEL_PASSWORD"
 )
 
 func NewConfig() *Config {
 	cfg := new(Config)
 	cfg.MetricsFlavour = commaListFlag("codahale", "prometheus")
 	cfg.StatusChecks = commaListFlag()
+	cfg.DebugTraceTokens = commaListFlag()
 	cfg.FilterPlugins = newPluginFlag()
 	cfg.PredicatePlugins = newPluginFlag()
 	cfg.DataclientPlugins = newPluginFlag()
@@ -288,6 +339,11 @@ func NewConfig() *Config {
 	cfg.ForwardedHeadersList = commaListFlag()
 	cfg.ForwardedHeadersExcludeCIDRList = commaListFlag()
 	cfg.CompressEncodings = commaListFlag("gzip", "deflate", "br")
+	cfg.ACMEAllowedHosts = commaListFlag()
+	cfg.AccessLogRedactHeaders = commaListFlag()
+	cfg.AccessLogRedactQueryParams = commaListFlag()
+	cfg.ApplicationLogSinks = commaListFlag()
+	cfg.AccessLogSinks = commaListFlag()
 
 	flag.StringVar(&cfg.ConfigFile, "config-file", "", "if provided the flags will be loaded/overwritten by the values on the file (yaml)")
 
@@ -303,6 +359,10 @@ func NewConfig() *Config {
 	flag.BoolVar(&cfg.DevMode, "dev-mode", false, "enables developer time behavior, like ubuffered routing updates")
 	flag.StringVar(&cfg.SupportListener, "support-listener", ":9911", "network address used for exposing the /metrics endpoint. An empty value disables support endpoint.")
 	flag.StringVar(&cfg.DebugListener, "debug-listener", "", "when this address is set, skipper starts an additional listener returning the original and transformed requests")
+	flag.StringVar(&cfg.DebugTraceHeader, "debug-trace-header", proxy.DefaultDebugTraceHeader, "name of the request header triggering the per-request debug trace")
+	flag.Var(cfg.DebugTraceTokens, "debug-trace-tokens", "comma separated list of debug trace header values that trigger the per-request debug trace")
+	flag.StringVar(&cfg.DebugTraceSigningKeyFile, "debug-trace-signing-key-file", "", "path of the file containing the HMAC key verifying the signed debug trace header values in the format of <expiry>.<signature>")
+	flag.IntVar(&cfg.DebugTraceStoreSize, "debug-trace-store-size", 0, "when set, the last debug traces are stored and served on the support listener under /debug/traces/, instead of returning them in a response header")
 	flag.StringVar(&cfg.CertPathTLS, "tls-cert", "", "the path on the local filesystem to the certificate file(s) (including any intermediates), multiple may be given comma separated")
 	flag.StringVar(&cfg.KeyPathTLS, "tls-key", "", "the path on the local filesystem to the certificate's private key file(s), multiple keys may be given comma separated - the order must match the certs")
 	flag.Var(cfg.StatusChecks, "status-checks", "experimental URLs to check before reporting healthy on startup")
@@ -317,9 +377,16 @@ func NewConfig() *Config {
 	flag.IntVar(&cfg.MaxAuditBody, "max-audit-body", 1024, "sets the max body to read to log in the audit log body")
 	flag.BoolVar(&cfg.EnableBreakers, "enable-breakers", false, enableBreakersUsage)
 	flag.Var(&cfg.Breakers, "breaker", breakerUsage)
+	flag.BoolVar(&cfg.EnableSharedBreakers, "enable-shared-breakers", false, "share the failure counts and the open state of the circuit breakers across the skipper instances, requires -enable-swarm")
+	flag.DurationVar(&cfg.BreakerSyncInterval, "breaker-sync-interval", circuit.DefaultSyncInterval, "interval of synchronizing the shared circuit breakers")
+	flag.BoolVar(&cfg.EnableQuotaAdminReset, "enable-quota-admin-reset", false, "enables resetting the usage of the quotas on the support listener, that doesn't authenticate the requests")
+	flag.BoolVar(&cfg.EnableBreakerAdminWrite, "enable-breaker-admin-write", false, "enables tripping and resetting the circuit breakers on the support listener, that doesn't authenticate the requests")
 	flag.BoolVar(&cfg.EnableRatelimiters, "enable-ratelimits", false, enableRatelimitsUsage)
 	flag.Var(&cfg.Ratelimits, "ratelimits", ratelimitsUsage)
 	flag.BoolVar(&cfg.EnableRouteLIFOMetrics, "enable-route-lifo-metrics", false, "enable metrics for the individual route LIFO queues")
+	flag.IntVar(&cfg.LoadSheddingMaxInflight, "load-shedding-max-inflight", 0, "enables the load shedding, and sets the number of concurrent backend requests considered as full load, when the lower priority classes are rejected first")
+	flag.Float64Var(&cfg.LoadSheddingMaxCPU, "load-shedding-max-cpu", 0, "enables the load shedding, and sets the CPU usage of the process, in percent of the available CPUs, considered as full load, when the lower priority classes are rejected first")
+	flag.DurationVar(&cfg.LoadSheddingRetryAfter, "load-shedding-retry-after", scheduler.DefaultLoadSheddingRetryAfter, "sets the Retry-After header of the requests rejected by the load shedding")
 	flag.Var(cfg.MetricsFlavour, "metrics-flavour", "Metrics flavour is used to change the exposed metrics format. Supported metric formats: 'codahale' and 'prometheus', you can select both of them")
 	flag.Var(cfg.FilterPlugins, "filter-plugin", "set a custom filter plugins to load, a comma separated list of name and arguments")
 	flag.Var(cfg.PredicatePlugins, "predicate-plugin", "set a custom predicate plugins to load, a comma separated list of name and arguments")
@@ -369,6 +436,13 @@ func NewConfig() *Config {
 	flag.BoolVar(&cfg.AccessLogDisabled, "access-log-disabled", false, "when this flag is set, no access log is printed")
 	flag.BoolVar(&cfg.AccessLogJSONEnabled, "access-log-json-enabled", false, "when this flag is set, log in JSON format is used")
 	flag.BoolVar(&cfg.AccessLogStripQuery, "access-log-strip-query", false, "when this flag is set, the access log strips the query strings from the access log")
+	flag.StringVar(&cfg.AccessLogFormat, "access-log-format", "", "template of the access log entries with ${field} placeholders, e.g. '${remote_host} \"${method} ${uri}\" ${status} ${route_id} ${backend} ${backend_duration}'. When not set, the Apache combined log format is used")
+	flag.Float64Var(&cfg.AccessLogSampleRate, "access-log-sample-rate", 1, "ratio of the requests logged in the access log, between 0 and 1")
+	flag.Var(cfg.AccessLogRedactHeaders, "access-log-redact-headers", "comma separated list of headers whose values are redacted in the access log. When not set, Authorization, Cookie, Set-Cookie and Proxy-Authorization are redacted")
+	flag.Var(cfg.AccessLogRedactQueryParams, "access-log-redact-query-params", "comma separated list of query params whose values are redacted in the access log")
+	flag.Var(cfg.ApplicationLogSinks, "application-log-sinks", "comma separated list of sinks receiving the application log in addition to the application log output, e.g. syslog+tcp://localhost:514 or otlp+http://localhost:4318. Supported schemes: syslog, syslog+tcp, syslog+tls, otlp+http and otlp+https")
+	flag.Var(cfg.AccessLogSinks, "access-log-sinks", "comma separated list of sinks receiving the access log in addition to the access log output, see -application-log-sinks")
+	flag.IntVar(&cfg.LogSinkBufferSize, "log-sink-buffer-size", logging.DefaultLogSinkBufferSize, "number of log entries buffered for each log sink, the new entries are dropped when the buffer is full")
 	flag.BoolVar(&cfg.SuppressRouteUpdateLogs, "suppress-route-update-logs", false, "print only summaries on route updates/deletes")
 
 	// route sources:
@@ -447,6 +521,9 @@ func NewConfig() *Config {
 	flag.StringVar(&cfg.Oauth2TokeninfoSubjectKey, "oauth2-tokeninfo-subject-key", "uid", "sets the access token to a header on the request with this name")
 	flag.StringVar(&cfg.Oauth2TokenCookieName, "oauth2-token-cookie-name", "oauth2-grant", "sets the name of the cookie where the encrypted token is stored")
 	flag.DurationVar(&cfg.WebhookTimeout, "webhook-timeout", 2*time.Second, "sets the webhook request timeout duration")
+	flag.StringVar(&cfg.OpenPolicyAgentBundleSource, "open-policy-agent-bundle-source", "", "enables the opaAuthorizeRequest filter and sets the directory or the URL of the HTTP server of the policy bundles")
+	flag.DurationVar(&cfg.OpenPolicyAgentReloadInterval, "open-policy-agent-reload-interval", time.Minute, "sets how often the policy bundles are checked for changes")
+	flag.BoolVar(&cfg.OpenPolicyAgentDecisionLogs, "open-policy-agent-decision-logs", false, "enables logging the decisions of the opaAuthorizeRequest filter")
 	flag.StringVar(&cfg.OidcSecretsFile, "oidc-secrets-file", "", "file storing the encryption key of the OID Connect token")
 	flag.DurationVar(&cfg.OidcDistributedClaimsTimeout, "oidc-distributed-claims-timeout", 2*time.Second, "sets the default OIDC distributed claims request timeout duration to 2000ms")
 	flag.Var(cfg.CredentialPaths, "credentials-paths", "directories or files to watch for credentials to use by bearerinjector filter")
@@ -458,6 +535,8 @@ func NewConfig() *Config {
 
 	// TLS version
 	flag.StringVar(&cfg.TLSMinVersion, "tls-min-version", defaultMinTLSVersion, "minimal TLS Version to be used in server, proxy and client connections")
+	flag.StringVar(&cfg.TLSClientAuthString, "tls-client-auth", "none", "client certificate policy of the TLS listener: none, request, optional or required. Verification with optional and required uses the CAs from -tls-client-ca")
+	flag.StringVar(&cfg.TLSClientCAFile, "tls-client-ca", "", "path of the PEM encoded CA certificate(s) used to verify the client certificates, multiple may be given comma separated")
 
 	// API Monitoring:
 	flag.BoolVar(&cfg.ApiUsageMonitoringEnable, "enable-api-usage-monitoring", false, "enables the apiUsageMonitoring filter")
@@ -492,6 +571,15 @@ func NewConfig() *Config {
 	flag.BoolVar(&cfg.DisableHTTPKeepalives, "disable-http-keepalives", false, "forces backend to always create a new connection")
 	flag.BoolVar(&cfg.KubernetesEnableTLS, "kubernetes-enable-tls", false, "enable using kubnernetes resources to terminate tls")
 
+	// ACME:
+	flag.Var(cfg.ACMEAllowedHosts, "acme-allowed-hosts", "enables obtaining TLS certificates from an ACME server for the given comma separated hosts, when they are found in the routing table. Entries like *.example.org allow any subdomain")
+	flag.StringVar(&cfg.ACMEDirectoryURL, "acme-directory-url", "", "directory URL of the ACME server, defaults to Let's Encrypt")
+	flag.StringVar(&cfg.ACMEEmail, "acme-email", "", "contact email address of the ACME account")
+	flag.StringVar(&cfg.ACMECacheDir, "acme-cache-dir", "", "local directory to store the ACME account and certificates")
+	flag.StringVar(&cfg.ACMEKubernetesSecretsNamespace, "acme-kubernetes-secrets-namespace", "", "when set, the ACME account and certificates are stored as Kubernetes secrets in this namespace instead of the cache directory")
+	flag.DurationVar(&cfg.ACMERenewBefore, "acme-renew-before", 30*24*time.Hour, "renew the ACME certificates this long before they expire")
+	flag.DurationVar(&cfg.ACMECheckInterval, "acme-check-interval", time.Hour, "interval to check the ACME certificates of the routed hosts")
+
 	// Swarm:
 	flag.BoolVar(&cfg.EnableSwarm, "enable-swarm", false, "enable swarm communication between nodes in a skipper fleet")
 	flag.Var(cfg.SwarmRedisURLs, "swarm-redis-urls", "Redis URLs as comma separated list, used for building a swarm, for example in redis based cluster ratelimits.\nUse "+redisPasswordEnv+" environment variable or 'swarm-redis-password' key in config file to set redis password")
@@ -502,6 +590,16 @@ func NewConfig() *Config {
 	flag.DurationVar(&cfg.SwarmRedisPoolTimeout, "swarm-redis-pool-timeout", net.DefaultPoolTimeout, "set redis get connection from pool timeout")
 	flag.IntVar(&cfg.SwarmRedisMinConns, "swarm-redis-min-conns", net.DefaultMinConns, "set min number of connections to redis")
 	flag.IntVar(&cfg.SwarmRedisMaxConns, "swarm-redis-max-conns", net.DefaultMaxConns, "set max number of connections to redis")
+	flag.StringVar(&cfg.SwarmRedisSRV, "swarm-redis-srv", "", "discover the redis shards from the DNS SRV records of this name, e.g. _redis._tcp.redis.example.org, instead of swarm-redis-urls")
+	flag.StringVar(&cfg.SwarmRedisRemote, "swarm-redis-remote", "", "discover the redis shards from this URL, responding with JSON in the format of {\"endpoints\": [{\"address\": \"10.2.0.1:6379\"}]}, instead of swarm-redis-urls")
+	flag.DurationVar(&cfg.SwarmRedisUpdateInterval, "swarm-redis-update-interval", 10*time.Second, "interval of the redis shard discovery")
+	flag.StringVar(&cfg.KubernetesRedisServiceNamespace, "kubernetes-redis-service-namespace", "", "namespace of the Kubernetes service to discover the redis shards from its endpoints, instead of swarm-redis-urls")
+	flag.StringVar(&cfg.SwarmRedisUsername, "swarm-redis-username", "", "ACL username of redis, used together with the redis password")
+	flag.BoolVar(&cfg.SwarmRedisTLS, "swarm-redis-tls", false, "enables TLS for the connections to redis")
+	flag.StringVar(&cfg.SwarmRedisTLSCAFile, "swarm-redis-tls-ca-file", "", "file of the CA certificates to verify the redis servers, the system CAs are used when not set")
+	flag.StringVar(&cfg.SwarmRedisMode, "swarm-redis-mode", net.RedisModeRing, "redis client mode, one of ring, sentinel or cluster. In sentinel mode, swarm-redis-urls are the addresses of the sentinels, and in cluster mode, the seed addresses of the cluster.\nUse "+redisSentinelPasswordEnv+" environment variable or 'swarm-redis-sentinel-password' key in config file to set the sentinel password")
+	flag.StringVar(&cfg.SwarmRedisSentinelMasterName, "swarm-redis-sentinel-master-name", "", "name of the redis master in sentinel mode")
+	flag.StringVar(&cfg.KubernetesRedisServiceName, "kubernetes-redis-service-name", "", "name of the Kubernetes service to discover the redis shards from its endpoints, instead of swarm-redis-urls, requires the Kubernetes data client")
 	flag.StringVar(&cfg.SwarmKubernetesNamespace, "swarm-namespace", swarm.DefaultNamespace, "Kubernetes namespace to find swarm peer instances")
 	flag.StringVar(&cfg.SwarmKubernetesLabelSelectorKey, "swarm-label-selector-key", swarm.DefaultLabelSelectorKey, "Kubernetes labelselector key to find swarm peer instances")
 	flag.StringVar(&cfg.SwarmKubernetesLabelSelectorValue, "swarm-label-selector-value", swarm.DefaultLabelSelectorValue, "Kubernetes labelselector value to find swarm peer instances")
@@ -568,10 +666,16 @@ func (c *Config) Parse() error {
 		return err
 	}
 
+	clientAuth, err := parseClientAuth(c.TLSClientAuthString)
+	if err != nil {
+		return err
+	}
+
 	c.ApplicationLogLevel = logLevel
 	c.KubernetesPathMode = kubernetesPathMode
 	c.KubernetesEastWestRangePredicates = kubernetesEastWestRangePredicates
 	c.HistogramMetricBuckets = histogramBuckets
+	c.TLSClientAuth = clientAuth
 
 	if c.ClientKeyFile != "" && c.ClientCertFile != "" {
 		certsFiles := strings.Split(c.ClientCertFile, ",")
@@ -665,6 +769,10 @@ func (c *Config) ToOptions() skipper.Options {
 		DevMode:                         c.DevMode,
 		SupportListener:                 c.SupportListener,
 		DebugListener:                   c.DebugListener,
+		DebugTraceHeader:                c.DebugTraceHeader,
+		DebugTraceTokens:                c.DebugTraceTokens.values,
+		DebugTraceSigningKeyFile:        c.DebugTraceSigningKeyFile,
+		DebugTraceStoreSize:             c.DebugTraceStoreSize,
 		CertPathTLS:                     c.CertPathTLS,
 		KeyPathTLS:                      c.KeyPathTLS,
 		MaxLoopbacks:                    c.MaxLoopbacks,
@@ -674,9 +782,16 @@ func (c *Config) ToOptions() skipper.Options {
 		MaxAuditBody:                    c.MaxAuditBody,
 		EnableBreakers:                  c.EnableBreakers,
 		BreakerSettings:                 c.Breakers,
+		EnableSharedBreakers:            c.EnableSharedBreakers,
+		BreakerSyncInterval:             c.BreakerSyncInterval,
+		EnableBreakerAdminWrite:         c.EnableBreakerAdminWrite,
+		EnableQuotaAdminReset:           c.EnableQuotaAdminReset,
 		EnableRatelimiters:              c.EnableRatelimiters,
 		RatelimitSettings:               c.Ratelimits,
 		EnableRouteLIFOMetrics:          c.EnableRouteLIFOMetrics,
+		LoadSheddingMaxInflight:         c.LoadSheddingMaxInflight,
+		LoadSheddingMaxCPU:              c.LoadSheddingMaxCPU,
+		LoadSheddingRetryAfter:          c.LoadSheddingRetryAfter,
 		MetricsFlavours:                 c.MetricsFlavour.values,
 		FilterPlugins:                   c.FilterPlugins.values,
 		PredicatePlugins:                c.PredicatePlugins.values,
@@ -724,6 +839,13 @@ func (c *Config) ToOptions() skipper.Options {
 		AccessLogDisabled:                   c.AccessLogDisabled,
 		AccessLogJSONEnabled:                c.AccessLogJSONEnabled,
 		AccessLogStripQuery:                 c.AccessLogStripQuery,
+		AccessLogFormat:                     c.AccessLogFormat,
+		AccessLogSampleRate:                 c.AccessLogSampleRate,
+		AccessLogRedactHeaders:              c.AccessLogRedactHeaders.values,
+		AccessLogRedactQueryParams:          c.AccessLogRedactQueryParams.values,
+		ApplicationLogSinks:                 c.ApplicationLogSinks.values,
+		AccessLogSinks:                      c.AccessLogSinks.values,
+		LogSinkBufferSize:                   c.LogSinkBufferSize,
 		SuppressRouteUpdateLogs:             c.SuppressRouteUpdateLogs,
 
 		// route sources:
@@ -801,6 +923,9 @@ func (c *Config) ToOptions() skipper.Options {
 		OAuth2TokeninfoSubjectKey:      c.Oauth2TokeninfoSubjectKey,
 		OAuth2TokenCookieName:          c.Oauth2TokenCookieName,
 		WebhookTimeout:                 c.WebhookTimeout,
+		OpenPolicyAgentBundleSource:    c.OpenPolicyAgentBundleSource,
+		OpenPolicyAgentReloadInterval:  c.OpenPolicyAgentReloadInterval,
+		OpenPolicyAgentDecisionLogs:    c.OpenPolicyAgentDecisionLogs,
 		OIDCSecretsFile:                c.OidcSecretsFile,
 		OIDCDistributedClaimsTimeout:   c.OidcDistributedClaimsTimeout,
 		CredentialsPaths:               c.CredentialPaths.values,
@@ -828,19 +953,41 @@ func (c *Config) ToOptions() skipper.Options {
 		MaxIdleConnsBackend:          c.MaxIdleConnsBackend,
 		DisableHTTPKeepalives:        c.DisableHTTPKeepalives,
 		KubernetesEnableTLS:          c.KubernetesEnableTLS,
+		TLSClientAuth:                c.TLSClientAuth,
+		TLSClientCAFile:              c.TLSClientCAFile,
+
+		// ACME:
+		ACMEAllowedHosts:               c.ACMEAllowedHosts.values,
+		ACMEDirectoryURL:               c.ACMEDirectoryURL,
+		ACMEEmail:                      c.ACMEEmail,
+		ACMECacheDir:                   c.ACMECacheDir,
+		ACMEKubernetesSecretsNamespace: c.ACMEKubernetesSecretsNamespace,
+		ACMERenewBefore:                c.ACMERenewBefore,
+		ACMECheckInterval:              c.ACMECheckInterval,
 
 		// swarm:
 		EnableSwarm: c.EnableSwarm,
 		// redis based
-		SwarmRedisURLs:          c.SwarmRedisURLs.values,
-		SwarmRedisPassword:      c.SwarmRedisPassword,
-		SwarmRedisHashAlgorithm: c.SwarmRedisHashAlgorithm,
-		SwarmRedisDialTimeout:   c.SwarmRedisDialTimeout,
-		SwarmRedisReadTimeout:   c.SwarmRedisReadTimeout,
-		SwarmRedisWriteTimeout:  c.SwarmRedisWriteTimeout,
-		SwarmRedisPoolTimeout:   c.SwarmRedisPoolTimeout,
-		SwarmRedisMinIdleConns:  c.SwarmRedisMinConns,
-		SwarmRedisMaxIdleConns:  c.SwarmRedisMaxConns,
+		SwarmRedisURLs:                c.SwarmRedisURLs.values,
+		SwarmRedisPassword:            c.SwarmRedisPassword,
+		SwarmRedisHashAlgorithm:       c.SwarmRedisHashAlgorithm,
+		SwarmRedisDialTimeout:         c.SwarmRedisDialTimeout,
+		SwarmRedisReadTimeout:         c.SwarmRedisReadTimeout,
+		SwarmRedisWriteTimeout:        c.SwarmRedisWriteTimeout,
+		SwarmRedisPoolTimeout:         c.SwarmRedisPoolTimeout,
+		SwarmRedisMinIdleConns:        c.SwarmRedisMinConns,
+		SwarmRedisMaxIdleConns:        c.SwarmRedisMaxConns,
+		SwarmRedisSRV:                 c.SwarmRedisSRV,
+		SwarmRedisRemote:              c.SwarmRedisRemote,
+		SwarmRedisUpdateInterval:      c.SwarmRedisUpdateInterval,
+		SwarmRedisKubernetesNamespace: c.KubernetesRedisServiceNamespace,
+		SwarmRedisKubernetesService:   c.KubernetesRedisServiceName,
+		SwarmRedisUsername:            c.SwarmRedisUsername,
+		SwarmRedisTLS:                 c.SwarmRedisTLS,
+		SwarmRedisTLSCAFile:           c.SwarmRedisTLSCAFile,
+		SwarmRedisMode:                c.SwarmRedisMode,
+		SwarmRedisSentinelMasterName:  c.SwarmRedisSentinelMasterName,
+		SwarmRedisSentinelPassword:    c.SwarmRedisSentinelPassword,
 		// swim based
 		SwarmKubernetesNamespace:          c.SwarmKubernetesNamespace,
 		SwarmKubernetesLabelSelectorKey:   c.SwarmKubernetesLabelSelectorKey,
@@ -939,6 +1086,21 @@ func (c *Config) getMinTLSVersion() uint16 {
 	return tlsVersionTable[defaultMinTLSVersion]
 }
 
+func parseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch s {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	case "required":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("invalid tls-client-auth: %s", s)
+	}
+}
+
 func (c *Config) parseHistogramBuckets() ([]float64, error) {
 	if c.HistogramMetricBucketsString == "" {
 		return prometheus.DefBuckets, nil
@@ -989,6 +1151,10 @@ func (c *Config) parseEnv() {
 	if c.SwarmRedisPassword == "" {
 		c.SwarmRedisPassword = os.Getenv(redisPasswordEnv)
 	}
+
+	if c.SwarmRedisSentinelPassword == "" {
+		c.SwarmRedisSentinelPassword = os.Getenv(redisSentinelPasswordEnv)
+	}
 }
 
 func checkDeprecated(configKeys map[string]interface{}, options ...string) {
@@ -89,6 +89,8 @@ func Test_NewConfig(t *testing.T) {
 				StatusChecks:                            nil,
 				ExpectedBytesPerRequest:                 50 * 1024,
 				SupportListener:                         ":9911",
+				DebugTraceHeader:                        "X-Skipper-Debug",
+				DebugTraceTokens:                        commaListFlag(),
 				MaxLoopbacks:                            12,
 				DefaultHTTPStatus:                       404,
 				MaxAuditBody:                            1024,
@@ -126,6 +128,7 @@ func Test_NewConfig(t *testing.T) {
 				Oauth2TokeninfoSubjectKey:               "uid",
 				Oauth2TokenCookieName:                   "oauth2-grant",
 				WebhookTimeout:                          2 * time.Second,
+				OpenPolicyAgentReloadInterval:           time.Minute,
 				OidcDistributedClaimsTimeout:            2 * time.Second,
 				CredentialPaths:                         commaListFlag(),
 				CredentialsUpdateInterval:               10 * time.Minute,
@@ -156,6 +159,10 @@ func Test_NewConfig(t *testing.T) {
 				SwarmRedisPoolTimeout:                   25 * time.Millisecond,
 				SwarmRedisMinConns:                      100,
 				SwarmRedisMaxConns:                      100,
+				SwarmRedisUpdateInterval:                10 * time.Second,
+				SwarmRedisMode:                          "ring",
+				BreakerSyncInterval:                     time.Second,
+				LoadSheddingRetryAfter:                  time.Second,
 				SwarmKubernetesNamespace:                "kube-system",
 				SwarmKubernetesLabelSelectorKey:         "application",
 				SwarmKubernetesLabelSelectorValue:       "skipper-ingress",
@@ -163,10 +170,20 @@ func Test_NewConfig(t *testing.T) {
 				SwarmMaxMessageBuffer:                   4194304,
 				SwarmLeaveTimeout:                       5 * time.Second,
 				TLSMinVersion:                           defaultMinTLSVersion,
+				TLSClientAuthString:                     "none",
 				RoutesURLs:                              commaListFlag(),
 				ForwardedHeadersList:                    commaListFlag(),
 				ForwardedHeadersExcludeCIDRList:         commaListFlag(),
 				ClusterRatelimitMaxGroupShards:          1,
+				ACMEAllowedHosts:                        commaListFlag(),
+				AccessLogSampleRate:                     1,
+				AccessLogRedactHeaders:                  commaListFlag(),
+				AccessLogRedactQueryParams:              commaListFlag(),
+				ApplicationLogSinks:                     commaListFlag(),
+				AccessLogSinks:                          commaListFlag(),
+				LogSinkBufferSize:                       4096,
+				ACMERenewBefore:                         30 * 24 * time.Hour,
+				ACMECheckInterval:                       time.Hour,
 				RefusePayload:                           multiFlag{"foo", "bar", "baz"},
 			},
 			wantErr: false,
@@ -85,3 +85,14 @@ func (state *clusterState) getEndpointsByTarget(namespace, name, protocol string
 	state.cachedEndpoints[epID] = targets
 	return targets
 }
+
+func (state *clusterState) getEndpointAddresses(namespace, name string) []string {
+	ep, ok := state.endpoints[newResourceID(namespace, name)]
+	if !ok {
+		return nil
+	}
+
+	addresses := ep.addresses()
+	sort.Strings(addresses)
+	return addresses
+}
@@ -138,6 +138,20 @@ func (ep endpoint) targetsByServiceTarget(protocol string, serviceTarget *defini
 	return nil
 }
 
+// returns the addresses of all the subsets and ports of the endpoint
+func (ep endpoint) addresses() []string {
+	var result []string
+	for _, s := range ep.Subsets {
+		for _, p := range s.Ports {
+			for _, a := range s.Addresses {
+				result = append(result, net.JoinHostPort(a.IP, strconv.Itoa(p.Port)))
+			}
+		}
+	}
+
+	return result
+}
+
 type subset struct {
 	Addresses []*address `json:"addresses"`
 	Ports     []*port    `json:"ports"`
@@ -7,6 +7,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -197,6 +198,9 @@ type Client struct {
 	current                map[string]*eskip.Route
 	quit                   chan struct{}
 	defaultFiltersDir      string
+
+	mu    sync.Mutex
+	state *clusterState
 }
 
 // New creates and initializes a Kubernetes DataClient.
@@ -335,6 +339,10 @@ func (c *Client) loadAndConvert() ([]*eskip.Route, error) {
 		return nil, err
 	}
 
+	c.mu.Lock()
+	c.state = state
+	c.mu.Unlock()
+
 	defaultFilters := c.fetchDefaultFilterConfigs()
 
 	ri, err := c.ingress.convert(state, defaultFilters, c.ClusterClient.certificateRegistry)
@@ -469,6 +477,20 @@ func (c *Client) LoadUpdate() ([]*eskip.Route, []string, error) {
 	return updatedRoutes, deletedIDs, nil
 }
 
+// GetEndpointAddresses returns the addresses of the endpoints of a
+// service, in the host:port format, from the cluster state loaded with
+// the latest route update. It can be used e.g. to discover the shards
+// of a redis ring.
+func (c *Client) GetEndpointAddresses(namespace, name string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == nil {
+		return nil
+	}
+
+	return c.state.getEndpointAddresses(namespace, name)
+}
+
 func (c *Client) Close() {
 	if c != nil && c.quit != nil {
 		close(c.quit)
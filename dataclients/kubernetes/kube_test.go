@@ -3179,3 +3179,19 @@ func (mockSecretProvider) Add(string) error {
 }
 
 func (mockSecretProvider) Close() {}
+
+func TestGetEndpointAddresses(t *testing.T) {
+	api := newTestAPIWithEndpoints(t, testServices(), &definitions.IngressList{}, testEndpointList(), testSecrets())
+	defer api.Close()
+	dc, err := New(Options{KubernetesURL: api.server.URL})
+	require.NoError(t, err)
+	defer dc.Close()
+
+	require.Empty(t, dc.GetEndpointAddresses("namespace2", "service4"), "addresses before the first load")
+
+	_, err = dc.LoadAll()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"2.1.4.0:4444", "2.1.4.0:5555"}, dc.GetEndpointAddresses("namespace2", "service4"))
+	assert.Empty(t, dc.GetEndpointAddresses("namespace2", "missing"))
+}
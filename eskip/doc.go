@@ -224,6 +224,32 @@ The dynamic backend means that a filter must be present in the filter chain whic
 must set the target url explicitly.
 
 
+Named Predicate Sets and Filter Chains
+
+An eskip document can define named predicate sets and filter chains, and
+reference them in the route definitions, to avoid repeating them in
+every route:
+
+	@api = Host(/^api[.]example[.]org$/) && Method("GET");
+	@auth = oauthTokeninfoAnyScope("read") -> clusterClientRatelimit("api", 10, "1m");
+
+	route1: @api && Path("/foo") -> @auth -> "https://foo.example.org";
+	route2: @api && Path("/bar") -> @auth -> setPath("/baz") -> "https://bar.example.org";
+
+The references are expanded during parsing, and the resulting routes
+contain the predicates and filters of the definitions. A definition
+containing a single predicate or filter can be referenced both in the
+match expression and in the filter chain. The definitions cannot contain
+references, and they can be referenced only in the document where they
+are defined.
+
+When printing a document, the references are preserved, as long as the
+routes still contain the predicates and filters of the definitions, and
+the used definitions are printed at the beginning of the document. To
+print the expanded routes, set the ExpandReferences field of
+PrettyPrintInfo.
+
+
 Comments
 
 An eskip document can contain comments. The rule for comments is simple:
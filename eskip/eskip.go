@@ -235,6 +235,7 @@ type parsedRoute struct {
 	backend     string
 	lbAlgorithm string
 	lbEndpoints []string
+	references  []*Reference
 }
 
 // A Predicate object represents a parsed, in-memory, route matching predicate
@@ -335,6 +336,12 @@ type Route struct {
 
 	// Namespace is deprecated and not used.
 	Namespace string
+
+	// References contains the named predicate sets and filter chains,
+	// that the route definition used, when it was parsed from eskip.
+	// Print preserves them, as long as the route still contains their
+	// predicates and filters.
+	References []*Reference
 }
 
 type RoutePredicate func(*Route) bool
@@ -413,6 +420,12 @@ func (r *Route) Copy() *Route {
 		copy(c.LBEndpoints, r.LBEndpoints)
 	}
 
+	// the references are not modified, it is enough to copy the slice
+	if len(r.References) > 0 {
+		c.References = make([]*Reference, len(r.References))
+		copy(c.References, r.References)
+	}
+
 	return &c
 }
 
@@ -571,6 +584,7 @@ func newRouteDefinition(r *parsedRoute) (*Route, error) {
 	rd.Backend = r.backend
 	rd.LBAlgorithm = r.lbAlgorithm
 	rd.LBEndpoints = r.lbEndpoints
+	rd.References = r.references
 
 	switch {
 	case r.shunt:
@@ -594,7 +608,15 @@ func newRouteDefinition(r *parsedRoute) (*Route, error) {
 func parse(code string) ([]*parsedRoute, error) {
 	l := newLexer(code)
 	eskipParse(l)
-	return l.routes, l.err
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	if err := expandReferences(l.routes, l.definitions); err != nil {
+		return nil, err
+	}
+
+	return l.routes, nil
 }
 
 func partialRouteToRoute(format, p string) string {
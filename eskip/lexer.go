@@ -29,6 +29,7 @@ type eskipLex struct {
 	err           error
 	initialLength int
 	routes        []*parsedRoute
+	definitions   []*definition
 }
 
 type fixedScanner string
@@ -63,6 +64,7 @@ var fixedTokens = []fixedScanner{
 	"<dynamic>",
 	"<",
 	">",
+	"=",
 }
 
 var fixedTokenIDs = map[fixedScanner]int{
@@ -79,6 +81,7 @@ var fixedTokenIDs = map[fixedScanner]int{
 	"<dynamic>":  dynamic,
 	"<":          openarrow,
 	">":          closearrow,
+	"=":          equals,
 }
 
 func (t token) String() string { return t.val }
@@ -295,6 +298,18 @@ func scanSymbol(code string) (t token, rest string, err error) {
 	return
 }
 
+func scanReference(code string) (t token, rest string, err error) {
+	b, rest := scanWhile(code[1:], isSymbolChar)
+	if len(b) == 0 {
+		err = incompleteToken
+		return
+	}
+
+	t.id = referencesymbol
+	t.val = string(b)
+	return
+}
+
 func selectFixed(code string) scanner {
 	for _, fixed := range fixedTokens {
 		if len(code) >= len(fixed) && strings.HasPrefix(code, string(fixed)) {
@@ -314,6 +329,8 @@ func selectVaryingScanner(code string) scanner {
 		sf = scanDoubleQuote
 	case '`':
 		sf = scanBacktick
+	case '@':
+		sf = scanReference
 	}
 
 	if isNumberChar(code[0]) {
@@ -41,6 +41,7 @@ type eskipSymType struct {
 	stringvals  []string
 	lbAlgorithm string
 	lbEndpoints []string
+	definition  *definition
 }
 
 const and = 57346
@@ -60,6 +61,8 @@ const stringliteral = 57359
 const symbol = 57360
 const openarrow = 57361
 const closearrow = 57362
+const referencesymbol = 57363
+const equals = 57364
 
 var eskipToknames = [...]string{
 	"$end",
@@ -82,6 +85,8 @@ var eskipToknames = [...]string{
 	"symbol",
 	"openarrow",
 	"closearrow",
+	"referencesymbol",
+	"equals",
 }
 
 var eskipStatenames = [...]string{}
@@ -90,7 +95,7 @@ const eskipEofCode = 1
 const eskipErrCode = 2
 const eskipInitialStackSize = 16
 
-//line parser.y:287
+//line parser.y:328
 
 //line yacctab:1
 var eskipExca = [...]int{
@@ -101,62 +106,69 @@ var eskipExca = [...]int{
 
 const eskipPrivate = 57344
 
-const eskipLast = 62
+const eskipLast = 77
 
 var eskipAct = [...]int{
-	34, 40, 32, 31, 24, 17, 20, 21, 22, 25,
-	27, 26, 19, 48, 36, 9, 37, 25, 41, 9,
-	16, 25, 25, 3, 10, 7, 14, 42, 29, 43,
-	4, 55, 8, 45, 44, 49, 45, 30, 28, 19,
-	50, 15, 13, 47, 46, 38, 23, 51, 52, 39,
-	53, 42, 54, 12, 35, 11, 33, 18, 5, 6,
-	2, 1,
+	43, 49, 41, 29, 40, 22, 9, 16, 25, 26,
+	27, 30, 32, 31, 24, 33, 32, 11, 11, 33,
+	59, 34, 21, 39, 30, 20, 30, 50, 3, 12,
+	36, 10, 51, 35, 8, 45, 5, 46, 17, 4,
+	52, 68, 30, 56, 37, 55, 60, 56, 24, 19,
+	61, 58, 18, 57, 15, 47, 28, 62, 64, 65,
+	63, 66, 51, 67, 53, 14, 54, 13, 48, 44,
+	42, 23, 6, 38, 7, 2, 1,
 }
 
 var eskipPact = [...]int{
-	14, -1000, 11, -1000, -1000, 49, 34, -1000, 15, -1000,
-	2, -8, 10, 10, 4, -1000, -1000, -1000, 39, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, 0, 18, -1000, 15,
-	-1000, 27, -1000, -1000, -1000, -1000, -1000, -1000, -8, -7,
-	26, 31, -1000, 4, -1000, 4, -1000, -1000, -1000, 5,
-	5, 24, -1000, -1000, 26, -1000,
+	13, -1000, 16, -1000, -1000, -1000, 61, 46, -15, -1000,
+	27, -1000, 4, -6, 12, 12, 12, 25, -1000, -1000,
+	-15, -1000, -1000, 49, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, 9, 29, -1000, -1000, -1000, 27, -1000, 60, -1000,
+	38, -1000, -1000, -1000, -1000, -1000, -1000, -6, 0, 37,
+	41, -1000, 25, 12, -2, -1000, 25, -1000, -1000, -1000,
+	7, 7, 34, -1000, -1000, -1000, -1000, 37, -1000,
 }
 
 var eskipPgo = [...]int{
-	0, 61, 60, 23, 30, 59, 58, 5, 57, 25,
-	3, 4, 2, 56, 0, 54, 1, 49, 46,
+	0, 76, 75, 28, 39, 36, 74, 73, 6, 3,
+	72, 5, 71, 4, 2, 70, 0, 69, 1, 68,
+	56,
 }
 
 var eskipR1 = [...]int{
-	0, 1, 1, 2, 2, 2, 2, 4, 5, 3,
-	3, 6, 6, 9, 9, 8, 8, 11, 10, 10,
-	10, 12, 12, 12, 16, 16, 17, 17, 18, 7,
-	7, 7, 7, 7, 13, 14, 15,
+	0, 1, 1, 2, 2, 2, 2, 2, 2, 4,
+	5, 7, 7, 7, 6, 3, 3, 10, 10, 8,
+	8, 8, 12, 12, 9, 9, 13, 13, 13, 14,
+	14, 14, 18, 18, 19, 19, 20, 11, 11, 11,
+	11, 11, 15, 16, 17,
 }
 
 var eskipR2 = [...]int{
-	0, 1, 1, 0, 1, 3, 2, 3, 1, 3,
-	5, 1, 3, 1, 4, 1, 3, 4, 0, 1,
-	3, 1, 1, 1, 1, 3, 1, 3, 3, 1,
-	1, 1, 1, 1, 1, 1, 1,
+	0, 1, 1, 0, 1, 1, 3, 3, 2, 3,
+	3, 1, 3, 3, 1, 3, 5, 1, 3, 1,
+	1, 4, 1, 3, 4, 1, 0, 1, 3, 1,
+	1, 1, 1, 3, 1, 3, 3, 1, 1, 1,
+	1, 1, 1, 1, 1,
 }
 
 var eskipChk = [...]int{
-	-1000, -1, -2, -3, -4, -6, -5, -9, 18, 5,
-	13, 6, 4, 8, 11, -4, 18, -7, -8, -14,
-	14, 15, 16, -18, -11, 17, 19, 18, -9, 18,
-	-3, -10, -12, -13, -14, -15, 10, 12, 6, -17,
-	-16, 18, -14, 11, 7, 9, -7, -11, 20, 9,
-	9, -10, -12, -14, -16, 7,
+	-1000, -1, -2, -3, -4, -5, -10, -6, 21, -8,
+	18, 5, 13, 6, 4, 8, 22, 11, -4, -5,
+	21, 18, -11, -12, -16, 14, 15, 16, -20, -9,
+	17, 19, 18, 21, -8, 21, 18, -3, -7, -8,
+	-13, -14, -15, -16, -17, 10, 12, 6, -19, -18,
+	18, -16, 11, 4, 6, 7, 9, -11, -9, 20,
+	9, 9, -13, -8, -9, -14, -16, -18, 7,
 }
 
 var eskipDef = [...]int{
-	3, -2, 1, 2, 4, 0, 0, 11, 8, 13,
-	6, 0, 0, 0, 18, 5, 8, 9, 0, 29,
-	30, 31, 32, 33, 15, 35, 0, 0, 12, 0,
-	7, 0, 19, 21, 22, 23, 34, 36, 0, 0,
-	26, 0, 24, 18, 14, 0, 10, 16, 28, 0,
-	0, 0, 20, 25, 27, 17,
+	3, -2, 1, 2, 4, 5, 0, 0, 20, 17,
+	14, 19, 8, 0, 0, 0, 0, 26, 6, 7,
+	0, 14, 15, 0, 37, 38, 39, 40, 41, 22,
+	43, 0, 0, 25, 18, 20, 0, 9, 10, 11,
+	0, 27, 29, 30, 31, 42, 44, 0, 0, 34,
+	0, 32, 26, 0, 0, 21, 0, 16, 23, 36,
+	0, 0, 0, 12, 13, 28, 33, 35, 24,
 }
 
 var eskipTok1 = [...]int{
@@ -165,7 +177,8 @@ var eskipTok1 = [...]int{
 
 var eskipTok2 = [...]int{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	12, 13, 14, 15, 16, 17, 18, 19, 20,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22,
 }
 
 var eskipTok3 = [...]int{
@@ -511,54 +524,94 @@ eskipdefault:
 
 	case 1:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:75
+//line parser.y:78
 		{
 			eskipVAL.routes = eskipDollar[1].routes
 			eskiplex.(*eskipLex).routes = eskipVAL.routes
 		}
 	case 2:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:80
+//line parser.y:83
 		{
 			eskipVAL.routes = []*parsedRoute{eskipDollar[1].route}
 			eskiplex.(*eskipLex).routes = eskipVAL.routes
 		}
 	case 4:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:87
+//line parser.y:90
 		{
 			eskipVAL.routes = []*parsedRoute{eskipDollar[1].route}
 		}
 	case 5:
+		eskipDollar = eskipS[eskippt-1 : eskippt+1]
+//line parser.y:94
+		{
+			eskipVAL.routes = nil
+		}
+	case 6:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:91
+//line parser.y:98
 		{
 			eskipVAL.routes = eskipDollar[1].routes
 			eskipVAL.routes = append(eskipVAL.routes, eskipDollar[3].route)
 		}
-	case 6:
+	case 7:
+		eskipDollar = eskipS[eskippt-3 : eskippt+1]
+//line parser.y:103
+		{
+			eskipVAL.routes = eskipDollar[1].routes
+		}
+	case 8:
 		eskipDollar = eskipS[eskippt-2 : eskippt+1]
-//line parser.y:96
+//line parser.y:107
 		{
 			eskipVAL.routes = eskipDollar[1].routes
 		}
-	case 7:
+	case 9:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:101
+//line parser.y:112
 		{
 			eskipVAL.route = eskipDollar[3].route
 			eskipVAL.route.id = eskipDollar[1].token
 		}
-	case 8:
+	case 10:
+		eskipDollar = eskipS[eskippt-3 : eskippt+1]
+//line parser.y:118
+		{
+			eskipVAL.definition = eskipDollar[3].definition
+			eskipVAL.definition.name = eskipDollar[1].token
+			eskiplex.(*eskipLex).definitions = append(eskiplex.(*eskipLex).definitions, eskipVAL.definition)
+		}
+	case 11:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:107
+//line parser.y:125
+		{
+			eskipVAL.definition = &definition{matchers: []*matcher{eskipDollar[1].matcher}}
+		}
+	case 12:
+		eskipDollar = eskipS[eskippt-3 : eskippt+1]
+//line parser.y:129
+		{
+			eskipVAL.definition = eskipDollar[1].definition
+			eskipVAL.definition.matchers = append(eskipVAL.definition.matchers, eskipDollar[3].matcher)
+		}
+	case 13:
+		eskipDollar = eskipS[eskippt-3 : eskippt+1]
+//line parser.y:134
+		{
+			eskipVAL.definition = eskipDollar[1].definition
+			eskipVAL.definition.filters = append(eskipVAL.definition.filters, eskipDollar[3].filter)
+		}
+	case 14:
+		eskipDollar = eskipS[eskippt-1 : eskippt+1]
+//line parser.y:140
 		{
 			eskipVAL.token = eskipDollar[1].token
 			eskiplex.(*eskipLex).lastRouteID = eskipDollar[1].token
 		}
-	case 9:
+	case 15:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:113
+//line parser.y:146
 		{
 			eskipVAL.route = &parsedRoute{
 				matchers:    eskipDollar[1].matchers,
@@ -573,9 +626,9 @@ eskipdefault:
 			eskipDollar[1].matchers = nil
 			eskipDollar[3].lbEndpoints = nil
 		}
-	case 10:
+	case 16:
 		eskipDollar = eskipS[eskippt-5 : eskippt+1]
-//line parser.y:128
+//line parser.y:161
 		{
 			eskipVAL.route = &parsedRoute{
 				matchers:    eskipDollar[1].matchers,
@@ -592,121 +645,133 @@ eskipdefault:
 			eskipDollar[3].filters = nil
 			eskipDollar[5].lbEndpoints = nil
 		}
-	case 11:
+	case 17:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:146
+//line parser.y:179
 		{
 			eskipVAL.matchers = []*matcher{eskipDollar[1].matcher}
 		}
-	case 12:
+	case 18:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:150
+//line parser.y:183
 		{
 			eskipVAL.matchers = eskipDollar[1].matchers
 			eskipVAL.matchers = append(eskipVAL.matchers, eskipDollar[3].matcher)
 		}
-	case 13:
+	case 19:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:156
+//line parser.y:189
 		{
 			eskipVAL.matcher = &matcher{"*", nil}
 		}
-	case 14:
+	case 20:
+		eskipDollar = eskipS[eskippt-1 : eskippt+1]
+//line parser.y:193
+		{
+			eskipVAL.matcher = &matcher{referencePrefix + eskipDollar[1].token, nil}
+		}
+	case 21:
 		eskipDollar = eskipS[eskippt-4 : eskippt+1]
-//line parser.y:160
+//line parser.y:197
 		{
 			eskipVAL.matcher = &matcher{eskipDollar[1].token, eskipDollar[3].args}
 			eskipDollar[3].args = nil
 		}
-	case 15:
+	case 22:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:166
+//line parser.y:203
 		{
 			eskipVAL.filters = []*Filter{eskipDollar[1].filter}
 		}
-	case 16:
+	case 23:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:170
+//line parser.y:207
 		{
 			eskipVAL.filters = eskipDollar[1].filters
 			eskipVAL.filters = append(eskipVAL.filters, eskipDollar[3].filter)
 		}
-	case 17:
+	case 24:
 		eskipDollar = eskipS[eskippt-4 : eskippt+1]
-//line parser.y:176
+//line parser.y:213
 		{
 			eskipVAL.filter = &Filter{
 				Name: eskipDollar[1].token,
 				Args: eskipDollar[3].args}
 			eskipDollar[3].args = nil
 		}
-	case 19:
+	case 25:
+		eskipDollar = eskipS[eskippt-1 : eskippt+1]
+//line parser.y:220
+		{
+			eskipVAL.filter = &Filter{Name: referencePrefix + eskipDollar[1].token}
+		}
+	case 27:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:185
+//line parser.y:226
 		{
 			eskipVAL.args = []interface{}{eskipDollar[1].arg}
 		}
-	case 20:
+	case 28:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:189
+//line parser.y:230
 		{
 			eskipVAL.args = eskipDollar[1].args
 			eskipVAL.args = append(eskipVAL.args, eskipDollar[3].arg)
 		}
-	case 21:
+	case 29:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:195
+//line parser.y:236
 		{
 			eskipVAL.arg = eskipDollar[1].numval
 		}
-	case 22:
+	case 30:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:199
+//line parser.y:240
 		{
 			eskipVAL.arg = eskipDollar[1].stringval
 		}
-	case 23:
+	case 31:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:203
+//line parser.y:244
 		{
 			eskipVAL.arg = eskipDollar[1].regexpval
 		}
-	case 24:
+	case 32:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:208
+//line parser.y:249
 		{
 			eskipVAL.stringvals = []string{eskipDollar[1].stringval}
 		}
-	case 25:
+	case 33:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:212
+//line parser.y:253
 		{
 			eskipVAL.stringvals = eskipDollar[1].stringvals
 			eskipVAL.stringvals = append(eskipVAL.stringvals, eskipDollar[3].stringval)
 		}
-	case 26:
+	case 34:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:218
+//line parser.y:259
 		{
 			eskipVAL.lbEndpoints = eskipDollar[1].stringvals
 		}
-	case 27:
+	case 35:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:222
+//line parser.y:263
 		{
 			eskipVAL.lbAlgorithm = eskipDollar[1].token
 			eskipVAL.lbEndpoints = eskipDollar[3].stringvals
 		}
-	case 28:
+	case 36:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-//line parser.y:228
+//line parser.y:269
 		{
 			eskipVAL.lbAlgorithm = eskipDollar[2].lbAlgorithm
 			eskipVAL.lbEndpoints = eskipDollar[2].lbEndpoints
 		}
-	case 29:
+	case 37:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:234
+//line parser.y:275
 		{
 			eskipVAL.backend = eskipDollar[1].stringval
 			eskipVAL.shunt = false
@@ -714,36 +779,36 @@ eskipdefault:
 			eskipVAL.dynamic = false
 			eskipVAL.lbBackend = false
 		}
-	case 30:
+	case 38:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:242
+//line parser.y:283
 		{
 			eskipVAL.shunt = true
 			eskipVAL.loopback = false
 			eskipVAL.dynamic = false
 			eskipVAL.lbBackend = false
 		}
-	case 31:
+	case 39:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:249
+//line parser.y:290
 		{
 			eskipVAL.shunt = false
 			eskipVAL.loopback = true
 			eskipVAL.dynamic = false
 			eskipVAL.lbBackend = false
 		}
-	case 32:
+	case 40:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:256
+//line parser.y:297
 		{
 			eskipVAL.shunt = false
 			eskipVAL.loopback = false
 			eskipVAL.dynamic = true
 			eskipVAL.lbBackend = false
 		}
-	case 33:
+	case 41:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:263
+//line parser.y:304
 		{
 			eskipVAL.shunt = false
 			eskipVAL.loopback = false
@@ -752,21 +817,21 @@ eskipdefault:
 			eskipVAL.lbAlgorithm = eskipDollar[1].lbAlgorithm
 			eskipVAL.lbEndpoints = eskipDollar[1].lbEndpoints
 		}
-	case 34:
+	case 42:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:273
+//line parser.y:314
 		{
 			eskipVAL.numval = convertNumber(eskipDollar[1].token)
 		}
-	case 35:
+	case 43:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:278
+//line parser.y:319
 		{
 			eskipVAL.stringval = eskipDollar[1].token
 		}
-	case 36:
+	case 44:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-//line parser.y:283
+//line parser.y:324
 		{
 			eskipVAL.regexpval = eskipDollar[1].token
 		}
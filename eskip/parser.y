@@ -49,6 +49,7 @@ func convertNumber(s string) float64 {
 	stringvals []string
 	lbAlgorithm string
 	lbEndpoints []string
+	definition *definition
 }
 
 %token and
@@ -68,6 +69,8 @@ func convertNumber(s string) float64 {
 %token symbol
 %token openarrow
 %token closearrow
+%token referencesymbol
+%token equals
 
 %%
 
@@ -88,11 +91,19 @@ routes:
 		$$.routes = []*parsedRoute{$1.route}
 	}
 	|
+	definition {
+		$$.routes = nil
+	}
+	|
 	routes semicolon routedef {
 		$$.routes = $1.routes
 		$$.routes = append($$.routes, $3.route)
 	}
 	|
+	routes semicolon definition {
+		$$.routes = $1.routes
+	}
+	|
 	routes semicolon {
 		$$.routes = $1.routes
 	}
@@ -103,6 +114,28 @@ routedef:
 		$$.route.id = $1.token
 	}
 
+definition:
+	referencesymbol equals definitionbody {
+		$$.definition = $3.definition
+		$$.definition.name = $1.token
+		eskiplex.(*eskipLex).definitions = append(eskiplex.(*eskipLex).definitions, $$.definition)
+	}
+
+definitionbody:
+	matcher {
+		$$.definition = &definition{matchers: []*matcher{$1.matcher}}
+	}
+	|
+	definitionbody and matcher {
+		$$.definition = $1.definition
+		$$.definition.matchers = append($$.definition.matchers, $3.matcher)
+	}
+	|
+	definitionbody arrow filter {
+		$$.definition = $1.definition
+		$$.definition.filters = append($$.definition.filters, $3.filter)
+	}
+
 routeid:
 	symbol {
 		$$.token = $1.token
@@ -157,6 +190,10 @@ matcher:
 		$$.matcher = &matcher{"*", nil}
 	}
 	|
+	referencesymbol {
+		$$.matcher = &matcher{referencePrefix + $1.token, nil}
+	}
+	|
 	symbol openparen args closeparen {
 		$$.matcher = &matcher{$1.token, $3.args}
 		$3.args = nil
@@ -179,6 +216,10 @@ filter:
 			Args: $3.args}
 		$3.args = nil
 	}
+	|
+	referencesymbol {
+		$$.filter = &Filter{Name: referencePrefix + $1.token}
+	}
 
 args:
 	|
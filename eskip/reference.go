@@ -0,0 +1,350 @@
+package eskip
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the parser keeps the references as placeholder predicates and filters
+// with the prefixed name, until they are expanded. Symbols cannot start
+// with the prefix, so the placeholders cannot conflict with the real
+// predicates and filters.
+const referencePrefix = "@"
+
+// A named predicate set or filter chain, e.g:
+//
+//	@auth = oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m");
+//
+// The parser collects the first element of the definition as a matcher,
+// because before the first separator it is not known whether the
+// definition is a predicate set or a filter chain.
+type definition struct {
+	name     string
+	matchers []*matcher
+	filters  []*Filter
+
+	// the references are created only once for every definition, so
+	// that the routes using the same definition share them
+	predicateRef *Reference
+	filterRef    *Reference
+}
+
+// Reference records that a route definition used a named predicate set
+// or filter chain, so that it can be preserved when printing the route in
+// a document.
+type Reference struct {
+	// Name of the definition, without the @ prefix.
+	Name string
+
+	// Predicate is true for predicate sets, and false for filter chains.
+	Predicate bool
+
+	// Items contains the printed predicates or filters of the
+	// definition, used to verify whether the route still contains them.
+	Items []string
+}
+
+func isReference(name string) bool {
+	return strings.HasPrefix(name, referencePrefix)
+}
+
+func referenceName(name string) string {
+	return strings.TrimPrefix(name, referencePrefix)
+}
+
+func (d *definition) validate() error {
+	for _, m := range d.matchers {
+		if isReference(m.name) {
+			return fmt.Errorf("invalid definition %s%s: references are not allowed in definitions", referencePrefix, d.name)
+		}
+	}
+
+	for _, f := range d.filters {
+		if isReference(f.Name) {
+			return fmt.Errorf("invalid definition %s%s: references are not allowed in definitions", referencePrefix, d.name)
+		}
+	}
+
+	if len(d.filters) > 0 && (len(d.matchers) > 1 || d.matchers[0].name == "*") {
+		return fmt.Errorf("invalid definition %s%s: mixed predicates and filters", referencePrefix, d.name)
+	}
+
+	return nil
+}
+
+// a definition with a single element can be used both as a predicate
+// set and as a filter chain.
+func (d *definition) predicates() ([]*matcher, error) {
+	if len(d.filters) > 0 {
+		return nil, fmt.Errorf("invalid reference %s%s: filter chain used as predicates", referencePrefix, d.name)
+	}
+
+	matchers := make([]*matcher, len(d.matchers))
+	for i, m := range d.matchers {
+		matchers[i] = &matcher{m.name, copyArgs(m.args)}
+	}
+
+	if d.predicateRef == nil {
+		d.predicateRef = &Reference{Name: d.name, Predicate: true}
+
+		// when the predicates are invalid, the route using them fails,
+		// too, so there is nothing to preserve
+		r := &Route{}
+		if err := applyPredicates(r, &parsedRoute{matchers: d.matchers}); err == nil {
+			d.predicateRef.Items = r.predicateList()
+		}
+	}
+
+	return matchers, nil
+}
+
+func (d *definition) filterChain() ([]*Filter, error) {
+	if len(d.matchers) > 1 || d.matchers[0].name == "*" {
+		return nil, fmt.Errorf("invalid reference %s%s: predicates used as filters", referencePrefix, d.name)
+	}
+
+	filters := []*Filter{{Name: d.matchers[0].name, Args: copyArgs(d.matchers[0].args)}}
+	for _, f := range d.filters {
+		filters = append(filters, f.Copy())
+	}
+
+	if d.filterRef == nil {
+		d.filterRef = &Reference{Name: d.name}
+		for _, f := range filters {
+			d.filterRef.Items = append(d.filterRef.Items, f.String())
+		}
+	}
+
+	return filters, nil
+}
+
+func (r *parsedRoute) expandReferences(definitions map[string]*definition) error {
+	var matchers []*matcher
+	for _, m := range r.matchers {
+		if !isReference(m.name) {
+			matchers = append(matchers, m)
+			continue
+		}
+
+		d, ok := definitions[referenceName(m.name)]
+		if !ok {
+			return fmt.Errorf("undefined reference: %s", m.name)
+		}
+
+		dm, err := d.predicates()
+		if err != nil {
+			return err
+		}
+
+		matchers = append(matchers, dm...)
+		r.references = append(r.references, d.predicateRef)
+	}
+
+	var filters []*Filter
+	for _, f := range r.filters {
+		if !isReference(f.Name) {
+			filters = append(filters, f)
+			continue
+		}
+
+		d, ok := definitions[referenceName(f.Name)]
+		if !ok {
+			return fmt.Errorf("undefined reference: %s", f.Name)
+		}
+
+		df, err := d.filterChain()
+		if err != nil {
+			return err
+		}
+
+		filters = append(filters, df...)
+		r.references = append(r.references, d.filterRef)
+	}
+
+	r.matchers = matchers
+	r.filters = filters
+	return nil
+}
+
+// expands the references to the named predicate sets and filter chains
+// in the parsed routes.
+func expandReferences(routes []*parsedRoute, definitions []*definition) error {
+	byName := make(map[string]*definition)
+	for _, d := range definitions {
+		if _, ok := byName[d.name]; ok {
+			return fmt.Errorf("duplicate definition: %s%s", referencePrefix, d.name)
+		}
+
+		if err := d.validate(); err != nil {
+			return err
+		}
+
+		byName[d.name] = d
+	}
+
+	for _, r := range routes {
+		if err := r.expandReferences(byName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// referencePrinter prints the references in the routes of a document
+// instead of the expanded predicates and filters, when the routes still
+// contain them, and collects the definitions that need to be printed.
+type referencePrinter struct {
+	definitions map[string]*Reference
+	used        []*Reference
+	usedNames   map[string]bool
+}
+
+func eqReferences(left, right *Reference) bool {
+	return left == right || left.Name == right.Name && left.Predicate == right.Predicate && eqStrings(left.Items, right.Items)
+}
+
+// returns nil when none of the routes contain references. When different
+// routes contain different definitions with the same name, e.g. because
+// they were loaded from different documents, only the first one is used.
+func newReferencePrinter(routes []*Route) *referencePrinter {
+	var rp *referencePrinter
+	for _, r := range routes {
+		for _, ref := range r.References {
+			if len(ref.Items) == 0 {
+				continue
+			}
+
+			if rp == nil {
+				rp = &referencePrinter{
+					definitions: make(map[string]*Reference),
+					usedNames:   make(map[string]bool),
+				}
+			}
+
+			if _, ok := rp.definitions[ref.Name]; !ok {
+				rp.definitions[ref.Name] = ref
+			}
+		}
+	}
+
+	return rp
+}
+
+func (rp *referencePrinter) use(ref *Reference) bool {
+	if d, ok := rp.definitions[ref.Name]; !ok || !eqReferences(d, ref) {
+		return false
+	}
+
+	if !rp.usedNames[ref.Name] {
+		rp.usedNames[ref.Name] = true
+		rp.used = append(rp.used, ref)
+	}
+
+	return true
+}
+
+// removes the items from the list, if the list contains all of them.
+func removeItems(list, items []string) ([]string, bool) {
+	rest := make([]string, len(list))
+	copy(rest, list)
+	for _, item := range items {
+		found := false
+		for i, li := range rest {
+			if li == item {
+				rest = append(rest[:i], rest[i+1:]...)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return rest, true
+}
+
+// replaces the predicates with the references to the predicate sets, when
+// the route contains all the predicates of the set. The order of the
+// predicates doesn't matter.
+func (rp *referencePrinter) predicates(r *Route, predicates []string) []string {
+	var refs []string
+	for _, ref := range r.References {
+		if !ref.Predicate || len(ref.Items) == 0 {
+			continue
+		}
+
+		if rest, ok := removeItems(predicates, ref.Items); ok && rp.use(ref) {
+			predicates = rest
+			refs = append(refs, referencePrefix+ref.Name)
+		}
+	}
+
+	return append(refs, predicates...)
+}
+
+func matchItems(filters []*Filter, items []string) bool {
+	if len(filters) < len(items) {
+		return false
+	}
+
+	for i, item := range items {
+		if filters[i].String() != item {
+			return false
+		}
+	}
+
+	return true
+}
+
+// replaces the filters with the references to the filter chains, when
+// the route still contains the complete chain.
+func (rp *referencePrinter) filters(r *Route) []string {
+	var refs []*Reference
+	for _, ref := range r.References {
+		if !ref.Predicate && len(ref.Items) > 0 {
+			refs = append(refs, ref)
+		}
+	}
+
+	var filters []string
+	for i := 0; i < len(r.Filters); {
+		matched := false
+		for j, ref := range refs {
+			if matchItems(r.Filters[i:], ref.Items) && rp.use(ref) {
+				filters = append(filters, referencePrefix+ref.Name)
+				i += len(ref.Items)
+				refs = append(refs[:j], refs[j+1:]...)
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			filters = append(filters, r.Filters[i].String())
+			i++
+		}
+	}
+
+	return filters
+}
+
+func (rp *referencePrinter) fprintDefinitions(w io.Writer, prettyPrintInfo PrettyPrintInfo) {
+	for _, ref := range rp.used {
+		separator := " -> "
+		switch {
+		case ref.Predicate:
+			separator = " && "
+		case prettyPrintInfo.Pretty:
+			separator = "\n" + prettyPrintInfo.IndentStr + "-> "
+		}
+
+		fmt.Fprintf(w, "%s%s = %s;\n", referencePrefix, ref.Name, strings.Join(ref.Items, separator))
+		if prettyPrintInfo.Pretty {
+			fmt.Fprint(w, "\n")
+		}
+	}
+}
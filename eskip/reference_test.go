@@ -0,0 +1,210 @@
+package eskip
+
+import (
+	"testing"
+)
+
+const referencesDoc = `
+	@auth = oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m");
+	@api = Host(/^api[.]example[.]org$/) && Method("GET");
+
+	r1: @api && Path("/foo") -> @auth -> setPath("/bar") -> "https://www.example.org";
+	r2: Path("/bar") -> setRequestHeader("X-Foo", "bar") -> @auth -> <shunt>;
+`
+
+func TestParseReferences(t *testing.T) {
+	routes, err := Parse(referencesDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := Parse(`
+		r1: Host(/^api[.]example[.]org$/) && Method("GET") && Path("/foo")
+			-> oauthTokeninfoAnyScope("read")
+			-> ratelimit(10, "1m")
+			-> setPath("/bar")
+			-> "https://www.example.org";
+		r2: Path("/bar")
+			-> setRequestHeader("X-Foo", "bar")
+			-> oauthTokeninfoAnyScope("read")
+			-> ratelimit(10, "1m")
+			-> <shunt>;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !EqLists(routes, expected) {
+		t.Errorf("invalid routes, expected: %v, got: %v", expected, routes)
+	}
+
+	if len(routes[0].References) != 2 || len(routes[1].References) != 1 {
+		t.Error("failed to record the references")
+	}
+}
+
+func TestParseReferencesSingleItem(t *testing.T) {
+	routes, err := Parse(`
+		@get = Method("GET");
+		@status = status(418);
+		r1: @get -> @status -> <shunt>;
+		r2: Path("/foo") -> @get -> <shunt>;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if routes[0].Method != "GET" || len(routes[0].Filters) != 1 || routes[0].Filters[0].Name != "status" {
+		t.Errorf("invalid route: %v", routes[0])
+	}
+
+	if len(routes[1].Filters) != 1 || routes[1].Filters[0].Name != "Method" {
+		t.Errorf("invalid route: %v", routes[1])
+	}
+}
+
+func TestParseReferencesErrors(t *testing.T) {
+	for _, ti := range []struct {
+		msg string
+		doc string
+	}{{
+		msg: "undefined predicates",
+		doc: `r1: @foo -> <shunt>`,
+	}, {
+		msg: "undefined filters",
+		doc: `r1: * -> @foo -> <shunt>`,
+	}, {
+		msg: "duplicate definition",
+		doc: `@foo = status(418); @foo = status(404); r1: * -> @foo -> <shunt>`,
+	}, {
+		msg: "mixed predicates and filters",
+		doc: `@foo = Method("GET") && Path("/foo") -> status(418)`,
+	}, {
+		msg: "any in filter chain",
+		doc: `@foo = * -> status(418)`,
+	}, {
+		msg: "filter chain used as predicates",
+		doc: `@foo = status(418) -> status(404); r1: @foo -> <shunt>`,
+	}, {
+		msg: "predicates used as filters",
+		doc: `@foo = Method("GET") && Path("/foo"); r1: * -> @foo -> <shunt>`,
+	}, {
+		msg: "reference in definition",
+		doc: `@foo = status(418); @bar = @foo -> status(404)`,
+	}, {
+		msg: "missing name",
+		doc: `@ = status(418)`,
+	}} {
+		t.Run(ti.msg, func(t *testing.T) {
+			if _, err := Parse(ti.doc); err == nil {
+				t.Error("failed to fail")
+			}
+		})
+	}
+}
+
+func TestParseFiltersReferences(t *testing.T) {
+	if _, err := ParseFilters(`@foo -> status(418)`); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func TestPrintReferences(t *testing.T) {
+	routes, err := Parse(referencesDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("preserved", func(t *testing.T) {
+		expected := `@api = Host(/^api[.]example[.]org$/) && Method("GET");
+@auth = oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m");
+r1: @api && Path("/foo") -> @auth -> setPath("/bar") -> "https://www.example.org";
+r2: Path("/bar") -> setRequestHeader("X-Foo", "bar") -> @auth -> <shunt>;`
+
+		if s := String(routes...); s != expected {
+			t.Errorf("invalid document, expected: %s, got: %s", expected, s)
+		}
+	})
+
+	t.Run("pretty", func(t *testing.T) {
+		expected := `@auth = oauthTokeninfoAnyScope("read")
+  -> ratelimit(10, "1m");
+
+r2: Path("/bar")
+  -> setRequestHeader("X-Foo", "bar")
+  -> @auth
+  -> <shunt>;`
+
+		if s := Print(PrettyPrintInfo{Pretty: true, IndentStr: "  "}, routes[1]); s != expected {
+			t.Errorf("invalid document, expected: %s, got: %s", expected, s)
+		}
+	})
+
+	t.Run("expanded", func(t *testing.T) {
+		expected := `r2: Path("/bar") -> setRequestHeader("X-Foo", "bar") -> oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m") -> <shunt>;`
+		if s := Print(PrettyPrintInfo{ExpandReferences: true}, routes[1]); s != expected {
+			t.Errorf("invalid document, expected: %s, got: %s", expected, s)
+		}
+	})
+
+	t.Run("route expression", func(t *testing.T) {
+		expected := `Path("/bar") -> setRequestHeader("X-Foo", "bar") -> oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m") -> <shunt>`
+		if s := routes[1].String(); s != expected {
+			t.Errorf("invalid route, expected: %s, got: %s", expected, s)
+		}
+	})
+
+	t.Run("modified route", func(t *testing.T) {
+		r := routes[0].Copy()
+		r.Filters = r.Filters[1:]
+		r.Method = ""
+
+		expected := `r1: Path("/foo") && Host(/^api[.]example[.]org$/) -> ratelimit(10, "1m") -> setPath("/bar") -> "https://www.example.org";`
+		if s := String(r); s != expected {
+			t.Errorf("invalid document, expected: %s, got: %s", expected, s)
+		}
+	})
+
+	t.Run("prepended filters", func(t *testing.T) {
+		r := routes[1].Copy()
+		r.Filters = append([]*Filter{{Name: "status", Args: []interface{}{float64(418)}}}, r.Filters...)
+
+		expected := `@auth = oauthTokeninfoAnyScope("read") -> ratelimit(10, "1m");
+r2: Path("/bar") -> status(418) -> setRequestHeader("X-Foo", "bar") -> @auth -> <shunt>;`
+
+		if s := String(r); s != expected {
+			t.Errorf("invalid document, expected: %s, got: %s", expected, s)
+		}
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		back, err := Parse(String(routes...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !EqLists(routes, back) {
+			t.Errorf("failed to parse the printed document, expected: %v, got: %v", routes, back)
+		}
+	})
+}
+
+func TestPrintReferencesConflictingDefinitions(t *testing.T) {
+	r1, err := Parse(`@foo = status(418); r1: * -> @foo -> <shunt>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r2, err := Parse(`@foo = status(404); r2: * -> @foo -> <shunt>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `@foo = status(418);
+r1: * -> @foo -> <shunt>;
+r2: * -> status(404) -> <shunt>;`
+
+	if s := String(append(r1, r2...)...); s != expected {
+		t.Errorf("invalid document, expected: %s, got: %s", expected, s)
+	}
+}
@@ -11,6 +11,11 @@ import (
 type PrettyPrintInfo struct {
 	Pretty    bool
 	IndentStr string
+
+	// ExpandReferences prints the named predicate sets and filter
+	// chains expanded in the routes of a document, instead of
+	// preserving the references to their definitions.
+	ExpandReferences bool
 }
 
 func escape(s string, chars string) string {
@@ -78,7 +83,7 @@ func argsString(args []interface{}) string {
 	return strings.Join(sargs, ", ")
 }
 
-func (r *Route) predicateString() string {
+func (r *Route) predicateList() []string {
 	var predicates []string
 
 	if r.Path != "" {
@@ -113,6 +118,15 @@ func (r *Route) predicateString() string {
 		}
 	}
 
+	return predicates
+}
+
+func (r *Route) predicateString(rp *referencePrinter) string {
+	predicates := r.predicateList()
+	if rp != nil {
+		predicates = rp.predicates(r, predicates)
+	}
+
 	if len(predicates) == 0 {
 		predicates = append(predicates, "*")
 	}
@@ -120,11 +134,16 @@ func (r *Route) predicateString() string {
 	return strings.Join(predicates, " && ")
 }
 
-func (r *Route) filterString(prettyPrintInfo PrettyPrintInfo) string {
+func (r *Route) filterString(prettyPrintInfo PrettyPrintInfo, rp *referencePrinter) string {
 	var sfilters []string
-	for _, f := range r.Filters {
-		sfilters = appendFmt(sfilters, "%s(%s)", f.Name, argsString(f.Args))
+	if rp != nil {
+		sfilters = rp.filters(r)
+	} else {
+		for _, f := range r.Filters {
+			sfilters = appendFmt(sfilters, "%s(%s)", f.Name, argsString(f.Args))
+		}
 	}
+
 	if prettyPrintInfo.Pretty {
 		return strings.Join(sfilters, "\n"+prettyPrintInfo.IndentStr+"-> ")
 	}
@@ -180,10 +199,16 @@ func (r *Route) String() string {
 	return r.Print(PrettyPrintInfo{Pretty: false, IndentStr: ""})
 }
 
+// Print serializes a route expression, with the named predicate sets and
+// filter chains expanded. Omits the route id if any.
 func (r *Route) Print(prettyPrintInfo PrettyPrintInfo) string {
-	s := []string{r.predicateString()}
+	return r.print(prettyPrintInfo, nil)
+}
 
-	fs := r.filterString(prettyPrintInfo)
+func (r *Route) print(prettyPrintInfo PrettyPrintInfo, rp *referencePrinter) string {
+	s := []string{r.predicateString(rp)}
+
+	fs := r.filterString(prettyPrintInfo, rp)
 	if fs != "" {
 		s = append(s, fs)
 	}
@@ -204,7 +229,10 @@ func String(routes ...*Route) string {
 // Print serializes a set of routes into a string. If there's only a
 // single route, and its ID is not set, it prints only a route expression.
 // If it has multiple routes with IDs, it prints full route definitions
-// with the IDs and separated by ';'.
+// with the IDs and separated by ';'. When printing route definitions, the
+// references to the named predicate sets and filter chains, that the
+// routes were parsed with, are preserved, as long as the routes still
+// contain them, unless ExpandReferences is set.
 func Print(pretty PrettyPrintInfo, routes ...*Route) string {
 	var buf bytes.Buffer
 	Fprint(&buf, pretty, routes...)
@@ -219,11 +247,11 @@ func fprintExpression(w io.Writer, route *Route, prettyPrintInfo PrettyPrintInfo
 	fmt.Fprint(w, route.Print(prettyPrintInfo))
 }
 
-func fprintDefinition(w io.Writer, route *Route, prettyPrintInfo PrettyPrintInfo) {
-	fmt.Fprintf(w, "%s: %s", route.Id, route.Print(prettyPrintInfo))
+func fprintDefinition(w io.Writer, route *Route, prettyPrintInfo PrettyPrintInfo, rp *referencePrinter) {
+	fmt.Fprintf(w, "%s: %s", route.Id, route.print(prettyPrintInfo, rp))
 }
 
-func fprintDefinitions(w io.Writer, routes []*Route, prettyPrintInfo PrettyPrintInfo) {
+func fprintRouteDefinitions(w io.Writer, routes []*Route, prettyPrintInfo PrettyPrintInfo, rp *referencePrinter) {
 	for i, r := range routes {
 		if i > 0 {
 			fmt.Fprint(w, "\n")
@@ -232,11 +260,29 @@ func fprintDefinitions(w io.Writer, routes []*Route, prettyPrintInfo PrettyPrint
 			}
 		}
 
-		fprintDefinition(w, r, prettyPrintInfo)
+		fprintDefinition(w, r, prettyPrintInfo, rp)
 		fmt.Fprint(w, ";")
 	}
 }
 
+func fprintDefinitions(w io.Writer, routes []*Route, prettyPrintInfo PrettyPrintInfo) {
+	var rp *referencePrinter
+	if !prettyPrintInfo.ExpandReferences {
+		rp = newReferencePrinter(routes)
+	}
+
+	if rp == nil {
+		fprintRouteDefinitions(w, routes, prettyPrintInfo, nil)
+		return
+	}
+
+	// the used definitions are known only after the routes were printed
+	var buf bytes.Buffer
+	fprintRouteDefinitions(&buf, routes, prettyPrintInfo, rp)
+	rp.fprintDefinitions(w, prettyPrintInfo)
+	buf.WriteTo(w)
+}
+
 func Fprint(w io.Writer, prettyPrintInfo PrettyPrintInfo, routes ...*Route) {
 	if len(routes) == 0 {
 		return
@@ -14,6 +14,12 @@ const (
 
 	// AccessLogAdditionalDataKey is the key used in the state bag to pass extra data to access log
 	AccessLogAdditionalDataKey = "statebag:access_log:additional"
+
+	// AccessLogFormatKey is the key used in the state bag to pass the access log format of the route
+	AccessLogFormatKey = "statebag:access_log:format"
+
+	// AccessLogSampleRateKey is the key used in the state bag to pass the access log sample rate of the route
+	AccessLogSampleRateKey = "statebag:access_log:sample_rate"
 )
 
 // Common filter struct for holding access log state
@@ -0,0 +1,88 @@
+package accesslog
+
+import (
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/logging"
+)
+
+type accessLogFormat struct {
+	format *logging.AccessLogFormat
+}
+
+// NewAccessLogFormat creates a filter spec to override the global access
+// log format for specific routes. It takes a template with ${name}
+// placeholders, see logging.ParseAccessLogFormat.
+//
+//	accessLogFormat("${remote_host} ${method} ${uri} ${status} ${route_id} ${backend} ${backend_duration}")
+func NewAccessLogFormat() filters.Spec {
+	return &accessLogFormat{}
+}
+
+func (*accessLogFormat) Name() string { return filters.AccessLogFormatName }
+
+func (*accessLogFormat) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) != 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	f, err := logging.ParseAccessLogFormat(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &accessLogFormat{format: f}, nil
+}
+
+func (f *accessLogFormat) Request(ctx filters.FilterContext) {
+	ctx.StateBag()[AccessLogFormatKey] = f.format
+}
+
+func (*accessLogFormat) Response(filters.FilterContext) {}
+
+type sampleAccessLog struct {
+	rate float64
+}
+
+// NewSampleAccessLog creates a filter spec to log only a ratio of the
+// requests of specific routes in the access log, overriding the global
+// sample rate.
+//
+//	sampleAccessLog(0.1)  to log every 10th request on average
+func NewSampleAccessLog() filters.Spec {
+	return &sampleAccessLog{}
+}
+
+func (*sampleAccessLog) Name() string { return filters.SampleAccessLogName }
+
+func (*sampleAccessLog) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) != 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	var rate float64
+	switch r := args[0].(type) {
+	case float64:
+		rate = r
+	case int:
+		rate = float64(r)
+	default:
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	if rate <= 0 || rate > 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return &sampleAccessLog{rate: rate}, nil
+}
+
+func (s *sampleAccessLog) Request(ctx filters.FilterContext) {
+	ctx.StateBag()[AccessLogSampleRateKey] = s.rate
+}
+
+func (*sampleAccessLog) Response(filters.FilterContext) {}
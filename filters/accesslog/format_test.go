@@ -0,0 +1,50 @@
+package accesslog
+
+import (
+	"testing"
+
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/logging"
+)
+
+func TestAccessLogFormat(t *testing.T) {
+	spec := NewAccessLogFormat()
+	for _, args := range [][]interface{}{nil, {42}, {"${unknown}"}, {"${status}", "${status}"}} {
+		if _, err := spec.CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+
+	f, err := spec.CreateFilter([]interface{}{"${status} ${route_id}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &filtertest.Context{FStateBag: make(map[string]interface{})}
+	f.Request(ctx)
+	if format, ok := ctx.FStateBag[AccessLogFormatKey].(*logging.AccessLogFormat); !ok || format.String() != "${status} ${route_id}" {
+		t.Errorf("unexpected format in the state bag: %v", ctx.FStateBag[AccessLogFormatKey])
+	}
+}
+
+func TestSampleAccessLog(t *testing.T) {
+	spec := NewSampleAccessLog()
+	for _, args := range [][]interface{}{nil, {"0.5"}, {0.0}, {1.5}, {-1}, {0.5, 0.5}} {
+		if _, err := spec.CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+
+	for _, args := range [][]interface{}{{0.25}, {1}} {
+		f, err := spec.CreateFilter(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := &filtertest.Context{FStateBag: make(map[string]interface{})}
+		f.Request(ctx)
+		if rate, _ := ctx.FStateBag[AccessLogSampleRateKey].(float64); rate <= 0 || rate > 1 {
+			t.Errorf("unexpected sample rate: %v", ctx.FStateBag[AccessLogSampleRateKey])
+		}
+	}
+}
@@ -84,7 +84,7 @@ func (f *apiUsageMonitoringFilter) Response(c filters.FilterContext) {
 
 	// Client metrics
 	if path.ClientTracking != nil {
-		realmClientKey := f.getRealmClientKey(request, path)
+		realmClientKey := f.getRealmClientKey(request, c.StateBag(), path)
 		clientMetricsNames := getClientMetricsNames(realmClientKey, path)
 		metrics.IncCounter(clientMetricsNames.countAll)
 		metrics.IncCounter(clientMetricsNames.countPerStatusCodeRange[classMetricsIndex])
@@ -123,15 +123,26 @@ func getClientMetricsNames(realmClientKey string, path *pathInfo) *clientMetricN
 const unknownUnknown = unknownPlaceholder + "." + unknownPlaceholder
 
 // getRealmClientKey generates the proper <realm>.<client> part of the client metrics name.
-func (f *apiUsageMonitoringFilter) getRealmClientKey(r *http.Request, path *pathInfo) string {
-	// no JWT ==> {unknown}.{unknown}
+func (f *apiUsageMonitoringFilter) getRealmClientKey(r *http.Request, stateBag map[string]interface{}, path *pathInfo) string {
+	var (
+		realm, client string
+		ok, clientOK  bool
+	)
+
 	jwt := parseJwtBody(r)
-	if jwt == nil {
-		return unknownUnknown
+	if jwt != nil {
+		realm, ok = jwt.getOneOfString(f.realmKeys)
 	}
 
-	// no realm in JWT ==> {unknown}.{unknown}
-	realm, ok := jwt.getOneOfString(f.realmKeys)
+	if ok {
+		client, clientOK = jwt.getOneOfString(f.clientKeys)
+	} else if id, isString := stateBag[filters.ClientIDKey].(string); isString && id != "" {
+		// clients authenticated by other means than JWT, e.g. API keys,
+		// are tracked in their own realm
+		realm, client, ok, clientOK = clientIDRealm, id, true, true
+	}
+
+	// no JWT or no realm in JWT ==> {unknown}.{unknown}
 	if !ok {
 		return unknownUnknown
 	}
@@ -142,8 +153,7 @@ func (f *apiUsageMonitoringFilter) getRealmClientKey(r *http.Request, path *path
 	}
 
 	// no client in JWT ==> realm.{unknown}
-	client, ok := jwt.getOneOfString(f.clientKeys)
-	if !ok {
+	if !clientOK {
 		return realm + "." + unknownPlaceholder
 	}
 
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"sync"
 	"testing"
+
+	"github.com/zalando/skipper/filters"
 )
 
 type clientMetricsTest struct {
@@ -15,6 +17,7 @@ type clientMetricsTest struct {
 	clientKeyName         string
 	clientTrackingPattern *string
 	header                http.Header
+	stateBag              map[string]interface{}
 
 	expectedEndpointMetricPrefix string
 	expectedClientMetricPrefix   string
@@ -388,6 +391,31 @@ func Test_Filter_ClientMetrics_EmptyClientTrackingPatternInRouteFilterJSON(t *te
 	})
 }
 
+func Test_Filter_ClientMetrics_ClientIDFromStateBag(t *testing.T) {
+	testClientMetrics(t, clientMetricsTest{
+		realmKeyName:                 "realm",
+		clientKeyName:                "client",
+		clientTrackingPattern:        s(".*"),
+		realmsTrackingPattern:        "services|apikey",
+		stateBag:                     map[string]interface{}{filters.ClientIDKey: "acme"},
+		expectedEndpointMetricPrefix: "apiUsageMonitoring.custom.my_app.my_tag.my_api.GET.foo/orders.*.*.",
+		expectedClientMetricPrefix:   "apiUsageMonitoring.custom.my_app.my_tag.my_api.*.*.apikey.acme.",
+	})
+}
+
+func Test_Filter_ClientMetrics_JWTPrecedesClientIDFromStateBag(t *testing.T) {
+	testClientMetrics(t, clientMetricsTest{
+		realmKeyName:                 "realm",
+		clientKeyName:                "client",
+		clientTrackingPattern:        s(".*"),
+		realmsTrackingPattern:        "users|apikey",
+		header:                       headerUsersJoe,
+		stateBag:                     map[string]interface{}{filters.ClientIDKey: "acme"},
+		expectedEndpointMetricPrefix: "apiUsageMonitoring.custom.my_app.my_tag.my_api.GET.foo/orders.*.*.",
+		expectedClientMetricPrefix:   "apiUsageMonitoring.custom.my_app.my_tag.my_api.*.*.users.joe.",
+	})
+}
+
 // may produce false-negatives
 func Test_Filter_ClientMetricsCache_ConcurrentAccess(t *testing.T) {
 	pathInfo := newPathInfo("application_id", "tag", "api_id", "orders",
@@ -19,6 +19,10 @@ const (
 	unknownPlaceholder = "{unknown}"
 	noMatchPlaceholder = "{no-match}"
 	noTagPlaceholder   = "{no-tag}"
+
+	// clientIDRealm is the realm of the clients identified by the
+	// client id in the state bag, e.g. by the apiKey filter
+	clientIDRealm = "apikey"
 )
 
 var (
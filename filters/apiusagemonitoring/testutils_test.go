@@ -98,6 +98,7 @@ type testWithFilterConf struct {
 	url          string
 	resStatus    *int
 	header       http.Header
+	stateBag     map[string]interface{}
 }
 
 func testWithFilterConfig(
@@ -168,6 +169,9 @@ func testWithFilterConfig(
 				FStateBag: make(map[string]interface{}),
 				FMetrics:  metricsMock,
 			}
+			for k, v := range conf.stateBag {
+				ctx.FStateBag[k] = v
+			}
 			filter.Request(ctx)
 			filter.Response(ctx)
 
@@ -181,8 +185,9 @@ func testWithFilterConfig(
 
 func testClientMetrics(t *testing.T, testCase clientMetricsTest) {
 	conf := testWithFilterConf{
-		url:    testCase.url,
-		header: testCase.header,
+		url:      testCase.url,
+		header:   testCase.header,
+		stateBag: testCase.stateBag,
 		filterCreate: func() (filters.Filter, error) {
 			filterConf := map[string]interface{}{
 				"application_id": "my_app",
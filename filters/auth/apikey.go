@@ -0,0 +1,418 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/ratelimit"
+	"github.com/zalando/skipper/secrets"
+)
+
+const (
+	apiKeyHeader   = "header"
+	apiKeyQuery    = "query"
+	apiKeyScopes   = "scopes"
+	apiKeyCacheTTL = "cacheTTL"
+
+	defaultApiKeyHeader = "X-Api-Key"
+
+	// apiKeyRedisPrefix marks the Redis stores in the first argument of
+	// the filter, e.g. redis:apikeys:
+	apiKeyRedisPrefix = "redis:"
+
+	defaultApiKeyCacheTTL  = 10 * time.Second
+	maxApiKeyCacheSize     = 10000
+	apiKeyHashPrefix       = "sha256:"
+	apiKeyRatelimitGroup   = "apikey"
+	apiKeyRedisLookupLimit = time.Second
+)
+
+const (
+	missingApiKey rejectReason = "missing-api-key"
+	invalidApiKey rejectReason = "invalid-api-key"
+)
+
+// ApiKeyOptions are used to configure the apiKey filter specification.
+type ApiKeyOptions struct {
+	// Secrets is used to read the file based key stores.
+	Secrets secrets.SecretsReader
+
+	// Redis, when set, enables the Redis based key stores.
+	Redis ApiKeyRedisClient
+
+	// Ratelimits, when set, enables the per-key rate limits.
+	Ratelimits *ratelimit.Registry
+
+	// ClusterRatelimit makes the per-key rate limits shared across
+	// the swarm.
+	ClusterRatelimit bool
+}
+
+// ApiKeyRedisClient is the subset of the Redis ring client used by
+// the Redis based key stores.
+type ApiKeyRedisClient interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type (
+	// apiKeyRecord is the metadata of a key in the store. Only the
+	// SHA-256 hash of the key is stored, in hex, optionally prefixed
+	// with sha256:.
+	apiKeyRecord struct {
+		Hash      string     `json:"hash"`
+		ClientID  string     `json:"clientId"`
+		Scopes    []string   `json:"scopes"`
+		RateLimit string     `json:"rateLimit"`
+		Expires   *time.Time `json:"expires"`
+
+		ratelimit *ratelimit.Settings
+	}
+
+	apiKeyStore interface {
+		lookup(ctx context.Context, hash string) (*apiKeyRecord, error)
+	}
+
+	// apiKeyFileStore reads the records from a JSON array stored in a
+	// secret. The records are parsed again when the secret changes.
+	apiKeyFileStore struct {
+		records *secrets.Parser
+	}
+
+	// apiKeyRedisStore reads the records from the Redis keys made of
+	// a prefix and the hash of the API key, and caches them, including
+	// the missing ones, for a short time.
+	apiKeyRedisStore struct {
+		client ApiKeyRedisClient
+		prefix string
+		ttl    time.Duration
+		cache  *ttlCache
+	}
+
+	apiKeySpec struct {
+		options ApiKeyOptions
+
+		mu     sync.Mutex
+		stores map[string]apiKeyStore
+	}
+
+	apiKeyFilter struct {
+		store            apiKeyStore
+		header           string
+		query            string
+		scopes           []string
+		ratelimits       *ratelimit.Registry
+		clusterRatelimit bool
+	}
+)
+
+// NewApiKey creates the filter specification of the apiKey filter. The
+// filter authenticates the requests with the API key found in a header,
+// X-Api-Key by default, or in a query parameter, and stores the client id
+// of the key in the state bag, where the ratelimit and the
+// apiUsageMonitoring filters can find it. The first argument is the key
+// store: the path of a secret containing a JSON array of key records, or
+// a Redis key prefix, prefixed with redis:. The rest of the arguments are
+// key-value options:
+//
+//	apiKey("/secrets/apikeys.json")
+//	apiKey("redis:apikeys:", "header", "Api-Key", "query", "api_key", "scopes", "orders.read,orders.write")
+func NewApiKey(o ApiKeyOptions) filters.Spec {
+	return &apiKeySpec{options: o, stores: make(map[string]apiKeyStore)}
+}
+
+func (*apiKeySpec) Name() string { return filters.ApiKeyName }
+
+func (s *apiKeySpec) getStore(source string, ttl time.Duration) (apiKeyStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := fmt.Sprintf("%s|%v", source, ttl)
+	if st, ok := s.stores[key]; ok {
+		return st, nil
+	}
+
+	var st apiKeyStore
+	if strings.HasPrefix(source, apiKeyRedisPrefix) {
+		if s.options.Redis == nil {
+			return nil, errors.New("redis key stores are not supported")
+		}
+
+		st = &apiKeyRedisStore{
+			client: s.options.Redis,
+			prefix: strings.TrimPrefix(source, apiKeyRedisPrefix),
+			ttl:    ttl,
+			cache:  newTTLCache(maxApiKeyCacheSize),
+		}
+	} else {
+		if s.options.Secrets == nil {
+			return nil, errors.New("file key stores are not supported")
+		}
+
+		st = &apiKeyFileStore{records: secrets.NewParser(s.options.Secrets, parseApiKeyRecords, source)}
+	}
+
+	s.stores[key] = st
+	return st, nil
+}
+
+func (s *apiKeySpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) == 0 || len(args)%2 != 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	sargs, err := getStrings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if sargs[0] == "" || sargs[0] == apiKeyRedisPrefix {
+		return nil, fmt.Errorf("%s: invalid key store: %s", filters.ApiKeyName, sargs[0])
+	}
+
+	f := &apiKeyFilter{
+		header:           defaultApiKeyHeader,
+		ratelimits:       s.options.Ratelimits,
+		clusterRatelimit: s.options.ClusterRatelimit,
+	}
+
+	ttl := defaultApiKeyCacheTTL
+	for i := 1; i < len(sargs); i += 2 {
+		value := sargs[i+1]
+		switch sargs[i] {
+		case apiKeyHeader:
+			f.header = value
+		case apiKeyQuery:
+			f.query = value
+		case apiKeyScopes:
+			f.scopes = splitList(value)
+		case apiKeyCacheTTL:
+			if ttl, err = time.ParseDuration(value); err != nil || ttl < 0 {
+				return nil, fmt.Errorf("%s: invalid cache ttl: %s", filters.ApiKeyName, value)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown option: %s", filters.ApiKeyName, sargs[i])
+		}
+	}
+
+	if f.store, err = s.getStore(sargs[0], ttl); err != nil {
+		return nil, fmt.Errorf("%s: %w", filters.ApiKeyName, err)
+	}
+
+	return f, nil
+}
+
+func apiKeyHash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// parseApiKeyRatelimit parses the per-key rate limits in the format of
+// <max hits>/<time window>, e.g. 100/1m.
+func parseApiKeyRatelimit(s string) (*ratelimit.Settings, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid rate limit: %s", s)
+	}
+
+	maxHits, err := strconv.Atoi(parts[0])
+	if err != nil || maxHits <= 0 {
+		return nil, fmt.Errorf("invalid rate limit: %s", s)
+	}
+
+	timeWindow, err := time.ParseDuration(parts[1])
+	if err != nil || timeWindow <= 0 {
+		return nil, fmt.Errorf("invalid rate limit: %s", s)
+	}
+
+	return &ratelimit.Settings{
+		MaxHits:       maxHits,
+		TimeWindow:    timeWindow,
+		CleanInterval: 10 * timeWindow,
+		Group:         apiKeyRatelimitGroup,
+	}, nil
+}
+
+func (r *apiKeyRecord) init() error {
+	r.Hash = strings.ToLower(strings.TrimPrefix(r.Hash, apiKeyHashPrefix))
+	if r.ClientID == "" {
+		return errors.New("missing client id")
+	}
+
+	if r.RateLimit != "" {
+		var err error
+		if r.ratelimit, err = parseApiKeyRatelimit(r.RateLimit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *apiKeyRecord) expired() bool {
+	return r.Expires != nil && time.Now().After(*r.Expires)
+}
+
+func parseApiKeyRecords(content ...[]byte) (interface{}, error) {
+	var records []*apiKeyRecord
+	if err := json.Unmarshal(content[0], &records); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*apiKeyRecord, len(records))
+	for i, r := range records {
+		if err := r.init(); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+
+		if len(r.Hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("record %d: invalid hash", i)
+		}
+
+		keys[r.Hash] = r
+	}
+
+	return keys, nil
+}
+
+func (s *apiKeyFileStore) lookup(_ context.Context, hash string) (*apiKeyRecord, error) {
+	keys, err := s.records.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.(map[string]*apiKeyRecord)[hash], nil
+}
+
+func (s *apiKeyRedisStore) lookup(ctx context.Context, hash string) (*apiKeyRecord, error) {
+	if r, ok := s.cache.get(hash); ok {
+		return r.(*apiKeyRecord), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiKeyRedisLookupLimit)
+	defer cancel()
+
+	v, err := s.client.Get(ctx, s.prefix+hash)
+	if err == redis.Nil {
+		s.store(hash, nil)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var r apiKeyRecord
+	if err := json.Unmarshal([]byte(v), &r); err != nil {
+		return nil, fmt.Errorf("invalid key record %s%s: %w", s.prefix, hash, err)
+	}
+
+	if err := r.init(); err != nil {
+		return nil, fmt.Errorf("invalid key record %s%s: %w", s.prefix, hash, err)
+	}
+
+	s.store(hash, &r)
+	return &r, nil
+}
+
+func (s *apiKeyRedisStore) store(hash string, r *apiKeyRecord) {
+	if s.ttl > 0 {
+		s.cache.set(hash, r, time.Now().Add(s.ttl))
+	}
+}
+
+func (f *apiKeyFilter) getKey(r *http.Request) string {
+	if k := r.Header.Get(f.header); k != "" {
+		return k
+	}
+
+	if f.query != "" {
+		return r.URL.Query().Get(f.query)
+	}
+
+	return ""
+}
+
+// removeKey makes sure that the key is not forwarded to the backend.
+func (f *apiKeyFilter) removeKey(r *http.Request) {
+	r.Header.Del(f.header)
+	if f.query == "" {
+		return
+	}
+
+	if q := r.URL.Query(); q.Get(f.query) != "" {
+		q.Del(f.query)
+		r.URL.RawQuery = q.Encode()
+	}
+}
+
+// allow applies the rate limit of the key, when it has one.
+func (f *apiKeyFilter) allow(ctx filters.FilterContext, r *apiKeyRecord) bool {
+	if r.ratelimit == nil || f.ratelimits == nil {
+		return true
+	}
+
+	s := *r.ratelimit
+	s.Type = ratelimit.ClientRatelimit
+	if f.clusterRatelimit {
+		s.Type = ratelimit.ClusterClientRatelimit
+	}
+
+	rl := f.ratelimits.Get(s)
+	if rl == nil || rl.AllowContext(ctx.Request().Context(), r.ClientID) {
+		return true
+	}
+
+	ctx.StateBag()[filters.ClientIDKey] = r.ClientID
+	ctx.Serve(&http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     ratelimit.Headers(s.MaxHits, s.TimeWindow, rl.RetryAfter(r.ClientID)),
+	})
+
+	return false
+}
+
+func (f *apiKeyFilter) Request(ctx filters.FilterContext) {
+	key := f.getKey(ctx.Request())
+	if key == "" {
+		unauthorized(ctx, "", missingApiKey, "", "")
+		return
+	}
+
+	r, err := f.store.lookup(ctx.Request().Context(), apiKeyHash(key))
+	if err != nil {
+		log.Errorf("Failed to look up api key: %v", err)
+		unauthorized(ctx, "", authServiceAccess, "", err.Error())
+		return
+	}
+
+	if r == nil || r.expired() {
+		unauthorized(ctx, "", invalidApiKey, "", "")
+		return
+	}
+
+	if !all(f.scopes, r.Scopes) {
+		forbidden(ctx, r.ClientID, invalidScope, "")
+		return
+	}
+
+	if !f.allow(ctx, r) {
+		return
+	}
+
+	f.removeKey(ctx.Request())
+	ctx.StateBag()[filters.ClientIDKey] = r.ClientID
+	authorized(ctx, r.ClientID)
+}
+
+func (*apiKeyFilter) Response(filters.FilterContext) {}
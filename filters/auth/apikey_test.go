@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/ratelimit"
+)
+
+type apiKeyTestRedis struct {
+	values map[string]string
+	calls  int
+}
+
+func (r *apiKeyTestRedis) Get(_ context.Context, key string) (string, error) {
+	r.calls++
+	v, ok := r.values[key]
+	if !ok {
+		return "", redis.Nil
+	}
+
+	return v, nil
+}
+
+func apiKeyRequest(t *testing.T, f filters.Filter, url string, header http.Header) *filtertest.Context {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	ctx := &filtertest.Context{FRequest: req, FStateBag: make(map[string]interface{})}
+	f.Request(ctx)
+	return ctx
+}
+
+func apiKeyStatus(ctx *filtertest.Context) int {
+	if !ctx.FServed {
+		return http.StatusOK
+	}
+
+	return ctx.FResponse.StatusCode
+}
+
+func TestApiKeyFileStore(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	store := fmt.Sprintf(`[
+		{"hash": "sha256:%s", "clientId": "acme", "scopes": ["orders.read", "orders.write"]},
+		{"hash": "%s", "clientId": "globex", "scopes": ["orders.read"]},
+		{"hash": "%s", "clientId": "initech", "expires": %q}
+	]`, apiKeyHash("acme-key"), apiKeyHash("globex-key"), apiKeyHash("initech-key"), expired)
+
+	sr := jwtTestSecrets{"/secrets/apikeys.json": []byte(store)}
+	spec := NewApiKey(ApiKeyOptions{Secrets: sr})
+
+	f, err := spec.CreateFilter([]interface{}{"/secrets/apikeys.json", "query", "api_key", "scopes", "orders.read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		title    string
+		url      string
+		header   http.Header
+		status   int
+		clientID string
+	}{{
+		title:  "missing key",
+		url:    "https://api.example.org/orders",
+		status: http.StatusUnauthorized,
+	}, {
+		title:  "unknown key",
+		url:    "https://api.example.org/orders",
+		header: http.Header{"X-Api-Key": []string{"unknown-key"}},
+		status: http.StatusUnauthorized,
+	}, {
+		title:  "expired key",
+		url:    "https://api.example.org/orders",
+		header: http.Header{"X-Api-Key": []string{"initech-key"}},
+		status: http.StatusUnauthorized,
+	}, {
+		title:    "key in header",
+		url:      "https://api.example.org/orders?page=2",
+		header:   http.Header{"X-Api-Key": []string{"acme-key"}},
+		status:   http.StatusOK,
+		clientID: "acme",
+	}, {
+		title:    "key in query",
+		url:      "https://api.example.org/orders?api_key=globex-key&page=2",
+		status:   http.StatusOK,
+		clientID: "globex",
+	}} {
+		t.Run(tc.title, func(t *testing.T) {
+			ctx := apiKeyRequest(t, f, tc.url, tc.header)
+			if s := apiKeyStatus(ctx); s != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, s)
+			}
+
+			if id, _ := ctx.FStateBag[filters.ClientIDKey].(string); id != tc.clientID {
+				t.Errorf("expected client id %q, got %q", tc.clientID, id)
+			}
+
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			if ctx.FRequest.Header.Get("X-Api-Key") != "" || ctx.FRequest.URL.Query().Get("api_key") != "" {
+				t.Error("api key forwarded")
+			}
+
+			if ctx.FRequest.URL.Query().Get("page") != "2" {
+				t.Error("query lost")
+			}
+		})
+	}
+
+	f, err = spec.CreateFilter([]interface{}{"/secrets/apikeys.json", "scopes", "orders.read,orders.write"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := apiKeyStatus(apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"X-Api-Key": []string{"globex-key"}})); s != http.StatusForbidden {
+		t.Errorf("expected forbidden, got %d", s)
+	}
+
+	// the store is reloaded when the secret changes, and the last valid
+	// version is kept when the new version is invalid
+	sr["/secrets/apikeys.json"] = []byte(fmt.Sprintf(`[{"hash": "%s", "clientId": "acme"}]`, apiKeyHash("new-acme-key")))
+	f, err = spec.CreateFilter([]interface{}{"/secrets/apikeys.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := apiKeyStatus(apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"X-Api-Key": []string{"acme-key"}})); s != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized with the old key, got %d", s)
+	}
+
+	sr["/secrets/apikeys.json"] = []byte("invalid")
+	if s := apiKeyStatus(apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"X-Api-Key": []string{"new-acme-key"}})); s != http.StatusOK {
+		t.Errorf("expected the last valid store, got %d", s)
+	}
+}
+
+func TestApiKeyRedisStore(t *testing.T) {
+	rc := &apiKeyTestRedis{values: map[string]string{
+		"apikeys:" + apiKeyHash("acme-key"): `{"clientId": "acme", "scopes": ["orders.read"]}`,
+		"apikeys:" + apiKeyHash("bad-key"):  `{"scopes": ["orders.read"]}`,
+	}}
+
+	f, err := NewApiKey(ApiKeyOptions{Redis: rc}).CreateFilter([]interface{}{"redis:apikeys:", "header", "Api-Key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		ctx := apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"Api-Key": []string{"acme-key"}})
+		if s := apiKeyStatus(ctx); s != http.StatusOK {
+			t.Fatalf("expected success, got %d", s)
+		}
+
+		if ctx.FStateBag[filters.ClientIDKey] != "acme" {
+			t.Errorf("unexpected client id: %v", ctx.FStateBag[filters.ClientIDKey])
+		}
+
+		if s := apiKeyStatus(apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"Api-Key": []string{"unknown-key"}})); s != http.StatusUnauthorized {
+			t.Errorf("expected unauthorized, got %d", s)
+		}
+	}
+
+	if rc.calls != 2 {
+		t.Errorf("expected cached lookups, got %d redis calls", rc.calls)
+	}
+
+	if s := apiKeyStatus(apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"Api-Key": []string{"bad-key"}})); s != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized for invalid record, got %d", s)
+	}
+}
+
+func TestApiKeyRatelimit(t *testing.T) {
+	sr := jwtTestSecrets{"/secrets/apikeys.json": []byte(fmt.Sprintf(
+		`[{"hash": "%s", "clientId": "acme", "rateLimit": "2/1m"}, {"hash": "%s", "clientId": "globex"}]`,
+		apiKeyHash("acme-key"), apiKeyHash("globex-key"),
+	))}
+
+	registry := ratelimit.NewRegistry()
+	defer registry.Close()
+
+	f, err := NewApiKey(ApiKeyOptions{Secrets: sr, Ratelimits: registry}).CreateFilter([]interface{}{"/secrets/apikeys.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		ctx := apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"X-Api-Key": []string{"acme-key"}})
+		if s := apiKeyStatus(ctx); s != expected {
+			t.Errorf("request %d: expected %d, got %d", i, expected, s)
+		}
+
+		if expected == http.StatusTooManyRequests && ctx.FResponse.Header.Get("Retry-After") == "" {
+			t.Error("missing retry after header")
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		if s := apiKeyStatus(apiKeyRequest(t, f, "https://api.example.org/orders", http.Header{"X-Api-Key": []string{"globex-key"}})); s != http.StatusOK {
+			t.Errorf("unexpected ratelimit without limit: %d", s)
+		}
+	}
+}
+
+func TestApiKeyInvalidArgs(t *testing.T) {
+	spec := NewApiKey(ApiKeyOptions{Secrets: jwtTestSecrets{}})
+	for _, args := range [][]interface{}{
+		nil,
+		{""},
+		{42},
+		{"/secrets/apikeys.json", "header"},
+		{"/secrets/apikeys.json", "unknown", "value"},
+		{"/secrets/apikeys.json", "cacheTTL", "forever"},
+		{"redis:apikeys:"},
+		{"redis:"},
+	} {
+		if _, err := spec.CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+
+	if _, err := NewApiKey(ApiKeyOptions{}).CreateFilter([]interface{}{"/secrets/apikeys.json"}); err == nil {
+		t.Error("expected error without secrets")
+	}
+}
+
+func TestParseApiKeyRatelimit(t *testing.T) {
+	s, err := parseApiKeyRatelimit("100/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.MaxHits != 100 || s.TimeWindow != time.Minute {
+		t.Errorf("unexpected settings: %v", s)
+	}
+
+	for _, v := range []string{"100", "x/1m", "0/1m", "10/x", "10/-1m"} {
+		if _, err := parseApiKeyRatelimit(v); err == nil {
+			t.Errorf("%s: expected error", v)
+		}
+	}
+}
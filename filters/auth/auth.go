@@ -79,6 +79,38 @@ func (kv kv) String() string {
 	return strings.Join(res, ",")
 }
 
+// matchAny tells whether any of the key-value pairs is present in the
+// map.
+func (kv kv) matchAny(h map[string]interface{}) bool {
+	for k, v := range kv {
+		for _, res := range v {
+			if v2, ok := h[k].(string); ok {
+				if res == v2 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchAll tells whether all of the key-value pairs are present in the
+// map.
+func (kv kv) matchAll(h map[string]interface{}) bool {
+	if len(h) < len(kv) {
+		return false
+	}
+	for k, v := range kv {
+		for _, res := range v {
+			v2, ok := h[k].(string)
+			if !ok || res != v2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (err *requestError) Error() string {
 	return err.err.Error()
 }
@@ -103,7 +135,7 @@ func reject(
 	status int,
 	username string,
 	reason rejectReason,
-	hostname,
+	challenge,
 	debuginfo string,
 ) {
 	if debuginfo == "" {
@@ -125,9 +157,9 @@ func reject(
 		Header:     make(map[string][]string),
 	}
 
-	if hostname != "" {
+	if challenge != "" {
 		// https://www.w3.org/Protocols/rfc2616/rfc2616-sec10.html#sec10.4.2
-		rsp.Header.Add("WWW-Authenticate", hostname)
+		rsp.Header.Add("WWW-Authenticate", challenge)
 	}
 
 	ctx.Serve(rsp)
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+type ttlCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// ttlCache stores values until they expire, limited to a maximum number
+// of entries. When it is full, storing a new key drops the expired
+// entries first, and when none of the entries has expired, it evicts an
+// arbitrary one.
+type ttlCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]ttlCacheEntry
+}
+
+func newTTLCache(size int) *ttlCache {
+	return &ttlCache{size: size, entries: make(map[string]ttlCacheEntry)}
+}
+
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+func (c *ttlCache) set(key string, value interface{}, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		for k := range c.entries {
+			if len(c.entries) < c.size {
+				break
+			}
+
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = ttlCacheEntry{value: value, expires: expires}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLCache(t *testing.T) {
+	c := newTTLCache(2)
+	c.set("a", 1, time.Now().Add(-time.Second))
+	c.set("b", 2, time.Now().Add(time.Minute))
+
+	if _, ok := c.get("a"); ok {
+		t.Error("expected expired entry dropped")
+	}
+
+	if v, ok := c.get("b"); !ok || v != 2 {
+		t.Errorf("failed to get the entry: %v", v)
+	}
+
+	c.set("a", 1, time.Now().Add(-time.Second))
+	c.set("c", 3, time.Now().Add(time.Minute))
+	if _, ok := c.get("c"); !ok || len(c.entries) != 2 {
+		t.Errorf("expected the expired entry evicted first, got: %v", c.entries)
+	}
+
+	c.set("d", 4, time.Now().Add(time.Minute))
+	if _, ok := c.get("d"); !ok || len(c.entries) != 2 {
+		t.Errorf("expected an arbitrary entry evicted, got: %v", c.entries)
+	}
+
+	// updating an existing key doesn't evict
+	c.set("d", 5, time.Now().Add(time.Minute))
+	if v, _ := c.get("d"); v != 5 || len(c.entries) != 2 {
+		t.Errorf("failed to update the entry, got: %v", c.entries)
+	}
+}
@@ -0,0 +1,538 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/http/httpguts"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const (
+	externalAuthzSpanName = "externalauthz"
+
+	externalAuthzResponseHeadersKey = "filter." + filters.ExternalAuthzName + ".responseHeaders"
+
+	defaultExternalAuthzCacheSize = 10000
+	maxExternalAuthzDeniedBody    = 1 << 20
+)
+
+const (
+	externalAuthzPathPrefix             = "pathPrefix"
+	externalAuthzAllowedHeaders         = "allowedHeaders"
+	externalAuthzMaxRequestBytes        = "maxRequestBytes"
+	externalAuthzAllowPartialMessage    = "allowPartialMessage"
+	externalAuthzUpstreamHeaders        = "upstreamHeaders"
+	externalAuthzClientHeaders          = "clientHeaders"
+	externalAuthzClientHeadersOnSuccess = "clientHeadersOnSuccess"
+	externalAuthzFailureMode            = "failureMode"
+	externalAuthzStatusOnError          = "statusOnError"
+	externalAuthzCacheTTL               = "cacheTTL"
+	externalAuthzCacheKey               = "cacheKey"
+	externalAuthzCacheSize              = "cacheSize"
+)
+
+const (
+	failureModeDeny  = "deny"
+	failureModeAllow = "allow"
+)
+
+// ExternalAuthzHeadersToRemove is the header that the authorization
+// service can use, when speaking the HTTP protocol, to list the headers
+// to be removed from the request before it is forwarded to the backend.
+const ExternalAuthzHeadersToRemove = "X-Envoy-Auth-Headers-To-Remove"
+
+var defaultExternalAuthzCacheKey = []string{"method", "host", "path", "header:Authorization"}
+
+// ExternalAuthzOptions are used to configure the externalAuthz filter
+// specification.
+type ExternalAuthzOptions struct {
+	Timeout      time.Duration
+	MaxIdleConns int
+	Tracer       opentracing.Tracer
+}
+
+type (
+	externalAuthzSpec struct {
+		options ExternalAuthzOptions
+
+		mu      sync.Mutex
+		clients map[string]externalAuthzClient
+	}
+
+	externalAuthzFilter struct {
+		client                 externalAuthzClient
+		config                 externalAuthzConfig
+		failOpen               bool
+		statusOnError          int
+		upstreamHeaders        []string
+		clientHeaders          []string
+		clientHeadersOnSuccess []string
+		cacheTTL               time.Duration
+		cacheKey               []string
+		cache                  *ttlCache
+	}
+
+	// externalAuthzConfig contains the settings of the check request
+	// shared by the protocols.
+	externalAuthzConfig struct {
+		pathPrefix          string
+		allowedHeaders      []string
+		maxRequestBytes     int64
+		allowPartialMessage bool
+	}
+
+	// externalAuthzCheck contains the attributes of the checked request.
+	externalAuthzCheck struct {
+		request *http.Request
+		headers http.Header
+		body    []byte
+	}
+
+	// externalAuthzDecision is the protocol independent result of an
+	// authorization check.
+	externalAuthzDecision struct {
+		allowed bool
+
+		// allowed requests
+		setHeaders      http.Header
+		appendHeaders   http.Header
+		addIfAbsent     http.Header
+		setIfExists     http.Header
+		removeHeaders   []string
+		responseHeaders http.Header
+
+		// denied requests
+		status int
+		header http.Header
+		body   []byte
+	}
+
+	externalAuthzClient interface {
+		check(filters.FilterContext, *externalAuthzConfig, *externalAuthzCheck) (*externalAuthzDecision, error)
+	}
+)
+
+// NewExternalAuthz creates the filter specification of the externalAuthz
+// filter. The filter checks the requests with an external authorization
+// service, speaking the protocol of the Envoy ext_authz filter, either
+// over HTTP or gRPC.
+//
+// The first argument is the address of the authorization service. With
+// http:// and https:// URLs, the HTTP protocol is used, with grpc:// and
+// grpcs:// addresses, the gRPC protocol, with plain text or TLS
+// connections. The further, optional arguments are key-value pairs:
+//
+//	externalAuthz("http://authz.example.org:9000")
+//	externalAuthz("grpc://authz.example.org:9191", "maxRequestBytes", "8192", "failureMode", "allow")
+//	externalAuthz("http://authz.example.org:9000", "upstreamHeaders", "X-User,X-Roles", "cacheTTL", "30s")
+func NewExternalAuthz(o ExternalAuthzOptions) filters.Spec {
+	return &externalAuthzSpec{options: o, clients: make(map[string]externalAuthzClient)}
+}
+
+func (*externalAuthzSpec) Name() string { return filters.ExternalAuthzName }
+
+func parseHeaderList(s string) ([]string, error) {
+	var headers []string
+	for _, h := range strings.Split(s, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+
+		if !httpguts.ValidHeaderFieldName(h) {
+			return nil, fmt.Errorf("header %s is invalid", h)
+		}
+
+		headers = append(headers, http.CanonicalHeaderKey(h))
+	}
+
+	return headers, nil
+}
+
+func parseCacheKey(s string) ([]string, error) {
+	var key []string
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		switch {
+		case k == "method", k == "host", k == "path", k == "query":
+			key = append(key, k)
+		case strings.HasPrefix(k, "header:") && httpguts.ValidHeaderFieldName(k[len("header:"):]):
+			key = append(key, "header:"+http.CanonicalHeaderKey(k[len("header:"):]))
+		default:
+			return nil, fmt.Errorf("invalid cache key: %s", k)
+		}
+	}
+
+	return key, nil
+}
+
+func (s *externalAuthzSpec) getClient(address string) (externalAuthzClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.clients[address]; ok {
+		return c, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var c externalAuthzClient
+	switch u.Scheme {
+	case "http", "https":
+		ac, err := newAuthClient(address, externalAuthzSpanName, s.options.Timeout, s.options.MaxIdleConns, s.options.Tracer)
+		if err != nil {
+			return nil, err
+		}
+
+		c = &externalAuthzHTTPClient{authClient: ac}
+	case "grpc", "grpcs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("missing host in address: %s", address)
+		}
+
+		c, err = newExternalAuthzGRPCClient(u.Host, u.Scheme == "grpcs", s.options.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme in address: %s", address)
+	}
+
+	s.clients[address] = c
+	return c, nil
+}
+
+func (s *externalAuthzSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) == 0 || len(args)%2 == 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	sargs, err := getStrings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &externalAuthzFilter{statusOnError: http.StatusForbidden}
+	cacheSize := defaultExternalAuthzCacheSize
+	for i := 1; i < len(sargs); i += 2 {
+		key, value := sargs[i], sargs[i+1]
+		switch key {
+		case externalAuthzPathPrefix:
+			f.config.pathPrefix = value
+		case externalAuthzAllowedHeaders:
+			f.config.allowedHeaders, err = parseHeaderList(value)
+		case externalAuthzMaxRequestBytes:
+			f.config.maxRequestBytes, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && f.config.maxRequestBytes < 0 {
+				err = filters.ErrInvalidFilterParameters
+			}
+		case externalAuthzAllowPartialMessage:
+			f.config.allowPartialMessage, err = strconv.ParseBool(value)
+		case externalAuthzUpstreamHeaders:
+			f.upstreamHeaders, err = parseHeaderList(value)
+		case externalAuthzClientHeaders:
+			f.clientHeaders, err = parseHeaderList(value)
+		case externalAuthzClientHeadersOnSuccess:
+			f.clientHeadersOnSuccess, err = parseHeaderList(value)
+		case externalAuthzFailureMode:
+			switch value {
+			case failureModeDeny:
+			case failureModeAllow:
+				f.failOpen = true
+			default:
+				err = filters.ErrInvalidFilterParameters
+			}
+		case externalAuthzStatusOnError:
+			f.statusOnError, err = strconv.Atoi(value)
+			if err == nil && (f.statusOnError < 100 || f.statusOnError > 599) {
+				err = filters.ErrInvalidFilterParameters
+			}
+		case externalAuthzCacheTTL:
+			f.cacheTTL, err = time.ParseDuration(value)
+		case externalAuthzCacheKey:
+			f.cacheKey, err = parseCacheKey(value)
+		case externalAuthzCacheSize:
+			cacheSize, err = strconv.Atoi(value)
+			if err == nil && cacheSize <= 0 {
+				err = filters.ErrInvalidFilterParameters
+			}
+		default:
+			err = filters.ErrInvalidFilterParameters
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f.cacheTTL > 0 {
+		if len(f.cacheKey) == 0 {
+			f.cacheKey = defaultExternalAuthzCacheKey
+		}
+
+		f.cache = newTTLCache(cacheSize)
+	}
+
+	f.client, err = s.getClient(sargs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+type bodyReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// readBody reads the request body up to the configured limit, and it
+// restores the request body, so that it can be forwarded to the
+// backend. It returns false when the body exceeds the limit and partial
+// messages are not allowed.
+func (c *externalAuthzConfig) readBody(r *http.Request) ([]byte, bool, error) {
+	if c.maxRequestBytes == 0 || r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+
+	if r.ContentLength > c.maxRequestBytes && !c.allowPartialMessage {
+		return nil, false, nil
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r.Body, c.maxRequestBytes+1))
+	r.Body = &bodyReadCloser{Reader: io.MultiReader(bytes.NewReader(b), r.Body), Closer: r.Body}
+	if err != nil {
+		return nil, true, err
+	}
+
+	if int64(len(b)) > c.maxRequestBytes {
+		if !c.allowPartialMessage {
+			return nil, false, nil
+		}
+
+		b = b[:c.maxRequestBytes]
+	}
+
+	return b, true, nil
+}
+
+func (c *externalAuthzConfig) checkHeaders(r *http.Request) http.Header {
+	if len(c.allowedHeaders) == 0 {
+		return r.Header.Clone()
+	}
+
+	h := make(http.Header)
+	for _, k := range c.allowedHeaders {
+		if v, ok := r.Header[k]; ok {
+			h[k] = v
+		}
+	}
+
+	return h
+}
+
+// cacheKeyOf returns the key of the cached decisions. When the body is
+// sent to the authorization service, the key includes its hash, too.
+func (f *externalAuthzFilter) cacheKeyOf(r *http.Request, body []byte) string {
+	var b strings.Builder
+	for _, k := range f.cacheKey {
+		switch k {
+		case "method":
+			b.WriteString(r.Method)
+		case "host":
+			b.WriteString(r.Host)
+		case "path":
+			b.WriteString(r.URL.Path)
+		case "query":
+			b.WriteString(r.URL.RawQuery)
+		default:
+			b.WriteString(strings.Join(r.Header.Values(k[len("header:"):]), ","))
+		}
+
+		b.WriteByte(0)
+	}
+
+	if f.config.maxRequestBytes > 0 {
+		h := sha256.Sum256(body)
+		b.Write(h[:])
+	}
+
+	return b.String()
+}
+
+func (f *externalAuthzFilter) fail(ctx filters.FilterContext, err error) {
+	if f.failOpen {
+		log.Errorf("Failed to check authorization, allowing request: %v", err)
+		return
+	}
+
+	log.Errorf("Failed to check authorization: %v", err)
+	ctx.Serve(&http.Response{StatusCode: f.statusOnError, Header: make(http.Header)})
+}
+
+func (f *externalAuthzFilter) deny(ctx filters.FilterContext, d *externalAuthzDecision) {
+	status := d.status
+	if status == 0 {
+		status = http.StatusForbidden
+	}
+
+	header := make(http.Header)
+	for k, v := range d.header {
+		if len(f.clientHeaders) > 0 && !contains(f.clientHeaders, k) {
+			continue
+		}
+
+		header[k] = v
+	}
+
+	header.Del("Content-Length")
+	ctx.Serve(&http.Response{
+		StatusCode:    status,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(d.body)),
+		ContentLength: int64(len(d.body)),
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, li := range list {
+		if li == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *externalAuthzFilter) allow(ctx filters.FilterContext, d *externalAuthzDecision) {
+	h := ctx.Request().Header
+	for _, k := range d.removeHeaders {
+		h.Del(k)
+	}
+
+	for k, v := range d.setHeaders {
+		h[k] = append([]string(nil), v...)
+	}
+
+	for k, v := range d.appendHeaders {
+		h[k] = append(h[k], v...)
+	}
+
+	for k, v := range d.addIfAbsent {
+		if _, ok := h[k]; !ok {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+
+	for k, v := range d.setIfExists {
+		if _, ok := h[k]; ok {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+
+	if len(d.responseHeaders) > 0 {
+		ctx.StateBag()[externalAuthzResponseHeadersKey] = d.responseHeaders
+	}
+
+	authorized(ctx, filters.ExternalAuthzName)
+}
+
+func (f *externalAuthzFilter) Request(ctx filters.FilterContext) {
+	r := ctx.Request()
+
+	body, ok, err := f.config.readBody(r)
+	if err != nil {
+		f.fail(ctx, err)
+		return
+	}
+
+	if !ok {
+		ctx.Serve(&http.Response{StatusCode: http.StatusRequestEntityTooLarge, Header: make(http.Header)})
+		return
+	}
+
+	var key string
+	if f.cache != nil {
+		key = f.cacheKeyOf(r, body)
+		if d, ok := f.cache.get(key); ok {
+			f.apply(ctx, d.(*externalAuthzDecision))
+			return
+		}
+	}
+
+	d, err := f.client.check(ctx, &f.config, &externalAuthzCheck{
+		request: r,
+		headers: f.config.checkHeaders(r),
+		body:    body,
+	})
+	if err != nil {
+		f.fail(ctx, err)
+		return
+	}
+
+	f.filterHeaders(d)
+	if f.cache != nil {
+		f.cache.set(key, d, time.Now().Add(f.cacheTTL))
+	}
+
+	f.apply(ctx, d)
+}
+
+// filterHeaders applies the configured header allow lists to the
+// headers received from the HTTP authorization service.
+func (f *externalAuthzFilter) filterHeaders(d *externalAuthzDecision) {
+	if _, ok := f.client.(*externalAuthzHTTPClient); !ok || !d.allowed {
+		return
+	}
+
+	upstream := make(http.Header)
+	for _, k := range f.upstreamHeaders {
+		if v, ok := d.setHeaders[k]; ok {
+			upstream[k] = v
+		}
+	}
+
+	response := make(http.Header)
+	for _, k := range f.clientHeadersOnSuccess {
+		if v, ok := d.responseHeaders[k]; ok {
+			response[k] = v
+		}
+	}
+
+	d.setHeaders, d.responseHeaders = upstream, response
+}
+
+func (f *externalAuthzFilter) apply(ctx filters.FilterContext, d *externalAuthzDecision) {
+	if d.allowed {
+		f.allow(ctx, d)
+	} else {
+		f.deny(ctx, d)
+	}
+}
+
+func (*externalAuthzFilter) Response(ctx filters.FilterContext) {
+	h, ok := ctx.StateBag()[externalAuthzResponseHeadersKey].(http.Header)
+	if !ok {
+		return
+	}
+
+	rh := ctx.Response().Header
+	for k, v := range h {
+		rh[k] = append(rh[k], v...)
+	}
+}
@@ -0,0 +1,413 @@
+package auth
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+func newExternalAuthzContext(method, url, body string) *filtertest.Context {
+	var b io.Reader
+	if body != "" {
+		b = strings.NewReader(body)
+	}
+
+	r := httptest.NewRequest(method, url, b)
+	return &filtertest.Context{
+		FRequest:  r,
+		FResponse: &http.Response{Header: make(http.Header)},
+		FStateBag: make(map[string]interface{}),
+	}
+}
+
+func TestExternalAuthzArgs(t *testing.T) {
+	for _, args := range [][]interface{}{
+		{},
+		{1},
+		{"http://authz.example.org", "failureMode"},
+		{"http://authz.example.org", "failureMode", "maybe"},
+		{"http://authz.example.org", "maxRequestBytes", "-1"},
+		{"http://authz.example.org", "statusOnError", "42"},
+		{"http://authz.example.org", "cacheTTL", "forever"},
+		{"http://authz.example.org", "cacheKey", "cookie"},
+		{"http://authz.example.org", "upstreamHeaders", "X User"},
+		{"http://authz.example.org", "foo", "bar"},
+		{"ftp://authz.example.org"},
+		{"grpc://"},
+	} {
+		if _, err := NewExternalAuthz(ExternalAuthzOptions{}).CreateFilter(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestExternalAuthzHTTP(t *testing.T) {
+	var calls int32
+	authz := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/authz/orders" || r.Method != "POST" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		switch r.Header.Get("Authorization") {
+		case "Bearer admin":
+			w.Header().Set("X-User", "admin")
+			w.Header().Set("X-Ignored", "foo")
+			w.Header().Set("X-Session", "abc")
+			w.Header().Set(ExternalAuthzHeadersToRemove, "Authorization")
+			w.Write(body)
+		case "Bearer broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("denied"))
+		}
+	}))
+	defer authz.Close()
+
+	f, err := NewExternalAuthz(ExternalAuthzOptions{Timeout: time.Second}).CreateFilter([]interface{}{
+		authz.URL,
+		"pathPrefix", "/authz",
+		"maxRequestBytes", "5",
+		"allowPartialMessage", "true",
+		"upstreamHeaders", "X-User",
+		"clientHeadersOnSuccess", "X-Session",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		ctx := newExternalAuthzContext("POST", "/orders", "hello world")
+		ctx.FRequest.Header.Set("Authorization", "Bearer admin")
+		f.Request(ctx)
+		if ctx.FServed {
+			t.Fatalf("unexpected response: %d", ctx.FResponse.StatusCode)
+		}
+
+		h := ctx.FRequest.Header
+		if h.Get("X-User") != "admin" || h.Get("X-Ignored") != "" || h.Get("Authorization") != "" {
+			t.Errorf("unexpected request headers: %v", h)
+		}
+
+		if b, _ := io.ReadAll(ctx.FRequest.Body); string(b) != "hello world" {
+			t.Errorf("request body not restored: %s", b)
+		}
+
+		f.Response(ctx)
+		if ctx.FResponse.Header.Get("X-Session") != "abc" {
+			t.Errorf("response header not set: %v", ctx.FResponse.Header)
+		}
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		ctx := newExternalAuthzContext("POST", "/orders", "")
+		f.Request(ctx)
+		if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected unauthorized, got: %v", ctx.FResponse)
+		}
+
+		if ctx.FResponse.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("unexpected response headers: %v", ctx.FResponse.Header)
+		}
+
+		if b, _ := io.ReadAll(ctx.FResponse.Body); string(b) != "denied" {
+			t.Errorf("unexpected body: %s", b)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ctx := newExternalAuthzContext("POST", "/orders", "")
+		ctx.FRequest.Header.Set("Authorization", "Bearer broken")
+		f.Request(ctx)
+		if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected forbidden, got: %v", ctx.FResponse)
+		}
+	})
+}
+
+func TestExternalAuthzBodyLimit(t *testing.T) {
+	f, err := NewExternalAuthz(ExternalAuthzOptions{}).CreateFilter([]interface{}{
+		"http://authz.example.org", "maxRequestBytes", "5",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := newExternalAuthzContext("POST", "/orders", "hello world")
+	f.Request(ctx)
+	if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected request entity too large, got: %v", ctx.FResponse)
+	}
+}
+
+func TestExternalAuthzFailureMode(t *testing.T) {
+	authz := httptest.NewServer(http.NotFoundHandler())
+	authz.Close()
+
+	for _, tc := range []struct {
+		args   []interface{}
+		served bool
+		status int
+	}{
+		{args: []interface{}{authz.URL}, served: true, status: http.StatusForbidden},
+		{args: []interface{}{authz.URL, "statusOnError", "503"}, served: true, status: http.StatusServiceUnavailable},
+		{args: []interface{}{authz.URL, "failureMode", "allow"}},
+	} {
+		f, err := NewExternalAuthz(ExternalAuthzOptions{}).CreateFilter(tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := newExternalAuthzContext("GET", "/", "")
+		f.Request(ctx)
+		if ctx.FServed != tc.served || tc.served && ctx.FResponse.StatusCode != tc.status {
+			t.Errorf("%v: unexpected result, served: %v, response: %v", tc.args, ctx.FServed, ctx.FResponse)
+		}
+	}
+}
+
+func TestExternalAuthzCache(t *testing.T) {
+	var calls int32
+	authz := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer authz.Close()
+
+	f, err := NewExternalAuthz(ExternalAuthzOptions{}).CreateFilter([]interface{}{
+		authz.URL, "cacheTTL", "1m", "cacheKey", "path,header:Authorization",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path, token string
+		allowed     bool
+		calls       int32
+	}{
+		{"/foo", "valid", true, 1},
+		{"/foo", "valid", true, 1},
+		{"/foo", "invalid", false, 2},
+		{"/foo", "invalid", false, 2},
+		{"/bar", "valid", true, 3},
+	} {
+		ctx := newExternalAuthzContext("GET", tc.path, "")
+		ctx.FRequest.Header.Set("Authorization", "Bearer "+tc.token)
+		f.Request(ctx)
+		if ctx.FServed == tc.allowed {
+			t.Errorf("%s %s: expected allowed: %v", tc.path, tc.token, tc.allowed)
+		}
+
+		if c := atomic.LoadInt32(&calls); c != tc.calls {
+			t.Errorf("%s %s: expected %d calls, got: %d", tc.path, tc.token, tc.calls, c)
+		}
+	}
+}
+
+func TestExternalAuthzCacheBody(t *testing.T) {
+	var calls int32
+	authz := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if b, _ := io.ReadAll(r.Body); string(b) != "allowed" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer authz.Close()
+
+	f, err := NewExternalAuthz(ExternalAuthzOptions{}).CreateFilter([]interface{}{
+		authz.URL, "cacheTTL", "1m", "maxRequestBytes", "8",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		body    string
+		allowed bool
+		status  int
+		calls   int32
+	}{
+		{"allowed", true, 0, 1},
+		{"allowed", true, 0, 1},
+		{"denied", false, http.StatusForbidden, 2},
+		{"allowed and too large", false, http.StatusRequestEntityTooLarge, 2},
+	} {
+		ctx := newExternalAuthzContext("POST", "/orders", tc.body)
+		ctx.FRequest.Header.Set("Authorization", "Bearer token")
+		f.Request(ctx)
+		if ctx.FServed == tc.allowed || !tc.allowed && ctx.FResponse.StatusCode != tc.status {
+			t.Errorf("%s: expected allowed: %v, status: %d, got: %v", tc.body, tc.allowed, tc.status, ctx.FResponse.StatusCode)
+		}
+
+		if c := atomic.LoadInt32(&calls); c != tc.calls {
+			t.Errorf("%s: expected %d calls, got: %d", tc.body, tc.calls, c)
+		}
+	}
+}
+
+func appendTestHeaderOption(b []byte, num protowire.Number, key, value string, appendValue bool) []byte {
+	var header, option []byte
+	header = appendStringField(header, headerValueKey, key)
+	header = appendStringField(header, headerValueValue, value)
+	option = appendMessageField(option, headerValueOptionHeader, header)
+	option = appendMessageField(option, headerValueOptionAppend, appendVarintField(nil, boolValueValue, boolToUint(appendValue)))
+	return appendMessageField(b, num, option)
+}
+
+func boolToUint(b bool) uint64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// checkRequestHeaders extracts the HTTP request headers from an encoded
+// CheckRequest.
+func checkRequestHeaders(t *testing.T, m []byte) map[string]string {
+	headers := make(map[string]string)
+	var walk func(path []protowire.Number) func(protowire.Number, uint64, []byte) error
+	walk = func(path []protowire.Number) func(protowire.Number, uint64, []byte) error {
+		return func(num protowire.Number, _ uint64, b []byte) error {
+			if num != path[0] {
+				return nil
+			}
+
+			if len(path) > 1 {
+				return consumeFields(b, walk(path[1:]))
+			}
+
+			var k, v string
+			consumeFields(b, func(num protowire.Number, _ uint64, b []byte) error {
+				if num == 1 {
+					k = string(b)
+				} else {
+					v = string(b)
+				}
+
+				return nil
+			})
+
+			headers[k] = v
+			return nil
+		}
+	}
+
+	if err := consumeFields(m, walk([]protowire.Number{
+		checkRequestAttributes, attributeContextRequest, requestHTTP, httpRequestHeaders,
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	return headers
+}
+
+func TestExternalAuthzGRPC(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		if m, _ := grpc.MethodFromServerStream(stream); m != externalAuthzCheckMethod {
+			t.Errorf("unexpected method: %s", m)
+		}
+
+		var req []byte
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+
+		headers := checkRequestHeaders(t, req)
+		if headers[":path"] != "/orders?limit=10" {
+			t.Errorf("unexpected path: %s", headers[":path"])
+		}
+
+		var rsp []byte
+		if headers["authorization"] == "Bearer admin" {
+			var ok []byte
+			ok = appendTestHeaderOption(ok, okResponseHeaders, "x-user", "admin", false)
+			ok = appendTestHeaderOption(ok, okResponseHeaders, "x-roles", "write", true)
+			ok = appendStringField(ok, okResponseHeadersToRemove, "authorization")
+			ok = appendTestHeaderOption(ok, okResponseResponseHeadersToAdd, "x-session", "abc", false)
+			rsp = appendMessageField(rsp, checkResponseStatus, nil)
+			rsp = appendMessageField(rsp, checkResponseOkResponse, ok)
+		} else {
+			var denied []byte
+			denied = appendMessageField(denied, deniedResponseStatus, appendVarintField(nil, httpStatusCode, http.StatusUnauthorized))
+			denied = appendTestHeaderOption(denied, deniedResponseHeaders, "www-authenticate", "Bearer", false)
+			denied = appendStringField(denied, deniedResponseBody, "denied")
+			rsp = appendMessageField(rsp, checkResponseStatus, appendVarintField(nil, statusCode, 7))
+			rsp = appendMessageField(rsp, checkResponseDeniedResponse, denied)
+		}
+
+		return stream.SendMsg(rsp)
+	}))
+
+	go server.Serve(l)
+	defer server.Stop()
+
+	f, err := NewExternalAuthz(ExternalAuthzOptions{Timeout: time.Second}).CreateFilter([]interface{}{"grpc://" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		ctx := newExternalAuthzContext("GET", "/orders?limit=10", "")
+		ctx.FRequest.Header.Set("Authorization", "Bearer admin")
+		ctx.FRequest.Header.Set("X-Roles", "read")
+		f.Request(ctx)
+		if ctx.FServed {
+			t.Fatalf("unexpected response: %d", ctx.FResponse.StatusCode)
+		}
+
+		h := ctx.FRequest.Header
+		if h.Get("X-User") != "admin" || h.Get("Authorization") != "" || strings.Join(h.Values("X-Roles"), ",") != "read,write" {
+			t.Errorf("unexpected request headers: %v", h)
+		}
+
+		f.Response(ctx)
+		if ctx.FResponse.Header.Get("X-Session") != "abc" {
+			t.Errorf("response header not set: %v", ctx.FResponse.Header)
+		}
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		ctx := newExternalAuthzContext("GET", "/orders?limit=10", "")
+		f.Request(ctx)
+		if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected unauthorized, got: %v", ctx.FResponse)
+		}
+
+		if ctx.FResponse.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("unexpected response headers: %v", ctx.FResponse.Header)
+		}
+
+		if b, _ := io.ReadAll(ctx.FResponse.Body); string(b) != "denied" {
+			t.Errorf("unexpected body: %s", b)
+		}
+	})
+}
+
+func TestExternalAuthzName(t *testing.T) {
+	if n := NewExternalAuthz(ExternalAuthzOptions{}).Name(); n != filters.ExternalAuthzName {
+		t.Errorf("unexpected name: %s", n)
+	}
+}
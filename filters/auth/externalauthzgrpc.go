@@ -0,0 +1,469 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/zalando/skipper/filters"
+)
+
+// externalAuthzCheckMethod is the full method name of the Envoy
+// authorization service, version 3.
+const externalAuthzCheckMethod = "/envoy.service.auth.v3.Authorization/Check"
+
+// Field numbers of the envoy.service.auth.v3 messages. The messages are
+// encoded directly, in order to avoid depending on the complete Envoy
+// API.
+const (
+	checkRequestAttributes = 1
+
+	attributeContextSource      = 1
+	attributeContextDestination = 2
+	attributeContextRequest     = 4
+
+	peerAddress          = 1
+	addressSocketAddress = 1
+	socketAddressAddress = 2
+	socketAddressPort    = 3
+
+	requestTime = 1
+	requestHTTP = 2
+
+	timestampSeconds = 1
+	timestampNanos   = 2
+
+	httpRequestID       = 1
+	httpRequestMethod   = 2
+	httpRequestHeaders  = 3
+	httpRequestPath     = 4
+	httpRequestHost     = 5
+	httpRequestScheme   = 6
+	httpRequestSize     = 9
+	httpRequestProtocol = 10
+	httpRequestBody     = 11
+	httpRequestRawBody  = 12
+
+	checkResponseStatus         = 1
+	checkResponseDeniedResponse = 2
+	checkResponseOkResponse     = 3
+
+	statusCode = 1
+
+	deniedResponseStatus  = 1
+	deniedResponseHeaders = 2
+	deniedResponseBody    = 3
+
+	httpStatusCode = 1
+
+	okResponseHeaders               = 2
+	okResponseHeadersToRemove       = 5
+	okResponseResponseHeadersToAdd  = 6
+	headerValueOptionHeader         = 1
+	headerValueOptionAppend         = 2
+	headerValueOptionAppendAction   = 3
+	headerValueKey                  = 1
+	headerValueValue                = 2
+	headerValueRawValue             = 3
+	boolValueValue                  = 1
+	appendActionAppendIfExistsOrAdd = 0
+	appendActionAddIfAbsent         = 1
+	appendActionOverwriteOrAdd      = 2
+	appendActionOverwriteIfExists   = 3
+)
+
+// rawCodec passes the already encoded protobuf messages to gRPC.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type: %T", v)
+	}
+
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", v)
+	}
+
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+type externalAuthzGRPCClient struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+func newExternalAuthzGRPCClient(address string, useTLS bool, timeout time.Duration) (*externalAuthzGRPCClient, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	return &externalAuthzGRPCClient{conn: conn, timeout: timeout}, nil
+}
+
+func appendStringField(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendMessageField(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func appendStringMap(b []byte, num protowire.Number, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendStringField(entry, 1, k)
+		entry = appendStringField(entry, 2, m[k])
+		b = appendMessageField(b, num, entry)
+	}
+
+	return b
+}
+
+func encodePeer(addr string) []byte {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	p, _ := strconv.ParseUint(port, 10, 32)
+
+	var socketAddress []byte
+	socketAddress = appendStringField(socketAddress, socketAddressAddress, host)
+	socketAddress = appendVarintField(socketAddress, socketAddressPort, p)
+	address := appendMessageField(nil, addressSocketAddress, socketAddress)
+	return appendMessageField(nil, peerAddress, address)
+}
+
+// encodeCheckRequest encodes an envoy.service.auth.v3.CheckRequest.
+func encodeCheckRequest(check *externalAuthzCheck, now time.Time) []byte {
+	r := check.request
+
+	headers := make(map[string]string)
+	for k, v := range check.headers {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+
+	if r.Host != "" {
+		headers[":authority"] = r.Host
+	}
+
+	headers[":method"] = r.Method
+	headers[":path"] = r.URL.RequestURI()
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	var h []byte
+	h = appendStringField(h, httpRequestID, r.Header.Get("X-Flow-Id"))
+	h = appendStringField(h, httpRequestMethod, r.Method)
+	h = appendStringMap(h, httpRequestHeaders, headers)
+	h = appendStringField(h, httpRequestPath, r.URL.RequestURI())
+	h = appendStringField(h, httpRequestHost, r.Host)
+	h = appendStringField(h, httpRequestScheme, scheme)
+	if r.ContentLength != 0 {
+		h = protowire.AppendTag(h, httpRequestSize, protowire.VarintType)
+		h = protowire.AppendVarint(h, uint64(r.ContentLength))
+	}
+
+	h = appendStringField(h, httpRequestProtocol, r.Proto)
+	if utf8.Valid(check.body) {
+		h = appendStringField(h, httpRequestBody, string(check.body))
+	} else {
+		h = appendBytesField(h, httpRequestRawBody, check.body)
+	}
+
+	var ts []byte
+	ts = appendVarintField(ts, timestampSeconds, uint64(now.Unix()))
+	ts = appendVarintField(ts, timestampNanos, uint64(now.Nanosecond()))
+
+	var req []byte
+	req = appendMessageField(req, requestTime, ts)
+	req = appendMessageField(req, requestHTTP, h)
+
+	var attributes []byte
+	if r.RemoteAddr != "" {
+		attributes = appendMessageField(attributes, attributeContextSource, encodePeer(r.RemoteAddr))
+	}
+
+	if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		attributes = appendMessageField(attributes, attributeContextDestination, encodePeer(local.String()))
+	}
+
+	attributes = appendMessageField(attributes, attributeContextRequest, req)
+	return appendMessageField(nil, checkRequestAttributes, attributes)
+}
+
+var errInvalidMessage = errors.New("invalid protobuf message")
+
+// consumeFields calls f with every field of the encoded message. The
+// value of varint fields is passed as v, the value of length-delimited
+// fields as b. Other field types are skipped.
+func consumeFields(m []byte, f func(num protowire.Number, v uint64, b []byte) error) error {
+	for len(m) > 0 {
+		num, typ, n := protowire.ConsumeTag(m)
+		if n < 0 {
+			return errInvalidMessage
+		}
+
+		m = m[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(m)
+			if n < 0 {
+				return errInvalidMessage
+			}
+
+			if err := f(num, v, nil); err != nil {
+				return err
+			}
+
+			m = m[n:]
+		case protowire.BytesType:
+			b, n := protowire.ConsumeBytes(m)
+			if n < 0 {
+				return errInvalidMessage
+			}
+
+			if err := f(num, 0, b); err != nil {
+				return err
+			}
+
+			m = m[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, m)
+			if n < 0 {
+				return errInvalidMessage
+			}
+
+			m = m[n:]
+		}
+	}
+
+	return nil
+}
+
+type headerValueOption struct {
+	key, value   string
+	appendSet    bool
+	appendValue  bool
+	appendAction uint64
+}
+
+func decodeHeaderValueOption(m []byte) (headerValueOption, error) {
+	var o headerValueOption
+	err := consumeFields(m, func(num protowire.Number, v uint64, b []byte) error {
+		switch num {
+		case headerValueOptionHeader:
+			return consumeFields(b, func(num protowire.Number, _ uint64, b []byte) error {
+				switch num {
+				case headerValueKey:
+					o.key = http.CanonicalHeaderKey(string(b))
+				case headerValueValue:
+					o.value = string(b)
+				case headerValueRawValue:
+					o.value = string(b)
+				}
+
+				return nil
+			})
+		case headerValueOptionAppend:
+			o.appendSet = true
+			return consumeFields(b, func(num protowire.Number, v uint64, _ []byte) error {
+				if num == boolValueValue {
+					o.appendValue = v != 0
+				}
+
+				return nil
+			})
+		case headerValueOptionAppendAction:
+			o.appendAction = v
+		}
+
+		return nil
+	})
+
+	return o, err
+}
+
+// addHeaderOption stores the header in the decision according to the
+// append settings. When neither append nor the append action is set,
+// the header is overwritten, as in Envoy.
+func (d *externalAuthzDecision) addHeaderOption(o headerValueOption) {
+	if o.key == "" {
+		return
+	}
+
+	target := d.setHeaders
+	switch {
+	case o.appendSet && o.appendValue:
+		target = d.appendHeaders
+	case o.appendSet:
+	case o.appendAction == appendActionAddIfAbsent:
+		target = d.addIfAbsent
+	case o.appendAction == appendActionOverwriteIfExists:
+		target = d.setIfExists
+	}
+
+	target.Add(o.key, o.value)
+}
+
+// decodeCheckResponse decodes an envoy.service.auth.v3.CheckResponse.
+func decodeCheckResponse(m []byte) (*externalAuthzDecision, error) {
+	d := &externalAuthzDecision{
+		setHeaders:      make(http.Header),
+		appendHeaders:   make(http.Header),
+		addIfAbsent:     make(http.Header),
+		setIfExists:     make(http.Header),
+		responseHeaders: make(http.Header),
+		header:          make(http.Header),
+	}
+
+	var code uint64
+	err := consumeFields(m, func(num protowire.Number, _ uint64, b []byte) error {
+		switch num {
+		case checkResponseStatus:
+			return consumeFields(b, func(num protowire.Number, v uint64, _ []byte) error {
+				if num == statusCode {
+					code = v
+				}
+
+				return nil
+			})
+		case checkResponseDeniedResponse:
+			return consumeFields(b, func(num protowire.Number, _ uint64, b []byte) error {
+				switch num {
+				case deniedResponseStatus:
+					return consumeFields(b, func(num protowire.Number, v uint64, _ []byte) error {
+						if num == httpStatusCode {
+							d.status = int(v)
+						}
+
+						return nil
+					})
+				case deniedResponseHeaders:
+					o, err := decodeHeaderValueOption(b)
+					if err != nil {
+						return err
+					}
+
+					if o.key != "" {
+						d.header.Add(o.key, o.value)
+					}
+				case deniedResponseBody:
+					d.body = append([]byte(nil), b...)
+				}
+
+				return nil
+			})
+		case checkResponseOkResponse:
+			return consumeFields(b, func(num protowire.Number, _ uint64, b []byte) error {
+				switch num {
+				case okResponseHeaders:
+					o, err := decodeHeaderValueOption(b)
+					if err != nil {
+						return err
+					}
+
+					d.addHeaderOption(o)
+				case okResponseHeadersToRemove:
+					d.removeHeaders = append(d.removeHeaders, http.CanonicalHeaderKey(string(b)))
+				case okResponseResponseHeadersToAdd:
+					o, err := decodeHeaderValueOption(b)
+					if err != nil {
+						return err
+					}
+
+					if o.key != "" {
+						d.responseHeaders.Add(o.key, o.value)
+					}
+				}
+
+				return nil
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.allowed = code == 0
+	return d, nil
+}
+
+func (c *externalAuthzGRPCClient) check(ctx filters.FilterContext, _ *externalAuthzConfig, check *externalAuthzCheck) (*externalAuthzDecision, error) {
+	rctx := ctx.Request().Context()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		rctx, cancel = context.WithTimeout(rctx, c.timeout)
+		defer cancel()
+	}
+
+	var rsp []byte
+	if err := c.conn.Invoke(rctx, externalAuthzCheckMethod, encodeCheckRequest(check, time.Now()), &rsp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return nil, err
+	}
+
+	return decodeCheckResponse(rsp)
+}
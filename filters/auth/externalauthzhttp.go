@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+)
+
+// externalAuthzHTTPClient implements the HTTP protocol of the Envoy
+// ext_authz filter: the checked request is sent to the authorization
+// service with the same method, path and headers, and a 200 response
+// means that the request is allowed. Any other response, except for
+// 5xx, is returned to the client.
+type externalAuthzHTTPClient struct {
+	authClient *authClient
+}
+
+func (c *externalAuthzHTTPClient) check(ctx filters.FilterContext, config *externalAuthzConfig, check *externalAuthzCheck) (*externalAuthzDecision, error) {
+	u := *c.authClient.url
+	u.Path = strings.TrimSuffix(u.Path, "/") + config.pathPrefix + check.request.URL.Path
+	u.RawPath = ""
+	u.RawQuery = check.request.URL.RawQuery
+
+	var body io.Reader
+	if len(check.body) > 0 {
+		body = bytes.NewReader(check.body)
+	}
+
+	req, err := http.NewRequest(check.request.Method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req = bindContext(ctx, req)
+	copyHeader(req.Header, check.headers)
+	req.Header.Del("Content-Length")
+	req.Header.Del("Transfer-Encoding")
+	if check.request.Host != "" {
+		req.Header.Set("X-Forwarded-Host", check.request.Host)
+	}
+
+	rsp, err := c.authClient.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode >= http.StatusInternalServerError {
+		io.Copy(io.Discard, rsp.Body)
+		return nil, fmt.Errorf("authorization service responded with status %d", rsp.StatusCode)
+	}
+
+	header := rsp.Header.Clone()
+	for _, h := range []string{"Connection", "Content-Length", "Transfer-Encoding", "Keep-Alive", "Date", "Server"} {
+		header.Del(h)
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		b, err := io.ReadAll(io.LimitReader(rsp.Body, maxExternalAuthzDeniedBody))
+		if err != nil {
+			return nil, err
+		}
+
+		header.Del(ExternalAuthzHeadersToRemove)
+		return &externalAuthzDecision{status: rsp.StatusCode, header: header, body: b}, nil
+	}
+
+	io.Copy(io.Discard, rsp.Body)
+
+	var remove []string
+	for _, v := range header.Values(ExternalAuthzHeadersToRemove) {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				remove = append(remove, http.CanonicalHeaderKey(h))
+			}
+		}
+	}
+
+	header.Del(ExternalAuthzHeadersToRemove)
+	return &externalAuthzDecision{
+		allowed:         true,
+		setHeaders:      header,
+		removeHeaders:   remove,
+		responseHeaders: header,
+	}, nil
+}
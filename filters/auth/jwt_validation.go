@@ -1,7 +1,12 @@
 package auth
 
 import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,6 +14,7 @@ import (
 	jwt "github.com/golang-jwt/jwt/v4"
 	log "github.com/sirupsen/logrus"
 	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/secrets"
 )
 
 const (
@@ -16,13 +22,66 @@ const (
 	JwtValidationName = filters.JwtValidationName
 )
 
+const (
+	jwtIssuerOption     = "issuer"
+	jwtJwksOption       = "jwks"
+	jwtKeyOption        = "key"
+	jwtAudienceOption   = "audience"
+	jwtLeewayOption     = "leeway"
+	jwtAlgorithmsOption = "algorithms"
+	jwtAnyKVOption      = "anyKV"
+	jwtAllKVOption      = "allKV"
+)
+
+// the algorithms accepted by default, all of them using public keys
+var defaultJwtAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+var (
+	errJwtMalformed        = errors.New("malformed token")
+	errJwtInvalidIssuer    = errors.New("invalid issuer")
+	errJwtInvalidSignature = errors.New("invalid signature")
+	errJwtExpired          = errors.New("token expired")
+	errJwtNotValidYet      = errors.New("token not valid yet")
+	errJwtInvalidAudience  = errors.New("invalid audience")
+)
+
+// JwtValidationOptions are used to configure the jwtValidation filter
+// specification.
+type JwtValidationOptions struct {
+	TokenintrospectionOptions
+
+	// Secrets is used to read the static public keys.
+	Secrets secrets.SecretsReader
+}
+
 type (
 	jwtValidationSpec struct {
-		options TokenintrospectionOptions
+		options JwtValidationOptions
 	}
 
-	jwtValidationFilter struct {
+	// jwtKeySource verifies the tokens of an issuer, or of any issuer
+	// when the issuer is empty.
+	jwtKeySource struct {
+		issuer  string
 		jwksUri string
+
+		// static public key read from a PEM encoded secret
+		keyPath string
+		key     *secrets.Parser
+	}
+
+	jwtValidationFilter struct {
+		sources    []*jwtKeySource
+		audience   []string
+		leeway     time.Duration
+		algorithms []string
+		anyKV      kv
+		allKV      kv
 	}
 )
 
@@ -36,7 +95,30 @@ var refreshUnknownKID = true
 //the map of jwks keyfunctions stored per jwksUri
 var jwksMap map[string]*keyfunc.JWKS = make(map[string]*keyfunc.JWKS)
 
+// NewJwtValidationWithOptions creates the filter specification of the
+// jwtValidation filter, without support for static keys.
 func NewJwtValidationWithOptions(o TokenintrospectionOptions) filters.Spec {
+	return NewJwtValidation(JwtValidationOptions{TokenintrospectionOptions: o})
+}
+
+// NewJwtValidation creates the filter specification of the
+// jwtValidation filter. The filter verifies the signature and the
+// claims of the bearer JWT tokens.
+//
+// With a single argument, the argument is the URL of the issuer, whose
+// keys are discovered via its /.well-known/openid-configuration
+// endpoint:
+//
+//	jwtValidation("https://login.example.org")
+//
+// The issuer URL can be followed by, or replaced with key-value
+// options. The issuer, jwks and key options can be repeated, and each
+// of them adds a source of the signing keys:
+//
+//	jwtValidation("issuer", "https://login.example.org", "audience", "my-app", "leeway", "30s")
+//	jwtValidation("jwks", "https://keys.example.org/jwks.json https://login.example.org", "key", "/secrets/key.pem")
+//	jwtValidation("https://login.example.org", "algorithms", "ES256", "anyKV", "realm=/employees")
+func NewJwtValidation(o JwtValidationOptions) filters.Spec {
 	return &jwtValidationSpec{
 		options: o,
 	}
@@ -46,29 +128,152 @@ func (s *jwtValidationSpec) Name() string {
 	return filters.JwtValidationName
 }
 
-func (s *jwtValidationSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
-	if len(args) != 1 {
-		return nil, filters.ErrInvalidFilterParameters
+// splitSource splits the value of a key source option to the location
+// and the optional issuer.
+func splitSource(value string) (string, string, error) {
+	fields := strings.Fields(value)
+	switch len(fields) {
+	case 1:
+		return fields[0], "", nil
+	case 2:
+		return fields[0], fields[1], nil
+	default:
+		return "", "", fmt.Errorf("invalid key source: %q", value)
 	}
-	sargs, err := getStrings(args)
-	if err != nil {
-		return nil, err
+}
+
+func parseKV(k kv, value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid claim: %q", value)
 	}
 
-	issuerURL := sargs[0]
+	k[value[:i]] = append(k[value[:i]], value[i+1:])
+	return nil
+}
 
+func discoverJwks(issuerURL string) (string, string, error) {
 	cfg, err := getOpenIDConfig(issuerURL)
 	if err != nil {
-		return nil, err
+		return "", "", err
+	}
+
+	if cfg.JwksURI == "" {
+		return "", "", fmt.Errorf("no jwks_uri discovered for %s", issuerURL)
 	}
 
-	err = registerKeyFunction(cfg.JwksURI)
+	issuer := cfg.Issuer
+	if issuer == "" {
+		issuer = issuerURL
+	}
+
+	return cfg.JwksURI, issuer, nil
+}
+
+func (s *jwtValidationSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) == 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+	sargs, err := getStrings(args)
 	if err != nil {
 		return nil, err
 	}
 
 	f := &jwtValidationFilter{
-		jwksUri: cfg.JwksURI,
+		algorithms: defaultJwtAlgorithms,
+		anyKV:      make(kv),
+		allKV:      make(kv),
+	}
+
+	if len(sargs)%2 == 1 {
+		// the issuer URL used only for discovery, for compatibility
+		// the issuer claim is not checked
+		jwksUri, _, err := discoverJwks(sargs[0])
+		if err != nil {
+			return nil, err
+		}
+
+		f.sources = append(f.sources, &jwtKeySource{jwksUri: jwksUri})
+		sargs = sargs[1:]
+	}
+
+	for i := 0; i < len(sargs); i += 2 {
+		value := sargs[i+1]
+		switch sargs[i] {
+		case jwtIssuerOption:
+			jwksUri, issuer, err := discoverJwks(value)
+			if err != nil {
+				return nil, err
+			}
+
+			f.sources = append(f.sources, &jwtKeySource{issuer: issuer, jwksUri: jwksUri})
+		case jwtJwksOption:
+			jwksUri, issuer, err := splitSource(value)
+			if err != nil {
+				return nil, err
+			}
+
+			f.sources = append(f.sources, &jwtKeySource{issuer: issuer, jwksUri: jwksUri})
+		case jwtKeyOption:
+			if s.options.Secrets == nil {
+				return nil, fmt.Errorf("%s: static keys are not supported", filters.JwtValidationName)
+			}
+
+			path, issuer, err := splitSource(value)
+			if err != nil {
+				return nil, err
+			}
+
+			f.sources = append(f.sources, &jwtKeySource{
+				issuer:  issuer,
+				keyPath: path,
+				key:     secrets.NewParser(s.options.Secrets, parsePublicKey, path),
+			})
+		case jwtAudienceOption:
+			for _, a := range strings.Split(value, ",") {
+				if a = strings.TrimSpace(a); a != "" {
+					f.audience = append(f.audience, a)
+				}
+			}
+		case jwtLeewayOption:
+			if f.leeway, err = time.ParseDuration(value); err != nil || f.leeway < 0 {
+				return nil, fmt.Errorf("%s: invalid leeway: %s", filters.JwtValidationName, value)
+			}
+		case jwtAlgorithmsOption:
+			f.algorithms = nil
+			for _, a := range strings.Split(value, ",") {
+				a = strings.TrimSpace(a)
+				if jwt.GetSigningMethod(a) == nil || a == jwt.SigningMethodNone.Alg() || strings.HasPrefix(a, "HS") {
+					return nil, fmt.Errorf("%s: unsupported algorithm: %s", filters.JwtValidationName, a)
+				}
+
+				f.algorithms = append(f.algorithms, a)
+			}
+		case jwtAnyKVOption:
+			err = parseKV(f.anyKV, value)
+		case jwtAllKVOption:
+			err = parseKV(f.allKV, value)
+		default:
+			err = fmt.Errorf("%s: unknown option: %s", filters.JwtValidationName, sargs[i])
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(f.sources) == 0 {
+		return nil, fmt.Errorf("%s: no key source configured", filters.JwtValidationName)
+	}
+
+	for _, s := range f.sources {
+		if s.jwksUri == "" {
+			continue
+		}
+
+		if err := registerKeyFunction(s.jwksUri); err != nil {
+			return nil, err
+		}
 	}
 
 	return f, nil
@@ -120,6 +325,121 @@ func getKeyFunction(url string) (jwks *keyfunc.JWKS) {
 	return jwksMap[url]
 }
 
+func parsePublicKey(content ...[]byte) (interface{}, error) {
+	block, _ := pem.Decode(content[0])
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+func (s *jwtKeySource) keyfunc(token *jwt.Token) (interface{}, error) {
+	if s.key != nil {
+		return s.key.Get()
+	}
+
+	jwks := getKeyFunction(s.jwksUri)
+	if jwks == nil {
+		return nil, fmt.Errorf("jwks not registered: %s", s.jwksUri)
+	}
+
+	return jwks.Keyfunc(token)
+}
+
+// verify checks the signature of the token with the key sources
+// matching its issuer, and returns its claims.
+func (f *jwtValidationFilter) verify(token string) (jwt.MapClaims, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods(f.algorithms), jwt.WithoutClaimsValidation())
+
+	var unverified jwt.MapClaims
+	if _, _, err := parser.ParseUnverified(token, &unverified); err != nil {
+		return nil, errJwtMalformed
+	}
+
+	iss, _ := unverified["iss"].(string)
+
+	var matching bool
+	for _, s := range f.sources {
+		if s.issuer != "" && s.issuer != iss {
+			continue
+		}
+
+		matching = true
+
+		var claims jwt.MapClaims
+		parsed, err := parser.ParseWithClaims(token, &claims, s.keyfunc)
+		if err == nil && parsed.Valid {
+			return claims, nil
+		}
+
+		log.Debugf("Failed to verify jwt token with %s: %v", s, err)
+	}
+
+	if !matching {
+		return nil, errJwtInvalidIssuer
+	}
+
+	return nil, errJwtInvalidSignature
+}
+
+func (s *jwtKeySource) String() string {
+	if s.key != nil {
+		return s.keyPath
+	}
+
+	return s.jwksUri
+}
+
+func (f *jwtValidationFilter) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-f.leeway).Unix(), false) {
+		return errJwtExpired
+	}
+
+	if !claims.VerifyNotBefore(now.Add(f.leeway).Unix(), false) || !claims.VerifyIssuedAt(now.Add(f.leeway).Unix(), false) {
+		return errJwtNotValidYet
+	}
+
+	if len(f.audience) > 0 {
+		var valid bool
+		for _, a := range f.audience {
+			if claims.VerifyAudience(a, true) {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return errJwtInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+// bearerChallenge returns the value of the WWW-Authenticate header as
+// defined in https://datatracker.ietf.org/doc/html/rfc6750#section-3
+func bearerChallenge(errorCode string, err error) string {
+	if errorCode == "" {
+		return "Bearer"
+	}
+
+	return fmt.Sprintf(`Bearer error="%s", error_description="%s"`, errorCode, err)
+}
+
 func (f *jwtValidationFilter) Request(ctx filters.FilterContext) {
 	r := ctx.Request()
 
@@ -128,14 +448,18 @@ func (f *jwtValidationFilter) Request(ctx filters.FilterContext) {
 	if !ok {
 		token, ok := getToken(r)
 		if !ok || token == "" {
-			unauthorized(ctx, "", missingToken, "", "")
+			unauthorized(ctx, "", missingToken, bearerChallenge("", nil), "")
 			return
 		}
 
-		claims, err := parseToken(token, f.jwksUri)
+		claims, err := f.verify(token)
+		if err == nil {
+			err = f.validateClaims(claims)
+		}
+
 		if err != nil {
-			log.Errorf("Error while parsing jwt token : %v.", err)
-			unauthorized(ctx, "", invalidToken, "", "")
+			log.Debugf("Invalid jwt token: %v.", err)
+			unauthorized(ctx, "", invalidToken, bearerChallenge("invalid_token", err), err.Error())
 			return
 		}
 
@@ -146,7 +470,12 @@ func (f *jwtValidationFilter) Request(ctx filters.FilterContext) {
 
 	sub, ok := info.Claims["sub"].(string)
 	if !ok {
-		unauthorized(ctx, sub, invalidSub, "", "")
+		unauthorized(ctx, sub, invalidSub, bearerChallenge("invalid_token", errors.New("missing subject")), "")
+		return
+	}
+
+	if len(f.anyKV) > 0 && !f.anyKV.matchAny(info.Claims) || len(f.allKV) > 0 && !f.allKV.matchAll(info.Claims) {
+		reject(ctx, http.StatusForbidden, sub, invalidClaim, bearerChallenge("insufficient_scope", errors.New("required claims not present")), "")
 		return
 	}
 
@@ -156,17 +485,3 @@ func (f *jwtValidationFilter) Request(ctx filters.FilterContext) {
 }
 
 func (f *jwtValidationFilter) Response(filters.FilterContext) {}
-
-func parseToken(token string, jwksUri string) (map[string]interface{}, error) {
-	jwks := getKeyFunction(jwksUri)
-
-	var claims jwt.MapClaims
-	parsedToken, err := jwt.ParseWithClaims(token, &claims, jwks.Keyfunc)
-	if err != nil {
-		return nil, fmt.Errorf("error while parsing jwt token : %w", err)
-	} else if !parsedToken.Valid {
-		return nil, fmt.Errorf("invalid token")
-	} else {
-		return claims, nil
-	}
-}
@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -15,6 +19,7 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/zalando/skipper/eskip"
 	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
 	"github.com/zalando/skipper/net"
 	"github.com/zalando/skipper/proxy/proxytest"
 )
@@ -183,3 +188,241 @@ func TestJWTValidationJwksError(t *testing.T) {
 	}*/
 
 }
+
+type jwtTestSecrets map[string][]byte
+
+func (s jwtTestSecrets) GetSecret(path string) ([]byte, bool) {
+	b, ok := s[path]
+	return b, ok
+}
+
+func (jwtTestSecrets) Close() {}
+
+func signClaims(t *testing.T, key *rsa.PrivateKey, keyID string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if keyID != "" {
+		token.Header["kid"] = keyID
+	}
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func jwtRequest(t *testing.T, f filters.Filter, token string) *filtertest.Context {
+	req, err := http.NewRequest("GET", "https://www.example.org/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "" {
+		req.Header.Set(authHeaderName, authHeaderPrefix+token)
+	}
+
+	ctx := &filtertest.Context{FRequest: req, FStateBag: make(map[string]interface{})}
+	f.Request(ctx)
+	return ctx
+}
+
+func TestJWTValidationOptions(t *testing.T) {
+	staticKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&staticKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := jwtTestSecrets{"/secrets/key.pem": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})}
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"keys":[{"kty":"RSA", "alg":"RS256", "kid": "%s", "n":"%s","e":"AQAB"}]}`,
+			kid, base64.RawURLEncoding.EncodeToString(privateKey.PublicKey.N.Bytes()))
+	}))
+	defer jwksServer.Close()
+
+	testOidcConfig := getTestOidcConfig()
+	testOidcConfig.JwksURI = jwksServer.URL
+	issuerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(testOidcConfig); err != nil {
+			t.Errorf("Could not encode testOidcConfig: %v", err)
+		}
+	}))
+	defer issuerServer.Close()
+
+	spec := NewJwtValidation(JwtValidationOptions{Secrets: secrets})
+	f, err := spec.CreateFilter([]interface{}{
+		"issuer", issuerServer.URL,
+		"key", "/secrets/key.pem https://static.example.org",
+		"audience", "app1,app2",
+		"leeway", "1m",
+		"allKV", "realm=/employees",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(iss string, extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":   iss,
+			"sub":   "alice",
+			"aud":   []string{"app2"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"realm": "/employees",
+		}
+
+		for k, v := range extra {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+
+		return c
+	}
+
+	for _, tc := range []struct {
+		title     string
+		token     string
+		status    int
+		challenge string
+	}{{
+		title:  "discovered issuer",
+		token:  signClaims(t, privateKey, kid, claims(testOidcConfig.Issuer, nil)),
+		status: http.StatusOK,
+	}, {
+		title:  "static key",
+		token:  signClaims(t, staticKey, "", claims("https://static.example.org", nil)),
+		status: http.StatusOK,
+	}, {
+		title:  "expired within leeway",
+		token:  signClaims(t, privateKey, kid, claims(testOidcConfig.Issuer, jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()})),
+		status: http.StatusOK,
+	}, {
+		title:     "missing token",
+		status:    http.StatusUnauthorized,
+		challenge: "Bearer",
+	}, {
+		title:     "malformed token",
+		token:     "foo",
+		status:    http.StatusUnauthorized,
+		challenge: `Bearer error="invalid_token", error_description="malformed token"`,
+	}, {
+		title:     "unknown issuer",
+		token:     signClaims(t, privateKey, kid, claims("https://other.example.org", nil)),
+		status:    http.StatusUnauthorized,
+		challenge: `Bearer error="invalid_token", error_description="invalid issuer"`,
+	}, {
+		title:     "key of other issuer",
+		token:     signClaims(t, staticKey, kid, claims(testOidcConfig.Issuer, nil)),
+		status:    http.StatusUnauthorized,
+		challenge: `Bearer error="invalid_token", error_description="invalid signature"`,
+	}, {
+		title:     "expired",
+		token:     signClaims(t, privateKey, kid, claims(testOidcConfig.Issuer, jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()})),
+		status:    http.StatusUnauthorized,
+		challenge: `Bearer error="invalid_token", error_description="token expired"`,
+	}, {
+		title:     "not valid yet",
+		token:     signClaims(t, privateKey, kid, claims(testOidcConfig.Issuer, jwt.MapClaims{"nbf": now.Add(2 * time.Minute).Unix()})),
+		status:    http.StatusUnauthorized,
+		challenge: `Bearer error="invalid_token", error_description="token not valid yet"`,
+	}, {
+		title:     "invalid audience",
+		token:     signClaims(t, privateKey, kid, claims(testOidcConfig.Issuer, jwt.MapClaims{"aud": "app3"})),
+		status:    http.StatusUnauthorized,
+		challenge: `Bearer error="invalid_token", error_description="invalid audience"`,
+	}, {
+		title:     "missing claim",
+		token:     signClaims(t, privateKey, kid, claims(testOidcConfig.Issuer, jwt.MapClaims{"realm": "/services"})),
+		status:    http.StatusForbidden,
+		challenge: `Bearer error="insufficient_scope", error_description="required claims not present"`,
+	}} {
+		t.Run(tc.title, func(t *testing.T) {
+			ctx := jwtRequest(t, f, tc.token)
+			if tc.status == http.StatusOK {
+				if ctx.FServed {
+					t.Fatalf("unexpected status: %d, %s", ctx.FResponse.StatusCode, ctx.FResponse.Header.Get("WWW-Authenticate"))
+				}
+
+				return
+			}
+
+			if !ctx.FServed || ctx.FResponse.StatusCode != tc.status {
+				t.Fatalf("expected status %d", tc.status)
+			}
+
+			if h := ctx.FResponse.Header.Get("WWW-Authenticate"); h != tc.challenge {
+				t.Errorf("expected challenge %q, got %q", tc.challenge, h)
+			}
+		})
+	}
+}
+
+func TestJWTValidationAlgorithms(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := jwtTestSecrets{"/secrets/key.pem": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})}
+	spec := NewJwtValidation(JwtValidationOptions{Secrets: secrets})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "alice"})
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := spec.CreateFilter([]interface{}{"key", "/secrets/key.pem", "algorithms", "ES256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx := jwtRequest(t, f, s); ctx.FServed {
+		t.Error("expected valid token")
+	}
+
+	f, err = spec.CreateFilter([]interface{}{"key", "/secrets/key.pem", "algorithms", "RS256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx := jwtRequest(t, f, s); !ctx.FServed {
+		t.Error("expected rejected algorithm")
+	}
+}
+
+func TestJWTValidationInvalidArgs(t *testing.T) {
+	spec := NewJwtValidation(JwtValidationOptions{Secrets: jwtTestSecrets{}})
+	for _, args := range [][]interface{}{
+		nil,
+		{"audience", "foo"},
+		{"key", "/a /b /c"},
+		{"key", "/a", "leeway", "forever"},
+		{"key", "/a", "algorithms", "none"},
+		{"key", "/a", "algorithms", "HS256"},
+		{"key", "/a", "anyKV", "=foo"},
+		{"key", "/a", "unknown", "foo"},
+	} {
+		if _, err := spec.CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+
+	if _, err := NewJwtValidationWithOptions(TokenintrospectionOptions{}).CreateFilter([]interface{}{"key", "/a"}); err == nil {
+		t.Error("expected error without secrets")
+	}
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/secrets"
+)
+
+const (
+	signJwtIssuer         = "issuer"
+	signJwtAudience       = "audience"
+	signJwtTTL            = "ttl"
+	signJwtClaims         = "claims"
+	signJwtHeader         = "header"
+	signJwtRequestContext = "requestContext"
+	signJwtKeyID          = "kid"
+	signJwtAlgorithm      = "algorithm"
+
+	defaultSignJwtTTL = time.Minute
+
+	// signJwtRequestClaim is the claim containing the attributes of the
+	// request, when the requestContext option is enabled.
+	signJwtRequestClaim = "req"
+)
+
+var defaultSignJwtClaims = []string{"sub"}
+
+// the algorithms that use the content of the secret as a shared key, enabled
+// only explicitly with the algorithm option
+var signJwtHMACMethods = map[string]jwt.SigningMethod{
+	"HS256": jwt.SigningMethodHS256,
+	"HS384": jwt.SigningMethodHS384,
+	"HS512": jwt.SigningMethodHS512,
+}
+
+type (
+	signJwtSpec struct {
+		secrets secrets.SecretsReader
+	}
+
+	// signingKey is a private key, or an HMAC key, parsed from a secret
+	signingKey struct {
+		key    interface{}
+		method jwt.SigningMethod
+	}
+
+	signJwtFilter struct {
+		key            *secrets.Parser
+		issuer         string
+		audience       []string
+		ttl            time.Duration
+		claims         []string
+		header         string
+		requestContext bool
+		keyID          string
+	}
+)
+
+// NewSignJwt creates the filter specification of the signJwt filter.
+// The filter mints a short-lived JWT for the backend, containing the
+// selected claims of the incoming credential, validated by a preceding
+// filter, e.g. jwtValidation, oauthTokeninfo*, oauthTokenintrospection*
+// or oauthOidc*. The token is signed with the key read from the secret
+// at the path passed as the first argument:
+//
+//	signJwt("/secrets/internal-jwt.pem")
+//	signJwt("/secrets/internal-jwt.pem", "issuer", "https://gateway.example.org", "audience", "orders", "claims", "sub,email", "ttl", "2m")
+//
+// PEM encoded RSA, ECDSA and Ed25519 private keys are supported. The
+// secret is used as an HMAC key only when the algorithm option is set
+// to HS256, HS384 or HS512:
+//
+//	signJwt("/secrets/internal-jwt-key", "algorithm", "HS256")
+func NewSignJwt(sr secrets.SecretsReader) filters.Spec {
+	return &signJwtSpec{secrets: sr}
+}
+
+func (*signJwtSpec) Name() string { return filters.SignJwtName }
+
+func (s *signJwtSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) == 0 || len(args)%2 != 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	sargs, err := getStrings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var hmac jwt.SigningMethod
+	f := &signJwtFilter{
+		ttl:    defaultSignJwtTTL,
+		claims: defaultSignJwtClaims,
+		header: authHeaderName,
+	}
+
+	for i := 1; i < len(sargs); i += 2 {
+		value := sargs[i+1]
+		switch sargs[i] {
+		case signJwtIssuer:
+			f.issuer = value
+		case signJwtAudience:
+			f.audience = splitList(value)
+		case signJwtTTL:
+			if f.ttl, err = time.ParseDuration(value); err != nil || f.ttl <= 0 {
+				return nil, fmt.Errorf("%s: invalid ttl: %s", filters.SignJwtName, value)
+			}
+		case signJwtClaims:
+			f.claims = splitList(value)
+		case signJwtHeader:
+			f.header = value
+		case signJwtRequestContext:
+			if f.requestContext, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("%s: invalid requestContext: %s", filters.SignJwtName, value)
+			}
+		case signJwtKeyID:
+			f.keyID = value
+		case signJwtAlgorithm:
+			m, ok := signJwtHMACMethods[value]
+			if !ok {
+				return nil, fmt.Errorf("%s: unsupported algorithm: %s", filters.SignJwtName, value)
+			}
+
+			hmac = m
+		default:
+			return nil, fmt.Errorf("%s: unknown option: %s", filters.SignJwtName, sargs[i])
+		}
+	}
+
+	parse := parsePrivateKey
+	if hmac != nil {
+		parse = func(content ...[]byte) (interface{}, error) {
+			if len(content[0]) == 0 {
+				return nil, errors.New("empty key")
+			}
+
+			return &signingKey{key: content[0], method: hmac}, nil
+		}
+	}
+
+	f.key = secrets.NewParser(s.secrets, parse, sargs[0])
+	return f, nil
+}
+
+func splitList(s string) []string {
+	var l []string
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			l = append(l, i)
+		}
+	}
+
+	return l
+}
+
+func parsePrivateKey(content ...[]byte) (interface{}, error) {
+	block, _ := pem.Decode(content[0])
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{key: k, method: jwt.SigningMethodRS256}, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return &signingKey{key: k, method: jwt.SigningMethodES256}, nil
+		case 384:
+			return &signingKey{key: k, method: jwt.SigningMethodES384}, nil
+		case 521:
+			return &signingKey{key: k, method: jwt.SigningMethodES512}, nil
+		}
+	case ed25519.PrivateKey:
+		return &signingKey{key: k, method: jwt.SigningMethodEdDSA}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %T", key)
+}
+
+// validatedClaims returns the claims of the incoming credential, stored
+// in the state bag by the authentication filters.
+func validatedClaims(ctx filters.FilterContext) (map[string]interface{}, bool) {
+	if c, ok := ctx.StateBag()[oidcClaimsCacheKey].(tokenContainer); ok {
+		return c.Claims, true
+	}
+
+	if c, ok := ctx.StateBag()[tokenintrospectionCacheKey].(tokenIntrospectionInfo); ok {
+		return c, true
+	}
+
+	if c, ok := ctx.StateBag()[tokeninfoCacheKey].(map[string]interface{}); ok {
+		return c, true
+	}
+
+	return nil, false
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (f *signJwtFilter) Request(ctx filters.FilterContext) {
+	incoming, ok := validatedClaims(ctx)
+	if !ok {
+		unauthorized(ctx, "", missingToken, "", "no validated credential")
+		return
+	}
+
+	v, err := f.key.Get()
+	if err != nil {
+		log.Errorf("Failed to get the signing key: %v", err)
+		ctx.Serve(&http.Response{StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(f.ttl).Unix(),
+		"jti": randomID(),
+	}
+
+	for _, c := range f.claims {
+		if v, ok := incoming[c]; ok {
+			claims[c] = v
+		}
+	}
+
+	if f.issuer != "" {
+		claims["iss"] = f.issuer
+	}
+
+	switch len(f.audience) {
+	case 0:
+	case 1:
+		claims["aud"] = f.audience[0]
+	default:
+		claims["aud"] = f.audience
+	}
+
+	if f.requestContext {
+		r := ctx.Request()
+		claims[signJwtRequestClaim] = map[string]interface{}{
+			"method":  r.Method,
+			"host":    r.Host,
+			"path":    r.URL.Path,
+			"flow_id": r.Header.Get("X-Flow-Id"),
+		}
+	}
+
+	key := v.(*signingKey)
+	token := jwt.NewWithClaims(key.method, claims)
+	if f.keyID != "" {
+		token.Header["kid"] = f.keyID
+	}
+
+	signed, err := token.SignedString(key.key)
+	if err != nil {
+		log.Errorf("Failed to sign jwt: %v", err)
+		ctx.Serve(&http.Response{StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if f.header == authHeaderName {
+		ctx.Request().Header.Set(authHeaderName, authHeaderPrefix+signed)
+	} else {
+		ctx.Request().Header.Set(f.header, signed)
+	}
+}
+
+func (*signJwtFilter) Response(filters.FilterContext) {}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/secrets"
+)
+
+const (
+	tokenExchangeSpanName = "tokenexchange"
+
+	tokenExchangeGrantType       = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenExchangeAccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+
+	tokenExchangeAudience           = "audience"
+	tokenExchangeResource           = "resource"
+	tokenExchangeScope              = "scope"
+	tokenExchangeRequestedTokenType = "requestedTokenType"
+	tokenExchangeSubjectTokenType   = "subjectTokenType"
+	tokenExchangeClientID           = "clientId"
+	tokenExchangeClientSecret       = "clientSecret"
+	tokenExchangeHeader             = "header"
+	tokenExchangeCacheSize          = "cacheSize"
+
+	defaultTokenExchangeCacheSize = 10000
+
+	// the exchanged tokens are dropped from the cache this long before
+	// they expire
+	tokenExchangeExpiryMargin = 10 * time.Second
+
+	maxTokenExchangeResponse = 1 << 20
+)
+
+// TokenExchangeOptions are used to configure the tokenExchange filter
+// specification.
+type TokenExchangeOptions struct {
+	TokenintrospectionOptions
+
+	// Secrets is used to read the client credentials.
+	Secrets secrets.SecretsReader
+}
+
+type (
+	tokenExchangeSpec struct {
+		options TokenExchangeOptions
+
+		mu      sync.Mutex
+		clients map[string]*authClient
+	}
+
+	tokenExchangeFilter struct {
+		client           *authClient
+		secrets          secrets.SecretsReader
+		params           url.Values
+		subjectTokenType string
+		clientID         string
+		clientSecret     string
+		header           string
+		cache            *ttlCache
+	}
+
+	tokenExchangeResponse struct {
+		AccessToken      string `json:"access_token"`
+		IssuedTokenType  string `json:"issued_token_type"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+)
+
+// NewTokenExchange creates the filter specification of the tokenExchange
+// filter. The filter exchanges the bearer token of the incoming request
+// for a token issued for the backend, using the OAuth 2.0 Token Exchange
+// defined in RFC 8693, and replaces the Authorization header of the
+// request with it. The first argument is the URL of the token endpoint,
+// the rest of the arguments are key-value options:
+//
+//	tokenExchange("https://sts.example.org/token", "audience", "orders")
+//	tokenExchange("https://sts.example.org/token", "scope", "read write", "clientId", "/secrets/sts-client-id", "clientSecret", "/secrets/sts-client-secret")
+func NewTokenExchange(o TokenExchangeOptions) filters.Spec {
+	return &tokenExchangeSpec{options: o, clients: make(map[string]*authClient)}
+}
+
+func (*tokenExchangeSpec) Name() string { return filters.TokenExchangeName }
+
+func (s *tokenExchangeSpec) getClient(endpoint string) (*authClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.clients[endpoint]; ok {
+		return c, nil
+	}
+
+	c, err := newAuthClient(endpoint, tokenExchangeSpanName, s.options.Timeout, s.options.MaxIdleConns, s.options.Tracer)
+	if err != nil {
+		return nil, err
+	}
+
+	s.clients[endpoint] = c
+	return c, nil
+}
+
+func (s *tokenExchangeSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) == 0 || len(args)%2 != 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	sargs, err := getStrings(args)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(sargs[0])
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%s: invalid token endpoint: %s", filters.TokenExchangeName, sargs[0])
+	}
+
+	f := &tokenExchangeFilter{
+		secrets:          s.options.Secrets,
+		params:           make(url.Values),
+		subjectTokenType: tokenExchangeAccessTokenType,
+		header:           authHeaderName,
+	}
+
+	cacheSize := defaultTokenExchangeCacheSize
+	for i := 1; i < len(sargs); i += 2 {
+		value := sargs[i+1]
+		switch sargs[i] {
+		case tokenExchangeAudience:
+			f.params.Add("audience", value)
+		case tokenExchangeResource:
+			f.params.Add("resource", value)
+		case tokenExchangeScope:
+			f.params.Set("scope", value)
+		case tokenExchangeRequestedTokenType:
+			f.params.Set("requested_token_type", value)
+		case tokenExchangeSubjectTokenType:
+			f.subjectTokenType = value
+		case tokenExchangeClientID:
+			f.clientID = value
+		case tokenExchangeClientSecret:
+			f.clientSecret = value
+		case tokenExchangeHeader:
+			f.header = value
+		case tokenExchangeCacheSize:
+			if cacheSize, err = strconv.Atoi(value); err != nil || cacheSize < 0 {
+				return nil, fmt.Errorf("%s: invalid cache size: %s", filters.TokenExchangeName, value)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown option: %s", filters.TokenExchangeName, sargs[i])
+		}
+	}
+
+	if (f.clientID != "" || f.clientSecret != "") && f.secrets == nil {
+		return nil, fmt.Errorf("%s: client credentials are not supported", filters.TokenExchangeName)
+	}
+
+	if f.client, err = s.getClient(sargs[0]); err != nil {
+		return nil, err
+	}
+
+	if cacheSize > 0 {
+		f.cache = newTTLCache(cacheSize)
+	}
+
+	return f, nil
+}
+
+func (f *tokenExchangeFilter) secret(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	b, ok := f.secrets.GetSecret(path)
+	if !ok {
+		return "", fmt.Errorf("secret not found: %s", path)
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// exchange requests a new token from the token endpoint. It returns a
+// requestError when the endpoint rejected the subject token.
+func (f *tokenExchangeFilter) exchange(ctx filters.FilterContext, subjectToken string) (*tokenExchangeResponse, error) {
+	params := make(url.Values, len(f.params)+3)
+	for k, v := range f.params {
+		params[k] = v
+	}
+
+	params.Set("grant_type", tokenExchangeGrantType)
+	params.Set("subject_token", subjectToken)
+	params.Set("subject_token_type", f.subjectTokenType)
+
+	req, err := http.NewRequest("POST", f.client.url.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req = bindContext(ctx, req)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	clientID, err := f.secret(f.clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSecret, err := f.secret(f.clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	rsp, err := f.client.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rsp.Body.Close()
+
+	var tr tokenExchangeResponse
+	if err := json.NewDecoder(io.LimitReader(rsp.Body, maxTokenExchangeResponse)).Decode(&tr); err != nil && rsp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("invalid token exchange response: %w", err)
+	}
+
+	switch {
+	case rsp.StatusCode == http.StatusOK && tr.AccessToken != "":
+		return &tr, nil
+	case rsp.StatusCode == http.StatusOK:
+		return nil, fmt.Errorf("token exchange response without access token")
+	case rsp.StatusCode >= 400 && rsp.StatusCode < 500:
+		return nil, requestErrorf("token exchange rejected: %s %s: %s", rsp.Status, tr.Error, tr.ErrorDescription)
+	default:
+		return nil, fmt.Errorf("token exchange failed: %s", rsp.Status)
+	}
+}
+
+func tokenExchangeCacheKey(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func (f *tokenExchangeFilter) setToken(ctx filters.FilterContext, token string) {
+	if f.header == authHeaderName {
+		ctx.Request().Header.Set(authHeaderName, authHeaderPrefix+token)
+	} else {
+		ctx.Request().Header.Set(f.header, token)
+	}
+}
+
+func (f *tokenExchangeFilter) Request(ctx filters.FilterContext) {
+	subjectToken, ok := getToken(ctx.Request())
+	if !ok || subjectToken == "" {
+		unauthorized(ctx, "", missingBearerToken, "", "")
+		return
+	}
+
+	key := tokenExchangeCacheKey(subjectToken)
+	if f.cache != nil {
+		if token, ok := f.cache.get(key); ok {
+			f.setToken(ctx, token.(string))
+			return
+		}
+	}
+
+	tr, err := f.exchange(ctx, subjectToken)
+	if err != nil {
+		if _, ok := err.(*requestError); ok {
+			unauthorized(ctx, "", invalidToken, "", err.Error())
+			return
+		}
+
+		log.Errorf("Failed to exchange token at %s: %v", f.client.url, err)
+		unauthorized(ctx, "", authServiceAccess, "", err.Error())
+		return
+	}
+
+	if f.cache != nil && tr.ExpiresIn > 0 {
+		expires := time.Now().Add(time.Duration(tr.ExpiresIn)*time.Second - tokenExchangeExpiryMargin)
+		f.cache.set(key, tr.AccessToken, expires)
+	}
+
+	f.setToken(ctx, tr.AccessToken)
+}
+
+func (*tokenExchangeFilter) Response(filters.FilterContext) {}
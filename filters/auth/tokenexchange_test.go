@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+func tokenExchangeRequest(t *testing.T, f filters.Filter, token string) *filtertest.Context {
+	req, err := http.NewRequest("GET", "https://www.example.org/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "" {
+		req.Header.Set(authHeaderName, authHeaderPrefix+token)
+	}
+
+	ctx := &filtertest.Context{FRequest: req, FStateBag: make(map[string]interface{})}
+	f.Request(ctx)
+	return ctx
+}
+
+func TestTokenExchange(t *testing.T) {
+	var requests int32
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.PostForm.Get("grant_type") != tokenExchangeGrantType ||
+			r.PostForm.Get("subject_token_type") != tokenExchangeAccessTokenType ||
+			r.PostForm.Get("audience") != "orders" ||
+			r.PostForm.Get("scope") != "read" {
+			t.Errorf("unexpected token exchange request: %v", r.PostForm)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("subject_token") != "valid" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":      "exchanged",
+			"issued_token_type": tokenExchangeAccessTokenType,
+			"token_type":        "Bearer",
+			"expires_in":        300,
+		})
+	}))
+	defer sts.Close()
+
+	secrets := jwtTestSecrets{"/secrets/id": []byte("client\n"), "/secrets/secret": []byte("s3cret")}
+	spec := NewTokenExchange(TokenExchangeOptions{Secrets: secrets})
+	f, err := spec.CreateFilter([]interface{}{
+		sts.URL,
+		"audience", "orders",
+		"scope", "read",
+		"clientId", "/secrets/id",
+		"clientSecret", "/secrets/secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		ctx := tokenExchangeRequest(t, f, "valid")
+		if ctx.FServed {
+			t.Fatalf("unexpected response: %d", ctx.FResponse.StatusCode)
+		}
+
+		if h := ctx.FRequest.Header.Get(authHeaderName); h != "Bearer exchanged" {
+			t.Errorf("unexpected authorization header: %s", h)
+		}
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected cached token, got %d requests", n)
+	}
+
+	if ctx := tokenExchangeRequest(t, f, "invalid"); !ctx.FServed || ctx.FResponse.StatusCode != http.StatusUnauthorized {
+		t.Error("expected unauthorized for rejected token")
+	}
+
+	if ctx := tokenExchangeRequest(t, f, ""); !ctx.FServed || ctx.FResponse.StatusCode != http.StatusUnauthorized {
+		t.Error("expected unauthorized for missing token")
+	}
+
+	f, err = spec.CreateFilter([]interface{}{sts.URL, "clientId", "/secrets/missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx := tokenExchangeRequest(t, f, "valid"); !ctx.FServed || ctx.FResponse.StatusCode != http.StatusUnauthorized {
+		t.Error("expected unauthorized for missing client credentials")
+	}
+}
+
+func TestTokenExchangeInvalidArgs(t *testing.T) {
+	spec := NewTokenExchange(TokenExchangeOptions{})
+	for _, args := range [][]interface{}{
+		nil,
+		{"not a url"},
+		{"https://sts.example.org/token", "audience"},
+		{"https://sts.example.org/token", "cacheSize", "-1"},
+		{"https://sts.example.org/token", "unknown", "value"},
+		{"https://sts.example.org/token", "clientId", "/secrets/id"},
+	} {
+		if _, err := spec.CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
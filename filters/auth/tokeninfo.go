@@ -279,31 +279,11 @@ func (f *tokeninfoFilter) validateAllScopes(h map[string]interface{}) bool {
 }
 
 func (f *tokeninfoFilter) validateAnyKV(h map[string]interface{}) bool {
-	for k, v := range f.kv {
-		for _, res := range v {
-			if v2, ok := h[k].(string); ok {
-				if res == v2 {
-					return true
-				}
-			}
-		}
-	}
-	return false
+	return f.kv.matchAny(h)
 }
 
 func (f *tokeninfoFilter) validateAllKV(h map[string]interface{}) bool {
-	if len(h) < len(f.kv) {
-		return false
-	}
-	for k, v := range f.kv {
-		for _, res := range v {
-			v2, ok := h[k].(string)
-			if !ok || res != v2 {
-				return false
-			}
-		}
-	}
-	return true
+	return f.kv.matchAll(h)
 }
 
 // Request handles authentication based on the defined auth type.
@@ -14,11 +14,13 @@ import (
 	"github.com/zalando/skipper/filters/diag"
 	"github.com/zalando/skipper/filters/fadein"
 	"github.com/zalando/skipper/filters/flowid"
+	"github.com/zalando/skipper/filters/hedge"
 	logfilter "github.com/zalando/skipper/filters/log"
 	"github.com/zalando/skipper/filters/rfc"
 	"github.com/zalando/skipper/filters/scheduler"
 	"github.com/zalando/skipper/filters/sed"
 	"github.com/zalando/skipper/filters/tee"
+	tlsfilters "github.com/zalando/skipper/filters/tls"
 	"github.com/zalando/skipper/filters/tracing"
 	"github.com/zalando/skipper/filters/xforward"
 	"github.com/zalando/skipper/script"
@@ -195,9 +197,11 @@ func MakeRegistry() filters.Registry {
 		cookie.NewJSCookie(),
 		circuit.NewConsecutiveBreaker(),
 		circuit.NewRateBreaker(),
+		circuit.NewSlowCallBreaker(),
 		circuit.NewDisableBreaker(),
 		script.NewLuaScript(),
 		cors.NewOrigin(),
+		cors.NewCors(),
 		logfilter.NewUnverifiedAuditLog(),
 		tracing.NewSpanName(),
 		tracing.NewBaggageToTagFilter(),
@@ -206,16 +210,23 @@ func MakeRegistry() filters.Registry {
 		accesslog.NewAccessLogDisabled(),
 		accesslog.NewDisableAccessLog(),
 		accesslog.NewEnableAccessLog(),
+		accesslog.NewAccessLogFormat(),
+		accesslog.NewSampleAccessLog(),
 		auth.NewForwardToken(),
 		auth.NewForwardTokenField(),
 		scheduler.NewLIFO(),
 		scheduler.NewLIFOGroup(),
+		scheduler.NewPriorityClass(),
+		scheduler.NewPriorityClassFromHeader(),
+		scheduler.NewPriorityClassFromClaim(),
 		rfc.NewPath(),
 		rfc.NewHost(),
 		fadein.NewFadeIn(),
 		fadein.NewEndpointCreated(),
 		consistenthash.NewConsistentHashKey(),
 		consistenthash.NewConsistentHashBalanceFactor(),
+		tlsfilters.NewForwardClientCert(),
+		hedge.NewHedge(),
 	} {
 		r.Register(s)
 	}
@@ -78,6 +78,20 @@ func NewRateBreaker() filters.Spec {
 	return &spec{typ: circuit.FailureRate}
 }
 
+// NewSlowCallBreaker creates a filter specification to instantiate slowCallBreaker() filters.
+//
+// These filters set a breaker for the current route that counts the backend responses slower than a threshold
+// as failures, and opens if the slow or failed requests reach a percentage of P within a window of the last M
+// requests. The threshold (milliseconds or duration string), P and M are mandatory arguments of the filter:
+//
+// 	slowCallBreaker("300ms", 50, 100)
+//
+// The filter accepts the following optional arguments: timeout (milliseconds or duration string),
+// half-open-requests (integer), idle-ttl (milliseconds or duration string).
+func NewSlowCallBreaker() filters.Spec {
+	return &spec{typ: circuit.SlowCalls}
+}
+
 // NewDisableBreaker disables the circuit breaker for a route. It doesn't accept any arguments.
 func NewDisableBreaker() filters.Spec {
 	return &spec{}
@@ -89,6 +103,8 @@ func (s *spec) Name() string {
 		return filters.ConsecutiveBreakerName
 	case circuit.FailureRate:
 		return filters.RateBreakerName
+	case circuit.SlowCalls:
+		return filters.SlowCallBreakerName
 	default:
 		return filters.DisableBreakerName
 	}
@@ -190,6 +206,67 @@ func rateFilter(args []interface{}) (filters.Filter, error) {
 	}, nil
 }
 
+func slowCallFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) < 3 || len(args) > 6 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	threshold, err := getDurationArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := getIntArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if rate <= 0 || rate > 100 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	window, err := getIntArg(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if len(args) > 3 {
+		timeout, err = getDurationArg(args[3])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var halfOpenRequests int
+	if len(args) > 4 {
+		halfOpenRequests, err = getIntArg(args[4])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var idleTTL time.Duration
+	if len(args) > 5 {
+		idleTTL, err = getDurationArg(args[5])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &filter{
+		settings: circuit.BreakerSettings{
+			Type:              circuit.SlowCalls,
+			Window:            window,
+			SlowCallThreshold: threshold,
+			SlowCallRate:      rate,
+			Timeout:           timeout,
+			HalfOpenRequests:  halfOpenRequests,
+			IdleTTL:           idleTTL,
+		},
+	}, nil
+}
+
 func disableFilter(args []interface{}) (filters.Filter, error) {
 	if len(args) != 0 {
 		return nil, filters.ErrInvalidFilterParameters
@@ -208,6 +285,8 @@ func (s *spec) CreateFilter(args []interface{}) (filters.Filter, error) {
 		return consecutiveFilter(args)
 	case circuit.FailureRate:
 		return rateFilter(args)
+	case circuit.SlowCalls:
+		return slowCallFilter(args)
 	default:
 		return disableFilter(args)
 	}
@@ -53,6 +53,20 @@ func TestArgs(t *testing.T) {
 		t.Run("with idle ttl", testOK(s, 30, 300, 60000, 12, "30m"))
 	})
 
+	t.Run("slow calls", func(t *testing.T) {
+		s := NewSlowCallBreaker()
+		t.Run("missing all", testErr(s, nil))
+		t.Run("missing window", testErr(s, "300ms", 50))
+		t.Run("too many", testErr(s, "300ms", 50, 100, "1m", 45, "30m", 42))
+		t.Run("wrong threshold", testErr(s, "foo", 50, 100))
+		t.Run("wrong rate", testErr(s, "300ms", "50", 100))
+		t.Run("rate out of range", testErr(s, "300ms", 150, 100))
+		t.Run("wrong window", testErr(s, "300ms", 50, "100"))
+		t.Run("only threshold, rate and window", testOK(s, "300ms", 50, 100))
+		t.Run("threshold as milliseconds", testOK(s, 300, 50, 100))
+		t.Run("full", testOK(s, "300ms", 50, 100, "1m", 45, "30m"))
+	})
+
 	t.Run("disable", func(t *testing.T) {
 		s := NewDisableBreaker()
 		t.Run("with args fail", testErr(s, 6))
@@ -121,6 +135,23 @@ func TestBreaker(t *testing.T) {
 		12,
 	))
 
+	t.Run("slow call breaker", test(
+		NewSlowCallBreaker,
+		circuit.BreakerSettings{
+			Type:              circuit.SlowCalls,
+			Window:            100,
+			SlowCallThreshold: 300 * time.Millisecond,
+			SlowCallRate:      50,
+			Timeout:           time.Minute,
+			HalfOpenRequests:  12,
+		},
+		"300ms",
+		50,
+		100,
+		"1m",
+		12,
+	))
+
 	t.Run("disable breaker", test(
 		NewDisableBreaker,
 		circuit.BreakerSettings{
@@ -1,5 +1,6 @@
 /*
-Package cors implements the origin header for CORS.
+Package cors implements the origin header for CORS, and a complete CORS
+policy filter, including the handling of the preflight requests.
 
 How It Works
 
@@ -12,5 +13,12 @@ Usage
 	corsOrigin()
 	corsOrigin("https://www.example.org")
 	corsOrigin("https://www.example.org", "http://localhost:9001")
+
+The cors filter validates the origin, the method and the headers of the
+cross-origin requests, responds to the preflight requests, and rejects
+the requests that are not allowed:
+
+	cors("origin", "https://www.example.org", "origin", "https://*.example.org")
+	cors("originRegexp", "^https://[a-z]+[.]example[.]org$", "methods", "GET,PUT", "credentials", "true")
 */
 package cors
@@ -0,0 +1,385 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const (
+	allowCredentialsHeader = "Access-Control-Allow-Credentials"
+	allowMethodsHeader     = "Access-Control-Allow-Methods"
+	allowHeadersHeader     = "Access-Control-Allow-Headers"
+	exposeHeadersHeader    = "Access-Control-Expose-Headers"
+	maxAgeHeader           = "Access-Control-Max-Age"
+	requestMethodHeader    = "Access-Control-Request-Method"
+	requestHeadersHeader   = "Access-Control-Request-Headers"
+)
+
+const (
+	originOption        = "origin"
+	originRegexpOption  = "originRegexp"
+	methodsOption       = "methods"
+	headersOption       = "headers"
+	exposeHeadersOption = "exposeHeaders"
+	credentialsOption   = "credentials"
+	maxAgeOption        = "maxAge"
+)
+
+var defaultMethods = []string{"GET", "HEAD", "POST"}
+
+type (
+	policySpec struct{}
+
+	// wildcardOrigin matches the subdomains of a domain, e.g.
+	// https://*.example.org.
+	wildcardOrigin struct {
+		scheme string
+		suffix string
+		port   string
+	}
+
+	policy struct {
+		anyOrigin     bool
+		origins       map[string]bool
+		wildcards     []wildcardOrigin
+		regexps       []*regexp.Regexp
+		methods       []string
+		anyHeader     bool
+		headers       map[string]bool
+		allowHeaders  string
+		exposeHeaders string
+		credentials   bool
+		maxAge        string
+		allowMethods  string
+	}
+)
+
+// NewCors creates the filter specification of the cors filter. The
+// filter implements a complete CORS policy: it responds to the
+// preflight requests, sets the CORS headers on the responses of the
+// allowed cross-origin requests, and rejects the cross-origin requests
+// from origins that are not allowed with 403.
+//
+// The arguments are key-value pairs, the origin and originRegexp keys
+// can be repeated. Allowing any origin with "*" cannot be combined with
+// credentials:
+//
+//	cors("origin", "https://www.example.org", "origin", "https://*.example.org")
+//	cors("originRegexp", "^https://[a-z]+[.]example[.]org$", "methods", "GET,PUT", "credentials", "true")
+//	cors("origin", "*", "headers", "Content-Type,X-Request-Id", "exposeHeaders", "X-Total", "maxAge", "10m")
+func NewCors() filters.Spec { return policySpec{} }
+
+func (policySpec) Name() string { return filters.CorsName }
+
+func parseList(s string, valid func(string) bool) ([]string, error) {
+	var l []string
+	for _, i := range strings.Split(s, ",") {
+		i = strings.TrimSpace(i)
+		if i == "" {
+			continue
+		}
+
+		if !valid(i) {
+			return nil, fmt.Errorf("invalid list item: %s", i)
+		}
+
+		l = append(l, i)
+	}
+
+	return l, nil
+}
+
+func parseWildcardOrigin(o string) (wildcardOrigin, error) {
+	u, err := url.Parse(strings.Replace(o, "*.", "wildcard.", 1))
+	if err != nil || u.Scheme == "" || u.Path != "" || !strings.HasPrefix(u.Host, "wildcard.") {
+		return wildcardOrigin{}, fmt.Errorf("invalid wildcard origin: %s", o)
+	}
+
+	return wildcardOrigin{
+		scheme: u.Scheme,
+		suffix: strings.TrimPrefix(u.Hostname(), "wildcard"),
+		port:   u.Port(),
+	}, nil
+}
+
+func (w wildcardOrigin) match(u *url.URL) bool {
+	host := u.Hostname()
+	return u.Scheme == w.scheme &&
+		u.Port() == w.port &&
+		len(host) > len(w.suffix) &&
+		strings.HasSuffix(host, w.suffix)
+}
+
+func parseMaxAge(s string) (string, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return s, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return "", fmt.Errorf("invalid max age: %s", s)
+	}
+
+	return strconv.Itoa(int(d / time.Second)), nil
+}
+
+func (policySpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args)%2 != 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	p := &policy{
+		origins: make(map[string]bool),
+		headers: make(map[string]bool),
+		methods: defaultMethods,
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		value, ok := args[i+1].(string)
+		if !ok {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		var err error
+		switch key {
+		case originOption:
+			switch {
+			case value == "*":
+				p.anyOrigin = true
+			case strings.Contains(value, "*"):
+				var w wildcardOrigin
+				w, err = parseWildcardOrigin(value)
+				p.wildcards = append(p.wildcards, w)
+			default:
+				p.origins[strings.ToLower(value)] = true
+			}
+		case originRegexpOption:
+			var rx *regexp.Regexp
+			rx, err = regexp.Compile(value)
+			p.regexps = append(p.regexps, rx)
+		case methodsOption:
+			p.methods, err = parseList(value, httpguts.ValidHeaderFieldName)
+			for i := range p.methods {
+				p.methods[i] = strings.ToUpper(p.methods[i])
+			}
+		case headersOption:
+			var headers []string
+			headers, err = parseList(value, func(h string) bool { return h == "*" || httpguts.ValidHeaderFieldName(h) })
+			for _, h := range headers {
+				if h == "*" {
+					p.anyHeader = true
+				} else {
+					p.headers[strings.ToLower(h)] = true
+				}
+			}
+
+			p.allowHeaders = strings.Join(headers, ", ")
+		case exposeHeadersOption:
+			var headers []string
+			headers, err = parseList(value, httpguts.ValidHeaderFieldName)
+			p.exposeHeaders = strings.Join(headers, ", ")
+		case credentialsOption:
+			p.credentials, err = strconv.ParseBool(value)
+		case maxAgeOption:
+			p.maxAge, err = parseMaxAge(value)
+		default:
+			err = fmt.Errorf("unknown option: %s", key)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filters.CorsName, err)
+		}
+	}
+
+	if len(p.methods) == 0 {
+		return nil, fmt.Errorf("%s: no allowed methods", filters.CorsName)
+	}
+
+	// the CORS protocol doesn't allow credentialed requests from any
+	// origin, and reflecting the origin instead of * would expose the
+	// credentialed responses to every website
+	if p.anyOrigin && p.credentials {
+		return nil, fmt.Errorf("%s: any origin is not allowed with credentials", filters.CorsName)
+	}
+
+	p.allowMethods = strings.Join(p.methods, ", ")
+	return p, nil
+}
+
+func (p *policy) originAllowed(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+
+	if p.origins[strings.ToLower(origin)] {
+		return true
+	}
+
+	if len(p.wildcards) > 0 {
+		if u, err := url.Parse(strings.ToLower(origin)); err == nil {
+			for _, w := range p.wildcards {
+				if w.match(u) {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, rx := range p.regexps {
+		if rx.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// crossOrigin tells whether the request is a cross-origin request.
+// Browsers send the Origin header with some same-origin requests, too.
+func crossOrigin(r *http.Request) (string, bool) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return "", false
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return origin, false
+	}
+
+	return origin, true
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get(requestMethodHeader) != ""
+}
+
+func (p *policy) methodAllowed(m string) bool {
+	for _, am := range p.methods {
+		if am == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowedRequestHeaders returns the value of the allow headers response
+// header, or false if some of the requested headers are not allowed.
+func (p *policy) allowedRequestHeaders(requested string) (string, bool) {
+	if requested == "" {
+		return p.allowHeaders, true
+	}
+
+	if p.anyHeader {
+		// the wildcard is not valid for credentialed requests, the
+		// requested headers need to be listed explicitly
+		if p.credentials {
+			return requested, true
+		}
+
+		return p.allowHeaders, true
+	}
+
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" && !p.headers[h] {
+			return "", false
+		}
+	}
+
+	return p.allowHeaders, true
+}
+
+func (p *policy) setOriginHeaders(h http.Header, origin string) {
+	if p.anyOrigin {
+		h.Set(allowOriginHeader, "*")
+	} else {
+		h.Set(allowOriginHeader, origin)
+		h.Add("Vary", "Origin")
+	}
+
+	if p.credentials {
+		h.Set(allowCredentialsHeader, "true")
+	}
+}
+
+func forbidden(ctx filters.FilterContext) {
+	ctx.Serve(&http.Response{
+		StatusCode: http.StatusForbidden,
+		Header:     http.Header{"Vary": []string{"Origin"}},
+	})
+}
+
+func (p *policy) Request(ctx filters.FilterContext) {
+	r := ctx.Request()
+	origin, cross := crossOrigin(r)
+	if !cross {
+		return
+	}
+
+	if !p.originAllowed(origin) {
+		forbidden(ctx)
+		return
+	}
+
+	if !isPreflight(r) {
+		return
+	}
+
+	if !p.methodAllowed(r.Header.Get(requestMethodHeader)) {
+		forbidden(ctx)
+		return
+	}
+
+	allowHeaders, ok := p.allowedRequestHeaders(r.Header.Get(requestHeadersHeader))
+	if !ok {
+		forbidden(ctx)
+		return
+	}
+
+	h := make(http.Header)
+	p.setOriginHeaders(h, origin)
+	h.Set(allowMethodsHeader, p.allowMethods)
+	if allowHeaders != "" {
+		h.Set(allowHeadersHeader, allowHeaders)
+	}
+
+	if p.maxAge != "" {
+		h.Set(maxAgeHeader, p.maxAge)
+	}
+
+	h.Add("Vary", requestMethodHeader)
+	h.Add("Vary", requestHeadersHeader)
+	ctx.Serve(&http.Response{StatusCode: http.StatusNoContent, Header: h})
+}
+
+func (p *policy) Response(ctx filters.FilterContext) {
+	h := ctx.Response().Header
+	origin, cross := crossOrigin(ctx.Request())
+	if !cross || !p.originAllowed(origin) {
+		if !p.anyOrigin {
+			h.Add("Vary", "Origin")
+		}
+
+		return
+	}
+
+	p.setOriginHeaders(h, origin)
+	if p.exposeHeaders != "" {
+		h.Set(exposeHeadersHeader, p.exposeHeaders)
+	}
+}
@@ -0,0 +1,221 @@
+package cors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+func createPolicy(t *testing.T, args ...interface{}) filters.Filter {
+	f, err := NewCors().CreateFilter(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func corsRequest(t *testing.T, f filters.Filter, method, origin string, header http.Header) *filtertest.Context {
+	req, err := http.NewRequest(method, "https://api.example.org/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+
+	ctx := &filtertest.Context{FRequest: req, FStateBag: make(map[string]interface{})}
+	f.Request(ctx)
+	if !ctx.FServed {
+		ctx.FResponse = &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+		f.Response(ctx)
+	}
+
+	return ctx
+}
+
+func preflight(method, headers string) http.Header {
+	h := http.Header{requestMethodHeader: []string{method}}
+	if headers != "" {
+		h.Set(requestHeadersHeader, headers)
+	}
+
+	return h
+}
+
+func TestCorsOrigins(t *testing.T) {
+	f := createPolicy(t,
+		"origin", "https://www.example.org",
+		"origin", "https://*.example.com",
+		"originRegexp", `^https://[a-z]+\.example\.net$`,
+	)
+
+	for _, tc := range []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://www.example.org", true},
+		{"HTTPS://WWW.EXAMPLE.ORG", true},
+		{"http://www.example.org", false},
+		{"https://foo.example.com", true},
+		{"https://foo.bar.example.com", true},
+		{"https://example.com", false},
+		{"https://foo.example.com:8443", false},
+		{"https://foo.example.net", true},
+		{"https://foo1.example.net", false},
+		{"https://evil.org", false},
+	} {
+		ctx := corsRequest(t, f, "GET", tc.origin, nil)
+		if tc.allowed {
+			if ctx.FServed {
+				t.Errorf("%s: expected allowed", tc.origin)
+				continue
+			}
+
+			if o := ctx.FResponse.Header.Get(allowOriginHeader); o != tc.origin {
+				t.Errorf("%s: unexpected allow origin header: %s", tc.origin, o)
+			}
+
+			if v := ctx.FResponse.Header.Get("Vary"); v != "Origin" {
+				t.Errorf("%s: unexpected vary header: %s", tc.origin, v)
+			}
+		} else if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: expected forbidden", tc.origin)
+		}
+	}
+}
+
+func TestCorsSameOrigin(t *testing.T) {
+	f := createPolicy(t, "origin", "https://www.example.org")
+
+	ctx := corsRequest(t, f, "POST", "https://api.example.org", nil)
+	if ctx.FServed {
+		t.Fatal("same-origin request rejected")
+	}
+
+	if o := ctx.FResponse.Header.Get(allowOriginHeader); o != "" {
+		t.Errorf("unexpected allow origin header: %s", o)
+	}
+
+	if v := ctx.FResponse.Header.Get("Vary"); v != "Origin" {
+		t.Errorf("unexpected vary header: %s", v)
+	}
+
+	if ctx := corsRequest(t, f, "GET", "", nil); ctx.FServed {
+		t.Error("request without origin rejected")
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	f := createPolicy(t,
+		"origin", "https://www.example.org",
+		"methods", "GET,put,DELETE",
+		"headers", "Content-Type, X-Request-Id",
+		"exposeHeaders", "X-Total",
+		"credentials", "true",
+		"maxAge", "10m",
+	)
+
+	ctx := corsRequest(t, f, "OPTIONS", "https://www.example.org", preflight("PUT", "content-type,x-request-id"))
+	if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusNoContent {
+		t.Fatal("expected preflight response")
+	}
+
+	for k, v := range map[string]string{
+		allowOriginHeader:      "https://www.example.org",
+		allowMethodsHeader:     "GET, PUT, DELETE",
+		allowHeadersHeader:     "Content-Type, X-Request-Id",
+		allowCredentialsHeader: "true",
+		maxAgeHeader:           "600",
+	} {
+		if h := ctx.FResponse.Header.Get(k); h != v {
+			t.Errorf("%s: expected %q, got %q", k, v, h)
+		}
+	}
+
+	if v := ctx.FResponse.Header.Values("Vary"); len(v) != 3 {
+		t.Errorf("unexpected vary headers: %v", v)
+	}
+
+	for _, h := range []http.Header{
+		preflight("PATCH", ""),
+		preflight("GET", "X-Custom"),
+	} {
+		if ctx := corsRequest(t, f, "OPTIONS", "https://www.example.org", h); !ctx.FServed || ctx.FResponse.StatusCode != http.StatusForbidden {
+			t.Errorf("%v: expected forbidden", h)
+		}
+	}
+
+	ctx = corsRequest(t, f, "OPTIONS", "https://www.example.org", nil)
+	if ctx.FServed {
+		t.Error("plain OPTIONS request answered as preflight")
+	}
+
+	ctx = corsRequest(t, f, "GET", "https://www.example.org", nil)
+	if ctx.FServed {
+		t.Fatal("expected allowed")
+	}
+
+	if h := ctx.FResponse.Header.Get(exposeHeadersHeader); h != "X-Total" {
+		t.Errorf("unexpected expose headers: %s", h)
+	}
+
+	if h := ctx.FResponse.Header.Get(allowCredentialsHeader); h != "true" {
+		t.Errorf("unexpected allow credentials: %s", h)
+	}
+}
+
+func TestCorsAnyOrigin(t *testing.T) {
+	f := createPolicy(t, "origin", "*", "headers", "*")
+
+	ctx := corsRequest(t, f, "OPTIONS", "https://www.example.org", preflight("POST", "X-Anything"))
+	if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusNoContent {
+		t.Fatal("expected preflight response")
+	}
+
+	if h := ctx.FResponse.Header.Get(allowOriginHeader); h != "*" {
+		t.Errorf("unexpected allow origin: %s", h)
+	}
+
+	if h := ctx.FResponse.Header.Get(allowHeadersHeader); h != "*" {
+		t.Errorf("unexpected allow headers: %s", h)
+	}
+
+	f = createPolicy(t, "origin", "https://www.example.org", "headers", "*", "credentials", "true")
+	ctx = corsRequest(t, f, "OPTIONS", "https://www.example.org", preflight("POST", "X-Anything"))
+	if h := ctx.FResponse.Header.Get(allowOriginHeader); h != "https://www.example.org" {
+		t.Errorf("unexpected allow origin with credentials: %s", h)
+	}
+
+	if h := ctx.FResponse.Header.Get(allowHeadersHeader); h != "X-Anything" {
+		t.Errorf("unexpected allow headers with credentials: %s", h)
+	}
+}
+
+func TestCorsInvalidArgs(t *testing.T) {
+	for _, args := range [][]interface{}{
+		{"origin"},
+		{"origin", 42},
+		{"unknown", "value"},
+		{"origin", "https://*"},
+		{"origin", "*.example.org"},
+		{"originRegexp", "("},
+		{"methods", ""},
+		{"headers", "Bad Header"},
+		{"credentials", "maybe"},
+		{"origin", "*", "credentials", "true"},
+		{"credentials", "true", "origin", "*"},
+		{"maxAge", "-1s"},
+	} {
+		if _, err := NewCors().CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
@@ -27,6 +27,16 @@ const (
 
 	// BackendRatelimit is the key used in the state bag to configure backend ratelimit in proxy
 	BackendRatelimit = "backend:ratelimit"
+
+	// BackendTLS is the key used in the state bag to configure the backend TLS connections in proxy
+	BackendTLS = "backend:tls"
+
+	// BackendHedging is the key used in the state bag to configure the hedged backend requests in proxy
+	BackendHedging = "backend:hedging"
+
+	// ClientIDKey is the key used in the state bag to store the identity of the authenticated client,
+	// e.g. by the apiKey filter
+	ClientIDKey = "auth:clientid"
 )
 
 // Context object providing state and information that is unique to a request.
@@ -235,6 +245,8 @@ const (
 	SedRequestDelimName                        = "sedRequestDelim"
 	BasicAuthName                              = "basicAuth"
 	WebhookName                                = "webhook"
+	ExternalAuthzName                          = "externalAuthz"
+	OpaAuthorizeRequestName                    = "opaAuthorizeRequest"
 	OAuthTokeninfoAnyScopeName                 = "oauthTokeninfoAnyScope"
 	OAuthTokeninfoAllScopeName                 = "oauthTokeninfoAllScope"
 	OAuthTokeninfoAnyKVName                    = "oauthTokeninfoAnyKV"
@@ -249,6 +261,9 @@ const (
 	SecureOAuthTokenintrospectionAllKVName     = "secureOauthTokenintrospectionAllKV"
 	ForwardTokenName                           = "forwardToken"
 	ForwardTokenFieldName                      = "forwardTokenField"
+	SignJwtName                                = "signJwt"
+	TokenExchangeName                          = "tokenExchange"
+	ApiKeyName                                 = "apiKey"
 	OAuthGrantName                             = "oauthGrant"
 	GrantCallbackName                          = "grantCallback"
 	GrantLogoutName                            = "grantLogout"
@@ -263,19 +278,24 @@ const (
 	JsCookieName                               = "jsCookie"
 	ConsecutiveBreakerName                     = "consecutiveBreaker"
 	RateBreakerName                            = "rateBreaker"
+	SlowCallBreakerName                        = "slowCallBreaker"
 	DisableBreakerName                         = "disableBreaker"
 	ClientRatelimitName                        = "clientRatelimit"
 	RatelimitName                              = "ratelimit"
 	ClusterClientRatelimitName                 = "clusterClientRatelimit"
 	ClusterRatelimitName                       = "clusterRatelimit"
 	ClusterLeakyBucketRatelimitName            = "clusterLeakyBucketRatelimit"
+	ClusterClientQuotaName                     = "clusterClientQuota"
 	BackendRateLimitName                       = "backendRatelimit"
 	LuaName                                    = "lua"
 	CorsOriginName                             = "corsOrigin"
+	CorsName                                   = "cors"
 	HeaderToQueryName                          = "headerToQuery"
 	QueryToHeaderName                          = "queryToHeader"
 	DisableAccessLogName                       = "disableAccessLog"
 	EnableAccessLogName                        = "enableAccessLog"
+	AccessLogFormatName                        = "accessLogFormat"
+	SampleAccessLogName                        = "sampleAccessLog"
 	AuditLogName                               = "auditLog"
 	UnverifiedAuditLogName                     = "unverifiedAuditLog"
 	SetDynamicBackendHostFromHeader            = "setDynamicBackendHostFromHeader"
@@ -287,6 +307,9 @@ const (
 	ApiUsageMonitoringName                     = "apiUsageMonitoring"
 	LifoName                                   = "lifo"
 	LifoGroupName                              = "lifoGroup"
+	PriorityClassName                          = "priorityClass"
+	PriorityClassFromHeaderName                = "priorityClassFromHeader"
+	PriorityClassFromClaimName                 = "priorityClassFromClaim"
 	RfcPathName                                = "rfcPath"
 	RfcHostName                                = "rfcHost"
 	BearerInjectorName                         = "bearerinjector"
@@ -299,6 +322,9 @@ const (
 	EndpointCreatedName                        = "endpointCreated"
 	ConsistentHashKeyName                      = "consistentHashKey"
 	ConsistentHashBalanceFactorName            = "consistentHashBalanceFactor"
+	ForwardClientCertName                      = "forwardClientCert"
+	BackendTLSName                             = "backendTLS"
+	HedgeName                                  = "hedge"
 
 	// Undocumented filters
 	HealthCheckName        = "healthcheck"
@@ -0,0 +1,196 @@
+/*
+Package hedge provides the hedge filter, that enables hedged backend requests for the routes with load balanced
+backends.
+
+When a route has the hedge filter, and the backend of the route doesn't respond to an idempotent request within
+the configured delay, the proxy sends the same request to a different endpoint of the backend, and uses the
+response that arrives first. The remaining requests are canceled. The number of the additional requests is
+limited by the second argument of the filter:
+
+	hedge("50ms", 1)
+
+The delay can be also derived from the observed latency of the route, as a percentile of the last backend
+responses. In this case, no hedged requests are sent until enough responses were observed:
+
+	hedge("p95", 2)
+
+Only the requests with the methods GET, HEAD, OPTIONS and TRACE, and without a request body, are hedged. The
+sent and the won hedged requests are counted in the hedge.sent.<route> and hedge.won.<route> metrics.
+*/
+package hedge
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const (
+	// the number of the observed latencies used for the percentile delay
+	latencyWindow = 1024
+
+	// the minimum number of the observed latencies before using the percentile delay
+	minObservations = 32
+
+	// the percentile delay is recalculated after this number of observations
+	recalculateAfter = 64
+)
+
+// Hedge is stored in the state bag by the hedge filter, with the filters.BackendHedging key, and it instructs
+// the proxy to send hedged requests to the load balanced backend of the route.
+type Hedge struct {
+
+	// Delay is the fixed delay after which a hedged request is sent. It is zero when the delay is derived from
+	// the observed latency.
+	Delay time.Duration
+
+	// Percentile of the observed latencies used as the delay, when set.
+	Percentile float64
+
+	// MaxHedges is the maximum number of the hedged requests sent in addition to the original request.
+	MaxHedges int
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+	count     int
+	current   time.Duration
+}
+
+type spec struct{}
+
+type filter struct {
+	hedge *Hedge
+}
+
+// NewHedge creates the filter specification of the hedge filter. The filter expects two arguments: the delay
+// after which a hedged request is sent, and the maximum number of the hedged requests. The delay can be a
+// duration string, milliseconds, or a percentile of the observed latency, e.g. "p95".
+func NewHedge() filters.Spec { return spec{} }
+
+func (spec) Name() string { return filters.HedgeName }
+
+func parseDelay(a interface{}) (time.Duration, float64, error) {
+	switch v := a.(type) {
+	case string:
+		if strings.HasPrefix(v, "p") {
+			p, err := strconv.ParseFloat(v[1:], 64)
+			if err != nil || p <= 0 || p >= 100 {
+				return 0, 0, filters.ErrInvalidFilterParameters
+			}
+
+			return 0, p, nil
+		}
+
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, 0, filters.ErrInvalidFilterParameters
+		}
+
+		return d, 0, nil
+	case float64:
+		if v <= 0 {
+			return 0, 0, filters.ErrInvalidFilterParameters
+		}
+
+		return time.Duration(v * float64(time.Millisecond)), 0, nil
+	case int:
+		if v <= 0 {
+			return 0, 0, filters.ErrInvalidFilterParameters
+		}
+
+		return time.Duration(v) * time.Millisecond, 0, nil
+	default:
+		return 0, 0, filters.ErrInvalidFilterParameters
+	}
+}
+
+func (spec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) != 2 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	delay, percentile, err := parseDelay(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var maxHedges int
+	switch v := args[1].(type) {
+	case float64:
+		maxHedges = int(v)
+	case int:
+		maxHedges = v
+	default:
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	if maxHedges < 1 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return &filter{hedge: &Hedge{Delay: delay, Percentile: percentile, MaxHedges: maxHedges}}, nil
+}
+
+func (f *filter) Request(ctx filters.FilterContext) {
+	ctx.StateBag()[filters.BackendHedging] = f.hedge
+}
+
+func (*filter) Response(filters.FilterContext) {}
+
+// HedgeDelay returns the delay after which a hedged request should be sent. When the delay is derived from the
+// observed latency, and not enough responses were observed yet, it returns false.
+func (h *Hedge) HedgeDelay() (time.Duration, bool) {
+	if h.Percentile == 0 {
+		return h.Delay, true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.current, h.current > 0
+}
+
+// Observe records the latency of a backend response, used to derive the delay from a percentile. It has no
+// effect when the delay is fixed.
+func (h *Hedge) Observe(d time.Duration) {
+	if h.Percentile == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < latencyWindow {
+		h.latencies = append(h.latencies, d)
+	} else {
+		h.latencies[h.next] = d
+		h.next = (h.next + 1) % latencyWindow
+	}
+
+	h.count++
+	if len(h.latencies) < minObservations {
+		return
+	}
+
+	if h.current == 0 || h.count >= recalculateAfter {
+		h.count = 0
+		h.current = percentile(h.latencies, h.Percentile)
+	}
+}
+
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	i := int(float64(len(sorted))*p/100+.5) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
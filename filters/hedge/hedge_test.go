@@ -0,0 +1,142 @@
+package hedge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+func TestCreateFilter(t *testing.T) {
+	for _, tt := range []struct {
+		title      string
+		args       []interface{}
+		err        bool
+		delay      time.Duration
+		percentile float64
+		maxHedges  int
+	}{{
+		title: "no args",
+		err:   true,
+	}, {
+		title: "missing max hedges",
+		args:  []interface{}{"50ms"},
+		err:   true,
+	}, {
+		title: "too many args",
+		args:  []interface{}{"50ms", 1, 2},
+		err:   true,
+	}, {
+		title: "invalid delay",
+		args:  []interface{}{"foo", 1},
+		err:   true,
+	}, {
+		title: "negative delay",
+		args:  []interface{}{-50.0, 1},
+		err:   true,
+	}, {
+		title: "invalid percentile",
+		args:  []interface{}{"p100", 1},
+		err:   true,
+	}, {
+		title: "invalid max hedges",
+		args:  []interface{}{"50ms", "1"},
+		err:   true,
+	}, {
+		title: "zero max hedges",
+		args:  []interface{}{"50ms", 0.0},
+		err:   true,
+	}, {
+		title:     "duration string",
+		args:      []interface{}{"50ms", 2.0},
+		delay:     50 * time.Millisecond,
+		maxHedges: 2,
+	}, {
+		title:     "milliseconds",
+		args:      []interface{}{50.0, 1.0},
+		delay:     50 * time.Millisecond,
+		maxHedges: 1,
+	}, {
+		title:      "percentile",
+		args:       []interface{}{"p99.9", 1},
+		percentile: 99.9,
+		maxHedges:  1,
+	}} {
+		t.Run(tt.title, func(t *testing.T) {
+			f, err := NewHedge().CreateFilter(tt.args)
+			if tt.err {
+				if err == nil {
+					t.Fatal("failed to fail")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := &filtertest.Context{FStateBag: make(map[string]interface{})}
+			f.Request(ctx)
+			h, ok := ctx.StateBag()[filters.BackendHedging].(*Hedge)
+			if !ok {
+				t.Fatal("failed to set the hedge settings")
+			}
+
+			if h.Delay != tt.delay || h.Percentile != tt.percentile || h.MaxHedges != tt.maxHedges {
+				t.Errorf("invalid settings: %v, %v, %d", h.Delay, h.Percentile, h.MaxHedges)
+			}
+		})
+	}
+}
+
+func TestHedgeDelay(t *testing.T) {
+	t.Run("fixed", func(t *testing.T) {
+		h := &Hedge{Delay: 50 * time.Millisecond, MaxHedges: 1}
+		h.Observe(time.Second)
+		if d, ok := h.HedgeDelay(); !ok || d != 50*time.Millisecond {
+			t.Errorf("invalid delay: %v", d)
+		}
+	})
+
+	t.Run("percentile", func(t *testing.T) {
+		h := &Hedge{Percentile: 90, MaxHedges: 1}
+		for i := 1; i < minObservations; i++ {
+			h.Observe(time.Duration(i) * time.Millisecond)
+		}
+
+		if _, ok := h.HedgeDelay(); ok {
+			t.Fatal("unexpected delay before enough observations")
+		}
+
+		h.Observe(minObservations * time.Millisecond)
+		if d, ok := h.HedgeDelay(); !ok || d != 29*time.Millisecond {
+			t.Errorf("invalid delay: %v", d)
+		}
+
+		var latencies []time.Duration
+		for i := 100; i > 0; i-- {
+			latencies = append(latencies, time.Duration(i)*time.Millisecond)
+		}
+
+		if d := percentile(latencies, 90); d != 90*time.Millisecond {
+			t.Errorf("invalid percentile: %v", d)
+		}
+	})
+
+	t.Run("sliding window", func(t *testing.T) {
+		h := &Hedge{Percentile: 50, MaxHedges: 1}
+		for i := 0; i < latencyWindow; i++ {
+			h.Observe(time.Second)
+		}
+
+		for i := 0; i < latencyWindow; i++ {
+			h.Observe(time.Millisecond)
+		}
+
+		if d, ok := h.HedgeDelay(); !ok || d != time.Millisecond {
+			t.Errorf("invalid delay: %v", d)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package openpolicyagent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+const (
+	manifestFile = ".manifest"
+	dataFile     = "data.json"
+	yamlDataFile = "data.yaml"
+	regoExt      = ".rego"
+
+	maxBundleSize = 64 << 20
+)
+
+// Bundle is a policy bundle compiled by OPA, with its base data
+// documents.
+type Bundle struct {
+	Name     string
+	Revision string
+
+	compiler *ast.Compiler
+	store    storage.Store
+	digest   string
+
+	mu      sync.Mutex
+	queries map[string]rego.PreparedEvalQuery
+}
+
+// bundleFiles contains the relevant files of a bundle, indexed by their
+// slash separated path relative to the bundle root.
+type bundleFiles map[string][]byte
+
+func (bf bundleFiles) add(name string, content []byte) {
+	name = path.Clean("/" + filepath.ToSlash(name))[1:]
+	base := path.Base(name)
+	if base == manifestFile || base == dataFile || base == yamlDataFile || strings.HasSuffix(base, regoExt) {
+		bf[name] = content
+	}
+}
+
+func (bf bundleFiles) digest() string {
+	names := make([]string, 0, len(bf))
+	for n := range bf {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+	h := sha256.New()
+	for _, n := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00", n, len(bf[n]))
+		h.Write(bf[n])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func readBundleDir(dir string) (bundleFiles, error) {
+	bf := make(bundleFiles)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		bf.add(rel, content)
+		return nil
+	})
+
+	return bf, err
+}
+
+func readBundleArchive(r io.Reader) (bundleFiles, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	defer gz.Close()
+
+	bf := make(bundleFiles)
+	tr := tar.NewReader(gz)
+	var size int64
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return bf, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		size += h.Size
+		if size > maxBundleSize {
+			return nil, fmt.Errorf("bundle exceeds the maximum size of %d bytes", maxBundleSize)
+		}
+
+		var b bytes.Buffer
+		if _, err := io.Copy(&b, tr); err != nil {
+			return nil, err
+		}
+
+		bf.add(h.Name, b.Bytes())
+	}
+}
+
+// archive writes the files into a gzipped tar archive, the format read
+// by the OPA bundle reader.
+func (bf bundleFiles) archive() (io.Reader, error) {
+	names := make([]string, 0, len(bf))
+	for n := range bf {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, n := range names {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     "/" + n,
+			Mode:     0644,
+			Size:     int64(len(bf[n])),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			return nil, err
+		}
+
+		if _, err := tw.Write(bf[n]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func compileBundle(name string, bf bundleFiles) (*Bundle, error) {
+	a, err := bf.archive()
+	if err != nil {
+		return nil, err
+	}
+
+	ob, err := bundle.NewCustomReader(bundle.NewTarballLoader(a)).Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %w", name, err)
+	}
+
+	if len(ob.Modules) == 0 {
+		return nil, fmt.Errorf("bundle %s contains no policies", name)
+	}
+
+	modules := make(map[string]*ast.Module, len(ob.Modules))
+	for _, m := range ob.Modules {
+		modules[m.Path] = m.Parsed
+	}
+
+	c := ast.NewCompiler()
+	if c.Compile(modules); c.Failed() {
+		return nil, fmt.Errorf("failed to compile bundle %s: %w", name, c.Errors)
+	}
+
+	return &Bundle{
+		Name:     name,
+		Revision: ob.Manifest.Revision,
+		compiler: c,
+		store:    inmem.NewFromObject(ob.Data),
+		digest:   bf.digest(),
+		queries:  make(map[string]rego.PreparedEvalQuery),
+	}, nil
+}
+
+// query returns the prepared query evaluating the decision at the slash
+// separated path, preparing it on the first use.
+func (b *Bundle) query(ctx context.Context, decisionPath string) (rego.PreparedEvalQuery, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q, ok := b.queries[decisionPath]; ok {
+		return q, nil
+	}
+
+	ref := ast.DefaultRootRef.Copy()
+	for _, s := range strings.Split(strings.Trim(decisionPath, "/"), "/") {
+		ref = append(ref, ast.StringTerm(s))
+	}
+
+	q, err := rego.New(
+		rego.Query(ref.String()),
+		rego.Compiler(b.compiler),
+		rego.Store(b.store),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, err
+	}
+
+	b.queries[decisionPath] = q
+	return q, nil
+}
+
+// Eval evaluates the decision at the slash separated path. When the
+// decision is undefined, it returns false.
+func (b *Bundle) Eval(ctx context.Context, decisionPath string, input interface{}) (interface{}, bool, error) {
+	q, err := b.query(ctx, decisionPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rs, err := q.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return nil, false, nil
+	}
+
+	return rs[0].Expressions[0].Value, true, nil
+}
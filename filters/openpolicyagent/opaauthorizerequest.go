@@ -0,0 +1,389 @@
+/*
+Package openpolicyagent implements the opaAuthorizeRequest filter, that
+authorizes the requests by evaluating policies written in Rego, the
+policy language of the Open Policy Agent, in process.
+
+The policies are loaded from bundles, directories or gzipped tar
+archives containing .rego files, data.json or data.yaml documents and
+an optional .manifest file. The bundles are reloaded periodically from
+a local directory or an HTTP bundle server, and they are compiled and
+evaluated by the OPA rego package.
+*/
+package openpolicyagent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/filters"
+	logfilter "github.com/zalando/skipper/filters/log"
+	"github.com/zalando/skipper/jwt"
+)
+
+const (
+	defaultDecisionPath = "envoy/authz/allow"
+
+	// the claims of the bearer token are passed to the policy without
+	// verifying the token
+	unverifiedClaimsKey = "unverified_jwt_claims"
+
+	responseHeadersKey = "filter." + filters.OpaAuthorizeRequestName + ".responseHeaders"
+)
+
+type (
+	spec struct {
+		registry *Registry
+	}
+
+	filter struct {
+		registry     *Registry
+		bundleName   string
+		decisionPath string
+		entry        *bundleEntry
+	}
+
+	// decision is the result of a policy evaluation. The policies can
+	// return either a boolean or an object with the fields of the
+	// decision.
+	decision struct {
+		Allowed         bool              `json:"allowed"`
+		Headers         map[string]string `json:"headers"`
+		ResponseHeaders map[string]string `json:"response_headers_to_add"`
+		HTTPStatus      int               `json:"http_status"`
+		Body            string            `json:"body"`
+	}
+
+	// DecisionLog is logged for every policy decision when the decision
+	// logs are enabled.
+	DecisionLog struct {
+		DecisionID string      `json:"decision_id"`
+		Bundle     string      `json:"bundle"`
+		Revision   string      `json:"revision,omitempty"`
+		Path       string      `json:"path"`
+		Input      interface{} `json:"input"`
+		Result     interface{} `json:"result"`
+		Error      string      `json:"error,omitempty"`
+		Timestamp  time.Time   `json:"timestamp"`
+		Duration   float64     `json:"duration_ms"`
+	}
+)
+
+// NewOpaAuthorizeRequestSpec creates the filter specification of the
+// opaAuthorizeRequest filter. The first argument of the filter is the
+// name of the policy bundle, the second, optional argument is the path
+// of the decision, defaulting to envoy/authz/allow:
+//
+//	opaAuthorizeRequest("my-app")
+//	opaAuthorizeRequest("my-app", "my/app/authz/decision")
+//
+// The policies receive an input document built from the request:
+//
+//	{
+//		"method": "GET",
+//		"path": "/orders/42",
+//		"parsed_path": ["orders", "42"],
+//		"parsed_query": {"filter": ["open"]},
+//		"host": "www.example.org",
+//		"remote_addr": "10.0.0.1:53478",
+//		"headers": {"authorization": "Bearer eyJ..."},
+//		"unverified_jwt_claims": {"sub": "alice"},
+//		"state_bag": {}
+//	}
+//
+// The unverified_jwt_claims field contains the claims of the bearer
+// token in the Authorization header. The token is not verified, its
+// signature needs to be checked by other filters or by the policy, as
+// the name of the field suggests. The state_bag
+// field contains the entries of the state bag with JSON compatible
+// values.
+//
+// The decision is either a boolean, or an object with the fields
+// allowed, headers (added to the request), response_headers_to_add,
+// http_status and body. Denied requests are responded with 403 by
+// default.
+func NewOpaAuthorizeRequestSpec(r *Registry) filters.Spec {
+	return &spec{registry: r}
+}
+
+func (*spec) Name() string { return filters.OpaAuthorizeRequestName }
+
+func (s *spec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	f := &filter{registry: s.registry, decisionPath: defaultDecisionPath}
+
+	var ok bool
+	if f.bundleName, ok = args[0].(string); !ok {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	if len(args) == 2 {
+		if f.decisionPath, ok = args[1].(string); !ok || f.decisionPath == "" {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+	}
+
+	e, err := s.registry.bundle(f.bundleName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to load bundle %s: %w", filters.OpaAuthorizeRequestName, f.bundleName, err)
+	}
+
+	// preparing the query reports the invalid decision paths early
+	if _, err := e.get().query(context.Background(), f.decisionPath); err != nil {
+		return nil, fmt.Errorf("%s: invalid decision path %s: %w", filters.OpaAuthorizeRequestName, f.decisionPath, err)
+	}
+
+	f.entry = e
+	return f, nil
+}
+
+// jsonValue tells whether the value can be passed to the policy.
+func jsonValue(v interface{}) bool {
+	switch vt := v.(type) {
+	case nil, bool, string, int, int64, float64:
+		return true
+	case []string, map[string]string:
+		return true
+	case []interface{}:
+		for _, e := range vt {
+			if !jsonValue(e) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]interface{}:
+		for _, e := range vt {
+			if !jsonValue(e) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
+func requestInput(ctx filters.FilterContext) map[string]interface{} {
+	req := ctx.Request()
+
+	var segments []interface{}
+	for _, s := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+
+	if segments == nil {
+		segments = []interface{}{}
+	}
+
+	query := make(map[string]interface{})
+	for k, v := range req.URL.Query() {
+		values := make([]interface{}, len(v))
+		for i, vi := range v {
+			values[i] = vi
+		}
+
+		query[k] = values
+	}
+
+	headers := make(map[string]interface{})
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ", ")
+	}
+
+	stateBag := make(map[string]interface{})
+	for k, v := range ctx.StateBag() {
+		if jsonValue(v) {
+			stateBag[k] = v
+		}
+	}
+
+	input := map[string]interface{}{
+		"method":       req.Method,
+		"path":         req.URL.Path,
+		"parsed_path":  segments,
+		"parsed_query": query,
+		"host":         req.Host,
+		"remote_addr":  req.RemoteAddr,
+		"headers":      headers,
+		"state_bag":    stateBag,
+	}
+
+	if ah := req.Header.Get("Authorization"); strings.HasPrefix(ah, "Bearer ") {
+		if token, err := jwt.Parse(ah[len("Bearer "):]); err == nil {
+			input[unverifiedClaimsKey] = token.Claims
+		}
+	}
+
+	return input
+}
+
+func parseDecision(result interface{}) (*decision, error) {
+	switch rt := result.(type) {
+	case bool:
+		return &decision{Allowed: rt}, nil
+	case map[string]interface{}:
+		b, err := json.Marshal(rt)
+		if err != nil {
+			return nil, err
+		}
+
+		var d decision
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, fmt.Errorf("invalid decision: %w", err)
+		}
+
+		return &d, nil
+	default:
+		return nil, fmt.Errorf("invalid decision type: %T", result)
+	}
+}
+
+func decisionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// redactedInput returns a copy of the input without the values of the
+// credential headers.
+func redactedInput(input map[string]interface{}) map[string]interface{} {
+	r := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		r[k] = v
+	}
+
+	headers := make(map[string]interface{})
+	for k, v := range input["headers"].(map[string]interface{}) {
+		if k == "authorization" || k == "cookie" || k == "proxy-authorization" {
+			v = "REDACTED"
+		}
+
+		headers[k] = v
+	}
+
+	r["headers"] = headers
+	return r
+}
+
+func (f *filter) logDecision(b *Bundle, input map[string]interface{}, result interface{}, err error, start time.Time) {
+	l := &DecisionLog{
+		DecisionID: decisionID(),
+		Bundle:     b.Name,
+		Revision:   b.Revision,
+		Path:       f.decisionPath,
+		Input:      redactedInput(input),
+		Result:     result,
+		Timestamp:  start,
+		Duration:   float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		l.Error = err.Error()
+	}
+
+	d, merr := json.Marshal(l)
+	if merr != nil {
+		log.Errorf("Failed to encode decision log: %v", merr)
+		return
+	}
+
+	log.Infof("Policy decision: %s", d)
+}
+
+func serveDenied(ctx filters.FilterContext, d *decision) {
+	status := http.StatusForbidden
+	if d.HTTPStatus >= 400 && d.HTTPStatus < 600 {
+		status = d.HTTPStatus
+	}
+
+	rsp := &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+	}
+
+	for k, v := range d.ResponseHeaders {
+		rsp.Header.Set(k, v)
+	}
+
+	if d.Body != "" {
+		rsp.Body = io.NopCloser(strings.NewReader(d.Body))
+		rsp.ContentLength = int64(len(d.Body))
+	}
+
+	ctx.Serve(rsp)
+}
+
+func (f *filter) Request(ctx filters.FilterContext) {
+	b := f.entry.get()
+	input := requestInput(ctx)
+
+	start := time.Now()
+	result, defined, err := b.Eval(ctx.Request().Context(), f.decisionPath, input)
+	if f.registry.options.DecisionLogs {
+		f.logDecision(b, input, result, err, start)
+	}
+
+	if err != nil {
+		log.Errorf("Failed to evaluate policy %s of bundle %s: %v", f.decisionPath, b.Name, err)
+		ctx.Serve(&http.Response{StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if !defined {
+		serveDenied(ctx, &decision{})
+		return
+	}
+
+	d, err := parseDecision(result)
+	if err != nil {
+		log.Errorf("Invalid result of policy %s of bundle %s: %v", f.decisionPath, b.Name, err)
+		ctx.Serve(&http.Response{StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if !d.Allowed {
+		serveDenied(ctx, d)
+		return
+	}
+
+	for k, v := range d.Headers {
+		ctx.Request().Header.Set(k, v)
+	}
+
+	if len(d.ResponseHeaders) > 0 {
+		ctx.StateBag()[responseHeadersKey] = d.ResponseHeaders
+	}
+
+	if claims, ok := input[unverifiedClaimsKey].(map[string]interface{}); ok {
+		if sub, ok := claims["sub"].(string); ok {
+			ctx.StateBag()[logfilter.AuthUserKey] = sub
+		}
+	}
+}
+
+func (*filter) Response(ctx filters.FilterContext) {
+	h, ok := ctx.StateBag()[responseHeadersKey].(map[string]string)
+	if !ok {
+		return
+	}
+
+	for k, v := range h {
+		ctx.Response().Header.Set(k, v)
+	}
+}
@@ -0,0 +1,391 @@
+package openpolicyagent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+const testPolicy = `
+package envoy.authz
+
+default allow = false
+
+allow {
+	input.method == "GET"
+	input.parsed_path[0] == "public"
+}
+
+allow {
+	input.unverified_jwt_claims.sub == data.admins[_]
+}
+
+decision = {"allowed": true, "headers": {"X-User": input.unverified_jwt_claims.sub}, "response_headers_to_add": {"X-Policy": "admin"}} {
+	allow
+	input.unverified_jwt_claims.sub
+}
+
+decision = {"allowed": false, "http_status": 401, "body": "unauthorized"} {
+	not input.unverified_jwt_claims
+}
+`
+
+func writeBundleDir(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func bundleArchive(t *testing.T, files map[string]string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     "/" + name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.Bytes()
+}
+
+func testToken(sub string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"none"}`)) + "." + enc([]byte(`{"sub":"`+sub+`"}`)) + ".sig"
+}
+
+func serve(t *testing.T, f filters.Filter, method, path, token string) *filtertest.Context {
+	req, err := http.NewRequest(method, "https://www.example.org"+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	ctx := &filtertest.Context{FRequest: req, FStateBag: make(map[string]interface{})}
+	f.Request(ctx)
+	if !ctx.FServed {
+		ctx.FResponse = &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+		f.Response(ctx)
+	}
+
+	return ctx
+}
+
+func TestAuthorizeRequest(t *testing.T) {
+	dir := t.TempDir()
+	writeBundleDir(t, filepath.Join(dir, "app"), map[string]string{
+		"policy.rego": testPolicy,
+		"data.json":   `{"admins": ["alice"]}`,
+		".manifest":   `{"revision": "1"}`,
+	})
+
+	r := NewRegistry(Options{BundleSource: dir, DecisionLogs: true})
+	defer r.Close()
+
+	spec := NewOpaAuthorizeRequestSpec(r)
+	f, err := spec.CreateFilter([]interface{}{"app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx := serve(t, f, "GET", "/public/index.html", ""); ctx.FServed {
+		t.Errorf("expected allowed, got: %d", ctx.FResponse.StatusCode)
+	}
+
+	if ctx := serve(t, f, "POST", "/orders", testToken("alice")); ctx.FServed {
+		t.Errorf("expected allowed, got: %d", ctx.FResponse.StatusCode)
+	}
+
+	if ctx := serve(t, f, "POST", "/orders", testToken("bob")); !ctx.FServed || ctx.FResponse.StatusCode != http.StatusForbidden {
+		t.Error("expected forbidden")
+	}
+
+	df, err := spec.CreateFilter([]interface{}{"app", "envoy/authz/decision"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := serve(t, df, "POST", "/orders", testToken("alice"))
+	if ctx.FServed {
+		t.Fatalf("expected allowed, got: %d", ctx.FResponse.StatusCode)
+	}
+
+	if h := ctx.FRequest.Header.Get("X-User"); h != "alice" {
+		t.Errorf("expected request header, got: %q", h)
+	}
+
+	if h := ctx.FResponse.Header.Get("X-Policy"); h != "admin" {
+		t.Errorf("expected response header, got: %q", h)
+	}
+
+	ctx = serve(t, df, "POST", "/orders", "")
+	if !ctx.FServed || ctx.FResponse.StatusCode != http.StatusUnauthorized {
+		t.Fatal("expected unauthorized")
+	}
+
+	if b, _ := io.ReadAll(ctx.FResponse.Body); string(b) != "unauthorized" {
+		t.Errorf("unexpected body: %s", b)
+	}
+
+	// undefined decision
+	if ctx := serve(t, df, "POST", "/orders", testToken("bob")); !ctx.FServed || ctx.FResponse.StatusCode != http.StatusForbidden {
+		t.Error("expected forbidden")
+	}
+}
+
+func TestOPACompatibility(t *testing.T) {
+	dir := t.TempDir()
+	writeBundleDir(t, filepath.Join(dir, "app"), map[string]string{
+		"policy.rego": `
+package envoy.authz
+
+import future.keywords.in
+
+default allow = false
+
+allow {
+	input.method in {"GET", "HEAD"}
+	startswith(input.path, "/public")
+}
+
+mocked {
+	allow with input as {"method": "GET", "path": "/public/index.html"}
+}
+
+sha = crypto.sha256("foo")
+`,
+	})
+
+	r := NewRegistry(Options{BundleSource: dir})
+	defer r.Close()
+
+	spec := NewOpaAuthorizeRequestSpec(r)
+	f, err := spec.CreateFilter([]interface{}{"app", "envoy/authz/mocked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the with keyword overrides the input of the POST request
+	if ctx := serve(t, f, "POST", "/orders", ""); ctx.FServed {
+		t.Errorf("expected allowed, got: %d", ctx.FResponse.StatusCode)
+	}
+
+	e, err := r.bundle("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, defined, err := e.get().Eval(context.Background(), "envoy/authz/sha", nil)
+	if err != nil || !defined || v != "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae" {
+		t.Errorf("unexpected result: %v, %v, %v", v, defined, err)
+	}
+}
+
+func TestCreateFilterErrors(t *testing.T) {
+	r := NewRegistry(Options{BundleSource: t.TempDir()})
+	defer r.Close()
+
+	spec := NewOpaAuthorizeRequestSpec(r)
+	for _, args := range [][]interface{}{
+		nil,
+		{42},
+		{"missing"},
+		{"../app"},
+		{"app", ""},
+		{"app", "path", "extra"},
+	} {
+		if _, err := spec.CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestReloadLocal(t *testing.T) {
+	dir := t.TempDir()
+	writeBundleDir(t, filepath.Join(dir, "app"), map[string]string{
+		"policy.rego": "package envoy.authz\nallow = true",
+	})
+
+	r := NewRegistry(Options{BundleSource: dir, ReloadInterval: time.Hour})
+	defer r.Close()
+
+	f, err := NewOpaAuthorizeRequestSpec(r).CreateFilter([]interface{}{"app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx := serve(t, f, "GET", "/", ""); ctx.FServed {
+		t.Fatal("expected allowed")
+	}
+
+	writeBundleDir(t, filepath.Join(dir, "app"), map[string]string{
+		"policy.rego": "package envoy.authz\nallow = false",
+	})
+
+	r.reload()
+	if ctx := serve(t, f, "GET", "/", ""); !ctx.FServed {
+		t.Fatal("expected denied after reload")
+	}
+
+	// invalid policies keep the previous version
+	writeBundleDir(t, filepath.Join(dir, "app"), map[string]string{
+		"policy.rego": "package envoy.authz\nallow = ",
+	})
+
+	r.reload()
+	if ctx := serve(t, f, "GET", "/", ""); !ctx.FServed {
+		t.Fatal("expected the previous version")
+	}
+}
+
+func TestReloadHTTP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		archive  []byte
+		etag     string
+		requests int
+		notMod   int
+	)
+
+	set := func(policy, tag string) {
+		mu.Lock()
+		defer mu.Unlock()
+		archive = bundleArchive(t, map[string]string{
+			"envoy/policy.rego": policy,
+			".manifest":         `{"revision": "` + tag + `"}`,
+		})
+
+		etag = tag
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != "/bundles/app.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			notMod++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Write(archive)
+	}))
+	defer s.Close()
+
+	set("package envoy.authz\nallow = true", "v1")
+
+	r := NewRegistry(Options{BundleSource: s.URL + "/bundles", ReloadInterval: time.Hour})
+	defer r.Close()
+
+	spec := NewOpaAuthorizeRequestSpec(r)
+	f, err := spec.CreateFilter([]interface{}{"app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := spec.CreateFilter([]interface{}{"other"}); err == nil {
+		t.Error("expected error for missing bundle")
+	}
+
+	if ctx := serve(t, f, "GET", "/", ""); ctx.FServed {
+		t.Fatal("expected allowed")
+	}
+
+	r.reload()
+	if notMod != 1 {
+		t.Errorf("expected a not modified response, got: %d", notMod)
+	}
+
+	set("package envoy.authz\nallow = false", "v2")
+	r.reload()
+	if ctx := serve(t, f, "GET", "/", ""); !ctx.FServed {
+		t.Fatal("expected denied after reload")
+	}
+
+	e, _ := r.bundle("app")
+	if rev := e.get().Revision; rev != "v2" {
+		t.Errorf("unexpected revision: %s", rev)
+	}
+}
+
+func TestInput(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://www.example.org/foo/bar?q=1&q=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+testToken("alice"))
+	ctx := &filtertest.Context{FRequest: req, FStateBag: map[string]interface{}{
+		"user":   "alice",
+		"client": struct{}{},
+	}}
+
+	input := requestInput(ctx)
+	if p := input["parsed_path"].([]interface{}); len(p) != 2 || p[0] != "foo" || p[1] != "bar" {
+		t.Errorf("unexpected path segments: %v", p)
+	}
+
+	if q := input["parsed_query"].(map[string]interface{})["q"].([]interface{}); len(q) != 2 {
+		t.Errorf("unexpected query: %v", q)
+	}
+
+	if sub := input["unverified_jwt_claims"].(map[string]interface{})["sub"]; sub != "alice" {
+		t.Errorf("unexpected claims: %v", input["unverified_jwt_claims"])
+	}
+
+	sb := input["state_bag"].(map[string]interface{})
+	if len(sb) != 1 || sb["user"] != "alice" {
+		t.Errorf("unexpected state bag: %v", sb)
+	}
+
+	if h := redactedInput(input)["headers"].(map[string]interface{})["authorization"]; h != "REDACTED" {
+		t.Errorf("expected redacted authorization header, got: %v", h)
+	}
+}
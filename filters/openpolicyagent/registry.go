@@ -0,0 +1,273 @@
+package openpolicyagent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultReloadInterval = time.Minute
+	defaultTimeout        = 10 * time.Second
+	archiveExt            = ".tar.gz"
+)
+
+var errRegistryClosed = errors.New("policy bundle registry closed")
+
+// Options configure the registry of the policy bundles.
+type Options struct {
+
+	// BundleSource is either a local directory or the base URL of an
+	// HTTP bundle server. A bundle with the name "foo" is loaded from
+	// the directory <source>/foo, from the archive <source>/foo.tar.gz,
+	// or, in case of a bundle server, from <source>/foo.tar.gz.
+	BundleSource string
+
+	// ReloadInterval sets how often the bundles are checked for
+	// changes. Defaults to one minute.
+	ReloadInterval time.Duration
+
+	// Timeout of the requests to the bundle server. Defaults to 10
+	// seconds.
+	Timeout time.Duration
+
+	// DecisionLogs enables logging every policy decision.
+	DecisionLogs bool
+}
+
+type bundleEntry struct {
+	mu     sync.RWMutex
+	bundle *Bundle
+	etag   string
+}
+
+// Registry loads the policy bundles on demand, and reloads them
+// periodically. When a changed bundle fails to load, the previous
+// version is used further.
+type Registry struct {
+	options Options
+	client  *http.Client
+
+	mu      sync.Mutex
+	bundles map[string]*bundleEntry
+	closed  bool
+	quit    chan struct{}
+}
+
+// NewRegistry creates a registry of policy bundles and starts the
+// periodic reloading.
+func NewRegistry(o Options) *Registry {
+	if o.ReloadInterval <= 0 {
+		o.ReloadInterval = defaultReloadInterval
+	}
+
+	if o.Timeout <= 0 {
+		o.Timeout = defaultTimeout
+	}
+
+	r := &Registry{
+		options: o,
+		client:  &http.Client{Timeout: o.Timeout},
+		bundles: make(map[string]*bundleEntry),
+		quit:    make(chan struct{}),
+	}
+
+	go r.reloadLoop()
+	return r
+}
+
+func isRemote(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+func validBundleName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// bundle returns the current version of the named bundle, loading it
+// when it is requested for the first time.
+func (r *Registry) bundle(name string) (*bundleEntry, error) {
+	if !validBundleName(name) {
+		return nil, fmt.Errorf("invalid bundle name: %q", name)
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, errRegistryClosed
+	}
+
+	if e, ok := r.bundles[name]; ok {
+		r.mu.Unlock()
+		return e, nil
+	}
+
+	r.mu.Unlock()
+
+	e := &bundleEntry{}
+	if err := r.load(name, e); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.bundles[name]; ok {
+		return existing, nil
+	}
+
+	r.bundles[name] = e
+	return e, nil
+}
+
+func (e *bundleEntry) get() *Bundle {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.bundle
+}
+
+// load loads the bundle, and sets it in the entry when it has changed.
+func (r *Registry) load(name string, e *bundleEntry) error {
+	var (
+		bf   bundleFiles
+		etag string
+		err  error
+	)
+
+	e.mu.RLock()
+	currentETag := e.etag
+	e.mu.RUnlock()
+
+	if isRemote(r.options.BundleSource) {
+		bf, etag, err = r.fetch(name, currentETag)
+	} else {
+		bf, err = r.readLocal(name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if bf == nil {
+		// not modified
+		return nil
+	}
+
+	if current := e.get(); current != nil && current.digest == bf.digest() {
+		return nil
+	}
+
+	b, err := compileBundle(name, bf)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.bundle = b
+	e.etag = etag
+	log.Infof("Policy bundle %s loaded, revision: %q", name, b.Revision)
+	return nil
+}
+
+func (r *Registry) readLocal(name string) (bundleFiles, error) {
+	p := filepath.Join(r.options.BundleSource, name)
+	if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+		return readBundleDir(p)
+	}
+
+	f, err := os.Open(p + archiveExt)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+	return readBundleArchive(f)
+}
+
+func (r *Registry) fetch(name, etag string) (bundleFiles, string, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(r.options.BundleSource, "/")+"/"+name+archiveExt, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	rsp, err := r.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, etag, nil
+	default:
+		return nil, "", fmt.Errorf("failed to download bundle %s: %s", name, rsp.Status)
+	}
+
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, io.LimitReader(rsp.Body, maxBundleSize+1)); err != nil {
+		return nil, "", err
+	}
+
+	if b.Len() > maxBundleSize {
+		return nil, "", fmt.Errorf("bundle %s exceeds the maximum size of %d bytes", name, maxBundleSize)
+	}
+
+	bf, err := readBundleArchive(&b)
+	return bf, rsp.Header.Get("ETag"), err
+}
+
+func (r *Registry) reloadLoop() {
+	ticker := time.NewTicker(r.options.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.reload()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *Registry) reload() {
+	r.mu.Lock()
+	entries := make(map[string]*bundleEntry, len(r.bundles))
+	for name, e := range r.bundles {
+		entries[name] = e
+	}
+
+	r.mu.Unlock()
+
+	for name, e := range entries {
+		if err := r.load(name, e); err != nil {
+			log.Errorf("Failed to reload policy bundle %s, keeping the previous version: %v", name, err)
+		}
+	}
+}
+
+// Close stops reloading the bundles.
+func (r *Registry) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.quit)
+	}
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/ratelimit"
+)
+
+const quotaStateBagKey = "ratelimit:quota"
+
+type quota interface {
+	Add(ctx context.Context, client string, increment int) (ratelimit.QuotaUsage, error)
+}
+
+type quotaSpec struct {
+	create func(group string, limit int, period ratelimit.QuotaPeriod) quota
+}
+
+type quotaFilter struct {
+	quota     quota
+	lookuper  ratelimit.Lookuper
+	softLimit int64
+}
+
+// NewClusterClientQuota creates a filter Spec, whose instances limit the
+// number of requests of the clients over a calendar period, a day, a week
+// or a month, counted in Redis across all instances. The responses get
+// the quota headers, and a warning header when the optional soft limit
+// is reached.
+//
+// Example to allow 100000 requests per month to each client
+// authenticated by the apiKey filter, warning from 80000 on:
+//
+//	clusterClientQuota("orders-api", 100000, "month", "auth:clientid", 80000)
+func NewClusterClientQuota(registry *ratelimit.Registry) filters.Spec {
+	return &quotaSpec{
+		create: func(group string, limit int, period ratelimit.QuotaPeriod) quota {
+			return ratelimit.NewClusterQuota(registry, group, limit, period)
+		},
+	}
+}
+
+func (s *quotaSpec) Name() string {
+	return filters.ClusterClientQuotaName
+}
+
+func (s *quotaSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) < 3 || len(args) > 5 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	group, err := getStringArg(args[0])
+	if err != nil || group == "" {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	limit, err := natural(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	periodString, err := getStringArg(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	period, err := ratelimit.ParseQuotaPeriod(periodString)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &quotaFilter{lookuper: ratelimit.NewXForwardedForLookuper()}
+	if len(args) > 3 {
+		lookuperString, err := getStringArg(args[3])
+		if err != nil {
+			return nil, err
+		}
+
+		f.lookuper = getLookuper(lookuperString)
+	}
+
+	if len(args) > 4 {
+		softLimit, err := natural(args[4])
+		if err != nil {
+			return nil, err
+		}
+
+		if softLimit > limit {
+			return nil, fmt.Errorf("soft limit %d exceeds the limit %d", softLimit, limit)
+		}
+
+		f.softLimit = int64(softLimit)
+	}
+
+	f.quota = s.create(group, limit, period)
+	return f, nil
+}
+
+func (f *quotaFilter) Request(ctx filters.FilterContext) {
+	client := ratelimit.LookupContext(f.lookuper, ctx)
+	if client == "" {
+		return
+	}
+
+	u, err := f.quota.Add(ctx.Request().Context(), client, 1)
+	if err != nil {
+		log.Errorf("Failed to check the quota of %s: %v", client, err)
+		return // allow on error
+	}
+
+	if !u.Exceeded {
+		ctx.StateBag()[quotaStateBagKey] = u
+		return
+	}
+
+	header := ratelimit.QuotaHeaders(u, time.Now())
+	header.Set(ratelimit.RetryAfterHeader, header.Get(ratelimit.QuotaResetHeader))
+	ctx.Serve(&http.Response{StatusCode: http.StatusTooManyRequests, Header: header})
+}
+
+func (f *quotaFilter) Response(ctx filters.FilterContext) {
+	u, ok := ctx.StateBag()[quotaStateBagKey].(ratelimit.QuotaUsage)
+	if !ok {
+		return
+	}
+
+	h := ctx.Response().Header
+	for k, v := range ratelimit.QuotaHeaders(u, time.Now()) {
+		h[k] = v
+	}
+
+	if f.softLimit > 0 && u.Used >= f.softLimit {
+		h.Set(ratelimit.QuotaWarningHeader, fmt.Sprintf("soft limit reached, %d of %d requests used", u.Used, u.Limit))
+	}
+}
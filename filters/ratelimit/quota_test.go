@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/ratelimit"
+)
+
+type testQuota struct {
+	limit int64
+	used  map[string]int64
+	err   error
+}
+
+func (q *testQuota) Add(_ context.Context, client string, increment int) (ratelimit.QuotaUsage, error) {
+	if q.err != nil {
+		return ratelimit.QuotaUsage{}, q.err
+	}
+
+	u := ratelimit.QuotaUsage{Client: client, Limit: q.limit, Reset: time.Now().Add(time.Hour)}
+	if q.used[client]+int64(increment) > q.limit {
+		u.Used, u.Exceeded = q.used[client], true
+	} else {
+		q.used[client] += int64(increment)
+		u.Used = q.used[client]
+	}
+
+	u.Remaining = q.limit - u.Used
+	return u, nil
+}
+
+func createQuotaFilter(t *testing.T, q *testQuota, args ...interface{}) filters.Filter {
+	spec := &quotaSpec{create: func(_ string, limit int, _ ratelimit.QuotaPeriod) quota {
+		q.limit = int64(limit)
+		return q
+	}}
+
+	f, err := spec.CreateFilter(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func quotaRequest(f filters.Filter, stateBag map[string]interface{}) *filtertest.Context {
+	ctx := &filtertest.Context{
+		FRequest:  &http.Request{Header: http.Header{}},
+		FStateBag: stateBag,
+	}
+
+	f.Request(ctx)
+	if !ctx.FServed {
+		ctx.FResponse = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+		f.Response(ctx)
+	}
+
+	return ctx
+}
+
+func TestQuota(t *testing.T) {
+	q := &testQuota{used: make(map[string]int64)}
+	f := createQuotaFilter(t, q, "orders", 3, "month", filters.ClientIDKey, 2)
+
+	for i, tc := range []struct {
+		status    int
+		remaining string
+		warning   bool
+	}{
+		{http.StatusOK, "2", false},
+		{http.StatusOK, "1", true},
+		{http.StatusOK, "0", true},
+		{http.StatusTooManyRequests, "0", false},
+	} {
+		ctx := quotaRequest(f, map[string]interface{}{filters.ClientIDKey: "acme"})
+		if ctx.FResponse.StatusCode != tc.status {
+			t.Errorf("request %d: expected status %d, got %d", i, tc.status, ctx.FResponse.StatusCode)
+		}
+
+		h := ctx.FResponse.Header
+		if h.Get(ratelimit.QuotaLimitHeader) != "3" || h.Get(ratelimit.QuotaRemainingHeader) != tc.remaining {
+			t.Errorf("request %d: unexpected quota headers: %v", i, h)
+		}
+
+		if h.Get(ratelimit.QuotaResetHeader) == "" {
+			t.Errorf("request %d: missing reset header", i)
+		}
+
+		if (h.Get(ratelimit.QuotaWarningHeader) != "") != tc.warning {
+			t.Errorf("request %d: unexpected warning header: %q", i, h.Get(ratelimit.QuotaWarningHeader))
+		}
+
+		if tc.status == http.StatusTooManyRequests && h.Get(ratelimit.RetryAfterHeader) == "" {
+			t.Errorf("request %d: missing retry after header", i)
+		}
+	}
+
+	ctx := quotaRequest(f, map[string]interface{}{filters.ClientIDKey: "globex"})
+	if ctx.FResponse.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status of another client: %d", ctx.FResponse.StatusCode)
+	}
+
+	ctx = quotaRequest(f, map[string]interface{}{})
+	if ctx.FResponse.StatusCode != http.StatusOK || ctx.FResponse.Header.Get(ratelimit.QuotaLimitHeader) != "" {
+		t.Error("unexpected quota without client")
+	}
+}
+
+func TestQuotaError(t *testing.T) {
+	q := &testQuota{err: context.DeadlineExceeded}
+	f := createQuotaFilter(t, q, "orders", 3, "day")
+
+	ctx := quotaRequest(f, map[string]interface{}{})
+	if ctx.FResponse.StatusCode != http.StatusOK {
+		t.Errorf("expected allowed on error, got %d", ctx.FResponse.StatusCode)
+	}
+}
+
+func TestQuotaArgs(t *testing.T) {
+	spec := &quotaSpec{create: func(string, int, ratelimit.QuotaPeriod) quota { return &testQuota{} }}
+	for _, args := range [][]interface{}{
+		{},
+		{"orders", 10},
+		{"", 10, "day"},
+		{"orders", 0, "day"},
+		{"orders", 10, "year"},
+		{"orders", 10, "day", 42},
+		{"orders", 10, "day", "X-Client", 11},
+		{"orders", 10, "day", "X-Client", 5, "extra"},
+	} {
+		if _, err := spec.CreateFilter(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+
+	if _, err := spec.CreateFilter([]interface{}{"orders", 10.0, "week", "X-Client", 5.0}); err != nil {
+		t.Error(err)
+	}
+}
@@ -331,6 +331,10 @@ func clusterClientRatelimitFilter(args []interface{}) (*filter, error) {
 }
 
 func getLookuper(s string) ratelimit.Lookuper {
+	if s == filters.ClientIDKey {
+		return ratelimit.NewClientIDLookuper()
+	}
+
 	headerName := http.CanonicalHeaderKey(s)
 	if headerName == "X-Forwarded-For" {
 		return ratelimit.NewXForwardedForLookuper()
@@ -366,6 +370,8 @@ func clientRatelimitFilter(args []interface{}) (*filter, error) {
 				lookupers = append(lookupers, getLookuper(ls))
 			}
 			lookuper = ratelimit.NewTupleLookuper(lookupers...)
+		} else if lookuperString == filters.ClientIDKey {
+			lookuper = ratelimit.NewClientIDLookuper()
 		} else {
 			lookuper = ratelimit.NewHeaderLookuper(lookuperString)
 		}
@@ -471,7 +477,7 @@ func (f *filter) Request(ctx filters.FilterContext) {
 		return
 	}
 
-	s := f.settings.Lookuper.Lookup(ctx.Request())
+	s := ratelimit.LookupContext(f.settings.Lookuper, ctx)
 	if s == "" {
 		log.Debugf("Lookuper found no data in request for settings: %s and request: %v", f.settings, ctx.Request())
 		return
@@ -464,6 +464,43 @@ func TestAllowsContext(t *testing.T) {
 	}
 }
 
+type recordingLimit struct {
+	keys []string
+}
+
+func (l *recordingLimit) get(ratelimit.Settings) limit { return l }
+
+func (l *recordingLimit) AllowContext(_ context.Context, key string) bool {
+	l.keys = append(l.keys, key)
+	return true
+}
+
+func (l *recordingLimit) RetryAfter(string) int { return 0 }
+
+func TestClientIDLookuper(t *testing.T) {
+	l := &recordingLimit{}
+	f, err := NewClusterClientRateLimit(l).CreateFilter([]interface{}{"apikeys", 10, "1m", filters.ClientIDKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &filtertest.Context{
+		FRequest:  &http.Request{Header: http.Header{}},
+		FStateBag: map[string]interface{}{filters.ClientIDKey: "acme"},
+	}
+
+	f.Request(ctx)
+	if len(l.keys) != 1 || l.keys[0] != "acme" {
+		t.Errorf("unexpected ratelimit keys: %v", l.keys)
+	}
+
+	delete(ctx.FStateBag, filters.ClientIDKey)
+	f.Request(ctx)
+	if len(l.keys) != 1 {
+		t.Errorf("unexpected ratelimit keys without client id: %v", l.keys)
+	}
+}
+
 func TestGetKeyShards(t *testing.T) {
 	for _, tc := range []struct {
 		maxHits      int
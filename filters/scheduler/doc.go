@@ -34,4 +34,11 @@
 //   - 502, if it can not get a request from data structure fast enough
 //   - 503, if the data structure is full and reached its boundary
 //
+// The priorityClass, priorityClassFromHeader and priorityClassFromClaim
+// filters set the priority class of the request, used by the load
+// shedding of the proxy, when it is enabled. Unlike the lifo filters,
+// the load shedding doesn't queue the requests, but when the proxy is
+// overloaded, it rejects the requests with lower priority classes
+// first, with the status code 503 and a Retry-After header.
+//
 package scheduler
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/jwt"
+	"github.com/zalando/skipper/scheduler"
+)
+
+const (
+	authHeaderName   = "Authorization"
+	authHeaderPrefix = "Bearer "
+)
+
+type (
+	prioritySpec struct {
+		name string
+	}
+
+	priorityFilter struct {
+		priority scheduler.Priority
+	}
+
+	priorityFromHeaderFilter struct {
+		header string
+		max    scheduler.Priority
+	}
+
+	priorityFromClaimFilter struct {
+		claim string
+		max   scheduler.Priority
+	}
+)
+
+// NewPriorityClass creates the filter specification of the priorityClass filter, that sets a fixed priority
+// class for the requests of the route, used by the load shedding:
+//
+//	priorityClass("high")
+func NewPriorityClass() filters.Spec {
+	return &prioritySpec{name: filters.PriorityClassName}
+}
+
+// NewPriorityClassFromHeader creates the filter specification of the priorityClassFromHeader filter, that
+// sets the priority class of the request from the value of a request header. The optional second argument
+// limits the highest class that can be set by the header, by default normal, because the clients can set
+// any header:
+//
+//	priorityClassFromHeader("X-Priority", "high")
+func NewPriorityClassFromHeader() filters.Spec {
+	return &prioritySpec{name: filters.PriorityClassFromHeaderName}
+}
+
+// NewPriorityClassFromClaim creates the filter specification of the priorityClassFromClaim filter, that sets
+// the priority class of the request from a claim of the JWT bearer token. The token is not verified, so the
+// filter should be used together with a filter validating the token. The optional second argument limits the
+// highest class that can be set by the claim, by default normal:
+//
+//	priorityClassFromClaim("priority", "high")
+func NewPriorityClassFromClaim() filters.Spec {
+	return &prioritySpec{name: filters.PriorityClassFromClaimName}
+}
+
+func (s *prioritySpec) Name() string { return s.name }
+
+func priorityArg(a interface{}) (scheduler.Priority, error) {
+	s, ok := a.(string)
+	if !ok {
+		return 0, filters.ErrInvalidFilterParameters
+	}
+
+	p, err := scheduler.ParsePriority(s)
+	if err != nil {
+		return 0, filters.ErrInvalidFilterParameters
+	}
+
+	return p, nil
+}
+
+func (s *prioritySpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if s.name == filters.PriorityClassName {
+		if len(args) != 1 {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		p, err := priorityArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return &priorityFilter{priority: p}, nil
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	key, ok := args[0].(string)
+	if !ok || key == "" {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	// the classes above normal need to be allowed explicitly, because the header and the claim are
+	// controlled by the client
+	max := scheduler.PriorityNormal
+	if len(args) == 2 {
+		var err error
+		if max, err = priorityArg(args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.name == filters.PriorityClassFromHeaderName {
+		return &priorityFromHeaderFilter{header: key, max: max}, nil
+	}
+
+	return &priorityFromClaimFilter{claim: key, max: max}, nil
+}
+
+// sets the priority class in the state bag, when the value is a valid class. Otherwise, the class set
+// earlier is kept.
+func setPriority(ctx filters.FilterContext, value string, max scheduler.Priority) {
+	p, err := scheduler.ParsePriority(value)
+	if err != nil {
+		return
+	}
+
+	if p > max {
+		p = max
+	}
+
+	ctx.StateBag()[scheduler.PriorityKey] = p
+}
+
+func (f *priorityFilter) Request(ctx filters.FilterContext) {
+	ctx.StateBag()[scheduler.PriorityKey] = f.priority
+}
+
+func (*priorityFilter) Response(filters.FilterContext) {}
+
+func (f *priorityFromHeaderFilter) Request(ctx filters.FilterContext) {
+	if v := ctx.Request().Header.Get(f.header); v != "" {
+		setPriority(ctx, v, f.max)
+	}
+}
+
+func (*priorityFromHeaderFilter) Response(filters.FilterContext) {}
+
+func (f *priorityFromClaimFilter) Request(ctx filters.FilterContext) {
+	ahead := ctx.Request().Header.Get(authHeaderName)
+	tv := strings.TrimPrefix(ahead, authHeaderPrefix)
+	if tv == ahead {
+		return
+	}
+
+	token, err := jwt.Parse(tv)
+	if err != nil {
+		return
+	}
+
+	if v, ok := token.Claims[f.claim].(string); ok {
+		setPriority(ctx, v, f.max)
+	}
+}
+
+func (*priorityFromClaimFilter) Response(filters.FilterContext) {}
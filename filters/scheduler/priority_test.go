@@ -0,0 +1,307 @@
+package scheduler
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/proxy"
+	"github.com/zalando/skipper/proxy/proxytest"
+	"github.com/zalando/skipper/scheduler"
+)
+
+func testToken(payload string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"none"}`)) + "." + enc([]byte(payload)) + ".c2ln"
+}
+
+func TestPriorityClass(t *testing.T) {
+	for _, tt := range []struct {
+		title   string
+		spec    filters.Spec
+		args    []interface{}
+		header  http.Header
+		initial interface{}
+		err     bool
+		expect  interface{}
+	}{{
+		title: "fixed, no args",
+		spec:  NewPriorityClass(),
+		err:   true,
+	}, {
+		title: "fixed, invalid class",
+		spec:  NewPriorityClass(),
+		args:  []interface{}{"urgent"},
+		err:   true,
+	}, {
+		title: "fixed, too many args",
+		spec:  NewPriorityClass(),
+		args:  []interface{}{"low", "high"},
+		err:   true,
+	}, {
+		title:  "fixed",
+		spec:   NewPriorityClass(),
+		args:   []interface{}{"low"},
+		expect: scheduler.PriorityLow,
+	}, {
+		title:   "fixed, overrides the previous class",
+		spec:    NewPriorityClass(),
+		args:    []interface{}{"critical"},
+		initial: scheduler.PriorityLow,
+		expect:  scheduler.PriorityCritical,
+	}, {
+		title: "header, no args",
+		spec:  NewPriorityClassFromHeader(),
+		err:   true,
+	}, {
+		title: "header, invalid max class",
+		spec:  NewPriorityClassFromHeader(),
+		args:  []interface{}{"X-Priority", "urgent"},
+		err:   true,
+	}, {
+		title:  "header",
+		spec:   NewPriorityClassFromHeader(),
+		args:   []interface{}{"X-Priority"},
+		header: http.Header{"X-Priority": []string{"low"}},
+		expect: scheduler.PriorityLow,
+	}, {
+		title:  "header, limited to normal by default",
+		spec:   NewPriorityClassFromHeader(),
+		args:   []interface{}{"X-Priority"},
+		header: http.Header{"X-Priority": []string{"critical"}},
+		expect: scheduler.PriorityNormal,
+	}, {
+		title:  "header, allowed by max class",
+		spec:   NewPriorityClassFromHeader(),
+		args:   []interface{}{"X-Priority", "critical"},
+		header: http.Header{"X-Priority": []string{"high"}},
+		expect: scheduler.PriorityHigh,
+	}, {
+		title:  "header, limited by max class",
+		spec:   NewPriorityClassFromHeader(),
+		args:   []interface{}{"X-Priority", "normal"},
+		header: http.Header{"X-Priority": []string{"critical"}},
+		expect: scheduler.PriorityNormal,
+	}, {
+		title:   "header, missing keeps the previous class",
+		spec:    NewPriorityClassFromHeader(),
+		args:    []interface{}{"X-Priority"},
+		initial: scheduler.PriorityHigh,
+		expect:  scheduler.PriorityHigh,
+	}, {
+		title:   "header, invalid keeps the previous class",
+		spec:    NewPriorityClassFromHeader(),
+		args:    []interface{}{"X-Priority"},
+		header:  http.Header{"X-Priority": []string{"urgent"}},
+		initial: scheduler.PriorityLow,
+		expect:  scheduler.PriorityLow,
+	}, {
+		title: "claim, invalid claim name",
+		spec:  NewPriorityClassFromClaim(),
+		args:  []interface{}{42},
+		err:   true,
+	}, {
+		title:  "claim",
+		spec:   NewPriorityClassFromClaim(),
+		args:   []interface{}{"priority"},
+		header: http.Header{"Authorization": []string{"Bearer " + testToken(`{"priority":"low"}`)}},
+		expect: scheduler.PriorityLow,
+	}, {
+		title:  "claim, limited to normal by default",
+		spec:   NewPriorityClassFromClaim(),
+		args:   []interface{}{"priority"},
+		header: http.Header{"Authorization": []string{"Bearer " + testToken(`{"priority":"critical"}`)}},
+		expect: scheduler.PriorityNormal,
+	}, {
+		title:  "claim, limited by max class",
+		spec:   NewPriorityClassFromClaim(),
+		args:   []interface{}{"priority", "high"},
+		header: http.Header{"Authorization": []string{"Bearer " + testToken(`{"priority":"critical"}`)}},
+		expect: scheduler.PriorityHigh,
+	}, {
+		title:  "claim, missing",
+		spec:   NewPriorityClassFromClaim(),
+		args:   []interface{}{"priority"},
+		header: http.Header{"Authorization": []string{"Bearer " + testToken(`{"sub":"foo"}`)}},
+	}, {
+		title:  "claim, not a string",
+		spec:   NewPriorityClassFromClaim(),
+		args:   []interface{}{"priority"},
+		header: http.Header{"Authorization": []string{"Bearer " + testToken(`{"priority":3}`)}},
+	}, {
+		title:   "claim, invalid token keeps the previous class",
+		spec:    NewPriorityClassFromClaim(),
+		args:    []interface{}{"priority"},
+		header:  http.Header{"Authorization": []string{"Bearer foo"}},
+		initial: scheduler.PriorityHigh,
+		expect:  scheduler.PriorityHigh,
+	}, {
+		title:  "claim, no bearer token",
+		spec:   NewPriorityClassFromClaim(),
+		args:   []interface{}{"priority"},
+		header: http.Header{"Authorization": []string{"Basic Zm9vOmJhcg=="}},
+	}} {
+		t.Run(tt.title, func(t *testing.T) {
+			f, err := tt.spec.CreateFilter(tt.args)
+			if tt.err {
+				if err == nil {
+					t.Fatal("failed to fail")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := &http.Request{Header: tt.header}
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+
+			ctx := &filtertest.Context{FRequest: req, FStateBag: make(map[string]interface{})}
+			if tt.initial != nil {
+				ctx.FStateBag[scheduler.PriorityKey] = tt.initial
+			}
+
+			f.Request(ctx)
+			if p := ctx.FStateBag[scheduler.PriorityKey]; p != tt.expect {
+				t.Errorf("invalid priority class, expected: %v, got: %v", tt.expect, p)
+			}
+		})
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+	blocking := make(chan struct{})
+	received := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			close(received)
+			<-blocking
+		}
+	}))
+	defer backend.Close()
+
+	r, err := eskip.Parse(`* -> priorityClassFromHeader("X-Priority", "critical") -> "` + backend.URL + `"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls := scheduler.NewLoadShedder(scheduler.LoadSheddingOptions{MaxInflight: 1, RetryAfter: 3 * time.Second}, nil)
+	defer ls.Close()
+
+	fr := make(filters.Registry)
+	fr.Register(NewPriorityClassFromHeader())
+	p := proxytest.WithParams(fr, proxy.Params{CloseIdleConnsPeriod: -time.Second, LoadShedder: ls}, r...)
+	defer p.Close()
+
+	request := func(path, priority string) *http.Response {
+		req, err := http.NewRequest("GET", p.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if priority != "" {
+			req.Header.Set("X-Priority", priority)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rsp.Body.Close()
+		return rsp
+	}
+
+	blocked := make(chan *http.Response)
+	go func() { blocked <- request("/block", "low") }()
+	<-received
+
+	for _, tt := range []struct {
+		priority string
+		status   int
+	}{
+		{"low", http.StatusServiceUnavailable},
+		{"", http.StatusServiceUnavailable},
+		{"high", http.StatusServiceUnavailable},
+		{"critical", http.StatusOK},
+	} {
+		rsp := request("/", tt.priority)
+		if rsp.StatusCode != tt.status {
+			t.Errorf("invalid status for priority %q, expected: %d, got: %d", tt.priority, tt.status, rsp.StatusCode)
+		}
+
+		if tt.status == http.StatusServiceUnavailable && rsp.Header.Get("Retry-After") != "3" {
+			t.Errorf("invalid Retry-After header: %q", rsp.Header.Get("Retry-After"))
+		}
+	}
+
+	close(blocking)
+	if rsp := <-blocked; rsp.StatusCode != http.StatusOK {
+		t.Errorf("invalid status of the blocked request: %d", rsp.StatusCode)
+	}
+
+	if rsp := request("/", "low"); rsp.StatusCode != http.StatusOK {
+		t.Errorf("failed to admit after the load decreased: %d", rsp.StatusCode)
+	}
+}
+
+func TestLoadSheddingCountsStreamedResponse(t *testing.T) {
+	blocking := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("head"))
+		w.(http.Flusher).Flush()
+		<-blocking
+	}))
+	defer backend.Close()
+
+	r, err := eskip.Parse(`* -> "` + backend.URL + `"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls := scheduler.NewLoadShedder(scheduler.LoadSheddingOptions{MaxInflight: 1}, nil)
+	defer ls.Close()
+
+	p := proxytest.WithParams(make(filters.Registry), proxy.Params{CloseIdleConnsPeriod: -time.Second, LoadShedder: ls}, r...)
+	defer p.Close()
+
+	rsp, err := http.Get(p.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rsp.Body.Close()
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(rsp.Body, head); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := ls.Inflight(); n != 1 {
+		t.Errorf("the request is not counted while streaming the response, got: %d", n)
+	}
+
+	close(blocking)
+	if _, err := io.Copy(io.Discard, rsp.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	rsp.Body.Close()
+	deadline := time.Now().Add(time.Second)
+	for ls.Inflight() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := ls.Inflight(); n != 0 {
+		t.Errorf("the request is not released after the response was served, got: %d", n)
+	}
+}
@@ -0,0 +1,191 @@
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/secrets"
+)
+
+const (
+	optionServerName         = "serverName"
+	optionMinVersion         = "minVersion"
+	optionInsecureSkipVerify = "insecureSkipVerify"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// BackendTLS is stored in the state bag by the backendTLS filter, with
+// the filters.BackendTLS key, and it instructs the proxy to use the
+// contained TLS configuration for the backend connections.
+type BackendTLS struct {
+
+	// Key identifies the configuration, including the content of the
+	// referenced secrets. The proxy caches the transports by this key.
+	Key string
+
+	// Config is the TLS configuration of the backend connections.
+	Config *tls.Config
+}
+
+type backendTLSSpec struct {
+	secretsReader secrets.SecretsReader
+}
+
+type backendTLSFilter struct {
+	caBundle           string
+	clientCert         string
+	serverName         string
+	minVersion         uint16
+	insecureSkipVerify bool
+	config             *secrets.Parser
+}
+
+// NewBackendTLS creates the filter specification of the backendTLS
+// filter. The filter configures the TLS connections to the backend of
+// the route: the CA bundle used to verify the backend certificates, the
+// client certificate used for mutual TLS, the server name sent in SNI,
+// the minimum TLS version and whether to skip the verification of the
+// backend certificates.
+//
+// The CA bundle and the client certificate are referenced by their names
+// in the secrets reader, e.g. the file paths of the credentials loaded
+// with secrets.SecretPaths. The client certificate secret needs to
+// contain both the PEM encoded certificate and the key. Both names are
+// optional, an empty string can be used to skip them. The further
+// options are passed as key-value pairs:
+//
+//	backendTLS("/secrets/ca.pem")
+//	backendTLS("/secrets/ca.pem", "/secrets/client.pem")
+//	backendTLS("", "/secrets/client.pem", "serverName", "api.internal", "minVersion", "1.3")
+//	backendTLS("", "", "insecureSkipVerify", "true")
+func NewBackendTLS(sr secrets.SecretsReader) filters.Spec {
+	return &backendTLSSpec{secretsReader: sr}
+}
+
+func (*backendTLSSpec) Name() string { return filters.BackendTLSName }
+
+func (s *backendTLSSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) == 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	sargs := make([]string, len(args))
+	for i, a := range args {
+		s, ok := a.(string)
+		if !ok {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		sargs[i] = s
+	}
+
+	f := &backendTLSFilter{caBundle: sargs[0]}
+	if len(sargs) > 1 {
+		f.clientCert = sargs[1]
+	}
+
+	if len(sargs) > 2 {
+		options := sargs[2:]
+		if len(options)%2 != 0 {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		for i := 0; i < len(options); i += 2 {
+			key, value := options[i], options[i+1]
+			switch key {
+			case optionServerName:
+				f.serverName = value
+			case optionMinVersion:
+				v, ok := tlsVersions[value]
+				if !ok {
+					return nil, filters.ErrInvalidFilterParameters
+				}
+
+				f.minVersion = v
+			case optionInsecureSkipVerify:
+				v, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, filters.ErrInvalidFilterParameters
+				}
+
+				f.insecureSkipVerify = v
+			default:
+				return nil, filters.ErrInvalidFilterParameters
+			}
+		}
+	}
+
+	if f.caBundle != "" || f.clientCert != "" {
+		if s.secretsReader == nil {
+			return nil, errors.New("backendTLS: no secrets reader configured")
+		}
+	}
+
+	// the secrets may be rotated, the config is only recreated when they change
+	f.config = secrets.NewParser(s.secretsReader, f.createConfig, f.caBundle, f.clientCert)
+	return f, nil
+}
+
+func (f *backendTLSFilter) createConfig(content ...[]byte) (interface{}, error) {
+	ca, cert := content[0], content[1]
+	/* #nosec */
+	config := &tls.Config{
+		ServerName:         f.serverName,
+		MinVersion:         f.minVersion,
+		InsecureSkipVerify: f.insecureSkipVerify,
+	}
+
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificate found in CA bundle: %s", f.caBundle)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if len(cert) > 0 {
+		keyPair, err := tls.X509KeyPair(cert, cert)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate %s: %w", f.clientCert, err)
+		}
+
+		config.Certificates = []tls.Certificate{keyPair}
+	}
+
+	h := sha256.New()
+	h.Write(ca)
+	h.Write([]byte{0})
+	h.Write(cert)
+	fmt.Fprintf(h, "\x00%s\x00%d\x00%t", f.serverName, f.minVersion, f.insecureSkipVerify)
+
+	return &BackendTLS{Key: hex.EncodeToString(h.Sum(nil)), Config: config}, nil
+}
+
+func (f *backendTLSFilter) Request(ctx filters.FilterContext) {
+	c, err := f.config.Get()
+	if err != nil {
+		log.Errorf("Failed to configure backend TLS: %v", err)
+		ctx.Serve(&http.Response{StatusCode: http.StatusBadGateway})
+		return
+	}
+
+	ctx.StateBag()[filters.BackendTLS] = c.(*BackendTLS)
+}
+
+func (*backendTLSFilter) Response(filters.FilterContext) {}
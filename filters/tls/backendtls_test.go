@@ -0,0 +1,166 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+type mapSecrets map[string][]byte
+
+func (s mapSecrets) GetSecret(name string) ([]byte, bool) {
+	b, ok := s[name]
+	return b, ok
+}
+
+func (mapSecrets) Close() {}
+
+func createKeyPairPEM(t *testing.T, cn string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...,
+	)
+}
+
+func TestBackendTLSArgs(t *testing.T) {
+	for _, args := range [][]interface{}{
+		{},
+		{1},
+		{"ca.pem", 2},
+		{"ca.pem", "client.pem", "serverName"},
+		{"ca.pem", "client.pem", "foo", "bar"},
+		{"ca.pem", "client.pem", "minVersion", "1.4"},
+		{"ca.pem", "client.pem", "insecureSkipVerify", "maybe"},
+	} {
+		if _, err := NewBackendTLS(mapSecrets{}).CreateFilter(args); err != filters.ErrInvalidFilterParameters {
+			t.Errorf("expected invalid parameters error for %v, got: %v", args, err)
+		}
+	}
+
+	if _, err := NewBackendTLS(nil).CreateFilter([]interface{}{"ca.pem"}); err == nil {
+		t.Error("expected error without secrets reader")
+	}
+}
+
+func TestBackendTLS(t *testing.T) {
+	ca := createKeyPairPEM(t, "ca")
+	client := createKeyPairPEM(t, "client")
+	secrets := mapSecrets{"ca.pem": ca, "client.pem": client, "invalid.pem": []byte("foo")}
+
+	for _, tc := range []struct {
+		title  string
+		args   []interface{}
+		status int
+		check  func(*testing.T, *tls.Config)
+	}{{
+		title: "CA bundle",
+		args:  []interface{}{"ca.pem"},
+		check: func(t *testing.T, c *tls.Config) {
+			if c.RootCAs == nil || len(c.Certificates) != 0 {
+				t.Errorf("unexpected config: %v", c)
+			}
+		},
+	}, {
+		title: "client certificate and options",
+		args:  []interface{}{"", "client.pem", "serverName", "api.internal", "minVersion", "1.3", "insecureSkipVerify", "true"},
+		check: func(t *testing.T, c *tls.Config) {
+			if c.RootCAs != nil || len(c.Certificates) != 1 {
+				t.Errorf("unexpected config: %v", c)
+			}
+
+			if c.ServerName != "api.internal" || c.MinVersion != tls.VersionTLS13 || !c.InsecureSkipVerify {
+				t.Errorf("unexpected options: %v", c)
+			}
+		},
+	}, {
+		title:  "missing secret",
+		args:   []interface{}{"missing.pem"},
+		status: http.StatusBadGateway,
+	}, {
+		title:  "invalid client certificate",
+		args:   []interface{}{"ca.pem", "invalid.pem"},
+		status: http.StatusBadGateway,
+	}} {
+		t.Run(tc.title, func(t *testing.T) {
+			f, err := NewBackendTLS(secrets).CreateFilter(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := &filtertest.Context{FRequest: &http.Request{}, FStateBag: make(map[string]interface{})}
+			f.Request(ctx)
+
+			if tc.status != 0 {
+				if !ctx.FServed || ctx.FResponse.StatusCode != tc.status {
+					t.Fatalf("expected status %d, got: %v", tc.status, ctx.FResponse)
+				}
+
+				return
+			}
+
+			c, ok := ctx.FStateBag[filters.BackendTLS].(*BackendTLS)
+			if !ok {
+				t.Fatal("backend TLS config not set")
+			}
+
+			tc.check(t, c.Config)
+		})
+	}
+}
+
+func TestBackendTLSRotation(t *testing.T) {
+	secrets := mapSecrets{"ca.pem": createKeyPairPEM(t, "ca")}
+	f, err := NewBackendTLS(secrets).CreateFilter([]interface{}{"ca.pem"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() *BackendTLS {
+		ctx := &filtertest.Context{FRequest: &http.Request{}, FStateBag: make(map[string]interface{})}
+		f.Request(ctx)
+		return ctx.FStateBag[filters.BackendTLS].(*BackendTLS)
+	}
+
+	first := get()
+	if second := get(); second != first {
+		t.Error("expected cached config")
+	}
+
+	secrets["ca.pem"] = createKeyPairPEM(t, "ca")
+	if rotated := get(); rotated.Key == first.Key {
+		t.Error("expected new config after rotation")
+	}
+}
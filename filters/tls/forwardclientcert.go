@@ -0,0 +1,152 @@
+/*
+Package tls provides filters related to the TLS connection of the
+incoming requests.
+
+The forwardClientCert filter passes the details of the verified client
+certificate to the backend in the X-Forwarded-Client-Cert header, using
+the format known from Envoy:
+
+https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/headers#x-forwarded-client-cert
+
+The header received from the client is always removed, so the backend can
+rely on its value being set by skipper.
+
+Examples:
+
+    // forward the default fields: Hash, Subject, URI and DNS
+    * -> forwardClientCert() -> "https://backend.example.org";
+
+    // forward the PEM encoded certificate, too
+    * -> forwardClientCert("Hash", "Subject", "Cert") -> "https://backend.example.org";
+*/
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/url"
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/predicates/clientcert"
+)
+
+// ClientCertHeader is the name of the header set by the
+// forwardClientCert filter.
+const ClientCertHeader = "X-Forwarded-Client-Cert"
+
+const (
+	fieldHash    = "Hash"
+	fieldCert    = "Cert"
+	fieldChain   = "Chain"
+	fieldSubject = "Subject"
+	fieldURI     = "URI"
+	fieldDNS     = "DNS"
+)
+
+var defaultFields = []string{fieldHash, fieldSubject, fieldURI, fieldDNS}
+
+type forwardClientCertSpec struct{}
+
+type forwardClientCert struct {
+	fields []string
+}
+
+// NewForwardClientCert creates the filter specification of the
+// forwardClientCert filter. The filter accepts an optional list of the
+// fields to forward: Hash, Cert, Chain, Subject, URI and DNS. When no
+// fields are specified, Hash, Subject, URI and DNS are forwarded.
+func NewForwardClientCert() filters.Spec { return forwardClientCertSpec{} }
+
+func (forwardClientCertSpec) Name() string { return filters.ForwardClientCertName }
+
+func (forwardClientCertSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	if len(args) == 0 {
+		return &forwardClientCert{fields: defaultFields}, nil
+	}
+
+	f := &forwardClientCert{}
+	for _, a := range args {
+		s, ok := a.(string)
+		if !ok {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		switch s {
+		case fieldHash, fieldCert, fieldChain, fieldSubject, fieldURI, fieldDNS:
+			f.fields = append(f.fields, s)
+		default:
+			return nil, filters.ErrInvalidFilterParameters
+		}
+	}
+
+	return f, nil
+}
+
+func quote(s string) string {
+	if !strings.ContainsAny(s, `,;="`) {
+		return s
+	}
+
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func encodePEM(certs ...*x509.Certificate) string {
+	var b strings.Builder
+	for _, c := range certs {
+		b.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}))
+	}
+
+	return url.QueryEscape(b.String())
+}
+
+// FormatClientCert formats the certificate chain as a single element of
+// the X-Forwarded-Client-Cert header, containing the requested fields.
+func FormatClientCert(chain []*x509.Certificate, fields ...string) string {
+	if len(chain) == 0 {
+		return ""
+	}
+
+	cert := chain[0]
+	var parts []string
+	for _, f := range fields {
+		switch f {
+		case fieldHash:
+			sum := sha256.Sum256(cert.Raw)
+			parts = append(parts, fieldHash+"="+hex.EncodeToString(sum[:]))
+		case fieldCert:
+			parts = append(parts, fieldCert+"="+quote(encodePEM(cert)))
+		case fieldChain:
+			parts = append(parts, fieldChain+"="+quote(encodePEM(chain...)))
+		case fieldSubject:
+			parts = append(parts, fieldSubject+"="+quote(cert.Subject.String()))
+		case fieldURI:
+			for _, u := range cert.URIs {
+				parts = append(parts, fieldURI+"="+quote(u.String()))
+			}
+		case fieldDNS:
+			for _, n := range cert.DNSNames {
+				parts = append(parts, fieldDNS+"="+quote(n))
+			}
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func (f *forwardClientCert) Request(ctx filters.FilterContext) {
+	r := ctx.Request()
+	r.Header.Del(ClientCertHeader)
+
+	if clientcert.VerifiedCertificate(r) == nil {
+		return
+	}
+
+	if v := FormatClientCert(r.TLS.VerifiedChains[0], f.fields...); v != "" {
+		r.Header.Set(ClientCertHeader, v)
+	}
+}
+
+func (*forwardClientCert) Response(filters.FilterContext) {}
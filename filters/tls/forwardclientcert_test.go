@@ -0,0 +1,140 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+func createCertificate(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("spiffe://cluster.local/ns/payments/sa/api")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "orders", Organization: []string{"Example, Inc."}},
+		DNSNames:     []string{"orders.example.org", "orders.example.com"},
+		URIs:         []*url.URL{u},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestForwardClientCertArgs(t *testing.T) {
+	for _, args := range [][]interface{}{
+		{1},
+		{"Hash", "Foo"},
+	} {
+		if _, err := NewForwardClientCert().CreateFilter(args); err != filters.ErrInvalidFilterParameters {
+			t.Errorf("expected invalid parameters error for %v, got: %v", args, err)
+		}
+	}
+}
+
+func TestForwardClientCert(t *testing.T) {
+	cert := createCertificate(t)
+	sum := sha256.Sum256(cert.Raw)
+	hash := hex.EncodeToString(sum[:])
+
+	for _, tc := range []struct {
+		title    string
+		args     []interface{}
+		verified bool
+		expected string
+	}{{
+		title:    "not verified",
+		verified: false,
+		expected: "",
+	}, {
+		title:    "default fields",
+		verified: true,
+		expected: "Hash=" + hash +
+			`;Subject="CN=orders,O=Example\, Inc."` +
+			`;URI=spiffe://cluster.local/ns/payments/sa/api` +
+			`;DNS=orders.example.org;DNS=orders.example.com`,
+	}, {
+		title:    "selected fields",
+		args:     []interface{}{"DNS", "Hash"},
+		verified: true,
+		expected: "DNS=orders.example.org;DNS=orders.example.com;Hash=" + hash,
+	}} {
+		t.Run(tc.title, func(t *testing.T) {
+			f, err := NewForwardClientCert().CreateFilter(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := &http.Request{
+				Header: http.Header{ClientCertHeader: []string{"Hash=spoofed"}},
+				TLS:    &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			}
+
+			if tc.verified {
+				req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+			}
+
+			f.Request(&filtertest.Context{FRequest: req})
+			if got := req.Header.Get(ClientCertHeader); got != tc.expected {
+				t.Errorf("expected header: %q, got: %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestForwardClientCertPEM(t *testing.T) {
+	cert := createCertificate(t)
+	f, err := NewForwardClientCert().CreateFilter([]interface{}{"Cert"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &http.Request{
+		Header: make(http.Header),
+		TLS:    &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}
+
+	f.Request(&filtertest.Context{FRequest: req})
+
+	v := req.Header.Get(ClientCertHeader)
+	if !strings.HasPrefix(v, "Cert=") {
+		t.Fatalf("unexpected header: %s", v)
+	}
+
+	pem, err := url.QueryUnescape(strings.Trim(strings.TrimPrefix(v, "Cert="), `"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(pem, "-----BEGIN CERTIFICATE-----") {
+		t.Errorf("unexpected certificate: %s", pem)
+	}
+}
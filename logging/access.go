@@ -2,6 +2,7 @@ package logging
 
 import (
 	"fmt"
+	"math/rand"
 	"net"
 	"net/http"
 	"strings"
@@ -44,6 +45,36 @@ type AccessEntry struct {
 
 	// The time that the request was received.
 	RequestTime time.Time
+
+	// The id of the matched route.
+	RouteID string
+
+	// The backend endpoint selected by the load balancer, or the
+	// network address of the route.
+	Backend string
+
+	// The time spent waiting for the backend response.
+	BackendDuration time.Duration
+
+	// The time spent executing the request filters.
+	RequestFiltersDuration time.Duration
+
+	// The time spent executing the response filters.
+	ResponseFiltersDuration time.Duration
+
+	// The state bag of the request, used by the ${statebag.<key>}
+	// format fields.
+	StateBag map[string]interface{}
+
+	// The headers of the response.
+	ResponseHeader http.Header
+
+	// Format, when set, overrides the global access log format.
+	Format *AccessLogFormat
+
+	// SampleRate, when greater than zero, overrides the global sample
+	// rate of the access log.
+	SampleRate float64
 }
 
 // TODO: create individual instances from the access log and
@@ -52,6 +83,10 @@ type AccessEntry struct {
 var (
 	accessLog  *logrus.Logger
 	stripQuery bool
+	format     *AccessLogFormat
+	sampleRate float64
+	redact     *redaction
+	sample     = rand.Float64
 )
 
 // strip port from addresses with hostname, ipv4 or ipv6
@@ -81,6 +116,10 @@ func omitWhitespace(h string) string {
 }
 
 func (f *accessLogFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	if line, ok := e.Data[formattedLineKey].(string); ok {
+		return []byte(line), nil
+	}
+
 	keys := []string{
 		"host", "timestamp", "method", "uri", "proto",
 		"status", "response-size", "referer", "user-agent",
@@ -113,6 +152,25 @@ func LogAccess(entry *AccessEntry, additional map[string]interface{}) {
 		return
 	}
 
+	rate := sampleRate
+	if entry.SampleRate > 0 {
+		rate = entry.SampleRate
+	}
+
+	if rate > 0 && rate < 1 && sample() >= rate {
+		return
+	}
+
+	f := format
+	if entry.Format != nil {
+		f = entry.Format
+	}
+
+	if f != nil {
+		logFormatted(f, entry, additional)
+		return
+	}
+
 	ts := entry.RequestTime.Format(dateFormat)
 
 	host := "-"
@@ -141,6 +199,8 @@ func LogAccess(entry *AccessEntry, additional map[string]interface{}) {
 		uri = entry.Request.RequestURI
 		if stripQuery {
 			uri = stripQueryString(uri)
+		} else {
+			uri = redact.uri(uri)
 		}
 
 		auditHeader = entry.Request.Header.Get(logFilter.UnverifiedAuditHeader)
@@ -168,3 +228,17 @@ func LogAccess(entry *AccessEntry, additional map[string]interface{}) {
 
 	accessLog.WithFields(logData).Infoln()
 }
+
+func logFormatted(f *AccessLogFormat, entry *AccessEntry, additional map[string]interface{}) {
+	var logData logrus.Fields
+	if _, ok := accessLog.Formatter.(*accessLogFormatter); ok {
+		logData = logrus.Fields{formattedLineKey: f.render(entry, redact)}
+	} else {
+		logData = f.data(entry, redact)
+		for k, v := range additional {
+			logData[k] = v
+		}
+	}
+
+	accessLog.WithFields(logData).Infoln()
+}
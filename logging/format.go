@@ -0,0 +1,346 @@
+package logging
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	flowidFilter "github.com/zalando/skipper/filters/flowid"
+	logFilter "github.com/zalando/skipper/filters/log"
+)
+
+const (
+	requestHeaderPrefix  = "request.header."
+	responseHeaderPrefix = "response.header."
+	requestQueryPrefix   = "request.query."
+	stateBagPrefix       = "statebag."
+
+	redactedValue = "[REDACTED]"
+
+	// the key of the rendered access log line, when a text template is
+	// used
+	formattedLineKey = "__access_log_line"
+)
+
+// DefaultRedactedHeaders lists the headers whose values are redacted in
+// the access log by default.
+var DefaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+type formatField struct {
+	name  string
+	value func(*AccessEntry, *redaction) interface{}
+}
+
+type formatPart struct {
+	literal string
+	field   *formatField
+}
+
+// AccessLogFormat is a parsed access log template. The templates contain
+// placeholders in the form of ${name}, e.g.:
+//
+//	${remote_host} "${method} ${uri}" ${status} ${duration} ${route_id} ${backend}
+//
+// When the access log is written in JSON format, only the fields of the
+// placeholders are logged, and the literal text is ignored.
+type AccessLogFormat struct {
+	template string
+	parts    []formatPart
+	fields   []*formatField
+}
+
+type redaction struct {
+	headers map[string]bool
+	query   map[string]bool
+}
+
+func newRedaction(headers, query []string) *redaction {
+	r := &redaction{headers: make(map[string]bool), query: make(map[string]bool)}
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+
+	for _, q := range query {
+		r.query[q] = true
+	}
+
+	return r
+}
+
+func (r *redaction) header(h http.Header, name string) string {
+	v := h.Get(name)
+	if v != "" && r != nil && r.headers[http.CanonicalHeaderKey(name)] {
+		return redactedValue
+	}
+
+	return v
+}
+
+func (r *redaction) queryValue(q url.Values, name string) string {
+	v := q.Get(name)
+	if v != "" && r != nil && r.query[name] {
+		return redactedValue
+	}
+
+	return v
+}
+
+// uri redacts the values of the sensitive query params in a request URI,
+// keeping the order of the params.
+func (r *redaction) uri(u string) string {
+	if r == nil || len(r.query) == 0 {
+		return u
+	}
+
+	i := strings.IndexRune(u, '?')
+	if i < 0 {
+		return u
+	}
+
+	params := strings.Split(u[i+1:], "&")
+	for j, p := range params {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		if key, err := url.QueryUnescape(kv[0]); err == nil && r.query[key] {
+			params[j] = kv[0] + "=" + url.QueryEscape(redactedValue)
+		}
+	}
+
+	return u[:i+1] + strings.Join(params, "&")
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func requestValue(f func(*http.Request) string) func(*AccessEntry, *redaction) interface{} {
+	return func(e *AccessEntry, _ *redaction) interface{} {
+		if e.Request == nil {
+			return ""
+		}
+
+		return f(e.Request)
+	}
+}
+
+func requestURI(e *AccessEntry, r *redaction) string {
+	if e.Request == nil {
+		return ""
+	}
+
+	u := e.Request.RequestURI
+	if stripQuery {
+		return stripQueryString(u)
+	}
+
+	return r.uri(u)
+}
+
+var formatFields = map[string]func(*AccessEntry, *redaction) interface{}{
+	"remote_host": requestValue(remoteHost),
+	"timestamp": func(e *AccessEntry, _ *redaction) interface{} {
+		return e.RequestTime.Format(dateFormat)
+	},
+	"method": requestValue(func(r *http.Request) string { return r.Method }),
+	"uri": func(e *AccessEntry, r *redaction) interface{} {
+		return requestURI(e, r)
+	},
+	"path": requestValue(func(r *http.Request) string {
+		if r.URL == nil {
+			return ""
+		}
+
+		return r.URL.Path
+	}),
+	"proto":      requestValue(func(r *http.Request) string { return r.Proto }),
+	"host":       requestValue(func(r *http.Request) string { return r.Host }),
+	"referer":    requestValue(func(r *http.Request) string { return r.Referer() }),
+	"user_agent": requestValue(func(r *http.Request) string { return r.UserAgent() }),
+	"flow_id":    requestValue(func(r *http.Request) string { return r.Header.Get(flowidFilter.HeaderName) }),
+	"audit":      requestValue(func(r *http.Request) string { return r.Header.Get(logFilter.UnverifiedAuditHeader) }),
+	"status": func(e *AccessEntry, _ *redaction) interface{} {
+		return e.StatusCode
+	},
+	"response_size": func(e *AccessEntry, _ *redaction) interface{} {
+		return e.ResponseSize
+	},
+	"duration": func(e *AccessEntry, _ *redaction) interface{} {
+		return milliseconds(e.Duration)
+	},
+	"route_id": func(e *AccessEntry, _ *redaction) interface{} {
+		return e.RouteID
+	},
+	"backend": func(e *AccessEntry, _ *redaction) interface{} {
+		return e.Backend
+	},
+	"backend_duration": func(e *AccessEntry, _ *redaction) interface{} {
+		return milliseconds(e.BackendDuration)
+	},
+	"request_filters_duration": func(e *AccessEntry, _ *redaction) interface{} {
+		return milliseconds(e.RequestFiltersDuration)
+	},
+	"response_filters_duration": func(e *AccessEntry, _ *redaction) interface{} {
+		return milliseconds(e.ResponseFiltersDuration)
+	},
+}
+
+func stateBagValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil:
+		return ""
+	case string, bool, int, int64, float64:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func newFormatField(name string) (*formatField, error) {
+	if f, ok := formatFields[name]; ok {
+		return &formatField{name: name, value: f}, nil
+	}
+
+	var arg string
+	switch {
+	case strings.HasPrefix(name, requestHeaderPrefix):
+		arg = name[len(requestHeaderPrefix):]
+		if arg != "" {
+			return &formatField{name: name, value: func(e *AccessEntry, r *redaction) interface{} {
+				if e.Request == nil {
+					return ""
+				}
+
+				return r.header(e.Request.Header, arg)
+			}}, nil
+		}
+	case strings.HasPrefix(name, responseHeaderPrefix):
+		arg = name[len(responseHeaderPrefix):]
+		if arg != "" {
+			return &formatField{name: name, value: func(e *AccessEntry, r *redaction) interface{} {
+				return r.header(e.ResponseHeader, arg)
+			}}, nil
+		}
+	case strings.HasPrefix(name, requestQueryPrefix):
+		arg = name[len(requestQueryPrefix):]
+		if arg != "" {
+			return &formatField{name: name, value: func(e *AccessEntry, r *redaction) interface{} {
+				if e.Request == nil || e.Request.URL == nil {
+					return ""
+				}
+
+				return r.queryValue(e.Request.URL.Query(), arg)
+			}}, nil
+		}
+	case strings.HasPrefix(name, stateBagPrefix):
+		arg = name[len(stateBagPrefix):]
+		if arg != "" {
+			return &formatField{name: name, value: func(e *AccessEntry, _ *redaction) interface{} {
+				return stateBagValue(e.StateBag[arg])
+			}}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid access log field: %s", name)
+}
+
+// ParseAccessLogFormat parses an access log template. The following
+// placeholders are supported:
+//
+//	${remote_host}, ${timestamp}, ${method}, ${uri}, ${path}, ${proto},
+//	${host}, ${status}, ${response_size}, ${duration}, ${referer},
+//	${user_agent}, ${flow_id}, ${audit}, ${route_id}, ${backend},
+//	${backend_duration}, ${request_filters_duration},
+//	${response_filters_duration}, ${request.header.<name>},
+//	${response.header.<name>}, ${request.query.<name>},
+//	${statebag.<key>}
+//
+// The durations are logged in milliseconds. The ${backend} field contains
+// the backend endpoint selected by the load balancer, or the network
+// address of the route.
+func ParseAccessLogFormat(s string) (*AccessLogFormat, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty access log format")
+	}
+
+	f := &AccessLogFormat{template: s}
+	for s != "" {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			f.parts = append(f.parts, formatPart{literal: s})
+			break
+		}
+
+		if i > 0 {
+			f.parts = append(f.parts, formatPart{literal: s[:i]})
+		}
+
+		s = s[i+2:]
+		end := strings.IndexRune(s, '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated access log field in format: %s", f.template)
+		}
+
+		field, err := newFormatField(strings.TrimSpace(s[:end]))
+		if err != nil {
+			return nil, err
+		}
+
+		f.parts = append(f.parts, formatPart{field: field})
+		f.fields = append(f.fields, field)
+		s = s[end+1:]
+	}
+
+	if len(f.fields) == 0 {
+		return nil, fmt.Errorf("no fields in access log format: %s", f.template)
+	}
+
+	return f, nil
+}
+
+// String returns the original template.
+func (f *AccessLogFormat) String() string {
+	return f.template
+}
+
+func formatValue(v interface{}) string {
+	switch vt := v.(type) {
+	case string:
+		return omitWhitespace(vt)
+	case int:
+		return strconv.Itoa(vt)
+	case int64:
+		return strconv.FormatInt(vt, 10)
+	default:
+		return omitWhitespace(fmt.Sprint(vt))
+	}
+}
+
+func (f *AccessLogFormat) render(e *AccessEntry, r *redaction) string {
+	var b strings.Builder
+	for _, p := range f.parts {
+		if p.field == nil {
+			b.WriteString(p.literal)
+			continue
+		}
+
+		b.WriteString(formatValue(p.field.value(e, r)))
+	}
+
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func (f *AccessLogFormat) data(e *AccessEntry, r *redaction) map[string]interface{} {
+	d := make(map[string]interface{}, len(f.fields))
+	for _, field := range f.fields {
+		d[field.name] = field.value(e, r)
+	}
+
+	return d
+}
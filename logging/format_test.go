@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFormattedEntry() *AccessEntry {
+	e := testAccessEntry()
+	e.Request.RequestURI = "/apache_pb.gif?token=secret&page=2"
+	e.Request.URL.RawQuery = "token=secret&page=2"
+	e.Request.Header.Set("Authorization", "Bearer secret")
+	e.Request.Header.Set("X-Tenant", "acme")
+	e.RouteID = "api"
+	e.Backend = "10.2.0.1:8080"
+	e.BackendDuration = 30 * time.Millisecond
+	e.RequestFiltersDuration = 2 * time.Millisecond
+	e.ResponseFiltersDuration = time.Millisecond
+	e.StateBag = map[string]interface{}{"auth:clientid": "acme-client", "count": 3}
+	e.ResponseHeader = http.Header{"Set-Cookie": []string{"session=secret"}, "Content-Type": []string{"image/gif"}}
+	return e
+}
+
+func TestParseAccessLogFormat(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"no fields",
+		"${unknown}",
+		"${status",
+		"${request.header.}",
+		"${statebag.}",
+	} {
+		if _, err := ParseAccessLogFormat(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+
+	f, err := ParseAccessLogFormat("${status} ${ route_id }")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.String() != "${status} ${ route_id }" {
+		t.Errorf("unexpected template: %s", f)
+	}
+}
+
+func TestAccessLogTemplate(t *testing.T) {
+	f, err := ParseAccessLogFormat(`${remote_host} "${method} ${uri}" ${status} ${route_id} ${backend} ` +
+		`${backend_duration}/${request_filters_duration}/${response_filters_duration} ` +
+		`${request.header.Authorization} ${request.header.X-Tenant} ${request.query.token} ${request.query.page} ` +
+		`${response.header.Set-Cookie} ${response.header.Content-Type} ${statebag.auth:clientid} ${statebag.count} ${referer}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testAccessLog(
+		t,
+		testFormattedEntry(),
+		`127.0.0.1 "GET /apache_pb.gif?token=%5BREDACTED%5D&page=2" 418 api 10.2.0.1:8080 30/2/1 `+
+			`[REDACTED] acme [REDACTED] 2 [REDACTED] image/gif acme-client 3 -`,
+		Options{AccessLogFormat: f, AccessLogRedactQueryParams: []string{"token"}},
+	)
+}
+
+func TestAccessLogTemplateJSON(t *testing.T) {
+	f, err := ParseAccessLogFormat("${status} ${duration} ${route_id} ${request.header.Authorization}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testAccessLogExtended(
+		t,
+		testFormattedEntry(),
+		map[string]interface{}{"extra": "extra"},
+		`{"duration":42,"extra":"extra","level":"info","msg":"","request.header.Authorization":"[REDACTED]","route_id":"api","status":418}`,
+		Options{AccessLogFormat: f, AccessLogJSONEnabled: true},
+	)
+}
+
+func TestAccessLogTemplatePerEntry(t *testing.T) {
+	f, err := ParseAccessLogFormat("${route_id} ${request.header.Authorization}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := testFormattedEntry()
+	e.Format = f
+	testAccessLog(t, e, "api Bearer secret", Options{AccessLogRedactHeaders: []string{}})
+}
+
+func TestAccessLogRedactDefaultFormat(t *testing.T) {
+	e := testAccessEntry()
+	e.Request.RequestURI = "/apache_pb.gif?access_token=secret"
+	testAccessLog(
+		t,
+		e,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?access_token=%5BREDACTED%5D HTTP/1.1" 418 2326 "-" "-" 42 example.com - -`,
+		Options{AccessLogRedactQueryParams: []string{"access_token"}},
+	)
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	defer func(f func() float64) { sample = f }(sample)
+
+	var next float64
+	sample = func() float64 { return next }
+
+	var buf bytes.Buffer
+	Init(Options{AccessLogOutput: &buf, AccessLogSampleRate: 0.5})
+
+	for _, next = range []float64{0.1, 0.7, 0.4, 0.5} {
+		LogAccess(testAccessEntry(), nil)
+	}
+
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("expected 2 sampled entries, got %d", n)
+	}
+
+	buf.Reset()
+	e := testAccessEntry()
+	e.SampleRate = 0.8
+	next = 0.7
+	LogAccess(e, nil)
+	if buf.Len() == 0 {
+		t.Error("expected the entry sample rate to override the global one")
+	}
+}
@@ -45,6 +45,38 @@ type Options struct {
 	// AccessLogJsonFormatter, when set and JSON logging is enabled, is passed along to to the underlying
 	// Logrus logger for access logs. To enable structured logging, use AccessLogJSONEnabled.
 	AccessLogJsonFormatter *logrus.JSONFormatter
+
+	// AccessLogFormat, when set, is used as the template of the access
+	// log entries instead of the Apache combined log format. See
+	// ParseAccessLogFormat.
+	AccessLogFormat *AccessLogFormat
+
+	// AccessLogSampleRate, when between 0 and 1, sets the ratio of the
+	// requests that are logged in the access log. Zero means that all
+	// the requests are logged.
+	AccessLogSampleRate float64
+
+	// AccessLogRedactHeaders lists the headers whose values are
+	// replaced in the access log. When nil, DefaultRedactedHeaders are
+	// used.
+	AccessLogRedactHeaders []string
+
+	// AccessLogRedactQueryParams lists the query params whose values
+	// are replaced in the access log.
+	AccessLogRedactQueryParams []string
+
+	// ApplicationLogSinks receive the application log entries in
+	// addition to the application log output, see NewLogSink.
+	ApplicationLogSinks []LogSink
+
+	// AccessLogSinks receive the access log entries in addition to
+	// the access log output, see NewLogSink.
+	AccessLogSinks []LogSink
+
+	// LogSinkBufferSize sets the number of entries buffered for each
+	// sink. When the buffer is full, the new entries are dropped.
+	// Defaults to DefaultLogSinkBufferSize.
+	LogSinkBufferSize int
 }
 
 func (f *prefixFormatter) Format(e *logrus.Entry) ([]byte, error) {
@@ -70,6 +102,8 @@ func initApplicationLog(o Options) {
 	if o.ApplicationLogOutput != nil {
 		logrus.SetOutput(o.ApplicationLogOutput)
 	}
+
+	addSinkHooks(logrus.StandardLogger(), "application", o.ApplicationLogSinks, o.LogSinkBufferSize)
 }
 
 func initAccessLog(o Options) {
@@ -85,8 +119,17 @@ func initAccessLog(o Options) {
 	}
 	l.Out = o.AccessLogOutput
 	l.Level = logrus.InfoLevel
+	addSinkHooks(l, "access", o.AccessLogSinks, o.LogSinkBufferSize)
 	accessLog = l
 	stripQuery = o.AccessLogStripQuery
+	format = o.AccessLogFormat
+	sampleRate = o.AccessLogSampleRate
+	redactHeaders := o.AccessLogRedactHeaders
+	if redactHeaders == nil {
+		redactHeaders = DefaultRedactedHeaders
+	}
+
+	redact = newRedaction(redactHeaders, o.AccessLogRedactQueryParams)
 }
 
 // Initializes logging.
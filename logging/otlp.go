@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const otlpLogsPath = "/v1/logs"
+
+// otlpSink exports the log entries with the OTLP/HTTP protocol, using
+// the JSON encoding.
+type otlpSink struct {
+	endpoint string
+	service  string
+	name     string
+	client   *http.Client
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string          `json:"timeUnixNano"`
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 otlpValue       `json:"body"`
+	Attributes           []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpScopeLogs struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+func newOTLPSink(u *url.URL, o LogSinkOptions) (*otlpSink, error) {
+	q := u.Query()
+	service := q.Get("service")
+	if service == "" {
+		service = "skipper"
+	}
+
+	endpoint := *u
+	endpoint.Scheme = u.Scheme[len("otlp+"):]
+	endpoint.RawQuery = ""
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = otlpLogsPath
+	}
+
+	return &otlpSink{
+		endpoint: endpoint.String(),
+		service:  service,
+		name:     o.Name,
+		client:   &http.Client{Timeout: o.Timeout},
+	}, nil
+}
+
+// severity numbers as defined by the OpenTelemetry log data model
+func otlpSeverity(l logrus.Level) int {
+	switch l {
+	case logrus.TraceLevel:
+		return 1
+	case logrus.DebugLevel:
+		return 5
+	case logrus.InfoLevel:
+		return 9
+	case logrus.WarnLevel:
+		return 13
+	case logrus.ErrorLevel:
+		return 17
+	case logrus.FatalLevel:
+		return 21
+	default:
+		return 24
+	}
+}
+
+func (s *otlpSink) request(records []LogRecord) *otlpLogsRequest {
+	var scope otlpScopeLogs
+	scope.Scope.Name = "skipper"
+	scope.LogRecords = make([]otlpLogRecord, len(records))
+	for i, r := range records {
+		ts := strconv.FormatInt(r.Time.UnixNano(), 10)
+		scope.LogRecords[i] = otlpLogRecord{
+			TimeUnixNano:         ts,
+			ObservedTimeUnixNano: ts,
+			SeverityNumber:       otlpSeverity(r.Level),
+			SeverityText:         r.Level.String(),
+			Body:                 otlpValue{StringValue: string(r.Message)},
+		}
+
+		if s.name != "" {
+			scope.LogRecords[i].Attributes = []otlpAttribute{{Key: "log.name", Value: otlpValue{StringValue: s.name}}}
+		}
+	}
+
+	var resource otlpResourceLogs
+	resource.Resource.Attributes = []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: s.service}}}
+	resource.ScopeLogs = []otlpScopeLogs{scope}
+	return &otlpLogsRequest{ResourceLogs: []otlpResourceLogs{resource}}
+}
+
+func (s *otlpSink) Send(records []LogRecord) error {
+	b, err := json.Marshal(s.request(records))
+	if err != nil {
+		return err
+	}
+
+	rsp, err := s.client.Post(s.endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+	_, _ = io.Copy(io.Discard, rsp.Body)
+	if rsp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to export logs: %s", rsp.Status)
+	}
+
+	return nil
+}
+
+func (s *otlpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,251 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/metrics"
+)
+
+const (
+	// DefaultLogSinkBufferSize is the default number of log entries
+	// buffered for a sink, before the new entries are dropped.
+	DefaultLogSinkBufferSize = 4096
+
+	maxLogSinkBatch     = 512
+	logSinkMetricPrefix = "logsink."
+)
+
+// LogRecord is a formatted log entry passed to the log sinks.
+type LogRecord struct {
+	Time    time.Time
+	Level   logrus.Level
+	Message []byte
+}
+
+// LogSink ships the log entries to an external system, in addition to
+// the log output. The sinks are called asynchronously, in batches,
+// never blocking the logging code.
+type LogSink interface {
+
+	// Send ships a batch of log records. The records must not be
+	// retained after Send returns.
+	Send([]LogRecord) error
+
+	// Close releases the resources of the sink.
+	Close() error
+}
+
+// LogSinkOptions are used when creating the sinks with NewLogSink.
+type LogSinkOptions struct {
+
+	// Name identifies the log in the shipped entries and in the
+	// metrics, e.g. access or application.
+	Name string
+
+	// Timeout of connecting and sending to the sink. Defaults to 5
+	// seconds.
+	Timeout time.Duration
+}
+
+// NewLogSink creates a log sink from a URL. The supported schemes are:
+//
+//	syslog://host:514        RFC 5424 syslog over UDP
+//	syslog+tcp://host:514    RFC 5424 syslog over TCP, with octet counting framing
+//	syslog+tls://host:6514   RFC 5424 syslog over TLS, with octet counting framing
+//	otlp+http://host:4318    OTLP logs over HTTP with JSON encoding
+//	otlp+https://host:4318   OTLP logs over HTTPS with JSON encoding
+//
+// The syslog sinks accept the facility and tag query parameters, e.g.
+// syslog://localhost:514?facility=local1&tag=skipper. The OTLP sinks use
+// the /v1/logs path, when no path is set, and accept the service query
+// parameter setting the service.name resource attribute.
+func NewLogSink(rawURL string, o LogSinkOptions) (LogSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log sink URL: %w", err)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid log sink URL, missing host: %s", rawURL)
+	}
+
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+
+	switch u.Scheme {
+	case "syslog", "syslog+udp":
+		return newSyslogSink("udp", u, o)
+	case "syslog+tcp":
+		return newSyslogSink("tcp", u, o)
+	case "syslog+tls":
+		return newSyslogSink("tls", u, o)
+	case "otlp+http", "otlp+https":
+		return newOTLPSink(u, o)
+	default:
+		return nil, fmt.Errorf("unsupported log sink: %s", rawURL)
+	}
+}
+
+// sinkHook passes the log entries to a sink through a bounded queue.
+// When the queue is full, the entries are dropped and counted in the
+// logsink.<name>.dropped metric. The entries that the sink failed to
+// send are counted in logsink.<name>.failed.
+type sinkHook struct {
+	name    string
+	sink    LogSink
+	queue   chan LogRecord
+	metrics metrics.Metrics
+	quit    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newSinkHook(name string, sink LogSink, bufferSize int) *sinkHook {
+	if bufferSize <= 0 {
+		bufferSize = DefaultLogSinkBufferSize
+	}
+
+	h := &sinkHook{
+		name:  name,
+		sink:  sink,
+		queue: make(chan LogRecord, bufferSize),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go h.run()
+	return h
+}
+
+func (h *sinkHook) getMetrics() metrics.Metrics {
+	if h.metrics != nil {
+		return h.metrics
+	}
+
+	return metrics.Default
+}
+
+func (h *sinkHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *sinkHook) Fire(e *logrus.Entry) error {
+	select {
+	case <-h.quit:
+		return nil
+	default:
+	}
+
+	b, err := e.Logger.Formatter.Format(e)
+	if err != nil {
+		return err
+	}
+
+	r := LogRecord{Time: e.Time, Level: e.Level, Message: bytes.TrimRight(b, "\n")}
+	select {
+	case h.queue <- r:
+	default:
+		h.getMetrics().IncCounter(logSinkMetricPrefix + h.name + ".dropped")
+	}
+
+	return nil
+}
+
+func (h *sinkHook) send(batch []LogRecord) {
+	if err := h.sink.Send(batch); err != nil {
+		h.getMetrics().IncCounterBy(logSinkMetricPrefix+h.name+".failed", int64(len(batch)))
+	}
+}
+
+func (h *sinkHook) run() {
+	defer close(h.done)
+	batch := make([]LogRecord, 0, maxLogSinkBatch)
+	for {
+		select {
+		case r := <-h.queue:
+			batch = append(batch[:0], r)
+		collect:
+			for len(batch) < maxLogSinkBatch {
+				select {
+				case r := <-h.queue:
+					batch = append(batch, r)
+				default:
+					break collect
+				}
+			}
+
+			h.send(batch)
+		case <-h.quit:
+			batch = batch[:0]
+			for {
+				select {
+				case r := <-h.queue:
+					batch = append(batch, r)
+					if len(batch) == maxLogSinkBatch {
+						h.send(batch)
+						batch = batch[:0]
+					}
+				default:
+					if len(batch) > 0 {
+						h.send(batch)
+					}
+
+					return
+				}
+			}
+		}
+	}
+}
+
+// close sends the buffered entries, and closes the sink.
+func (h *sinkHook) close() error {
+	h.once.Do(func() { close(h.quit) })
+	<-h.done
+	return h.sink.Close()
+}
+
+var (
+	sinkHooksMu sync.Mutex
+	sinkHooks   []*sinkHook
+)
+
+func addSinkHooks(l *logrus.Logger, name string, sinks []LogSink, bufferSize int) {
+	sinkHooksMu.Lock()
+	defer sinkHooksMu.Unlock()
+	for i, s := range sinks {
+		hookName := name
+		if len(sinks) > 1 {
+			hookName += "." + strconv.Itoa(i)
+		}
+
+		h := newSinkHook(hookName, s, bufferSize)
+		l.AddHook(h)
+		sinkHooks = append(sinkHooks, h)
+	}
+}
+
+// CloseSinks sends the buffered log entries to the sinks, and closes
+// them. Logging to the sinks stops after CloseSinks returns.
+func CloseSinks() error {
+	sinkHooksMu.Lock()
+	hooks := sinkHooks
+	sinkHooks = nil
+	sinkHooksMu.Unlock()
+
+	var lastErr error
+	for _, h := range hooks {
+		if err := h.close(); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
@@ -0,0 +1,319 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/zalando/skipper/metrics/metricstest"
+)
+
+type testSink struct {
+	mu      sync.Mutex
+	records []LogRecord
+	block   chan struct{}
+	err     error
+	closed  bool
+}
+
+func (s *testSink) Send(records []LogRecord) error {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range records {
+		r.Message = append([]byte(nil), r.Message...)
+		s.records = append(s.records, r)
+	}
+
+	return s.err
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var m []string
+	for _, r := range s.records {
+		m = append(m, string(r.Message))
+	}
+
+	return m
+}
+
+func TestLogSinkHook(t *testing.T) {
+	sink := &testSink{}
+	l := logrus.New()
+	l.Out = io.Discard
+	l.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+
+	h := newSinkHook("test", sink, 0)
+	l.AddHook(h)
+
+	l.Info("foo")
+	l.Warn("bar")
+	if err := h.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := sink.messages()
+	if len(m) != 2 || m[0] != `level=info msg=foo` || m[1] != `level=warning msg=bar` {
+		t.Errorf("unexpected messages: %q", m)
+	}
+
+	if !sink.closed {
+		t.Error("sink not closed")
+	}
+
+	l.Info("baz")
+	if len(sink.messages()) != 2 {
+		t.Error("unexpected message after close")
+	}
+}
+
+func TestLogSinkDropsWhenFull(t *testing.T) {
+	sink := &testSink{block: make(chan struct{})}
+	m := &metricstest.MockMetrics{}
+	l := logrus.New()
+	l.Out = io.Discard
+
+	h := newSinkHook("test", sink, 2)
+	h.metrics = m
+	l.AddHook(h)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			l.Info("entry")
+		}
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("logging blocked by the sink")
+	}
+
+	close(sink.block)
+	h.close()
+
+	var dropped int64
+	m.WithCounters(func(c map[string]int64) { dropped = c["logsink.test.dropped"] })
+	if dropped == 0 || dropped+int64(len(sink.messages())) != 10 {
+		t.Errorf("unexpected drops: %d, sent: %d", dropped, len(sink.messages()))
+	}
+}
+
+func TestLogSinkFailures(t *testing.T) {
+	sink := &testSink{err: io.ErrClosedPipe}
+	m := &metricstest.MockMetrics{}
+	l := logrus.New()
+	l.Out = io.Discard
+
+	h := newSinkHook("test", sink, 0)
+	h.metrics = m
+	l.AddHook(h)
+
+	l.Info("entry")
+	h.close()
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["logsink.test.failed"] != 1 {
+			t.Errorf("unexpected failure count: %d", c["logsink.test.failed"])
+		}
+	})
+}
+
+func TestNewLogSink(t *testing.T) {
+	for _, u := range []string{
+		"",
+		"syslog://",
+		"file:///var/log/skipper",
+		"kafka://localhost:9092",
+		"syslog://localhost:514?facility=unknown",
+	} {
+		if _, err := NewLogSink(u, LogSinkOptions{}); err == nil {
+			t.Errorf("%q: expected error", u)
+		}
+	}
+
+	s, err := NewLogSink("otlp+https://collector.example.org?service=ingress", LogSinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o := s.(*otlpSink); o.endpoint != "https://collector.example.org/v1/logs" || o.service != "ingress" {
+		t.Errorf("unexpected otlp sink: %s %s", o.endpoint, o.service)
+	}
+}
+
+func testRecords() []LogRecord {
+	ts := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	return []LogRecord{
+		{Time: ts, Level: logrus.InfoLevel, Message: []byte("first")},
+		{Time: ts, Level: logrus.ErrorLevel, Message: []byte("second")},
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	s, err := NewLogSink("syslog://"+conn.LocalAddr().String()+"?facility=local1&tag=ingress", LogSinkOptions{Name: "access"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+	if err := s.Send(testRecords()); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	for _, expected := range []struct{ pri, msg string }{{"<142>", "first"}, {"<139>", "second"}} {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := string(buf[:n])
+		if !strings.HasPrefix(m, expected.pri+"1 2026-10-19T12:00:00.000000Z ") ||
+			!strings.Contains(m, " ingress "+s.(*syslogSink).procID+" access - ") ||
+			!strings.HasSuffix(m, expected.msg) {
+			t.Errorf("unexpected message: %s", m)
+		}
+	}
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var messages []string
+		for len(messages) < 2 {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			m := make([]byte, n)
+			if _, err := io.ReadFull(r, m); err != nil {
+				return
+			}
+
+			messages = append(messages, string(m))
+		}
+
+		received <- messages
+	}()
+
+	s, err := NewLogSink("syslog+tcp://"+l.Addr().String(), LogSinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+	if err := s.Send(testRecords()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-received:
+		if !strings.HasPrefix(m[0], "<134>1 ") || !strings.HasSuffix(m[0], " skipper "+s.(*syslogSink).procID+" - - first") {
+			t.Errorf("unexpected message: %s", m[0])
+		}
+
+		if !strings.HasSuffix(m[1], "second") {
+			t.Errorf("unexpected message: %s", m[1])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestOTLPSink(t *testing.T) {
+	var received otlpLogsRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		if err := json.NewDecoder(bytes.NewReader(b)).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := NewLogSink("otlp+"+srv.URL, LogSinkOptions{Name: "access"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+	if err := s.Send(testRecords()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received.ResourceLogs) != 1 || len(received.ResourceLogs[0].ScopeLogs) != 1 {
+		t.Fatalf("unexpected request: %+v", received)
+	}
+
+	rl := received.ResourceLogs[0]
+	if rl.Resource.Attributes[0].Key != "service.name" || rl.Resource.Attributes[0].Value.StringValue != "skipper" {
+		t.Errorf("unexpected resource: %+v", rl.Resource)
+	}
+
+	records := rl.ScopeLogs[0].LogRecords
+	if len(records) != 2 ||
+		records[0].Body.StringValue != "first" || records[0].SeverityNumber != 9 ||
+		records[1].Body.StringValue != "second" || records[1].SeverityNumber != 17 ||
+		records[0].TimeUnixNano != strconv.FormatInt(testRecords()[0].Time.UnixNano(), 10) ||
+		records[0].Attributes[0].Value.StringValue != "access" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+
+	srv.Close()
+	if err := s.Send(testRecords()); err == nil {
+		t.Error("expected error")
+	}
+}
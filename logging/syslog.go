@@ -0,0 +1,186 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSink sends the log entries in RFC 5424 format. Over UDP, every
+// entry is sent in a separate datagram, while over TCP and TLS, the
+// entries are framed with octet counting, as described in RFC 6587.
+type syslogSink struct {
+	network  string
+	address  string
+	tls      *tls.Config
+	timeout  time.Duration
+	facility int
+	hostname string
+	tag      string
+	msgID    string
+	procID   string
+	conn     net.Conn
+	buf      bytes.Buffer
+}
+
+func newSyslogSink(network string, u *url.URL, o LogSinkOptions) (*syslogSink, error) {
+	q := u.Query()
+
+	facility := syslogFacilities["local0"]
+	if f := q.Get("facility"); f != "" {
+		var ok bool
+		if facility, ok = syslogFacilities[f]; !ok {
+			return nil, fmt.Errorf("invalid syslog facility: %s", f)
+		}
+	}
+
+	tag := q.Get("tag")
+	if tag == "" {
+		tag = "skipper"
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	msgID := o.Name
+	if msgID == "" {
+		msgID = "-"
+	}
+
+	s := &syslogSink{
+		network:  network,
+		address:  u.Host,
+		timeout:  o.Timeout,
+		facility: facility,
+		hostname: hostname,
+		tag:      tag,
+		msgID:    msgID,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+
+	if network == "tls" {
+		s.network = "tcp"
+		s.tls = &tls.Config{ServerName: u.Hostname()}
+	}
+
+	return s, nil
+}
+
+func syslogSeverity(l logrus.Level) int {
+	switch l {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+func (s *syslogSink) format(r LogRecord) []byte {
+	return []byte(fmt.Sprintf(
+		"<%d>1 %s %s %s %s %s - %s",
+		s.facility*8+syslogSeverity(r.Level),
+		r.Time.UTC().Format(syslogTimeFormat),
+		s.hostname,
+		s.tag,
+		s.procID,
+		s.msgID,
+		r.Message,
+	))
+}
+
+func (s *syslogSink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+
+	d := &net.Dialer{Timeout: s.timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if s.tls != nil {
+		conn, err = tls.DialWithDialer(d, s.network, s.address, s.tls)
+	} else {
+		conn, err = d.Dial(s.network, s.address)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	return nil
+}
+
+func (s *syslogSink) Send(records []LogRecord) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+
+	var err error
+	if s.network == "udp" {
+		for _, r := range records {
+			if _, werr := s.conn.Write(s.format(r)); werr != nil {
+				err = werr
+			}
+		}
+	} else {
+		s.buf.Reset()
+		for _, r := range records {
+			m := s.format(r)
+			s.buf.WriteString(strconv.Itoa(len(m)))
+			s.buf.WriteByte(' ')
+			s.buf.Write(m)
+		}
+
+		_, err = s.conn.Write(s.buf.Bytes())
+	}
+
+	if err != nil {
+		// reconnect on the next batch
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return err
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
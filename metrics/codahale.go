@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rcrowley/go-metrics"
@@ -67,18 +68,41 @@ func NewCodaHale(o Options) *CodaHale {
 	c.options = o
 
 	if o.EnableDebugGcMetrics {
-		metrics.RegisterDebugGCStats(c.reg)
-		go metrics.CaptureDebugGCStats(c.reg, statsRefreshDuration)
+		registerShared(c.reg, debugGCStats, &debugGCStatsOnce, metrics.RegisterDebugGCStats, metrics.CaptureDebugGCStats)
 	}
 
 	if o.EnableRuntimeMetrics {
-		metrics.RegisterRuntimeMemStats(c.reg)
-		go metrics.CaptureRuntimeMemStats(c.reg, statsRefreshDuration)
+		registerShared(c.reg, runtimeMemStats, &runtimeMemStatsOnce, metrics.RegisterRuntimeMemStats, metrics.CaptureRuntimeMemStats)
 	}
 
 	return c
 }
 
+// The go-metrics library registers the debug GC and the runtime stats only in the first registry of the
+// process, so they are registered in separate registries, and shared with every CodaHale instance.
+var (
+	debugGCStats        = metrics.NewRegistry()
+	debugGCStatsOnce    sync.Once
+	runtimeMemStats     = metrics.NewRegistry()
+	runtimeMemStatsOnce sync.Once
+)
+
+func registerShared(
+	r, shared metrics.Registry,
+	once *sync.Once,
+	register func(metrics.Registry),
+	capture func(metrics.Registry, time.Duration),
+) {
+	once.Do(func() {
+		register(shared)
+		go capture(shared, statsRefreshDuration)
+	})
+
+	shared.Each(func(name string, m interface{}) {
+		r.Register(name, m)
+	})
+}
+
 func NewVoid() *CodaHale {
 	c := &CodaHale{}
 	c.reg = metrics.NewRegistry()
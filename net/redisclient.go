@@ -2,8 +2,10 @@ package net
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -23,11 +25,31 @@ import (
 
 // RedisOptions is used to configure the redis.Ring
 type RedisOptions struct {
-	// Addrs are the list of redis shards
+	// Addrs are the list of redis shards. In sentinel mode, these are
+	// the addresses of the sentinels, and in cluster mode, the seed
+	// addresses of the cluster nodes.
 	Addrs []string
+	// Username is the ACL username used together with the Password,
+	// e.g. for Redis 6 ACLs
+	Username string
 	// Password is the password needed to connect to Redis server
 	Password string
 
+	// TLSConfig, when set, enables TLS for the connections to Redis
+	TLSConfig *tls.Config
+
+	// Mode is one of ring, sentinel or cluster, defaults to ring. In
+	// ring mode, the client shards the keys across the Addrs by
+	// consistent hashing. In sentinel mode, the client connects to the
+	// master discovered by the sentinels in Addrs. In cluster mode, the
+	// client uses Redis Cluster.
+	Mode string
+	// SentinelMasterName is the name of the master in sentinel mode
+	SentinelMasterName string
+	// SentinelPassword is the password of the sentinels, when it
+	// differs from Password
+	SentinelPassword string
+
 	// ReadTimeout for redis socket reads
 	ReadTimeout time.Duration
 	// WriteTimeout for redis socket writes
@@ -63,17 +85,43 @@ type RedisOptions struct {
 	// Log is the logger that is used
 	Log logging.Logger
 
-	// HashAlgorithm is one of rendezvous, rendezvousVnodes, jump, mpchash, defaults to github.com/go-redis/redis default
+	// HashAlgorithm is one of rendezvous, rendezvousVnodes, jump, mpchash, defaults to github.com/go-redis/redis default,
+	// used only in ring mode
 	HashAlgorithm string
+
+	// AddrUpdater, when set, is used to discover the redis shards
+	// instead of the static Addrs, e.g. from the endpoints of a
+	// Kubernetes service, from DNS SRV records or from a remote URL.
+	// It is called initially, and then every UpdateInterval. The
+	// discovered shards are identified by their address, so that a
+	// change of the ring membership moves only the keys of the added
+	// or removed shards, when using the rendezvous, rendezvousVnodes
+	// or mpchash hash algorithm. It is used only in ring mode.
+	AddrUpdater func() ([]string, error)
+	// UpdateInterval is the interval of the shard discovery with the
+	// AddrUpdater, defaults to 10 seconds.
+	UpdateInterval time.Duration
+}
+
+// the common interface of redis.Ring, redis.Client and
+// redis.ClusterClient used by the RedisRingClient
+type redisClient interface {
+	redis.UniversalClient
+	PoolStats() *redis.PoolStats
 }
 
 // RedisRingClient is a redis client that does access redis by
-// computing a ring hash. It logs to the logging.Logger interface,
-// that you can pass. It adds metrics and operations are traced with
-// opentracing. You can set timeouts and the defaults are set to be ok
-// to be in the hot path of low latency production requests.
+// computing a ring hash, or, depending on the Mode of the RedisOptions,
+// via the sentinels or as a Redis Cluster client. It logs to the
+// logging.Logger interface, that you can pass. It adds metrics and
+// operations are traced with opentracing. You can set timeouts and the
+// defaults are set to be ok to be in the hot path of low latency
+// production requests.
 type RedisRingClient struct {
-	ring          *redis.Ring
+	mu            sync.RWMutex
+	ring          redisClient
+	ringOptions   *redis.RingOptions
+	shards        []string
 	log           logging.Logger
 	metrics       metrics.Metrics
 	metricsPrefix string
@@ -101,6 +149,14 @@ const (
 	DefaultMaxConns = 100
 
 	defaultConnMetricsInterval = 60 * time.Second
+	defaultUpdateInterval      = 10 * time.Second
+)
+
+// Redis client modes
+const (
+	RedisModeRing     = "ring"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
 )
 
 // https://arxiv.org/pdf/1406.2294.pdf
@@ -204,16 +260,15 @@ func NewRedisRingClient(ro *RedisOptions) *RedisRingClient {
 			ringOptions.NewConsistentHash = NewMultiprobe
 		}
 
-		for idx, addr := range ro.Addrs {
-			ringOptions.Addrs[fmt.Sprintf("redis%d", idx)] = addr
-		}
 		ringOptions.ReadTimeout = ro.ReadTimeout
 		ringOptions.WriteTimeout = ro.WriteTimeout
 		ringOptions.PoolTimeout = ro.PoolTimeout
 		ringOptions.DialTimeout = ro.DialTimeout
 		ringOptions.MinIdleConns = ro.MinIdleConns
 		ringOptions.PoolSize = ro.MaxIdleConns
+		ringOptions.Username = ro.Username
 		ringOptions.Password = ro.Password
+		ringOptions.TLSConfig = ro.TLSConfig
 
 		if ro.ConnMetricsInterval <= 0 {
 			ro.ConnMetricsInterval = defaultConnMetricsInterval
@@ -222,19 +277,98 @@ func NewRedisRingClient(ro *RedisOptions) *RedisRingClient {
 			r.tracer = ro.Tracer
 		}
 
+		if ro.Log == nil {
+			ro.Log = &logging.DefaultLog{}
+		}
+
 		r.options = ro
-		r.ring = redis.NewRing(ringOptions)
 		r.log = ro.Log
 		r.metricsPrefix = ro.MetricsPrefix
+		r.ringOptions = ringOptions
+
+		switch ro.Mode {
+		case RedisModeSentinel:
+			r.ring = redis.NewFailoverClient(failoverOptions(ro))
+		case RedisModeCluster:
+			r.ring = redis.NewClusterClient(clusterOptions(ro))
+		case "", RedisModeRing:
+			r.ring = r.newRing(ro)
+		default:
+			r.log.Errorf("Unknown redis mode %q, using ring", ro.Mode)
+			r.ring = r.newRing(ro)
+		}
 	}
 
 	return r
 }
 
+func (r *RedisRingClient) newRing(ro *RedisOptions) *redis.Ring {
+	if ro.AddrUpdater != nil {
+		if ro.UpdateInterval <= 0 {
+			ro.UpdateInterval = defaultUpdateInterval
+		}
+
+		addrs, err := ro.AddrUpdater()
+		if err != nil || len(addrs) == 0 {
+			r.log.Errorf("Failed to discover redis shards, using the static addresses until the next update: %v", err)
+			addrs = ro.Addrs
+		}
+
+		r.shards = sortedShards(addrs)
+		r.metrics.UpdateGauge(r.metricsPrefix+"shards", float64(len(r.shards)))
+		go r.startUpdater()
+		return redis.NewRing(r.ringOptionsForShards(addrs))
+	}
+
+	for idx, addr := range ro.Addrs {
+		r.ringOptions.Addrs[fmt.Sprintf("redis%d", idx)] = addr
+	}
+
+	return redis.NewRing(r.ringOptions)
+}
+
+func failoverOptions(ro *RedisOptions) *redis.FailoverOptions {
+	return &redis.FailoverOptions{
+		MasterName:       ro.SentinelMasterName,
+		SentinelAddrs:    ro.Addrs,
+		SentinelPassword: ro.SentinelPassword,
+		Username:         ro.Username,
+		Password:         ro.Password,
+		DialTimeout:      ro.DialTimeout,
+		ReadTimeout:      ro.ReadTimeout,
+		WriteTimeout:     ro.WriteTimeout,
+		PoolTimeout:      ro.PoolTimeout,
+		MinIdleConns:     ro.MinIdleConns,
+		PoolSize:         ro.MaxIdleConns,
+		TLSConfig:        ro.TLSConfig,
+	}
+}
+
+func clusterOptions(ro *RedisOptions) *redis.ClusterOptions {
+	return &redis.ClusterOptions{
+		Addrs:        ro.Addrs,
+		Username:     ro.Username,
+		Password:     ro.Password,
+		DialTimeout:  ro.DialTimeout,
+		ReadTimeout:  ro.ReadTimeout,
+		WriteTimeout: ro.WriteTimeout,
+		PoolTimeout:  ro.PoolTimeout,
+		MinIdleConns: ro.MinIdleConns,
+		PoolSize:     ro.MaxIdleConns,
+		TLSConfig:    ro.TLSConfig,
+	}
+}
+
+func (r *RedisRingClient) getRing() redisClient {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ring
+}
+
 func (r *RedisRingClient) RingAvailable() bool {
 	var err error
 	err = backoff.Retry(func() error {
-		_, err = r.ring.Ping(context.Background()).Result()
+		_, err = r.getRing().Ping(context.Background()).Result()
 		if err != nil {
 			r.log.Infof("Failed to ping redis, retry with backoff: %v", err)
 		}
@@ -249,7 +383,7 @@ func (r *RedisRingClient) StartMetricsCollection() {
 		for {
 			select {
 			case <-time.After(r.options.ConnMetricsInterval):
-				stats := r.ring.PoolStats()
+				stats := r.getRing().PoolStats()
 				// counter values
 				r.metrics.UpdateGauge(r.metricsPrefix+"hits", float64(stats.Hits))
 				r.metrics.UpdateGauge(r.metricsPrefix+"misses", float64(stats.Misses))
@@ -278,37 +412,37 @@ func (r *RedisRingClient) Close() {
 }
 
 func (r *RedisRingClient) Get(ctx context.Context, key string) (string, error) {
-	res := r.ring.Get(ctx, key)
+	res := r.getRing().Get(ctx, key)
 	return res.Val(), res.Err()
 }
 
 func (r *RedisRingClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) (string, error) {
-	res := r.ring.Set(ctx, key, value, expiration)
+	res := r.getRing().Set(ctx, key, value, expiration)
 	return res.Result()
 }
 
 func (r *RedisRingClient) ZAdd(ctx context.Context, key string, val int64, score float64) (int64, error) {
-	res := r.ring.ZAdd(ctx, key, &redis.Z{Member: val, Score: score})
+	res := r.getRing().ZAdd(ctx, key, &redis.Z{Member: val, Score: score})
 	return res.Val(), res.Err()
 }
 
 func (r *RedisRingClient) ZRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
-	res := r.ring.ZRem(ctx, key, members...)
+	res := r.getRing().ZRem(ctx, key, members...)
 	return res.Val(), res.Err()
 }
 
 func (r *RedisRingClient) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
-	res := r.ring.Expire(ctx, key, expiration)
+	res := r.getRing().Expire(ctx, key, expiration)
 	return res.Val(), res.Err()
 }
 
 func (r *RedisRingClient) ZRemRangeByScore(ctx context.Context, key string, min, max float64) (int64, error) {
-	res := r.ring.ZRemRangeByScore(ctx, key, fmt.Sprint(min), fmt.Sprint(max))
+	res := r.getRing().ZRemRangeByScore(ctx, key, fmt.Sprint(min), fmt.Sprint(max))
 	return res.Val(), res.Err()
 }
 
 func (r *RedisRingClient) ZCard(ctx context.Context, key string) (int64, error) {
-	res := r.ring.ZCard(ctx, key)
+	res := r.getRing().ZCard(ctx, key)
 	return res.Val(), res.Err()
 }
 
@@ -319,7 +453,7 @@ func (r *RedisRingClient) ZRangeByScoreWithScoresFirst(ctx context.Context, key
 		Offset: offset,
 		Count:  count,
 	}
-	res := r.ring.ZRangeByScoreWithScores(ctx, key, opt)
+	res := r.getRing().ZRangeByScoreWithScores(ctx, key, opt)
 	zs, err := res.Result()
 	if err != nil {
 		return nil, err
@@ -336,5 +470,5 @@ func (r *RedisRingClient) NewScript(source string) *RedisScript {
 }
 
 func (r *RedisRingClient) RunScript(ctx context.Context, s *RedisScript, keys []string, args ...interface{}) (interface{}, error) {
-	return s.script.Run(ctx, r.ring, keys, args...).Result()
+	return s.script.Run(ctx, r.getRing(), keys, args...).Result()
 }
@@ -0,0 +1,180 @@
+package net
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RemoteRedisEndpoints is the format of the response expected from the
+// remote URL used for the redis shard discovery, e.g:
+//
+//	{"endpoints": [{"address": "10.2.0.1:6379"}, {"address": "10.2.0.2:6379"}]}
+type RemoteRedisEndpoints struct {
+	Endpoints []RemoteRedisEndpoint `json:"endpoints"`
+}
+
+type RemoteRedisEndpoint struct {
+	Address string `json:"address"`
+}
+
+// used in the tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+func sortedShards(addrs []string) []string {
+	shards := make([]string, 0, len(addrs))
+	seen := make(map[string]bool)
+	for _, a := range addrs {
+		if a == "" || seen[a] {
+			continue
+		}
+
+		seen[a] = true
+		shards = append(shards, a)
+	}
+
+	sort.Strings(shards)
+	return shards
+}
+
+// returns the shards of next that are not in current
+func diffShards(current, next []string) []string {
+	m := make(map[string]bool)
+	for _, s := range current {
+		m[s] = true
+	}
+
+	var diff []string
+	for _, s := range next {
+		if !m[s] {
+			diff = append(diff, s)
+		}
+	}
+
+	return diff
+}
+
+// the discovered shards are named by their address, so that the name of
+// a shard doesn't depend on the other shards in the ring
+func (r *RedisRingClient) ringOptionsForShards(shards []string) *redis.RingOptions {
+	o := *r.ringOptions
+	o.Addrs = make(map[string]string)
+	for _, s := range shards {
+		o.Addrs[s] = s
+	}
+
+	return &o
+}
+
+func (r *RedisRingClient) startUpdater() {
+	ticker := time.NewTicker(r.options.UpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.updateShards()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// updateShards replaces the ring, when the discovered shards changed. The
+// previous ring is closed with a delay, to let the pending commands
+// finish.
+func (r *RedisRingClient) updateShards() {
+	addrs, err := r.options.AddrUpdater()
+	if err != nil {
+		r.log.Errorf("Failed to discover redis shards: %v", err)
+		r.metrics.IncCounter(r.metricsPrefix + "shards.discovery.errors")
+		return
+	}
+
+	next := sortedShards(addrs)
+	if len(next) == 0 {
+		r.log.Errorf("Failed to discover redis shards: no shards found, keeping the current ones")
+		r.metrics.IncCounter(r.metricsPrefix + "shards.discovery.errors")
+		return
+	}
+
+	r.mu.Lock()
+	added, removed := diffShards(r.shards, next), diffShards(next, r.shards)
+	if len(added) == 0 && len(removed) == 0 {
+		r.mu.Unlock()
+		return
+	}
+
+	previous := r.ring
+	r.ring = redis.NewRing(r.ringOptionsForShards(next))
+	r.shards = next
+	r.mu.Unlock()
+
+	r.log.Infof("Redis ring shards changed, added: %v, removed: %v", added, removed)
+	r.metrics.UpdateGauge(r.metricsPrefix+"shards", float64(len(next)))
+	r.metrics.IncCounterBy(r.metricsPrefix+"shards.added", int64(len(added)))
+	r.metrics.IncCounterBy(r.metricsPrefix+"shards.removed", int64(len(removed)))
+
+	time.AfterFunc(r.options.UpdateInterval, func() { previous.Close() })
+}
+
+// NewRedisSRVUpdater returns an AddrUpdater, that discovers the redis
+// shards from the DNS SRV records of the provided name, e.g.
+// _redis._tcp.redis.example.org.
+func NewRedisSRVUpdater(name string) func() ([]string, error) {
+	return func() ([]string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, records, err := lookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+
+		var addrs []string
+		for _, r := range records {
+			host := strings.TrimSuffix(r.Target, ".")
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+		}
+
+		return addrs, nil
+	}
+}
+
+// NewRedisRemoteUpdater returns an AddrUpdater, that discovers the redis
+// shards from a remote URL, responding with the RemoteRedisEndpoints
+// JSON format.
+func NewRedisRemoteUpdater(url string) func() ([]string, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func() ([]string, error) {
+		rsp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		defer rsp.Body.Close()
+		if rsp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to get redis endpoints from %s: %s", url, rsp.Status)
+		}
+
+		var endpoints RemoteRedisEndpoints
+		if err := json.NewDecoder(rsp.Body).Decode(&endpoints); err != nil {
+			return nil, fmt.Errorf("failed to decode redis endpoints from %s: %w", url, err)
+		}
+
+		var addrs []string
+		for _, ep := range endpoints.Endpoints {
+			addrs = append(addrs, ep.Address)
+		}
+
+		return addrs, nil
+	}
+}
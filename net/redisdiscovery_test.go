@@ -0,0 +1,170 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/zalando/skipper/metrics/metricstest"
+)
+
+func TestSortedShards(t *testing.T) {
+	shards := sortedShards([]string{"10.0.0.2:6379", "", "10.0.0.1:6379", "10.0.0.2:6379"})
+	if diff := cmp.Diff([]string{"10.0.0.1:6379", "10.0.0.2:6379"}, shards); diff != "" {
+		t.Errorf("invalid shards: %s", diff)
+	}
+}
+
+func TestDiffShards(t *testing.T) {
+	current := []string{"a", "b", "c"}
+	next := []string{"b", "c", "d", "e"}
+	if diff := cmp.Diff([]string{"d", "e"}, diffShards(current, next)); diff != "" {
+		t.Errorf("invalid added shards: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"a"}, diffShards(next, current)); diff != "" {
+		t.Errorf("invalid removed shards: %s", diff)
+	}
+}
+
+func TestRedisShardDiscovery(t *testing.T) {
+	addrs := []string{"10.0.0.1:6379", "10.0.0.2:6379"}
+	var discoveryErr error
+	cli := NewRedisRingClient(&RedisOptions{
+		AddrUpdater: func() ([]string, error) {
+			return addrs, discoveryErr
+		},
+		UpdateInterval: time.Hour,
+	})
+	defer cli.Close()
+
+	m := &metricstest.MockMetrics{}
+	cli.metrics = m
+
+	checkShards := func(t *testing.T, expected []string) {
+		t.Helper()
+		if diff := cmp.Diff(expected, cli.shards); diff != "" {
+			t.Errorf("invalid shards: %s", diff)
+		}
+
+		if n := len(cli.getRing().(*redis.Ring).Options().Addrs); n != len(expected) {
+			t.Errorf("invalid number of shards in the ring, expected: %d, got: %d", len(expected), n)
+		}
+	}
+
+	checkShards(t, addrs)
+
+	ring := cli.getRing()
+	cli.updateShards()
+	if cli.getRing() != ring {
+		t.Error("unexpected ring update without changes")
+	}
+
+	addrs = []string{"10.0.0.3:6379", "10.0.0.2:6379", "10.0.0.4:6379"}
+	cli.updateShards()
+	checkShards(t, []string{"10.0.0.2:6379", "10.0.0.3:6379", "10.0.0.4:6379"})
+
+	if v, ok := m.Gauge("shards"); !ok || v != 3 {
+		t.Errorf("invalid shards gauge: %v", v)
+	}
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["shards.added"] != 2 || c["shards.removed"] != 1 {
+			t.Errorf("invalid shard counters: %v", c)
+		}
+	})
+
+	discoveryErr = errors.New("test error")
+	cli.updateShards()
+
+	addrs, discoveryErr = nil, nil
+	cli.updateShards()
+	checkShards(t, []string{"10.0.0.2:6379", "10.0.0.3:6379", "10.0.0.4:6379"})
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["shards.discovery.errors"] != 2 {
+			t.Errorf("invalid discovery error counter: %v", c)
+		}
+	})
+}
+
+func TestRedisShardDiscoveryFallback(t *testing.T) {
+	cli := NewRedisRingClient(&RedisOptions{
+		Addrs: []string{"10.0.0.1:6379"},
+		AddrUpdater: func() ([]string, error) {
+			return nil, errors.New("test error")
+		},
+		UpdateInterval: time.Hour,
+	})
+	defer cli.Close()
+
+	if diff := cmp.Diff([]string{"10.0.0.1:6379"}, cli.shards); diff != "" {
+		t.Errorf("invalid shards: %s", diff)
+	}
+}
+
+func TestRedisSRVUpdater(t *testing.T) {
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) {
+		lookupSRV = f
+	}(lookupSRV)
+
+	lookupSRV = func(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+		if name != "_redis._tcp.redis.example.org" {
+			return "", nil, errors.New("not found")
+		}
+
+		return name, []*net.SRV{
+			{Target: "redis-0.redis.example.org.", Port: 6379},
+			{Target: "redis-1.redis.example.org.", Port: 6380},
+		}, nil
+	}
+
+	addrs, err := NewRedisSRVUpdater("_redis._tcp.redis.example.org")()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"redis-0.redis.example.org:6379", "redis-1.redis.example.org:6380"}
+	if diff := cmp.Diff(expected, addrs); diff != "" {
+		t.Errorf("invalid addresses: %s", diff)
+	}
+
+	if _, err := NewRedisSRVUpdater("_redis._tcp.missing.example.org")(); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func TestRedisRemoteUpdater(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/endpoints":
+			w.Write([]byte(`{"endpoints": [{"address": "10.0.0.1:6379"}, {"address": "10.0.0.2:6379"}]}`))
+		case "/invalid":
+			w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	addrs, err := NewRedisRemoteUpdater(s.URL + "/endpoints")()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"10.0.0.1:6379", "10.0.0.2:6379"}, addrs); diff != "" {
+		t.Errorf("invalid addresses: %s", diff)
+	}
+
+	for _, p := range []string{"/invalid", "/missing"} {
+		if _, err := NewRedisRemoteUpdater(s.URL + p)(); err == nil {
+			t.Errorf("failed to fail for %s", p)
+		}
+	}
+}
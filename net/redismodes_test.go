@@ -0,0 +1,302 @@
+package net
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a minimal stand-in of a Redis server, a sentinel and a
+// Redis Cluster node, supporting the commands needed to test the
+// connection modes of the client.
+type fakeRedis struct {
+	listener net.Listener
+	username string
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, tlsConfig *tls.Config, username, password string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
+	r := &fakeRedis{
+		listener: l,
+		username: username,
+		password: password,
+		values:   make(map[string]string),
+	}
+
+	go r.serve()
+	return r
+}
+
+func (r *fakeRedis) addr() string {
+	return r.listener.Addr().String()
+}
+
+func (r *fakeRedis) close() {
+	r.listener.Close()
+}
+
+func (r *fakeRedis) received(command string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.commands {
+		if c == command {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *fakeRedis) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go r.handle(conn)
+	}
+}
+
+func readCommand(br *bufio.Reader) ([]string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+
+		arg, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+
+	return args, nil
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func (r *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	host, port, _ := net.SplitHostPort(r.addr())
+	authenticated := r.password == ""
+	br := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(br)
+		if err != nil || len(args) == 0 {
+			return
+		}
+
+		command := strings.ToLower(strings.Join(args, " "))
+		r.mu.Lock()
+		r.commands = append(r.commands, command)
+		r.mu.Unlock()
+
+		var rsp string
+		switch strings.ToLower(args[0]) {
+		case "auth":
+			// the password alone authenticates as the default user
+			if len(args) == 2 && args[1] == r.password ||
+				len(args) == 3 && args[1] == r.username && args[2] == r.password {
+				authenticated = true
+				rsp = "+OK\r\n"
+			} else {
+				rsp = "-WRONGPASS invalid username-password pair\r\n"
+			}
+		default:
+			if !authenticated {
+				rsp = "-NOAUTH Authentication required.\r\n"
+				break
+			}
+
+			switch strings.ToLower(args[0]) {
+			case "ping":
+				rsp = "+PONG\r\n"
+			case "set":
+				r.mu.Lock()
+				r.values[args[1]] = args[2]
+				r.mu.Unlock()
+				rsp = "+OK\r\n"
+			case "get":
+				r.mu.Lock()
+				v, ok := r.values[args[1]]
+				r.mu.Unlock()
+				if ok {
+					rsp = bulk(v)
+				} else {
+					rsp = "$-1\r\n"
+				}
+			case "sentinel":
+				switch strings.ToLower(args[1]) {
+				case "get-master-addr-by-name":
+					rsp = "*2\r\n" + bulk(host) + bulk(port)
+				default:
+					rsp = "*0\r\n"
+				}
+			case "subscribe":
+				for i, ch := range args[1:] {
+					rsp += "*3\r\n" + bulk("subscribe") + bulk(ch) + fmt.Sprintf(":%d\r\n", i+1)
+				}
+			case "cluster":
+				rsp = fmt.Sprintf("*1\r\n*3\r\n:0\r\n:16383\r\n*2\r\n%s:%s\r\n", bulk(host), port)
+			default:
+				rsp = "+OK\r\n"
+			}
+		}
+
+		if _, err := io.WriteString(conn, rsp); err != nil {
+			return
+		}
+	}
+}
+
+// returns a server and a client TLS config trusting each other
+func testTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	s := httptest.NewTLSServer(http.NotFoundHandler())
+	defer s.Close()
+
+	return s.TLS.Clone(), s.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+}
+
+func TestRedisClientModes(t *testing.T) {
+	serverTLS, clientTLS := testTLSConfigs(t)
+
+	for _, tt := range []struct {
+		name      string
+		mode      string
+		tls       bool
+		username  string
+		password  string
+		configure func(*RedisOptions)
+	}{{
+		name: "ring",
+	}, {
+		name:     "ring with TLS and ACL",
+		tls:      true,
+		username: "skipper",
+		password: "secret",
+	}, {
+		name:     "sentinel with TLS and ACL",
+		mode:     RedisModeSentinel,
+		tls:      true,
+		username: "skipper",
+		password: "secret",
+		configure: func(o *RedisOptions) {
+			o.SentinelMasterName = "mymaster"
+			o.SentinelPassword = "secret"
+		},
+	}, {
+		name: "cluster",
+		mode: RedisModeCluster,
+	}, {
+		name:     "cluster with TLS and ACL",
+		mode:     RedisModeCluster,
+		tls:      true,
+		username: "skipper",
+		password: "secret",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			var stls, ctls *tls.Config
+			if tt.tls {
+				stls, ctls = serverTLS, clientTLS
+			}
+
+			server := newFakeRedis(t, stls, tt.username, tt.password)
+			defer server.close()
+
+			o := &RedisOptions{
+				Addrs:        []string{server.addr()},
+				Mode:         tt.mode,
+				Username:     tt.username,
+				Password:     tt.password,
+				TLSConfig:    ctls,
+				DialTimeout:  time.Second,
+				ReadTimeout:  time.Second,
+				WriteTimeout: time.Second,
+				PoolTimeout:  time.Second,
+			}
+
+			if tt.configure != nil {
+				tt.configure(o)
+			}
+
+			cli := NewRedisRingClient(o)
+			defer cli.Close()
+
+			ctx := context.Background()
+			if _, err := cli.Set(ctx, "foo", "bar", time.Minute); err != nil {
+				t.Fatalf("Failed to set: %v", err)
+			}
+
+			if v, err := cli.Get(ctx, "foo"); err != nil || v != "bar" {
+				t.Fatalf("Failed to get, value: %q, error: %v", v, err)
+			}
+
+			if tt.password != "" && !server.received("auth skipper secret") {
+				t.Error("Failed to authenticate with ACL")
+			}
+
+			if tt.mode == RedisModeSentinel && !server.received("sentinel get-master-addr-by-name mymaster") {
+				t.Error("Failed to discover the master via the sentinel")
+			}
+
+			if tt.mode == RedisModeCluster && !server.received("cluster slots") {
+				t.Error("Failed to discover the cluster slots")
+			}
+		})
+	}
+}
+
+func TestRedisClientWrongPassword(t *testing.T) {
+	server := newFakeRedis(t, nil, "skipper", "secret")
+	defer server.close()
+
+	cli := NewRedisRingClient(&RedisOptions{
+		Addrs:    []string{server.addr()},
+		Username: "skipper",
+		Password: "wrong",
+	})
+	defer cli.Close()
+
+	if _, err := cli.Get(context.Background(), "foo"); err == nil {
+		t.Error("Failed to fail with a wrong password")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Package clientcert implements custom predicates to match routes based on
+the verified TLS client certificate of the incoming request.
+
+The predicates only match when the listener is configured to verify the
+client certificates, and the client presented a certificate that could be
+verified against the configured client CAs.
+
+Examples:
+
+    // only match clients with a certificate issued for the "orders" service
+    example1: ClientCertSubject("^CN=orders,") -> "http://orders.example.org";
+
+    // match SPIFFE identities of a namespace
+    example2: ClientCertSAN("^spiffe://cluster[.]local/ns/payments/") -> "http://payments.example.org";
+*/
+package clientcert
+
+import (
+	"crypto/x509"
+	"net/http"
+	"regexp"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+type subjectSpec struct{}
+
+type sanSpec struct{}
+
+type subjectPredicate struct {
+	rxs []*regexp.Regexp
+}
+
+type sanPredicate struct {
+	rxs []*regexp.Regexp
+}
+
+// NewSubject creates a predicate specification, whose instances match
+// the subject distinguished name of the verified client certificate,
+// in the RFC 2253 format, e.g. "CN=orders,O=Example", against any of
+// the regular expression arguments.
+func NewSubject() routing.PredicateSpec { return &subjectSpec{} }
+
+// NewSAN creates a predicate specification, whose instances match the
+// subject alternative names of the verified client certificate - DNS
+// names, email addresses, URIs and IP addresses - against any of the
+// regular expression arguments.
+func NewSAN() routing.PredicateSpec { return &sanSpec{} }
+
+func (*subjectSpec) Name() string { return predicates.ClientCertSubjectName }
+
+func (*sanSpec) Name() string { return predicates.ClientCertSANName }
+
+func compileArgs(args []interface{}) ([]*regexp.Regexp, error) {
+	if len(args) == 0 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	var rxs []*regexp.Regexp
+	for _, a := range args {
+		s, ok := a.(string)
+		if !ok {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+
+		rx, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+
+		rxs = append(rxs, rx)
+	}
+
+	return rxs, nil
+}
+
+func (*subjectSpec) Create(args []interface{}) (routing.Predicate, error) {
+	rxs, err := compileArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subjectPredicate{rxs: rxs}, nil
+}
+
+func (*sanSpec) Create(args []interface{}) (routing.Predicate, error) {
+	rxs, err := compileArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sanPredicate{rxs: rxs}, nil
+}
+
+// VerifiedCertificate returns the leaf certificate of the first verified
+// chain of the client, or nil if the client didn't present a certificate
+// or it was not verified.
+func VerifiedCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return r.TLS.VerifiedChains[0][0]
+}
+
+func matchAny(rxs []*regexp.Regexp, values ...string) bool {
+	for _, v := range values {
+		for _, rx := range rxs {
+			if rx.MatchString(v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *subjectPredicate) Match(r *http.Request) bool {
+	cert := VerifiedCertificate(r)
+	if cert == nil {
+		return false
+	}
+
+	return matchAny(p.rxs, cert.Subject.String())
+}
+
+// SANs returns the subject alternative names of the certificate.
+func SANs(cert *x509.Certificate) []string {
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return sans
+}
+
+func (p *sanPredicate) Match(r *http.Request) bool {
+	cert := VerifiedCertificate(r)
+	if cert == nil {
+		return false
+	}
+
+	return matchAny(p.rxs, SANs(cert)...)
+}
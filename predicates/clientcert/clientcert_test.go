@@ -0,0 +1,138 @@
+package clientcert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func testCertificate() *x509.Certificate {
+	u, _ := url.Parse("spiffe://cluster.local/ns/payments/sa/api")
+	return &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "orders", Organization: []string{"Example"}},
+		DNSNames:       []string{"orders.example.org"},
+		EmailAddresses: []string{"orders@example.org"},
+		URIs:           []*url.URL{u},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+	}
+}
+
+func testRequest(verified bool) *http.Request {
+	r := &http.Request{TLS: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{testCertificate()}}}
+	if verified {
+		r.TLS.VerifiedChains = [][]*x509.Certificate{{testCertificate()}}
+	}
+
+	return r
+}
+
+func TestArgs(t *testing.T) {
+	for _, args := range [][]interface{}{
+		{},
+		{1.2},
+		{"^CN=", 3.4},
+		{"["},
+	} {
+		if _, err := NewSubject().Create(args); err == nil {
+			t.Errorf("expected error for subject arguments: %v", args)
+		}
+
+		if _, err := NewSAN().Create(args); err == nil {
+			t.Errorf("expected error for SAN arguments: %v", args)
+		}
+	}
+}
+
+func TestSubjectMatch(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		args    []interface{}
+		request *http.Request
+		match   bool
+	}{{
+		title:   "no tls",
+		args:    []interface{}{"CN=orders"},
+		request: &http.Request{},
+	}, {
+		title:   "not verified",
+		args:    []interface{}{"CN=orders"},
+		request: testRequest(false),
+	}, {
+		title:   "no match",
+		args:    []interface{}{"^CN=payments,"},
+		request: testRequest(true),
+	}, {
+		title:   "match",
+		args:    []interface{}{"^CN=orders,O=Example$"},
+		request: testRequest(true),
+		match:   true,
+	}, {
+		title:   "match any",
+		args:    []interface{}{"^CN=payments,", "^CN=orders,"},
+		request: testRequest(true),
+		match:   true,
+	}} {
+		t.Run(tc.title, func(t *testing.T) {
+			p, err := NewSubject().Create(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m := p.Match(tc.request); m != tc.match {
+				t.Errorf("expected match: %v, got: %v", tc.match, m)
+			}
+		})
+	}
+}
+
+func TestSANMatch(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		args    []interface{}
+		request *http.Request
+		match   bool
+	}{{
+		title:   "not verified",
+		args:    []interface{}{"^orders[.]example[.]org$"},
+		request: testRequest(false),
+	}, {
+		title:   "no match",
+		args:    []interface{}{"^payments[.]example[.]org$"},
+		request: testRequest(true),
+	}, {
+		title:   "dns",
+		args:    []interface{}{"^orders[.]example[.]org$"},
+		request: testRequest(true),
+		match:   true,
+	}, {
+		title:   "email",
+		args:    []interface{}{"^orders@example[.]org$"},
+		request: testRequest(true),
+		match:   true,
+	}, {
+		title:   "uri",
+		args:    []interface{}{"^spiffe://cluster[.]local/ns/payments/"},
+		request: testRequest(true),
+		match:   true,
+	}, {
+		title:   "ip",
+		args:    []interface{}{"^10[.]0[.]0[.]1$"},
+		request: testRequest(true),
+		match:   true,
+	}} {
+		t.Run(tc.title, func(t *testing.T) {
+			p, err := NewSAN().Create(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m := p.Match(tc.request); m != tc.match {
+				t.Errorf("expected match: %v, got: %v", tc.match, m)
+			}
+		})
+	}
+}
@@ -43,4 +43,6 @@ const (
 	ClientIPName              = "ClientIP"
 	TeeName                   = "Tee"
 	TrafficName               = "Traffic"
+	ClientCertSubjectName     = "ClientCertSubject"
+	ClientCertSANName         = "ClientCertSAN"
 )
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tlsfilters "github.com/zalando/skipper/filters/tls"
+)
+
+// the transports of the backend TLS configurations that were not used for this period are dropped, e.g.
+// after the referenced secrets were rotated. It is applied independent of the period of closing the idle
+// connections, which can be disabled.
+const backendTLSUnusedPeriod = time.Minute
+
+type backendTLSTransport struct {
+	transport    *http.Transport
+	roundTripper http.RoundTripper
+
+	// unix nanoseconds, updated atomically
+	lastUsed int64
+}
+
+// backendTLSTransports caches the transports used for the routes with
+// custom backend TLS configuration. The transports are derived from the
+// default transport of the proxy, and only differ in their TLS client
+// configuration.
+type backendTLSTransports struct {
+	mu         sync.RWMutex
+	base       *http.Transport
+	wrap       func(http.RoundTripper) http.RoundTripper
+	insecure   bool
+	transports map[string]*backendTLSTransport
+}
+
+func newBackendTLSTransports(base *http.Transport, wrap func(http.RoundTripper) http.RoundTripper, insecure bool) *backendTLSTransports {
+	return &backendTLSTransports{
+		base:       base,
+		wrap:       wrap,
+		insecure:   insecure,
+		transports: make(map[string]*backendTLSTransport),
+	}
+}
+
+func (t *backendTLSTransports) get(c *tlsfilters.BackendTLS) http.RoundTripper {
+	t.mu.RLock()
+	bt, ok := t.transports[c.Key]
+	t.mu.RUnlock()
+	if ok {
+		atomic.StoreInt64(&bt.lastUsed, time.Now().UnixNano())
+		return bt.roundTripper
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if bt, ok := t.transports[c.Key]; ok {
+		atomic.StoreInt64(&bt.lastUsed, time.Now().UnixNano())
+		return bt.roundTripper
+	}
+
+	config := c.Config.Clone()
+	if t.insecure {
+		/* #nosec */
+		config.InsecureSkipVerify = true
+	}
+
+	tr := t.base.Clone()
+	tr.TLSClientConfig = config
+	bt = &backendTLSTransport{
+		transport:    tr,
+		roundTripper: t.wrap(tr),
+		lastUsed:     time.Now().UnixNano(),
+	}
+
+	t.transports[c.Key] = bt
+	return bt.roundTripper
+}
+
+// closeIdleConnections closes the idle connections of the cached
+// transports.
+func (t *backendTLSTransports) closeIdleConnections() {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, bt := range t.transports {
+		bt.transport.CloseIdleConnections()
+	}
+}
+
+// dropUnused drops the transports that were not used since the provided
+// time, and closes their idle connections.
+func (t *backendTLSTransports) dropUnused(unusedSince time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, bt := range t.transports {
+		if atomic.LoadInt64(&bt.lastUsed) < unusedSince.UnixNano() {
+			bt.transport.CloseIdleConnections()
+			delete(t.transports, key)
+		}
+	}
+}
+
+// close drops all the cached transports, and closes their idle
+// connections.
+func (t *backendTLSTransports) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, bt := range t.transports {
+		bt.transport.CloseIdleConnections()
+		delete(t.transports, key)
+	}
+}
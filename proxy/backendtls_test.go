@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters/builtin"
+	tlsfilters "github.com/zalando/skipper/filters/tls"
+)
+
+type backendTLSSecrets map[string][]byte
+
+func (s backendTLSSecrets) GetSecret(name string) ([]byte, bool) {
+	b, ok := s[name]
+	return b, ok
+}
+
+func (backendTLSSecrets) Close() {}
+
+func TestBackendTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	backend.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	backend.StartTLS()
+	defer backend.Close()
+
+	secrets := backendTLSSecrets{
+		"ca.pem": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw}),
+		"client.pem": append(
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...,
+		),
+	}
+
+	fr := builtin.MakeRegistry()
+	fr.Register(tlsfilters.NewBackendTLS(secrets))
+
+	doc := fmt.Sprintf(`
+		mtls: Path("/mtls") -> backendTLS("ca.pem", "client.pem") -> "%[1]s";
+		noClientCert: Path("/ca") -> backendTLS("ca.pem") -> "%[1]s";
+		default: Path("/default") -> "%[1]s";
+	`, backend.URL)
+
+	tp, err := newTestProxyWithFilters(fr, doc, FlagsNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tp.close()
+
+	ps := httptest.NewServer(tp.proxy)
+	defer ps.Close()
+
+	for _, tc := range []struct {
+		path    string
+		success bool
+	}{
+		{"/mtls", true},
+		{"/ca", false},
+		{"/default", false},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			rsp, err := http.Get(ps.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rsp.Body.Close()
+
+			if success := rsp.StatusCode == http.StatusOK; success != tc.success {
+				t.Errorf("expected success: %v, got status: %d", tc.success, rsp.StatusCode)
+			}
+		})
+	}
+
+	if n := len(tp.proxy.backendTLS.transports); n != 2 {
+		t.Errorf("expected 2 cached transports, got: %d", n)
+	}
+}
+
+func TestBackendTLSDropUnused(t *testing.T) {
+	transports := newBackendTLSTransports(&http.Transport{}, func(rt http.RoundTripper) http.RoundTripper { return rt }, false)
+	transports.get(&tlsfilters.BackendTLS{Key: "old", Config: &tls.Config{}})
+	transports.get(&tlsfilters.BackendTLS{Key: "new", Config: &tls.Config{}})
+
+	// the old configuration is not used after the rotation
+	transports.transports["old"].lastUsed = time.Now().Add(-2 * backendTLSUnusedPeriod).UnixNano()
+
+	transports.dropUnused(time.Now().Add(-backendTLSUnusedPeriod))
+	if _, ok := transports.transports["old"]; ok || len(transports.transports) != 1 {
+		t.Errorf("failed to drop the unused transport: %v", transports.transports)
+	}
+
+	transports.close()
+	if len(transports.transports) != 0 {
+		t.Errorf("failed to close the transports: %v", transports.transports)
+	}
+}
@@ -40,6 +40,8 @@ const (
 	testHalfOpenRequests        = 3
 	testRateWindow              = 10
 	testRateFailures            = 4
+	testSlowCallThreshold       = 30 * time.Millisecond
+	testSlowCallRate            = 40
 	defaultHost                 = "default"
 )
 
@@ -495,6 +497,64 @@ func TestBreakerRate(t *testing.T) {
 	}
 }
 
+func setBackendSlow(c *breakerTestContext) {
+	c.backends[defaultHost].slow(2 * testSlowCallThreshold)
+}
+
+func TestBreakerSlowCalls(t *testing.T) {
+	settings := []circuit.BreakerSettings{{
+		Type:              circuit.SlowCalls,
+		Window:            testRateWindow,
+		SlowCallThreshold: testSlowCallThreshold,
+		SlowCallRate:      testSlowCallRate,
+		Timeout:           testBreakerTimeout,
+		HalfOpenRequests:  testHalfOpenRequests,
+	}}
+
+	slowCalls := testRateWindow * testSlowCallRate / 100
+	for _, s := range []breakerScenario{{
+		title:    "open",
+		settings: settings,
+		steps: []scenarioStep{
+			times(testRateWindow-slowCalls, request(200)),
+			setBackendSlow,
+			times(slowCalls, request(200)),
+			checkBackendCounter(testRateWindow),
+			requestOpen,
+			checkBackendCounter(0),
+		},
+	}, {
+		title:    "failures and slow calls",
+		settings: settings,
+		steps: []scenarioStep{
+			times(testRateWindow-slowCalls, request(200)),
+			setBackendFail,
+			times(slowCalls/2, request(500)),
+			setBackendSlow,
+			times(slowCalls-slowCalls/2, request(500)),
+			checkBackendCounter(testRateWindow),
+			requestOpen,
+			checkBackendCounter(0),
+		},
+	}, {
+		title:    "open, fixed during timeout",
+		settings: settings,
+		steps: []scenarioStep{
+			setBackendSlow,
+			times(testRateWindow, request(200)),
+			requestOpen,
+			wait(2 * testBreakerTimeout),
+			func(c *breakerTestContext) { c.backends[defaultHost].slow(0) },
+			times(testHalfOpenRequests+1, request(200)),
+			checkBackendCounter(testRateWindow + testHalfOpenRequests + 1),
+		},
+	}} {
+		t.Run(s.title, func(t *testing.T) {
+			testBreaker(t, s)
+		})
+	}
+}
+
 func TestBreakerMultipleHosts(t *testing.T) {
 	testBreaker(t, breakerScenario{
 		settings: []circuit.BreakerSettings{{
@@ -47,6 +47,18 @@ type context struct {
 	proxy                *Proxy
 	routeLookup          *routing.RouteLookup
 	cancelBackendContext stdlibcontext.CancelFunc
+
+	// releases the request admitted by the load shedding, after the response was served
+	loadSheddingDone func()
+
+	// collected for the access log
+	backendEndpoint         string
+	backendDuration         time.Duration
+	requestFiltersDuration  time.Duration
+	responseFiltersDuration time.Duration
+
+	// set when the per-request debug trace is enabled
+	debugTrace *debugTrace
 }
 
 type filterMetrics struct {
@@ -0,0 +1,382 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+const (
+	// DefaultDebugTraceHeader is the default name of the request
+	// header triggering the per-request debug trace.
+	DefaultDebugTraceHeader = "X-Skipper-Debug"
+
+	// DebugTraceResponseHeader contains the base64 encoded JSON debug
+	// trace, when the traces are not stored.
+	DebugTraceResponseHeader = "X-Skipper-Debug-Trace"
+
+	// DebugTraceIDHeader contains the id of the stored debug trace.
+	DebugTraceIDHeader = "X-Skipper-Debug-Trace-Id"
+
+	// DebugTraceHandlerPrefix is the path prefix of the admin API
+	// serving the stored debug traces.
+	DebugTraceHandlerPrefix = "/debug/traces/"
+
+	redactedDebugValue = "[REDACTED]"
+)
+
+var redactedDebugHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"Proxy-Authorization": true,
+}
+
+// DebugTraceParams enables recording the processing of individual
+// requests, while the requests are proxied as usual. The trace is
+// recorded, when the request contains the trigger header, with a value
+// that is either one of the allowed tokens, or signed with the signing
+// key.
+type DebugTraceParams struct {
+
+	// Header is the name of the trigger header. Defaults to
+	// X-Skipper-Debug. The header is not forwarded to the backends.
+	Header string
+
+	// Tokens lists the header values that trigger the trace.
+	Tokens []string
+
+	// SigningKey, when set, allows triggering the trace with signed
+	// header values in the format of <expiry>.<signature>, where the
+	// expiry is a unix timestamp in seconds, and the signature is the
+	// hex encoded HMAC-SHA256 of the expiry, using the signing key.
+	SigningKey []byte
+
+	// Store, when set, keeps the traces for retrieval via the admin
+	// API, and only their id is returned in the
+	// X-Skipper-Debug-Trace-Id response header. Otherwise, the trace
+	// is returned in the X-Skipper-Debug-Trace response header.
+	Store *DebugTraceStore
+}
+
+type debugPredicateEval struct {
+	RouteID  string `json:"route_id"`
+	Type     string `json:"type"`
+	Matched  bool   `json:"matched"`
+	Duration string `json:"duration"`
+}
+
+type debugFilterTrace struct {
+	Name           string      `json:"name"`
+	Duration       string      `json:"duration"`
+	HeadersSet     http.Header `json:"headers_set,omitempty"`
+	HeadersRemoved []string    `json:"headers_removed,omitempty"`
+	Served         bool        `json:"served,omitempty"`
+	Panic          string      `json:"panic,omitempty"`
+}
+
+type debugBackendAttempt struct {
+	Endpoint string `json:"endpoint"`
+	Retry    bool   `json:"retry,omitempty"`
+	Duration string `json:"duration"`
+	Status   int    `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type debugTrace struct {
+	ID                   string                `json:"id"`
+	Start                time.Time             `json:"start"`
+	Duration             string                `json:"duration,omitempty"`
+	Method               string                `json:"method"`
+	Host                 string                `json:"host"`
+	URI                  string                `json:"uri"`
+	LookupDuration       string                `json:"lookup_duration,omitempty"`
+	RouteID              string                `json:"route_id,omitempty"`
+	Route                string                `json:"route,omitempty"`
+	PathParams           map[string]string     `json:"path_params,omitempty"`
+	Predicates           []string              `json:"predicates,omitempty"`
+	PredicateEvaluations []debugPredicateEval  `json:"predicate_evaluations,omitempty"`
+	RequestFilters       []debugFilterTrace    `json:"request_filters,omitempty"`
+	ResponseFilters      []debugFilterTrace    `json:"response_filters,omitempty"`
+	Backend              []debugBackendAttempt `json:"backend,omitempty"`
+	Status               int                   `json:"status,omitempty"`
+	Error                string                `json:"error,omitempty"`
+}
+
+// DebugTraceStore keeps the last debug traces in memory, and serves them
+// on the admin API:
+//
+//	GET /debug/traces/       lists the ids of the stored traces
+//	GET /debug/traces/<id>   returns a trace
+type DebugTraceStore struct {
+	mu     sync.Mutex
+	size   int
+	ids    []string
+	traces map[string][]byte
+}
+
+// NewDebugTraceStore creates a store keeping the last size traces.
+func NewDebugTraceStore(size int) *DebugTraceStore {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &DebugTraceStore{size: size, traces: make(map[string][]byte)}
+}
+
+func (s *DebugTraceStore) add(id string, trace []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ids) == s.size {
+		delete(s.traces, s.ids[0])
+		s.ids = s.ids[1:]
+	}
+
+	s.ids = append(s.ids, id)
+	s.traces[id] = trace
+}
+
+func (s *DebugTraceStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, DebugTraceHandlerPrefix)
+
+	s.mu.Lock()
+	var b []byte
+	if id == "" {
+		ids := make([]string, len(s.ids))
+		copy(ids, s.ids)
+		s.mu.Unlock()
+		b, _ = json.Marshal(ids)
+	} else {
+		t, ok := s.traces[id]
+		s.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		b = t
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+type debugTracer struct {
+	header     string
+	tokens     [][]byte
+	signingKey []byte
+	store      *DebugTraceStore
+	now        func() time.Time
+}
+
+func newDebugTracer(p *DebugTraceParams) *debugTracer {
+	if p == nil || len(p.Tokens) == 0 && len(p.SigningKey) == 0 {
+		return nil
+	}
+
+	t := &debugTracer{
+		header:     p.Header,
+		signingKey: p.SigningKey,
+		store:      p.Store,
+		now:        time.Now,
+	}
+
+	if t.header == "" {
+		t.header = DefaultDebugTraceHeader
+	}
+
+	for _, token := range p.Tokens {
+		if token != "" {
+			t.tokens = append(t.tokens, []byte(token))
+		}
+	}
+
+	return t
+}
+
+func (t *debugTracer) validSignature(v string) bool {
+	if len(t.signingKey) == 0 {
+		return false
+	}
+
+	parts := strings.SplitN(v, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || t.now().Unix() > expiry {
+		return false
+	}
+
+	signature, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, t.signingKey)
+	mac.Write([]byte(parts[0]))
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+func (t *debugTracer) allowed(v string) bool {
+	for _, token := range t.tokens {
+		if subtle.ConstantTimeCompare(token, []byte(v)) == 1 {
+			return true
+		}
+	}
+
+	return t.validSignature(v)
+}
+
+// start checks the trigger header, removes it from the request, and
+// returns a new trace when the header is valid.
+func (t *debugTracer) start(r *http.Request) *debugTrace {
+	if t == nil {
+		return nil
+	}
+
+	v := r.Header.Get(t.header)
+	if v == "" {
+		return nil
+	}
+
+	r.Header.Del(t.header)
+	if !t.allowed(v) {
+		return nil
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil
+	}
+
+	return &debugTrace{
+		ID:     hex.EncodeToString(id),
+		Start:  t.now(),
+		Method: r.Method,
+		Host:   r.Host,
+		URI:    r.RequestURI,
+	}
+}
+
+// finish sets the response header with the trace, or with its id, when
+// the traces are stored.
+func (t *debugTracer) finish(trace *debugTrace, h http.Header, status int, err error) {
+	trace.Duration = t.now().Sub(trace.Start).String()
+	trace.Status = status
+	if err != nil {
+		trace.Error = err.Error()
+	}
+
+	b, jerr := json.Marshal(trace)
+	if jerr != nil {
+		return
+	}
+
+	if t.store != nil {
+		t.store.add(trace.ID, b)
+		h.Set(DebugTraceIDHeader, trace.ID)
+		return
+	}
+
+	h.Set(DebugTraceResponseHeader, base64.StdEncoding.EncodeToString(b))
+}
+
+// setPredicateEvaluations records the custom predicates evaluated during the route lookup, of the matched and
+// of the rejected candidate routes.
+func (trace *debugTrace) setPredicateEvaluations(evals []routing.PredicateEvaluation) {
+	trace.PredicateEvaluations = nil
+	for _, e := range evals {
+		trace.PredicateEvaluations = append(trace.PredicateEvaluations, debugPredicateEval{
+			RouteID:  e.RouteID,
+			Type:     fmt.Sprintf("%T", e.Predicate),
+			Matched:  e.Matched,
+			Duration: e.Duration.String(),
+		})
+	}
+}
+
+func (trace *debugTrace) setRoute(r *routing.Route, params map[string]string, lookup time.Duration) {
+	trace.LookupDuration = lookup.String()
+	trace.RouteID = r.Id
+	trace.Route = r.String()
+	trace.PathParams = params
+	trace.Predicates = nil
+	for _, p := range eskip.Canonical(&r.Route).Predicates {
+		trace.Predicates = append(trace.Predicates, p.String())
+	}
+}
+
+func (trace *debugTrace) addBackendAttempt(endpoint string, d time.Duration, rsp *http.Response, err error) {
+	a := debugBackendAttempt{Endpoint: endpoint, Retry: len(trace.Backend) > 0, Duration: d.String()}
+	if err != nil {
+		a.Error = err.Error()
+	} else if rsp != nil {
+		a.Status = rsp.StatusCode
+	}
+
+	trace.Backend = append(trace.Backend, a)
+}
+
+func debugHeaderValues(name string, v []string) []string {
+	if redactedDebugHeaders[name] {
+		return []string{redactedDebugValue}
+	}
+
+	return v
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterTrace records the changes of the headers made by a filter.
+func filterTrace(name string, d time.Duration, before, after http.Header) debugFilterTrace {
+	ft := debugFilterTrace{Name: name, Duration: d.String()}
+	for k, v := range after {
+		if !equalValues(before[k], v) {
+			if ft.HeadersSet == nil {
+				ft.HeadersSet = make(http.Header)
+			}
+
+			ft.HeadersSet[k] = debugHeaderValues(k, v)
+		}
+	}
+
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			ft.HeadersRemoved = append(ft.HeadersRemoved, k)
+		}
+	}
+
+	return ft
+}
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signDebugTrace(key []byte, expiry time.Time) string {
+	e := strconv.FormatInt(expiry.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(e))
+	return e + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func testDebugTraceProxy(t *testing.T, params *DebugTraceParams) (*testProxy, chan http.Header) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header
+		w.Header().Set("X-Backend", "b1")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	t.Cleanup(backend.Close)
+
+	doc := fmt.Sprintf(`rejected: Path("/hello/:name") && Tee("no-match") && Weight(10) -> <shunt>;
+		traced: Path("/hello/:name") && Header("X-Test", "1")
+		-> setRequestHeader("X-Name", "${name}")
+		-> setResponseHeader("X-Response", "foo")
+		-> dropRequestHeader("X-Test")
+		-> "%s"`, backend.URL)
+
+	tp, err := newTestProxyWithParams(doc, Params{DebugTrace: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(tp.close)
+	return tp, received
+}
+
+func debugTraceRequest(tp *testProxy, value string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "https://www.example.org/hello/world", nil)
+	r.Header.Set("X-Test", "1")
+	r.Header.Set("Authorization", "Bearer secret")
+	if value != "" {
+		r.Header.Set(DefaultDebugTraceHeader, value)
+	}
+
+	w := httptest.NewRecorder()
+	tp.proxy.ServeHTTP(w, r)
+	return w
+}
+
+func decodeDebugTrace(t *testing.T, w *httptest.ResponseRecorder) *debugTrace {
+	v := w.Header().Get(DebugTraceResponseHeader)
+	if v == "" {
+		t.Fatal("missing debug trace")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var trace debugTrace
+	if err := json.Unmarshal(b, &trace); err != nil {
+		t.Fatal(err)
+	}
+
+	return &trace
+}
+
+func TestDebugTrace(t *testing.T) {
+	tp, received := testDebugTraceProxy(t, &DebugTraceParams{Tokens: []string{"let-me-debug"}})
+
+	w := debugTraceRequest(tp, "")
+	<-received
+	if w.Header().Get(DebugTraceResponseHeader) != "" {
+		t.Error("unexpected debug trace without the header")
+	}
+
+	w = debugTraceRequest(tp, "invalid")
+	if h := <-received; h.Get(DefaultDebugTraceHeader) != "" {
+		t.Error("debug header forwarded")
+	}
+
+	if w.Header().Get(DebugTraceResponseHeader) != "" {
+		t.Error("unexpected debug trace with invalid token")
+	}
+
+	w = debugTraceRequest(tp, "let-me-debug")
+	if h := <-received; h.Get(DefaultDebugTraceHeader) != "" || h.Get("X-Name") != "world" {
+		t.Errorf("unexpected backend request headers: %v", h)
+	}
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("request not proxied: %d", w.Code)
+	}
+
+	trace := decodeDebugTrace(t, w)
+	if trace.RouteID != "traced" || trace.PathParams["name"] != "world" || trace.Status != http.StatusTeapot {
+		t.Errorf("unexpected trace: %+v", trace)
+	}
+
+	if len(trace.Predicates) != 2 {
+		t.Errorf("unexpected predicates: %v", trace.Predicates)
+	}
+
+	if len(trace.PredicateEvaluations) != 1 ||
+		trace.PredicateEvaluations[0].RouteID != "rejected" || trace.PredicateEvaluations[0].Matched {
+		t.Errorf("unexpected predicate evaluations: %+v", trace.PredicateEvaluations)
+	}
+
+	if len(trace.RequestFilters) != 3 ||
+		trace.RequestFilters[0].HeadersSet.Get("X-Name") != "world" ||
+		len(trace.RequestFilters[1].HeadersSet) != 0 ||
+		len(trace.RequestFilters[2].HeadersRemoved) != 1 || trace.RequestFilters[2].HeadersRemoved[0] != "X-Test" {
+		t.Errorf("unexpected request filters: %+v", trace.RequestFilters)
+	}
+
+	if len(trace.ResponseFilters) != 3 || trace.ResponseFilters[1].HeadersSet.Get("X-Response") != "foo" {
+		t.Errorf("unexpected response filters: %+v", trace.ResponseFilters)
+	}
+
+	if len(trace.Backend) != 1 || trace.Backend[0].Status != http.StatusTeapot || trace.Backend[0].Endpoint == "" {
+		t.Errorf("unexpected backend attempts: %+v", trace.Backend)
+	}
+}
+
+func TestDebugTraceSigned(t *testing.T) {
+	key := []byte("signing-key")
+	tp, received := testDebugTraceProxy(t, &DebugTraceParams{SigningKey: key})
+
+	for _, tc := range []struct {
+		title string
+		value string
+		trace bool
+	}{
+		{"valid", signDebugTrace(key, time.Now().Add(time.Minute)), true},
+		{"expired", signDebugTrace(key, time.Now().Add(-time.Minute)), false},
+		{"wrong key", signDebugTrace([]byte("other-key"), time.Now().Add(time.Minute)), false},
+		{"malformed", "12345", false},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			w := debugTraceRequest(tp, tc.value)
+			<-received
+			if (w.Header().Get(DebugTraceResponseHeader) != "") != tc.trace {
+				t.Errorf("unexpected trace header: %q", w.Header().Get(DebugTraceResponseHeader))
+			}
+		})
+	}
+}
+
+func TestDebugTraceStore(t *testing.T) {
+	store := NewDebugTraceStore(1)
+	tp, received := testDebugTraceProxy(t, &DebugTraceParams{Tokens: []string{"let-me-debug"}, Store: store})
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		w := debugTraceRequest(tp, "let-me-debug")
+		<-received
+		if w.Header().Get(DebugTraceResponseHeader) != "" {
+			t.Error("unexpected inline trace")
+		}
+
+		ids = append(ids, w.Header().Get(DebugTraceIDHeader))
+	}
+
+	rsp := httptest.NewRecorder()
+	store.ServeHTTP(rsp, httptest.NewRequest("GET", DebugTraceHandlerPrefix+ids[0], nil))
+	if rsp.Code != http.StatusNotFound {
+		t.Errorf("expected evicted trace, got %d", rsp.Code)
+	}
+
+	rsp = httptest.NewRecorder()
+	store.ServeHTTP(rsp, httptest.NewRequest("GET", DebugTraceHandlerPrefix+ids[1], nil))
+	var trace debugTrace
+	if err := json.Unmarshal(rsp.Body.Bytes(), &trace); err != nil || trace.ID != ids[1] || trace.RouteID != "traced" {
+		t.Errorf("unexpected stored trace: %s", rsp.Body.String())
+	}
+
+	rsp = httptest.NewRecorder()
+	store.ServeHTTP(rsp, httptest.NewRequest("GET", DebugTraceHandlerPrefix, nil))
+	if rsp.Body.String() != fmt.Sprintf(`["%s"]`, ids[1]) {
+		t.Errorf("unexpected trace list: %s", rsp.Body.String())
+	}
+
+	rsp = httptest.NewRecorder()
+	store.ServeHTTP(rsp, httptest.NewRequest("DELETE", DebugTraceHandlerPrefix+ids[1], nil))
+	if rsp.Code != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected status: %d", rsp.Code)
+	}
+}
+
+func TestDebugTraceRedactsHeaders(t *testing.T) {
+	ft := filterTrace("test", 0, http.Header{}, http.Header{"Authorization": []string{"Bearer secret"}, "X-Foo": []string{"bar"}})
+	if ft.HeadersSet.Get("Authorization") != redactedDebugValue || ft.HeadersSet.Get("X-Foo") != "bar" {
+		t.Errorf("unexpected headers: %v", ft.HeadersSet)
+	}
+}
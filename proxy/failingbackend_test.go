@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 	"testing"
+	"time"
 )
 
 type failingBackend struct {
@@ -15,6 +16,7 @@ type failingBackend struct {
 	url     string
 	server  *http.Server
 	count   int
+	delay   time.Duration
 }
 
 func freeAddress() string {
@@ -67,6 +69,12 @@ func (b *failingBackend) fail() {
 	})
 }
 
+func (b *failingBackend) slow(d time.Duration) {
+	b.synced(func() {
+		b.delay = d
+	})
+}
+
 func (b *failingBackend) counter() int {
 	var count int
 	b.synced(func() {
@@ -127,12 +135,16 @@ func (b *failingBackend) Close() {
 func (b *failingBackend) down() { b.close() }
 
 func (b *failingBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var delay time.Duration
 	b.synced(func() {
 		b.count++
+		delay = b.delay
 		if !b.healthy {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 	})
+
+	time.Sleep(delay)
 }
 
 func TestFailingBackend(t *testing.T) {
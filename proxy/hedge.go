@@ -0,0 +1,245 @@
+package proxy
+
+import (
+	stdlibcontext "context"
+	"fmt"
+	"net/http"
+	"time"
+
+	ot "github.com/opentracing/opentracing-go"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/hedge"
+	"github.com/zalando/skipper/routing"
+)
+
+// only the idempotent requests without a body are hedged, because the body can be sent only once
+var hedgeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+type hedgeAttempt struct {
+	req    *http.Request
+	span   ot.Span
+	cancel stdlibcontext.CancelFunc
+	start  time.Time
+	hedged bool
+}
+
+type hedgeResult struct {
+	attempt  *hedgeAttempt
+	response *http.Response
+	err      error
+	perr     *proxyError
+	duration time.Duration
+}
+
+func hedging(ctx *context, req *http.Request) (*hedge.Hedge, bool) {
+	h, ok := ctx.StateBag()[filters.BackendHedging].(*hedge.Hedge)
+	if !ok {
+		return nil, false
+	}
+
+	if ctx.route.BackendType != eskip.LBBackend || len(ctx.route.LBEndpoints) < 2 {
+		return nil, false
+	}
+
+	if !hedgeMethods[req.Method] || req.Body != nil || isUpgradeRequest(req) {
+		return nil, false
+	}
+
+	return h, true
+}
+
+// returns an endpoint of the load balanced backend not used yet by the current request, preferring the one
+// selected by the load balancer algorithm
+func hedgeEndpoint(ctx *context, used map[string]bool) (*routing.LBEndpoint, bool) {
+	rt := ctx.route
+	e := rt.LBAlgorithm.Apply(&routing.LBContext{Request: ctx.request, Route: rt, Params: ctx.StateBag()})
+	if !used[e.Host] {
+		return &e, true
+	}
+
+	for i := range rt.LBEndpoints {
+		if !used[rt.LBEndpoints[i].Host] {
+			return &rt.LBEndpoints[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// starts a backend roundtrip in a separate goroutine, sending the result to the results channel. The request is
+// prepared in the calling goroutine, because it accesses the request context.
+func (p *Proxy) startHedgeAttempt(
+	ctx *context,
+	base *http.Request,
+	endpoint *routing.LBEndpoint,
+	hedged bool,
+	results chan<- *hedgeResult,
+) (*hedgeAttempt, *proxyError) {
+	attemptContext, cancel := stdlibcontext.WithCancel(base.Context())
+	req := base.Clone(attemptContext)
+	if endpoint != nil {
+		req.URL.Scheme = endpoint.Scheme
+		req.URL.Host = endpoint.Host
+	}
+
+	roundTripper, err := p.getRoundTripper(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, &proxyError{err: fmt.Errorf("failed to get roundtripper: %w", err), code: http.StatusBadGateway}
+	}
+
+	req, span := p.startProxySpan(ctx, req)
+	if hedged {
+		p.tracing.setTag(span, HedgedTag, true)
+	}
+
+	a := &hedgeAttempt{req: req, span: span, cancel: cancel, start: time.Now(), hedged: hedged}
+	go func() {
+		if endpoint != nil {
+			endpoint.Metrics.IncInflightRequest()
+			defer endpoint.Metrics.DecInflightRequest()
+		}
+
+		rsp, err := roundTripper.RoundTrip(req)
+		r := &hedgeResult{attempt: a, err: err, duration: time.Since(a.start)}
+		r.response, r.perr = p.roundTripResult(span, req, rsp, err)
+		results <- r
+	}()
+
+	return a, nil
+}
+
+// makeHedgedBackendRequest sends the request to the backend, and when it doesn't respond within the hedge
+// delay, it sends the same request to a different endpoint, up to the maximum number of hedges. The first
+// response wins, and the other requests are canceled. Failed requests don't stop the remaining ones, and the
+// last failure is returned only when all of them failed.
+func (p *Proxy) makeHedgedBackendRequest(ctx *context, req *http.Request, endpoint *routing.LBEndpoint, h *hedge.Hedge) (*http.Response, *proxyError) {
+	results := make(chan *hedgeResult, h.MaxHedges+1)
+	first, perr := p.startHedgeAttempt(ctx, req, endpoint, false, results)
+	if perr != nil {
+		return nil, perr
+	}
+
+	attempts := []*hedgeAttempt{first}
+	used := map[string]bool{req.URL.Host: true}
+	pending := 1
+
+	var (
+		timer   *time.Timer
+		timeout <-chan time.Time
+	)
+
+	delay, ok := h.HedgeDelay()
+	if ok {
+		timer = time.NewTimer(delay)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-timeout:
+			timeout = nil
+			e, ok := hedgeEndpoint(ctx, used)
+			if !ok {
+				continue
+			}
+
+			used[e.Host] = true
+			hreq := req.Clone(req.Context())
+			hreq.URL.Scheme = e.Scheme
+			hreq.URL.Host = e.Host
+			if _, rejected := p.rejectBackend(ctx, hreq); rejected {
+				continue
+			}
+
+			a, perr := p.startHedgeAttempt(ctx, req, e, true, results)
+			if perr != nil {
+				p.log.Errorf("Failed to send hedged request: %v", perr)
+				continue
+			}
+
+			attempts = append(attempts, a)
+			pending++
+			p.metrics.IncCounter("hedge.sent." + ctx.route.Id)
+			if len(attempts) <= h.MaxHedges {
+				timer.Reset(delay)
+				timeout = timer.C
+			}
+		case r := <-results:
+			pending--
+			if ctx.debugTrace != nil {
+				ctx.debugTrace.addBackendAttempt(r.attempt.req.URL.Host, r.duration, r.response, r.err)
+			}
+
+			if r.perr != nil && pending > 0 {
+				r.attempt.cancel()
+				r.attempt.span.Finish()
+				continue
+			}
+
+			p.finishHedging(ctx, h, r, attempts, pending, results)
+			if r.perr != nil {
+				return nil, r.perr
+			}
+
+			// the latency seen by the client, measured from the original request also when a hedged one won
+			h.Observe(r.attempt.start.Add(r.duration).Sub(first.start))
+			if r.attempt.hedged {
+				p.metrics.IncCounter("hedge.won." + ctx.route.Id)
+			}
+
+			return r.response, nil
+		}
+	}
+}
+
+// applies the result to the request context, cancels the other requests, and discards their results in the
+// background. The latency of the requests that still received a response is observed, too, otherwise only the
+// faster requests would be taken into account for the hedge delay. Like for the winner, it is measured from the
+// start of the original request.
+func (p *Proxy) finishHedging(ctx *context, h *hedge.Hedge, r *hedgeResult, attempts []*hedgeAttempt, pending int, results <-chan *hedgeResult) {
+	ctx.proxySpan = r.attempt.span
+	ctx.backendEndpoint = r.attempt.req.URL.Host
+
+	// the winner request needs to stay alive until the response body is consumed
+	cancel := r.attempt.cancel
+	if previous := ctx.cancelBackendContext; previous != nil {
+		ctx.cancelBackendContext = func() {
+			cancel()
+			previous()
+		}
+	} else {
+		ctx.cancelBackendContext = cancel
+	}
+
+	for _, a := range attempts {
+		if a != r.attempt {
+			a.cancel()
+		}
+	}
+
+	if pending == 0 {
+		return
+	}
+
+	start := attempts[0].start
+	go func() {
+		for i := 0; i < pending; i++ {
+			lost := <-results
+			if lost.response != nil {
+				h.Observe(lost.attempt.start.Add(lost.duration).Sub(start))
+				lost.response.Body.Close()
+			}
+
+			lost.attempt.span.Finish()
+		}
+	}()
+}
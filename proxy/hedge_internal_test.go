@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ot "github.com/opentracing/opentracing-go"
+
+	"github.com/zalando/skipper/filters/hedge"
+)
+
+func TestHedgeObservesLosersFromOriginalStart(t *testing.T) {
+	h := &hedge.Hedge{Percentile: 100, MaxHedges: 1}
+	p := &Proxy{}
+	start := time.Now()
+
+	newAttempt := func(start time.Time) *hedgeAttempt {
+		return &hedgeAttempt{
+			req:    httptest.NewRequest("GET", "http://backend", nil),
+			span:   ot.NoopTracer{}.StartSpan(""),
+			cancel: func() {},
+			start:  start,
+		}
+	}
+
+	// the original request wins, while the hedged request sent after 100ms
+	// still receives a response after another 100ms
+	for i := 0; i < 32; i++ {
+		first, hedged := newAttempt(start), newAttempt(start.Add(100*time.Millisecond))
+		results := make(chan *hedgeResult, 1)
+		results <- &hedgeResult{
+			attempt:  hedged,
+			response: &http.Response{Body: io.NopCloser(strings.NewReader(""))},
+			duration: 100 * time.Millisecond,
+		}
+
+		p.finishHedging(&context{}, h, &hedgeResult{attempt: first}, []*hedgeAttempt{first, hedged}, 1, results)
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		if d, ok := h.HedgeDelay(); ok {
+			if d != 200*time.Millisecond {
+				t.Errorf("expected the latency of the loser measured from the original start, got: %v", d)
+			}
+
+			return
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("the latency of the losers was not observed")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package proxy_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/metrics"
+	"github.com/zalando/skipper/proxy"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+// hedgeBackends holds the first request received by any of the backends until it is canceled or until the
+// timeout, while the other requests are served immediately
+type hedgeBackends struct {
+	mu       sync.Mutex
+	received int
+	canceled chan struct{}
+	timeout  time.Duration
+}
+
+func (b *hedgeBackends) backend(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+
+		b.mu.Lock()
+		b.received++
+		first := b.received == 1
+		b.mu.Unlock()
+
+		if first {
+			select {
+			case <-r.Context().Done():
+				close(b.canceled)
+				return
+			case <-time.After(b.timeout):
+			}
+		}
+
+		w.Write([]byte(name))
+	}))
+}
+
+func (b *hedgeBackends) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.received
+}
+
+// hedgeMetrics records only the counters
+type hedgeMetrics struct {
+	metrics.Metrics
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+func (m *hedgeMetrics) IncCounter(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[key]++
+}
+
+func (m *hedgeMetrics) counter(key string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[key]
+}
+
+func TestHedge(t *testing.T) {
+	for _, tt := range []struct {
+		title       string
+		method      string
+		body        string
+		filter      string
+		timeout     time.Duration
+		expectCount int
+		expectHedge bool
+	}{{
+		title:       "hedges the slow request",
+		method:      "GET",
+		filter:      `hedge("20ms", 1)`,
+		timeout:     3 * time.Second,
+		expectCount: 2,
+		expectHedge: true,
+	}, {
+		title:       "doesn't hedge a fast request",
+		method:      "GET",
+		filter:      `hedge("1s", 1)`,
+		timeout:     20 * time.Millisecond,
+		expectCount: 1,
+	}, {
+		title:       "doesn't hedge non-idempotent requests",
+		method:      "POST",
+		body:        "foo",
+		filter:      `hedge("20ms", 1)`,
+		timeout:     200 * time.Millisecond,
+		expectCount: 1,
+	}, {
+		title:       "doesn't hedge without observed latency",
+		method:      "GET",
+		filter:      `hedge("p99", 1)`,
+		timeout:     200 * time.Millisecond,
+		expectCount: 1,
+	}} {
+		t.Run(tt.title, func(t *testing.T) {
+			m := &hedgeMetrics{Metrics: metrics.Void, counters: make(map[string]int64)}
+			defer func(dm metrics.Metrics) { metrics.Default = dm }(metrics.Default)
+			metrics.Default = m
+
+			b := &hedgeBackends{canceled: make(chan struct{}), timeout: tt.timeout}
+			b1, b2 := b.backend("b1"), b.backend("b2")
+			defer b1.Close()
+			defer b2.Close()
+
+			r, err := eskip.Parse(fmt.Sprintf(`r: * -> %s -> <roundRobin, "%s", "%s">`, tt.filter, b1.URL, b2.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := proxytest.WithParams(builtin.MakeRegistry(), proxy.Params{CloseIdleConnsPeriod: -1}, r...)
+			defer p.Close()
+
+			req, err := http.NewRequest(tt.method, p.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.body == "" {
+				req.Body = nil
+			}
+
+			start := time.Now()
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer rsp.Body.Close()
+			if rsp.StatusCode != http.StatusOK {
+				t.Fatalf("invalid status: %d", rsp.StatusCode)
+			}
+
+			if _, err := io.ReadAll(rsp.Body); err != nil {
+				t.Fatal(err)
+			}
+
+			if c := b.count(); c != tt.expectCount {
+				t.Errorf("invalid number of backend requests, expected: %d, got: %d", tt.expectCount, c)
+			}
+
+			if !tt.expectHedge {
+				if c := m.counter("hedge.sent.r"); c != 0 {
+					t.Errorf("unexpected hedged requests: %d", c)
+				}
+
+				return
+			}
+
+			if d := time.Since(start); d >= tt.timeout {
+				t.Errorf("the hedged request didn't win: %v", d)
+			}
+
+			select {
+			case <-b.canceled:
+			case <-time.After(time.Second):
+				t.Error("failed to cancel the slow request")
+			}
+
+			if m.counter("hedge.sent.r") != 1 || m.counter("hedge.won.r") != 1 {
+				t.Errorf("invalid hedge counters: %d, %d", m.counter("hedge.sent.r"), m.counter("hedge.won.r"))
+			}
+		})
+	}
+}
@@ -28,6 +28,7 @@ import (
 	circuitfilters "github.com/zalando/skipper/filters/circuit"
 	flowidFilter "github.com/zalando/skipper/filters/flowid"
 	ratelimitfilters "github.com/zalando/skipper/filters/ratelimit"
+	tlsfilters "github.com/zalando/skipper/filters/tls"
 	tracingfilter "github.com/zalando/skipper/filters/tracing"
 	"github.com/zalando/skipper/loadbalancer"
 	"github.com/zalando/skipper/logging"
@@ -203,6 +204,10 @@ type Params struct {
 	// LoadBalancer to report unhealthy or dead backends to
 	LoadBalancer *loadbalancer.LB
 
+	// LoadShedder decides, based on the priority class of the requests and the current load, which backend
+	// requests are rejected. If not set, no requests are shed.
+	LoadShedder *scheduler.LoadShedder
+
 	// Defines the time period of how often the idle connections are
 	// forcibly closed. The default is 12 seconds. When set to less than
 	// 0, the proxy doesn't force closing the idle connections.
@@ -241,6 +246,10 @@ type Params struct {
 	// It allows to add additional logic (for example tracing) by providing a wrapper function
 	// which accepts original skipper http.RoundTripper as an argument and returns a wrapped roundtripper
 	CustomHttpRoundTripperWrap func(http.RoundTripper) http.RoundTripper
+
+	// DebugTrace, when set, enables the per-request debug traces,
+	// triggered by a request header. See DebugTraceParams.
+	DebugTrace *DebugTraceParams
 }
 
 type (
@@ -266,6 +275,7 @@ var (
 		code:             http.StatusServiceUnavailable,
 		additionalHeader: http.Header{"X-Circuit-Open": []string{"true"}},
 	}
+	errLoadShedding = errors.New("load shedding")
 
 	disabledAccessLog = al.AccessLogFilter{Enable: false, Prefixes: nil}
 	enabledAccessLog  = al.AccessLogFilter{Enable: true, Prefixes: nil}
@@ -320,6 +330,7 @@ type Proxy struct {
 	defaultHTTPStatus        int
 	routing                  *routing.Routing
 	roundTripper             http.RoundTripper
+	backendTLS               *backendTLSTransports
 	priorityRoutes           []PriorityRoute
 	flags                    Flags
 	metrics                  metrics.Metrics
@@ -327,6 +338,7 @@ type Proxy struct {
 	flushInterval            time.Duration
 	breakers                 *circuit.Registry
 	limiters                 *ratelimit.Registry
+	loadShedder              *scheduler.LoadShedder
 	log                      logging.Logger
 	tracing                  *proxyTracing
 	lb                       *loadbalancer.LB
@@ -335,6 +347,7 @@ type Proxy struct {
 	auditLogHook             chan struct{}
 	clientTLS                *tls.Config
 	hostname                 string
+	debugTracer              *debugTracer
 }
 
 // proxyError is used to wrap errors during proxying and to indicate
@@ -631,6 +644,8 @@ func WithParams(p Params) *Proxy {
 		Proxy:                 proxyFromHeader,
 	}
 
+	backendTLS := newBackendTLSTransports(tr, p.CustomHttpRoundTripperWrap, p.Flags.Insecure())
+
 	quit := make(chan struct{})
 	// We need this to reliably fade on DNS change, which is right
 	// now not fixed with IdleConnTimeout in the http.Transport.
@@ -641,6 +656,7 @@ func WithParams(p Params) *Proxy {
 				select {
 				case <-time.After(p.CloseIdleConnsPeriod):
 					tr.CloseIdleConnections()
+					backendTLS.closeIdleConnections()
 				case <-quit:
 					return
 				}
@@ -648,6 +664,19 @@ func WithParams(p Params) *Proxy {
 		}()
 	}
 
+	go func() {
+		ticker := time.NewTicker(backendTLSUnusedPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				backendTLS.dropUnused(time.Now().Add(-backendTLSUnusedPeriod))
+			case <-quit:
+				return
+			}
+		}
+	}()
+
 	if p.ClientTLS != nil {
 		tr.TLSClientConfig = p.ClientTLS
 	}
@@ -684,6 +713,7 @@ func WithParams(p Params) *Proxy {
 	return &Proxy{
 		routing:                  p.Routing,
 		roundTripper:             p.CustomHttpRoundTripperWrap(tr),
+		backendTLS:               backendTLS,
 		priorityRoutes:           p.PriorityRoutes,
 		flags:                    p.Flags,
 		metrics:                  m,
@@ -695,6 +725,7 @@ func WithParams(p Params) *Proxy {
 		breakers:                 p.CircuitBreakers,
 		lb:                       p.LoadBalancer,
 		limiters:                 p.RateLimiters,
+		loadShedder:              p.LoadShedder,
 		log:                      &logging.DefaultLog{},
 		defaultHTTPStatus:        defaultHTTPStatus,
 		tracing:                  newProxyTracing(p.OpenTracing),
@@ -703,6 +734,7 @@ func WithParams(p Params) *Proxy {
 		upgradeAuditLogErr:       os.Stderr,
 		clientTLS:                tr.TLSClientConfig,
 		hostname:                 hostname,
+		debugTracer:              newDebugTracer(p.DebugTrace),
 	}
 }
 
@@ -750,11 +782,19 @@ func (p *Proxy) applyFiltersToRequest(f []*routing.RouteFilter, ctx *context) []
 	for _, fi := range f {
 		start := time.Now()
 		filterTracing.logStart(fi.Name)
+
+		var headerBefore http.Header
+		var filterPanic interface{}
+		if ctx.debugTrace != nil {
+			headerBefore = ctx.request.Header.Clone()
+		}
+
 		tryCatch(func() {
 			ctx.setMetricsPrefix(fi.Name)
 			fi.Request(ctx)
 			p.metrics.MeasureFilterRequest(fi.Name, start)
 		}, func(err interface{}, stack string) {
+			filterPanic = err
 			if p.flags.Debug() {
 				// these errors are collected for the debug mode to be able
 				// to report in the response which filters failed.
@@ -766,6 +806,16 @@ func (p *Proxy) applyFiltersToRequest(f []*routing.RouteFilter, ctx *context) []
 		})
 		filterTracing.logEnd(fi.Name)
 
+		if ctx.debugTrace != nil {
+			ft := filterTrace(fi.Name, time.Since(start), headerBefore, ctx.request.Header)
+			ft.Served = ctx.deprecatedShunted() || ctx.shunted()
+			if filterPanic != nil {
+				ft.Panic = fmt.Sprint(filterPanic)
+			}
+
+			ctx.debugTrace.RequestFilters = append(ctx.debugTrace.RequestFilters, ft)
+		}
+
 		filters = append(filters, fi)
 		if ctx.deprecatedShunted() || ctx.shunted() {
 			break
@@ -773,6 +823,7 @@ func (p *Proxy) applyFiltersToRequest(f []*routing.RouteFilter, ctx *context) []
 	}
 
 	p.metrics.MeasureAllFiltersRequest(ctx.route.Id, filtersStart)
+	ctx.requestFiltersDuration += time.Since(filtersStart)
 	return filters
 }
 
@@ -787,11 +838,19 @@ func (p *Proxy) applyFiltersToResponse(filters []*routing.RouteFilter, ctx *cont
 		fi := filters[last-i]
 		start := time.Now()
 		filterTracing.logStart(fi.Name)
+
+		var headerBefore http.Header
+		var filterPanic interface{}
+		if ctx.debugTrace != nil {
+			headerBefore = ctx.response.Header.Clone()
+		}
+
 		tryCatch(func() {
 			ctx.setMetricsPrefix(fi.Name)
 			fi.Response(ctx)
 			p.metrics.MeasureFilterResponse(fi.Name, start)
 		}, func(err interface{}, stack string) {
+			filterPanic = err
 			if p.flags.Debug() {
 				// these errors are collected for the debug mode to be able
 				// to report in the response which filters failed.
@@ -802,9 +861,19 @@ func (p *Proxy) applyFiltersToResponse(filters []*routing.RouteFilter, ctx *cont
 			p.log.Errorf("error while processing filters during response: %s: %v (%s)", fi.Name, err, stack)
 		})
 		filterTracing.logEnd(fi.Name)
+
+		if ctx.debugTrace != nil {
+			ft := filterTrace(fi.Name, time.Since(start), headerBefore, ctx.response.Header)
+			if filterPanic != nil {
+				ft.Panic = fmt.Sprint(filterPanic)
+			}
+
+			ctx.debugTrace.ResponseFilters = append(ctx.debugTrace.ResponseFilters, ft)
+		}
 	}
 
 	p.metrics.MeasureAllFiltersResponse(ctx.route.Id, filtersStart)
+	ctx.responseFiltersDuration += time.Since(filtersStart)
 }
 
 // addBranding overwrites any existing `X-Powered-By` or `Server` header from headerMap
@@ -822,6 +891,14 @@ func (p *Proxy) lookupRoute(ctx *context) (rt *routing.Route, params map[string]
 		}
 	}
 
+	if ctx.debugTrace != nil {
+		// the custom predicates are traced during the lookup, to avoid evaluating them again
+		var evals []routing.PredicateEvaluation
+		rt, params, evals = ctx.routeLookup.DoWithEvaluations(ctx.request)
+		ctx.debugTrace.setPredicateEvaluations(evals)
+		return rt, params
+	}
+
 	return ctx.routeLookup.Do(ctx.request)
 }
 
@@ -874,10 +951,15 @@ func (p *Proxy) makeBackendRequest(ctx *context, requestContext stdlibcontext.Co
 		return nil, &proxyError{err: fmt.Errorf("could not map backend request: %w", err)}
 	}
 
+	ctx.backendEndpoint = req.URL.Host
 	if res, ok := p.rejectBackend(ctx, req); ok {
 		return res, nil
 	}
 
+	if h, ok := hedging(ctx, req); ok {
+		return p.makeHedgedBackendRequest(ctx, req, endpoint, h)
+	}
+
 	if endpoint != nil {
 		endpoint.Metrics.IncInflightRequest()
 		defer endpoint.Metrics.DecInflightRequest()
@@ -897,42 +979,58 @@ func (p *Proxy) makeBackendRequest(ctx *context, requestContext stdlibcontext.Co
 		return nil, &proxyError{err: fmt.Errorf("failed to get roundtripper: %w", err), code: http.StatusBadGateway}
 	}
 
+	req, ctx.proxySpan = p.startProxySpan(ctx, req)
+
+	roundTripStart := time.Now()
+	response, err := roundTripper.RoundTrip(req)
+	if ctx.debugTrace != nil {
+		ctx.debugTrace.addBackendAttempt(req.URL.Host, time.Since(roundTripStart), response, err)
+	}
+
+	return p.roundTripResult(ctx.proxySpan, req, response, err)
+}
+
+// startProxySpan creates the tracing span of a backend roundtrip, and returns the request carrying it.
+func (p *Proxy) startProxySpan(ctx *context, req *http.Request) (*http.Request, ot.Span) {
 	bag := ctx.StateBag()
 	spanName, ok := bag[tracingfilter.OpenTracingProxySpanKey].(string)
 	if !ok {
 		spanName = "proxy"
 	}
-	ctx.proxySpan = tracing.CreateSpan(spanName, req.Context(), p.tracing.tracer)
+	proxySpan := tracing.CreateSpan(spanName, req.Context(), p.tracing.tracer)
 
 	u := cloneURL(req.URL)
 	u.RawQuery = ""
 	p.tracing.
-		setTag(ctx.proxySpan, SpanKindTag, SpanKindClient).
-		setTag(ctx.proxySpan, SkipperRouteIDTag, ctx.route.Id).
-		setTag(ctx.proxySpan, HTTPUrlTag, u.String())
-	p.setCommonSpanInfo(u, req, ctx.proxySpan)
+		setTag(proxySpan, SpanKindTag, SpanKindClient).
+		setTag(proxySpan, SkipperRouteIDTag, ctx.route.Id).
+		setTag(proxySpan, HTTPUrlTag, u.String())
+	p.setCommonSpanInfo(u, req, proxySpan)
 
 	carrier := ot.HTTPHeadersCarrier(req.Header)
-	_ = p.tracing.tracer.Inject(ctx.proxySpan.Context(), ot.HTTPHeaders, carrier)
+	_ = p.tracing.tracer.Inject(proxySpan.Context(), ot.HTTPHeaders, carrier)
 
-	req = req.WithContext(ot.ContextWithSpan(req.Context(), ctx.proxySpan))
+	req = req.WithContext(ot.ContextWithSpan(req.Context(), proxySpan))
 
 	p.metrics.IncCounter("outgoing." + req.Proto)
-	ctx.proxySpan.LogKV("http_roundtrip", StartEvent)
-	req = injectClientTrace(req, ctx.proxySpan)
-
-	response, err := roundTripper.RoundTrip(req)
+	proxySpan.LogKV("http_roundtrip", StartEvent)
+	req = injectClientTrace(req, proxySpan)
+	return req, proxySpan
+}
 
-	ctx.proxySpan.LogKV("http_roundtrip", EndEvent)
+// roundTripResult maps the outcome of a backend roundtrip to the proxy response or error. It doesn't access the
+// request context, so it can be used concurrently by the hedged requests.
+func (p *Proxy) roundTripResult(proxySpan ot.Span, req *http.Request, response *http.Response, err error) (*http.Response, *proxyError) {
+	proxySpan.LogKV("http_roundtrip", EndEvent)
 	if err != nil {
-		p.tracing.setTag(ctx.proxySpan, ErrorTag, true)
+		p.tracing.setTag(proxySpan, ErrorTag, true)
 
 		// Check if the request has been cancelled or timed out
 		// The roundtrip error `err` may be different:
 		// - for `Canceled` it could be either the same `context canceled` or `unexpected EOF` (net.OpError)
 		// - for `DeadlineExceeded` it is net.Error(timeout=true, temporary=true) wrapping this `context deadline exceeded`
 		if cerr := req.Context().Err(); cerr != nil {
-			ctx.proxySpan.LogKV("event", "error", "message", cerr.Error())
+			proxySpan.LogKV("event", "error", "message", cerr.Error())
 			if cerr == stdlibcontext.Canceled {
 				return nil, &proxyError{err: cerr, code: 499}
 			} else if cerr == stdlibcontext.DeadlineExceeded {
@@ -940,7 +1038,7 @@ func (p *Proxy) makeBackendRequest(ctx *context, requestContext stdlibcontext.Co
 			}
 		}
 
-		ctx.proxySpan.LogKV("event", "error", "message", err.Error())
+		proxySpan.LogKV("event", "error", "message", err.Error())
 
 		if perr, ok := err.(*proxyError); ok {
 			//p.lb.AddHealthcheck(ctx.route.Backend)
@@ -955,14 +1053,14 @@ func (p *Proxy) makeBackendRequest(ctx *context, requestContext stdlibcontext.Co
 			} else {
 				status = http.StatusServiceUnavailable
 			}
-			p.tracing.setTag(ctx.proxySpan, HTTPStatusCodeTag, uint16(status))
+			p.tracing.setTag(proxySpan, HTTPStatusCodeTag, uint16(status))
 			//lint:ignore SA1019 Temporary is deprecated in Go 1.18, but keep it for now (https://github.com/zalando/skipper/issues/1992)
 			return nil, &proxyError{err: fmt.Errorf("net.Error during backend roundtrip to %s: timeout=%v temporary='%v': %w", req.URL.Host, nerr.Timeout(), nerr.Temporary(), err), code: status}
 		}
 
 		return nil, &proxyError{err: fmt.Errorf("unexpected error from Go stdlib net/http package during roundtrip: %w", err)}
 	}
-	p.tracing.setTag(ctx.proxySpan, HTTPStatusCodeTag, uint16(response.StatusCode))
+	p.tracing.setTag(proxySpan, HTTPStatusCodeTag, uint16(response.StatusCode))
 	return response, nil
 }
 
@@ -991,6 +1089,10 @@ func (p *Proxy) getRoundTripper(ctx *context, req *http.Request) (http.RoundTrip
 
 		return rt, nil
 	default:
+		if c, ok := ctx.StateBag()[filters.BackendTLS].(*tlsfilters.BackendTLS); ok {
+			return p.backendTLS.get(c), nil
+		}
+
 		return p.roundTripper, nil
 	}
 }
@@ -1032,6 +1134,32 @@ func (p *Proxy) checkBreaker(c *context) (func(bool), bool) {
 	return done, ok
 }
 
+func (p *Proxy) admitRequest(c *context) (func(), bool) {
+	if p.loadShedder == nil {
+		return nil, true
+	}
+
+	priority, ok := c.StateBag()[scheduler.PriorityKey].(scheduler.Priority)
+	if !ok {
+		priority = scheduler.PriorityNormal
+	}
+
+	done, ok := p.loadShedder.Admit(priority)
+	if !ok && c.request.Body != nil {
+		// consume the body to prevent goroutine leaks
+		io.Copy(io.Discard, c.request.Body)
+	}
+	return done, ok
+}
+
+func newLoadSheddingError(retryAfter int) error {
+	return &proxyError{
+		err:              errLoadShedding,
+		code:             http.StatusServiceUnavailable,
+		additionalHeader: http.Header{"Retry-After": []string{strconv.Itoa(retryAfter)}},
+	}
+}
+
 func newRatelimitError(settings ratelimit.Settings, retryAfter int) error {
 	return &proxyError{
 		err:              errRatelimit,
@@ -1074,6 +1202,10 @@ func (p *Proxy) do(ctx *context) error {
 		return errRouteLookupFailed
 	}
 
+	if ctx.debugTrace != nil {
+		ctx.debugTrace.setRoute(route, params, time.Since(lookupStart))
+	}
+
 	ctx.applyRoute(route, params, p.flags.PreserveHost())
 
 	processedFilters := p.applyFiltersToRequest(ctx.route.Filters, ctx)
@@ -1091,12 +1223,18 @@ func (p *Proxy) do(ctx *context) error {
 		ctx.ensureDefaultResponse()
 	} else if ctx.route.BackendType == eskip.LoopBackend {
 		loopCTX := ctx.clone()
-		if err := p.do(loopCTX); err != nil {
+		err := p.do(loopCTX)
+		ctx.loadSheddingDone = loopCTX.loadSheddingDone
+		if err != nil {
 			return err
 		}
 
 		ctx.setResponse(loopCTX.response, p.flags.PreserveOriginal())
 		ctx.proxySpan = loopCTX.proxySpan
+		ctx.backendEndpoint = loopCTX.backendEndpoint
+		ctx.backendDuration = loopCTX.backendDuration
+		ctx.requestFiltersDuration = loopCTX.requestFiltersDuration
+		ctx.responseFiltersDuration = loopCTX.responseFiltersDuration
 	} else if p.flags.Debug() {
 		debugReq, _, err := mapRequest(ctx, ctx.request.Context(), p.flags.HopHeadersRemoval())
 		if err != nil {
@@ -1107,6 +1245,15 @@ func (p *Proxy) do(ctx *context) error {
 		ctx.setResponse(&http.Response{Header: make(http.Header)}, p.flags.PreserveOriginal())
 	} else {
 
+		admitted, ok := p.admitRequest(ctx)
+		if !ok {
+			tracing.LogKV("load_shedding", "rejected", ctx.request.Context())
+			return newLoadSheddingError(p.loadShedder.RetryAfter())
+		}
+
+		// the request is counted until the response body was streamed to the client
+		ctx.loadSheddingDone = admitted
+
 		done, allow := p.checkBreaker(ctx)
 		if !allow {
 			tracing.LogKV("circuit_breaker", "open", ctx.request.Context())
@@ -1120,6 +1267,7 @@ func (p *Proxy) do(ctx *context) error {
 
 		backendStart := time.Now()
 		rsp, perr := p.makeBackendRequest(ctx, backendContext)
+		ctx.backendDuration = time.Since(backendStart)
 		if perr != nil {
 			if done != nil {
 				done(false)
@@ -1138,6 +1286,7 @@ func (p *Proxy) do(ctx *context) error {
 				perr = nil
 				var perr2 *proxyError
 				rsp, perr2 = p.makeBackendRequest(ctx, backendContext)
+				ctx.backendDuration = time.Since(backendStart)
 				if perr2 != nil {
 					p.log.Errorf("Failed to retry backend request: %v", perr2)
 					if perr2.code >= http.StatusInternalServerError {
@@ -1202,6 +1351,10 @@ func (p *Proxy) serveResponse(ctx *context) {
 
 	p.tracing.setTag(ctx.initialSpan, HTTPStatusCodeTag, uint16(ctx.response.StatusCode))
 
+	if ctx.debugTrace != nil {
+		p.debugTracer.finish(ctx.debugTrace, ctx.responseWriter.Header(), ctx.response.StatusCode, nil)
+	}
+
 	ctx.responseWriter.WriteHeader(ctx.response.StatusCode)
 	ctx.responseWriter.Flush()
 	p.tracing.logStreamEvent(ctx.proxySpan, StreamHeadersEvent, EndEvent)
@@ -1276,6 +1429,10 @@ func (p *Proxy) errorResponse(ctx *context, err error) {
 		copyHeader(ctx.responseWriter.Header(), perr.additionalHeader)
 	}
 
+	if ctx.debugTrace != nil {
+		p.debugTracer.finish(ctx.debugTrace, ctx.responseWriter.Header(), code, err)
+	}
+
 	msgPrefix := "error while proxying"
 	logFunc := p.log.Errorf
 	if code == 499 {
@@ -1393,8 +1550,22 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				StatusCode:   statusCode,
 				RequestTime:  ctx.startServe,
 				Duration:     time.Since(ctx.startServe),
+
+				Backend:                 ctx.backendEndpoint,
+				BackendDuration:         ctx.backendDuration,
+				RequestFiltersDuration:  ctx.requestFiltersDuration,
+				ResponseFiltersDuration: ctx.responseFiltersDuration,
+				StateBag:                ctx.stateBag,
+				ResponseHeader:          lw.Header(),
 			}
 
+			if ctx.route != nil {
+				entry.RouteID = ctx.route.Id
+			}
+
+			entry.Format, _ = ctx.stateBag[al.AccessLogFormatKey].(*logging.AccessLogFormat)
+			entry.SampleRate, _ = ctx.stateBag[al.AccessLogSampleRateKey].(float64)
+
 			additionalData, _ := ctx.stateBag[al.AccessLogAdditionalDataKey].(map[string]interface{})
 
 			logging.LogAccess(entry, additionalData)
@@ -1416,8 +1587,10 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	p.setCommonSpanInfo(r.URL, r, span)
 	r = r.WithContext(ot.ContextWithSpan(r.Context(), span))
 
+	debugTrace := p.debugTracer.start(r)
 	ctx = newContext(lw, r, p)
 	ctx.startServe = time.Now()
+	ctx.debugTrace = debugTrace
 	ctx.tracer = p.tracing.tracer
 	ctx.initialSpan = span
 
@@ -1430,6 +1603,12 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	defer func() {
+		if ctx.loadSheddingDone != nil {
+			ctx.loadSheddingDone()
+		}
+	}()
+
 	err := p.do(ctx)
 
 	if err != nil {
@@ -1444,10 +1623,12 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // Close causes the proxy to stop closing idle
-// connections and, currently, has no other effect.
-// It's primary purpose is to support testing.
+// connections, and closes the transports used for the
+// backends with custom TLS configuration. It's primary
+// purpose is to support testing.
 func (p *Proxy) Close() error {
 	close(p.quit)
+	p.backendTLS.close()
 	return nil
 }
 
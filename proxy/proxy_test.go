@@ -1808,6 +1808,34 @@ func TestLogsAccess(t *testing.T) {
 	}
 }
 
+func TestLogsAccessFormat(t *testing.T) {
+	var accessLog bytes.Buffer
+	logging.Init(logging.Options{AccessLogOutput: &accessLog})
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "b1")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+
+	doc := fmt.Sprintf(`hello: Path("/hello") -> accessLogFormat("${route_id} ${backend} ${status} ${response.header.X-Backend}") -> "%s"`, backend.URL)
+	tp, err := newTestProxy(doc, FlagsNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tp.close()
+
+	r := httptest.NewRequest("GET", "https://www.example.org/hello", nil)
+	tp.proxy.ServeHTTP(httptest.NewRecorder(), r)
+
+	u, _ := url.Parse(backend.URL)
+	expected := fmt.Sprintf("hello %s %d b1\n", u.Host, http.StatusTeapot)
+	if output := accessLog.String(); output != expected {
+		t.Errorf("unexpected access log: %q, expected: %q", output, expected)
+	}
+}
+
 func TestDisableAccessLog(t *testing.T) {
 	var buf bytes.Buffer
 	logging.Init(logging.Options{
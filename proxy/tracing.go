@@ -11,6 +11,7 @@ const (
 	ComponentTag          = "component"
 	ErrorTag              = "error"
 	FlowIDTag             = "flow_id"
+	HedgedTag             = "skipper.hedged"
 	HostnameTag           = "hostname"
 	HTTPHostTag           = "http.host"
 	HTTPMethodTag         = "http.method"
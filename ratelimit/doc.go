@@ -22,6 +22,12 @@ calculate rate limiting. If there is no such header it will use the
 remote IP of the request. This is the default Lookuper and may be the
 one most users want to use.
 
+Lookuper Type - ClientIDLookuper
+
+This lookuper will use the client id stored in the state bag by the
+authentication filters, e.g. apiKey, to calculate rate limiting. It is
+selected in the ratelimit filters by the auth:clientid lookuper name.
+
 Usage
 
 When imported as a package, the Registry can be used to hold the rate
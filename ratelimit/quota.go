@@ -0,0 +1,318 @@
+package ratelimit
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/zalando/skipper/metrics"
+	"github.com/zalando/skipper/net"
+)
+
+// QuotaPeriod is the calendar period of a quota. The periods are aligned
+// to the calendar in UTC, e.g. the monthly quotas reset at midnight UTC
+// of the first day of the month.
+type QuotaPeriod int
+
+const (
+	QuotaDay QuotaPeriod = iota + 1
+	QuotaWeek
+	QuotaMonth
+)
+
+const (
+	// QuotaLimitHeader is the name of the header containing the number of
+	// requests allowed in the current period
+	QuotaLimitHeader = "X-Quota-Limit"
+
+	// QuotaRemainingHeader is the name of the header containing the number
+	// of requests left in the current period
+	QuotaRemainingHeader = "X-Quota-Remaining"
+
+	// QuotaResetHeader is the name of the header containing the number of
+	// seconds until the quota resets
+	QuotaResetHeader = "X-Quota-Reset"
+
+	// QuotaWarningHeader is set on the responses when the used quota
+	// reached the soft limit
+	QuotaWarningHeader = "X-Quota-Warning"
+)
+
+const (
+	quotaRedisKeyPrefix = "quota."
+	quotaMetricPrefix   = "quota.redis."
+	quotaMetricLatency  = quotaMetricPrefix + "latency"
+	quotaSpanName       = "redis_quota"
+
+	// the counters are kept a bit longer than the period, to tolerate
+	// the clock skew between the instances
+	quotaExpiryMargin = time.Hour
+)
+
+// Implements the quota counters as a Redis lua script, see
+// NewClusterQuota.
+//
+//go:embed quota.lua
+var quotaScript string
+
+// identifies the quotas in the registry, the same way as their counters
+type quotaKey struct {
+	group  string
+	period QuotaPeriod
+	limit  int64
+}
+
+// ClusterQuota counts the requests of the clients over a calendar
+// period in Redis, and rejects them when the limit is reached.
+type ClusterQuota struct {
+	group      string
+	limit      int64
+	period     QuotaPeriod
+	script     *net.RedisScript
+	ringClient *net.RedisRingClient
+	metrics    metrics.Metrics
+	now        func() time.Time
+}
+
+// QuotaUsage is the state of the quota of a client in the current
+// period.
+type QuotaUsage struct {
+	Group     string    `json:"group"`
+	Period    string    `json:"period"`
+	Client    string    `json:"client"`
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+
+	// Exceeded is true when the last request was rejected.
+	Exceeded bool `json:"-"`
+}
+
+// ParseQuotaPeriod parses the period names day, week and month.
+func ParseQuotaPeriod(s string) (QuotaPeriod, error) {
+	switch s {
+	case "day":
+		return QuotaDay, nil
+	case "week":
+		return QuotaWeek, nil
+	case "month":
+		return QuotaMonth, nil
+	default:
+		return 0, fmt.Errorf("invalid quota period: %s", s)
+	}
+}
+
+func (p QuotaPeriod) String() string {
+	switch p {
+	case QuotaDay:
+		return "day"
+	case QuotaWeek:
+		return "week"
+	case QuotaMonth:
+		return "month"
+	default:
+		return "unknown"
+	}
+}
+
+// bounds returns the start and the end of the period containing t. The
+// weeks start on Monday.
+func (p QuotaPeriod) bounds(t time.Time) (start, end time.Time) {
+	t = t.UTC()
+	y, m, d := t.Date()
+	switch p {
+	case QuotaWeek:
+		start = time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 0, 7)
+	case QuotaMonth:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, 0)
+	default:
+		start = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 0, 1)
+	}
+
+	return
+}
+
+// NewClusterQuota creates the quota of a group, shared by all the
+// instances using the same Redis ring. The counters of the clients are
+// reset at the start of every period. The quota is registered in the
+// registry by its group, period and limit, replacing the previous quota
+// with the same ones, so that its usage can be read and reset with the
+// QuotaHandler.
+//
+// The counters are stored per group, period, limit and client, this way
+// the quotas of the same group with different periods or limits don't
+// share the counters, and changing the limit of a quota starts new
+// counters.
+//
+// Unlike the cluster rate limits, the quotas don't store the timestamps
+// of the requests, only a counter per client and period, which makes
+// them suitable for long periods with a high number of requests.
+func NewClusterQuota(r *Registry, group string, limit int, period QuotaPeriod) *ClusterQuota {
+	q := newClusterQuota(r.redisRing, group, limit, period, time.Now)
+
+	r.Lock()
+	defer r.Unlock()
+	if r.quotas == nil {
+		r.quotas = make(map[quotaKey]*ClusterQuota)
+	}
+
+	r.quotas[quotaKey{group: group, period: period, limit: int64(limit)}] = q
+	return q
+}
+
+func newClusterQuota(ringClient *net.RedisRingClient, group string, limit int, period QuotaPeriod, now func() time.Time) *ClusterQuota {
+	return &ClusterQuota{
+		group:      group,
+		limit:      int64(limit),
+		period:     period,
+		script:     ringClient.NewScript(quotaScript),
+		ringClient: ringClient,
+		metrics:    metrics.Default,
+		now:        now,
+	}
+}
+
+// Quotas returns the quotas registered for the group and the period,
+// ordered by their limit. The same group and period can have multiple
+// quotas, when they are used with different limits.
+func (r *Registry) Quotas(group string, period QuotaPeriod) []*ClusterQuota {
+	r.Lock()
+	defer r.Unlock()
+
+	var quotas []*ClusterQuota
+	for k, q := range r.quotas {
+		if k.group == group && k.period == period {
+			quotas = append(quotas, q)
+		}
+	}
+
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].limit < quotas[j].limit })
+	return quotas
+}
+
+func (q *ClusterQuota) key(client string, start time.Time) string {
+	return quotaRedisKeyPrefix + getHashedKey(fmt.Sprintf(
+		"%s.%s.%d.%s.%s",
+		q.group,
+		q.period,
+		q.limit,
+		start.Format("20060102"),
+		client,
+	))
+}
+
+func (q *ClusterQuota) usage(client string, used int64, end time.Time) QuotaUsage {
+	remaining := q.limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return QuotaUsage{
+		Group:     q.group,
+		Period:    q.period.String(),
+		Client:    client,
+		Limit:     q.limit,
+		Used:      used,
+		Remaining: remaining,
+		Reset:     end,
+	}
+}
+
+// Add adds the increment to the usage of the client in the current
+// period, unless it would exceed the limit, in which case the returned
+// usage is marked as exceeded.
+func (q *ClusterQuota) Add(ctx context.Context, client string, increment int) (QuotaUsage, error) {
+	now := q.now()
+	span := q.startSpan(ctx)
+	defer span.Finish()
+	defer q.metrics.MeasureSince(quotaMetricLatency, now)
+
+	start, end := q.period.bounds(now)
+	r, err := q.ringClient.RunScript(ctx, q.script,
+		[]string{q.key(client, start)},
+		q.limit,
+		increment,
+		end.Add(quotaExpiryMargin).Unix(),
+	)
+	if err != nil {
+		ext.Error.Set(span, true)
+		return QuotaUsage{}, err
+	}
+
+	used, ok := r.(int64)
+	if !ok {
+		return QuotaUsage{}, fmt.Errorf("unexpected quota script result: %v", r)
+	}
+
+	if used < 0 {
+		u := q.usage(client, -used-1, end)
+		u.Exceeded = true
+		return u, nil
+	}
+
+	return q.usage(client, used, end), nil
+}
+
+// Usage returns the usage of the client in the current period.
+func (q *ClusterQuota) Usage(ctx context.Context, client string) (QuotaUsage, error) {
+	start, end := q.period.bounds(q.now())
+	v, err := q.ringClient.Get(ctx, q.key(client, start))
+	if err == redis.Nil {
+		return q.usage(client, 0, end), nil
+	} else if err != nil {
+		return QuotaUsage{}, err
+	}
+
+	used, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return QuotaUsage{}, fmt.Errorf("invalid quota counter: %w", err)
+	}
+
+	return q.usage(client, used, end), nil
+}
+
+// Reset sets the usage of the client in the current period to zero.
+func (q *ClusterQuota) Reset(ctx context.Context, client string) error {
+	now := q.now()
+	start, end := q.period.bounds(now)
+	_, err := q.ringClient.Set(ctx, q.key(client, start), 0, end.Add(quotaExpiryMargin).Sub(now))
+	return err
+}
+
+func (q *ClusterQuota) startSpan(ctx context.Context) (span opentracing.Span) {
+	parent := opentracing.SpanFromContext(ctx)
+	if parent != nil {
+		span = q.ringClient.StartSpan(quotaSpanName, opentracing.ChildOf(parent.Context()))
+	} else {
+		span = opentracing.NoopTracer{}.StartSpan("")
+	}
+	ext.Component.Set(span, "skipper")
+	ext.SpanKind.Set(span, "client")
+	return
+}
+
+// QuotaHeaders returns the quota headers of the response.
+func QuotaHeaders(u QuotaUsage, now time.Time) http.Header {
+	reset := int64(u.Reset.Sub(now) / time.Second)
+	if reset < 0 {
+		reset = 0
+	}
+
+	return http.Header{
+		QuotaLimitHeader:     []string{strconv.FormatInt(u.Limit, 10)},
+		QuotaRemainingHeader: []string{strconv.FormatInt(u.Remaining, 10)},
+		QuotaResetHeader:     []string{strconv.FormatInt(reset, 10)},
+	}
+}
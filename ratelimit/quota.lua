@@ -0,0 +1,18 @@
+local key = KEYS[1]                 -- quota counter of the client in the current period
+local limit = tonumber(ARGV[1])     -- maximum number of units in the period
+local increment = tonumber(ARGV[2]) -- increment in units
+local expire_at = tonumber(ARGV[3]) -- end of the period in unix seconds
+
+-- The counter is not incremented beyond the limit, so that the rejected
+-- requests are not counted as used.
+-- Returns the used units after the increment, or -(used units + 1) when
+-- the increment would exceed the limit.
+local used = tonumber(redis.call("GET", key) or "0")
+if used + increment > limit then
+    return -used - 1
+end
+
+used = redis.call("INCRBY", key, increment)
+redis.call("EXPIREAT", key, expire_at)
+
+return used
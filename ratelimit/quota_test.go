@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zalando/skipper/net"
+	"github.com/zalando/skipper/net/redistest"
+)
+
+func TestQuotaPeriodBounds(t *testing.T) {
+	for _, tc := range []struct {
+		period QuotaPeriod
+		now    string
+		start  string
+		end    string
+	}{
+		{QuotaDay, "2026-10-19T13:45:00Z", "2026-10-19T00:00:00Z", "2026-10-20T00:00:00Z"},
+		{QuotaDay, "2026-12-31T23:59:59Z", "2026-12-31T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{QuotaDay, "2026-10-19T01:00:00+02:00", "2026-10-18T00:00:00Z", "2026-10-19T00:00:00Z"},
+		{QuotaWeek, "2026-10-19T13:45:00Z", "2026-10-19T00:00:00Z", "2026-10-26T00:00:00Z"},
+		{QuotaWeek, "2026-10-25T23:00:00Z", "2026-10-19T00:00:00Z", "2026-10-26T00:00:00Z"},
+		{QuotaWeek, "2026-11-01T10:00:00Z", "2026-10-26T00:00:00Z", "2026-11-02T00:00:00Z"},
+		{QuotaMonth, "2026-10-19T13:45:00Z", "2026-10-01T00:00:00Z", "2026-11-01T00:00:00Z"},
+		{QuotaMonth, "2026-12-15T00:00:00Z", "2026-12-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{QuotaMonth, "2028-02-29T12:00:00Z", "2028-02-01T00:00:00Z", "2028-03-01T00:00:00Z"},
+	} {
+		now, err := time.Parse(time.RFC3339, tc.now)
+		require.NoError(t, err)
+
+		start, end := tc.period.bounds(now)
+		assert.Equal(t, tc.start, start.Format(time.RFC3339), "%v start of %s", tc.period, tc.now)
+		assert.Equal(t, tc.end, end.Format(time.RFC3339), "%v end of %s", tc.period, tc.now)
+	}
+}
+
+func TestParseQuotaPeriod(t *testing.T) {
+	for _, p := range []QuotaPeriod{QuotaDay, QuotaWeek, QuotaMonth} {
+		parsed, err := ParseQuotaPeriod(p.String())
+		assert.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+
+	_, err := ParseQuotaPeriod("year")
+	assert.Error(t, err)
+}
+
+func TestQuotaKey(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	q1 := newClusterQuota(nil, "a", 10, QuotaDay, time.Now)
+	q2 := newClusterQuota(nil, "b", 10, QuotaDay, time.Now)
+	q3 := newClusterQuota(nil, "a", 10, QuotaMonth, time.Now)
+	q4 := newClusterQuota(nil, "a", 20, QuotaDay, time.Now)
+
+	assert.NotEqual(t, q1.key("client", now), q2.key("client", now))
+	assert.NotEqual(t, q1.key("client", now), q3.key("client", now), "the periods starting at the same time")
+	assert.NotEqual(t, q1.key("client", now), q4.key("client", now))
+	assert.NotEqual(t, q1.key("client", now), q1.key("client", now.AddDate(0, 0, 1)))
+	assert.NotEqual(t, q1.key("client", now), q1.key("other", now))
+}
+
+func TestQuotaHeaders(t *testing.T) {
+	now := time.Now()
+	h := QuotaHeaders(QuotaUsage{Limit: 100, Used: 40, Remaining: 60, Reset: now.Add(time.Hour)}, now)
+
+	assert.Equal(t, "100", h.Get(QuotaLimitHeader))
+	assert.Equal(t, "60", h.Get(QuotaRemainingHeader))
+	assert.Equal(t, "3600", h.Get(QuotaResetHeader))
+}
+
+func TestClusterQuota(t *testing.T) {
+	redisAddr, done := redistest.NewTestRedis(t)
+	defer done()
+
+	ringClient := net.NewRedisRingClient(&net.RedisOptions{Addrs: []string{redisAddr}})
+	defer ringClient.Close()
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	q := newClusterQuota(ringClient, "orders", 3, QuotaDay, func() time.Time { return now })
+	ctx := context.Background()
+
+	for i, exceeded := range []bool{false, false, false, true, true} {
+		u, err := q.Add(ctx, "acme", 1)
+		require.NoError(t, err)
+		assert.Equal(t, exceeded, u.Exceeded, "request %d", i)
+	}
+
+	u, err := q.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), u.Used)
+	assert.Equal(t, int64(0), u.Remaining)
+	assert.Equal(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), u.Reset)
+
+	u, err = q.Usage(ctx, "globex")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), u.Used)
+
+	// the quota resets at the start of the next day
+	now = now.Add(12 * time.Hour)
+	u, err = q.Add(ctx, "acme", 1)
+	require.NoError(t, err)
+	assert.False(t, u.Exceeded)
+	assert.Equal(t, int64(1), u.Used)
+
+	require.NoError(t, q.Reset(ctx, "acme"))
+	u, err = q.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), u.Used)
+}
+
+func TestQuotaRedisError(t *testing.T) {
+	ringClient := net.NewRedisRingClient(&net.RedisOptions{Addrs: []string{"no-such-host.test:123"}})
+	defer ringClient.Close()
+
+	q := newClusterQuota(ringClient, "orders", 1, QuotaDay, time.Now)
+	_, err := q.Add(context.Background(), "acme", 1)
+	assert.Error(t, err)
+}
+
+func TestQuotaHandler(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+
+	NewClusterQuota(r, "orders", 10, QuotaMonth)
+	NewClusterQuota(r, "orders", 100, QuotaDay)
+	NewClusterQuota(r, "orders", 20, QuotaMonth)
+	NewClusterQuota(r, "orders", 10, QuotaMonth)
+	if q := r.Quotas("orders", QuotaMonth); len(q) != 2 || q[0].limit != 10 || q[1].limit != 20 {
+		t.Fatal("failed to register the quotas of the same group by period and limit")
+	}
+
+	h := NewQuotaHandler(r)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/quotas/", http.StatusNotFound},
+		{"GET", "/quotas/orders", http.StatusNotFound},
+		{"GET", "/quotas/orders/month", http.StatusNotFound},
+		{"GET", "/quotas/orders/month/", http.StatusNotFound},
+		{"GET", "/quotas/orders/year/acme", http.StatusNotFound},
+		{"GET", "/quotas/orders/week/acme", http.StatusNotFound},
+		{"GET", "/quotas/payments/month/acme", http.StatusNotFound},
+		{"POST", "/quotas/orders/month/acme", http.StatusMethodNotAllowed},
+		{"DELETE", "/quotas/orders/month/acme", http.StatusForbidden},
+	} {
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.status, rsp.Code, "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestQuotaHandlerUsage(t *testing.T) {
+	redisAddr, done := redistest.NewTestRedis(t)
+	defer done()
+
+	r := NewSwarmRegistry(nil, &net.RedisOptions{Addrs: []string{redisAddr}})
+	defer r.Close()
+
+	q := NewClusterQuota(r, "orders", 10, QuotaMonth)
+	q2 := NewClusterQuota(r, "orders", 20, QuotaMonth)
+	for i := 0; i < 4; i++ {
+		_, err := q.Add(context.Background(), "acme", 1)
+		require.NoError(t, err)
+	}
+
+	_, err := q2.Add(context.Background(), "acme", 1)
+	require.NoError(t, err)
+
+	h := NewQuotaHandlerWithOptions(r, QuotaHandlerOptions{EnableReset: true})
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest("GET", "/quotas/orders/month/acme", nil))
+	require.Equal(t, http.StatusOK, rsp.Code)
+
+	var u []QuotaUsage
+	require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &u))
+	require.Len(t, u, 2)
+	assert.Equal(t, "acme", u[0].Client)
+	assert.Equal(t, "month", u[0].Period)
+	assert.Equal(t, int64(4), u[0].Used)
+	assert.Equal(t, int64(6), u[0].Remaining)
+	assert.Equal(t, int64(20), u[1].Limit)
+	assert.Equal(t, int64(1), u[1].Used)
+
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest("DELETE", "/quotas/orders/month/acme", nil))
+	require.Equal(t, http.StatusNoContent, rsp.Code)
+
+	for _, q := range []*ClusterQuota{q, q2} {
+		u, err := q.Usage(context.Background(), "acme")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), u.Used)
+	}
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// QuotaHandlerPrefix is the path prefix of the QuotaHandler.
+const QuotaHandlerPrefix = "/quotas/"
+
+// QuotaHandlerOptions defines the behavior of the admin handler of the
+// cluster quotas.
+type QuotaHandlerOptions struct {
+
+	// EnableReset enables resetting the usage of the clients. The handler
+	// doesn't authenticate the requests, so it should be enabled only when
+	// the listener is not accessible for untrusted clients.
+	EnableReset bool
+}
+
+type quotaHandler struct {
+	registry *Registry
+	options  QuotaHandlerOptions
+}
+
+// NewQuotaHandler returns the read-only admin handler of the cluster
+// quotas. It serves the usage of a client in the current period under
+// /quotas/<group>/<period>/<client> for GET requests, as a list
+// containing the usage for each limit used with the group and the
+// period.
+func NewQuotaHandler(r *Registry) http.Handler {
+	return NewQuotaHandlerWithOptions(r, QuotaHandlerOptions{})
+}
+
+// NewQuotaHandlerWithOptions returns the admin handler of the cluster
+// quotas. When EnableReset is set, besides the GET requests served by
+// NewQuotaHandler, it resets the usage of a client for DELETE requests,
+// for all the limits used with the group and the period.
+// Otherwise, the DELETE requests are rejected with 403 Forbidden.
+func NewQuotaHandlerWithOptions(r *Registry, o QuotaHandlerOptions) http.Handler {
+	return &quotaHandler{registry: r, options: o}
+}
+
+func (h *quotaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, QuotaHandlerPrefix)
+	parts := strings.SplitN(p, "/", 3)
+	if p == r.URL.Path || len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	group, client := parts[0], parts[2]
+	period, err := ParseQuotaPeriod(parts[1])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	quotas := h.registry.Quotas(group, period)
+	if len(quotas) == 0 {
+		http.Error(w, "quota not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		u := make([]QuotaUsage, len(quotas))
+		for i, q := range quotas {
+			var err error
+			if u[i], err = q.Usage(r.Context(), client); err != nil {
+				log.Errorf("Failed to get the quota usage of %s/%s/%s: %v", group, period, client, err)
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(u); err != nil {
+			log.Errorf("Failed to write the quota usage of %s/%s/%s: %v", group, period, client, err)
+		}
+	case http.MethodDelete:
+		if !h.options.EnableReset {
+			http.Error(w, "resetting the quotas is disabled", http.StatusForbidden)
+			return
+		}
+
+		for _, q := range quotas {
+			if err := q.Reset(r.Context(), client); err != nil {
+				log.Errorf("Failed to reset the quota usage of %s/%s/%s: %v", group, period, client, err)
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		log.Infof("Quota usage of %s/%s/%s reset", group, period, client)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
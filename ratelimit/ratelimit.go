@@ -217,6 +217,51 @@ func (h HeaderLookuper) String() string {
 	return "HeaderLookuper"
 }
 
+// FilterContextLookuper is implemented by the Lookupers that select the
+// bucket based on the filter context, e.g. on the state bag, instead of
+// the request alone. The filters use LookupContext when the Lookuper
+// implements it.
+type FilterContextLookuper interface {
+	Lookuper
+	LookupContext(filters.FilterContext) string
+}
+
+// ClientIDLookuper implements the FilterContextLookuper interface and
+// will select a bucket by the client id, set in the state bag by the
+// authentication filters, e.g. apiKey.
+type ClientIDLookuper struct{}
+
+// NewClientIDLookuper returns a ClientIDLookuper.
+func NewClientIDLookuper() ClientIDLookuper {
+	return ClientIDLookuper{}
+}
+
+// Lookup returns an empty string, because the client id is not
+// available from the request.
+func (ClientIDLookuper) Lookup(*http.Request) string {
+	return ""
+}
+
+// LookupContext returns the client id from the state bag.
+func (ClientIDLookuper) LookupContext(ctx filters.FilterContext) string {
+	id, _ := ctx.StateBag()[filters.ClientIDKey].(string)
+	return id
+}
+
+func (ClientIDLookuper) String() string {
+	return "ClientIDLookuper"
+}
+
+// LookupContext selects the bucket with the Lookuper, and uses the
+// filter context when the Lookuper supports it.
+func LookupContext(l Lookuper, ctx filters.FilterContext) string {
+	if cl, ok := l.(FilterContextLookuper); ok {
+		return cl.LookupContext(ctx)
+	}
+
+	return l.Lookup(ctx.Request())
+}
+
 // Lookupers is a slice of Lookuper, required to get a hashable member
 // in the TupleLookuper.
 type Lookupers []Lookuper
@@ -249,6 +294,20 @@ func (t TupleLookuper) Lookup(req *http.Request) string {
 	return buf.String()
 }
 
+// LookupContext returns the combined string of all Lookupers part of
+// the tuple, using the filter context where supported
+func (t TupleLookuper) LookupContext(ctx filters.FilterContext) string {
+	if t.l == nil {
+		return ""
+	}
+
+	buf := bytes.Buffer{}
+	for _, l := range *(t.l) {
+		buf.WriteString(LookupContext(l, ctx))
+	}
+	return buf.String()
+}
+
 func (t TupleLookuper) String() string {
 	return "TupleLookuper"
 }
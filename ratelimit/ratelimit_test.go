@@ -6,6 +6,9 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
 )
 
 func checkRatelimitted(t *testing.T, rl *Ratelimit, client string) {
@@ -213,6 +216,38 @@ func TestTupleLookuper(t *testing.T) {
 	})
 }
 
+func TestClientIDLookuper(t *testing.T) {
+	req, err := http.NewRequest("GET", "/foo", nil)
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+
+	req.Header.Set("X-Foo", "bar")
+	ctx := &filtertest.Context{FRequest: req, FStateBag: map[string]interface{}{filters.ClientIDKey: "acme"}}
+
+	l := NewClientIDLookuper()
+	if l.Lookup(req) != "" {
+		t.Error("Failed to lookup request without state bag")
+	}
+
+	if LookupContext(l, ctx) != "acme" {
+		t.Error("Failed to lookup client id")
+	}
+
+	if LookupContext(NewHeaderLookuper("X-Foo"), ctx) != "bar" {
+		t.Error("Failed to lookup header with context")
+	}
+
+	if LookupContext(NewTupleLookuper(l, NewHeaderLookuper("X-Foo")), ctx) != "acmebar" {
+		t.Error("Failed to lookup tuple with context")
+	}
+
+	delete(ctx.FStateBag, filters.ClientIDKey)
+	if LookupContext(l, ctx) != "" {
+		t.Error("Unexpected client id")
+	}
+}
+
 func TestRoundRobinLookuper(t *testing.T) {
 	for _, tc := range []struct {
 		n, concurrency, iterations int
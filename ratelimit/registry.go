@@ -25,6 +25,7 @@ type Registry struct {
 	lookup    map[Settings]*Ratelimit
 	swarm     Swarmer
 	redisRing *net.RedisRingClient
+	quotas    map[quotaKey]*ClusterQuota
 }
 
 // NewRegistry initializes a registry with the provided default settings.
@@ -65,6 +66,14 @@ func NewSwarmRegistry(swarm Swarmer, ro *net.RedisOptions, settings ...Settings)
 	return r
 }
 
+// RedisRing returns the Redis ring client of the registry, that can be
+// shared with other components using the Redis based swarm. When the
+// registry was created without Redis options, the client has no shards.
+// The client is closed by Close.
+func (r *Registry) RedisRing() *net.RedisRingClient {
+	return r.redisRing
+}
+
 // Close teardown Registry and dependent resources
 func (r *Registry) Close() {
 	r.redisRing.Close()
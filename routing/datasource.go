@@ -480,6 +480,15 @@ func processRouteDef(cpm map[string]PredicateSpec, fr filters.Registry, def *esk
 	return r, nil
 }
 
+// ValidateRoute processes a route definition the same way as the routing
+// does, using the filter registry and the predicates of the options, and
+// returns the error when the route is invalid, e.g. due to an unknown
+// predicate or invalid filter arguments.
+func ValidateRoute(o Options, def *eskip.Route) error {
+	_, err := processRouteDef(mapPredicates(o.Predicates), o.FilterRegistry, def)
+	return err
+}
+
 // convert a slice of predicate specs to a map keyed by their names
 func mapPredicates(cps []PredicateSpec) map[string]PredicateSpec {
 	cpm := make(map[string]PredicateSpec)
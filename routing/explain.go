@@ -0,0 +1,291 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dimfeld/httppath"
+)
+
+const (
+	// ExplainPath is the path of the admin endpoint explaining the route
+	// matching of a synthetic request.
+	ExplainPath = "/routes/explain"
+
+	maxExplainCandidates = 10
+)
+
+// ExplainRequest describes the synthetic request, whose route matching is
+// explained. The path may contain a query string.
+type ExplainRequest struct {
+	Method     string      `json:"method,omitempty"`
+	Host       string      `json:"host,omitempty"`
+	Path       string      `json:"path,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
+	RemoteAddr string      `json:"remote_addr,omitempty"`
+}
+
+// MatchCandidate is a route evaluated during the lookup. When the route was
+// not matched, RejectedBy contains the predicate that rejected the request,
+// and Reason tells why.
+type MatchCandidate struct {
+	RouteID    string `json:"route_id"`
+	Matched    bool   `json:"matched"`
+	RejectedBy string `json:"rejected_by,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// MatchExplanation contains the result of the route lookup, and the
+// candidate routes in the order they were evaluated.
+type MatchExplanation struct {
+	RouteID    string            `json:"route_id,omitempty"`
+	Route      string            `json:"route,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+	Candidates []MatchCandidate  `json:"candidates"`
+}
+
+// Request creates the http request used for the route lookup.
+func (er ExplainRequest) Request() (*http.Request, error) {
+	method := er.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	p := er.Path
+	if p == "" {
+		p = "/"
+	}
+
+	u, err := url.ParseRequestURI(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	h := make(http.Header)
+	for k, v := range er.Headers {
+		h[http.CanonicalHeaderKey(k)] = v
+	}
+
+	host := er.Host
+	if host == "" {
+		host = h.Get("Host")
+	}
+
+	h.Del("Host")
+	return &http.Request{
+		Method:     method,
+		URL:        u,
+		RequestURI: u.RequestURI(),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     h,
+		Host:       host,
+		RemoteAddr: er.RemoteAddr,
+	}, nil
+}
+
+// explainRecorder records the leaves evaluated during the lookup, and why
+// they were rejected.
+type explainRecorder struct {
+	r          *http.Request
+	path       string
+	exactPath  string
+	candidates []MatchCandidate
+}
+
+func (rec *explainRecorder) leaf(l *leafMatcher, rj leafRejection) {
+	rejectedBy, reason := explainLeaf(l, rj, rec.r, rec.path, rec.exactPath)
+	rec.candidates = append(rec.candidates, MatchCandidate{
+		RouteID:    l.route.Id,
+		Matched:    rj.condition == leafMatched,
+		RejectedBy: rejectedBy,
+		Reason:     reason,
+	})
+}
+
+func (*explainRecorder) predicate(*leafMatcher, int, bool, time.Duration) {}
+
+func rxString(name string, rx *regexp.Regexp) string {
+	return fmt.Sprintf("%s(/%s/)", name, strings.ReplaceAll(rx.String(), "/", `\/`))
+}
+
+func headerValues(h http.Header, key string) string {
+	vals, has := h[key]
+	if !has {
+		return fmt.Sprintf("header %q is missing", key)
+	}
+
+	return fmt.Sprintf("header %q is %q", key, strings.Join(vals, ", "))
+}
+
+// customPredicateDefs returns the definitions of the custom predicates of
+// a route, in the same order as the predicate instances.
+func customPredicateDefs(r *Route) []string {
+	var defs []string
+	for _, p := range r.Route.Predicates {
+		if p.Name == "Weight" || isTreePredicate(p.Name) {
+			continue
+		}
+
+		defs = append(defs, p.String())
+	}
+
+	return defs
+}
+
+// explainLeaf describes the condition of a leaf that rejected the request,
+// and the reason. It returns empty strings when the leaf matched.
+func explainLeaf(l *leafMatcher, rj leafRejection, req *http.Request, path, exactPath string) (string, string) {
+	switch rj.condition {
+	case leafExactPath:
+		return fmt.Sprintf("Path(%q)", l.exactPath), fmt.Sprintf("path is %q", path)
+	case leafMethod:
+		return fmt.Sprintf("Method(%q)", l.method), fmt.Sprintf("method is %q", req.Method)
+	case leafHost:
+		return rxString("Host", l.hostRxs[rj.index]), fmt.Sprintf("host is %q", req.Host)
+	case leafPathRegexp:
+		return rxString("PathRegexp", l.pathRxs[rj.index]), fmt.Sprintf("path is %q", exactPath)
+	case leafHeader:
+		return fmt.Sprintf("Header(%q, %q)", rj.header, l.headersExact[rj.header]), headerValues(req.Header, rj.header)
+	case leafHeaderRegexp:
+		rx := l.headersRegexp[rj.header][rj.index]
+		return fmt.Sprintf("HeaderRegexp(%q, /%s/)", rj.header, rx), headerValues(req.Header, rj.header)
+	case leafPredicate:
+		if defs := customPredicateDefs(l.route); rj.index < len(defs) {
+			return defs[rj.index], "predicate not matched"
+		}
+
+		return fmt.Sprintf("%T", l.predicates[rj.index]), "predicate not matched"
+	default:
+		return "", ""
+	}
+}
+
+// explain matches the request the same way as match, but records the
+// evaluated candidates.
+func (m *matcher) explain(r *http.Request) *MatchExplanation {
+	path := httppath.Clean(r.URL.Path)
+	exact := path
+	if m.matchingOptions.ignoreTrailingSlash() {
+		path = trimTrailingSlash(path)
+	}
+
+	rec := &explainRecorder{r: r, path: path, exactPath: exact}
+	route, params := m.matchWith(r, rec)
+	e := &MatchExplanation{Params: params, Candidates: rec.candidates}
+	if route != nil {
+		e.RouteID = route.Id
+		e.Route = route.String()
+	}
+
+	// keep the first candidates, and the matching one
+	if len(e.Candidates) > maxExplainCandidates {
+		last := e.Candidates[len(e.Candidates)-1]
+		e.Candidates = e.Candidates[:maxExplainCandidates]
+		if last.Matched {
+			e.Candidates[maxExplainCandidates-1] = last
+		}
+	}
+
+	if e.Candidates == nil {
+		e.Candidates = []MatchCandidate{}
+	}
+
+	return e
+}
+
+// Explain looks up the route of a synthetic request, and returns which
+// route matched, the wildcard params, and why the other candidate routes
+// were rejected. The candidates are limited to the first ten evaluated
+// routes.
+func (r *Routing) Explain(er ExplainRequest) (*MatchExplanation, error) {
+	return r.Get().Explain(er)
+}
+
+// Explain explains the route lookup against the captured routing table.
+// Equivalent to Routing.Explain().
+func (rl *RouteLookup) Explain(er ExplainRequest) (*MatchExplanation, error) {
+	req, err := er.Request()
+	if err != nil {
+		return nil, err
+	}
+
+	return rl.matcher.explain(req), nil
+}
+
+func explainRequestFromQuery(q url.Values) (ExplainRequest, error) {
+	er := ExplainRequest{
+		Method:     q.Get("method"),
+		Host:       q.Get("host"),
+		Path:       q.Get("path"),
+		RemoteAddr: q.Get("remote_addr"),
+	}
+
+	for _, h := range q["header"] {
+		kv := strings.SplitN(h, ":", 2)
+		if len(kv) != 2 {
+			return er, fmt.Errorf("invalid header: %s", h)
+		}
+
+		if er.Headers == nil {
+			er.Headers = make(http.Header)
+		}
+
+		er.Headers.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+
+	return er, nil
+}
+
+// ExplainHandler serves the route matching explanations. The synthetic
+// request is described either with the method, host, path, remote_addr and
+// the repeatable header query parameters of a GET request, e.g.:
+//
+//	GET /routes/explain?method=POST&host=www.example.org&path=/api&header=X-Foo:+bar
+//
+// or with the JSON representation of ExplainRequest, in the body of a POST
+// request.
+func (r *Routing) ExplainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var (
+			er  ExplainRequest
+			err error
+		)
+
+		switch req.Method {
+		case http.MethodGet:
+			er, err = explainRequestFromQuery(req.URL.Query())
+		case http.MethodPost:
+			err = json.NewDecoder(req.Body).Decode(&er)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		e, err := r.Explain(er)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(e); err != nil {
+			http.Error(
+				w,
+				http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError,
+			)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package routing_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/routing/testdataclient"
+)
+
+const explainRoutes = `
+	api: Path("/api/:id") && Method("POST") && Header("X-Foo", "bar") -> "https://api.example.org";
+	apiHost: Path("/api/:id") && Host(/^www[.]example[.]org$/) -> "https://host.example.org";
+	custom: PathSubtree("/") && CustomPredicate("foo") -> "https://custom.example.org";
+	catchAll: * -> "https://www.example.org";
+`
+
+func newExplainRouting(t *testing.T) *testRouting {
+	dc, err := testdataclient.NewDoc(explainRoutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := newTestRoutingWithPredicates([]routing.PredicateSpec{&predicate{}}, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tr
+}
+
+func TestExplain(t *testing.T) {
+	tr := newExplainRouting(t)
+	defer tr.close()
+
+	for _, test := range []struct {
+		title      string
+		request    routing.ExplainRequest
+		routeID    string
+		params     map[string]string
+		candidates []routing.MatchCandidate
+	}{{
+		title: "matching the first candidate",
+		request: routing.ExplainRequest{
+			Method:  "POST",
+			Path:    "/api/42",
+			Headers: http.Header{"X-Foo": []string{"bar"}},
+		},
+		routeID: "api",
+		params:  map[string]string{"id": "42"},
+		candidates: []routing.MatchCandidate{
+			{RouteID: "api", Matched: true},
+		},
+	}, {
+		title: "rejected by method",
+		request: routing.ExplainRequest{
+			Host: "www.example.org",
+			Path: "/api/42",
+		},
+		routeID: "apiHost",
+		params:  map[string]string{"id": "42"},
+		candidates: []routing.MatchCandidate{
+			{RouteID: "api", RejectedBy: `Method("POST")`, Reason: `method is "GET"`},
+			{RouteID: "apiHost", Matched: true},
+		},
+	}, {
+		title: "falling back to the root leaves",
+		request: routing.ExplainRequest{
+			Method:  "POST",
+			Host:    "api.example.org",
+			Path:    "/api/42",
+			Headers: http.Header{"X-Foo": []string{"baz"}},
+		},
+		routeID: "catchAll",
+		candidates: []routing.MatchCandidate{
+			{RouteID: "api", RejectedBy: `Header("X-Foo", "bar")`, Reason: `header "X-Foo" is "baz"`},
+			{RouteID: "apiHost", RejectedBy: "Host(/^www[.]example[.]org$/)", Reason: `host is "api.example.org"`},
+			{RouteID: "custom", RejectedBy: `CustomPredicate("foo")`, Reason: "predicate not matched"},
+			{RouteID: "catchAll", Matched: true},
+		},
+	}, {
+		title: "rejected by missing header",
+		request: routing.ExplainRequest{
+			Method:  "POST",
+			Path:    "/api/42",
+			Headers: http.Header{"X-Custom-Predicate": []string{"foo"}},
+		},
+		routeID: "custom",
+		params:  map[string]string{"*": "/api/42"},
+		candidates: []routing.MatchCandidate{
+			{RouteID: "api", RejectedBy: `Header("X-Foo", "bar")`, Reason: `header "X-Foo" is missing`},
+			{RouteID: "apiHost", RejectedBy: "Host(/^www[.]example[.]org$/)", Reason: `host is ""`},
+			{RouteID: "custom", Matched: true},
+		},
+	}} {
+		t.Run(test.title, func(t *testing.T) {
+			e, err := tr.routing.Explain(test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if e.RouteID != test.routeID {
+				t.Errorf("invalid route, expected: %s, got: %s", test.routeID, e.RouteID)
+			}
+
+			if len(e.Params) != len(test.params) {
+				t.Errorf("invalid params, expected: %v, got: %v", test.params, e.Params)
+			}
+
+			for k, v := range test.params {
+				if e.Params[k] != v {
+					t.Errorf("invalid param %s, expected: %s, got: %s", k, v, e.Params[k])
+				}
+			}
+
+			if len(e.Candidates) != len(test.candidates) {
+				t.Fatalf("invalid candidates, expected: %v, got: %v", test.candidates, e.Candidates)
+			}
+
+			for i, c := range test.candidates {
+				if e.Candidates[i] != c {
+					t.Errorf("invalid candidate, expected: %v, got: %v", c, e.Candidates[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExplainInvalidPath(t *testing.T) {
+	tr := newExplainRouting(t)
+	defer tr.close()
+
+	if _, err := tr.routing.Explain(routing.ExplainRequest{Path: "api"}); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func TestExplainHandler(t *testing.T) {
+	tr := newExplainRouting(t)
+	defer tr.close()
+
+	h := tr.routing.ExplainHandler()
+	q := url.Values{
+		"method": []string{"POST"},
+		"path":   []string{"/api/42"},
+		"header": []string{"X-Foo: bar"},
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", routing.ExplainPath+"?"+q.Encode(), nil),
+		httptest.NewRequest("POST", routing.ExplainPath, strings.NewReader(`{"method": "POST", "path": "/api/42", "headers": {"X-Foo": ["bar"]}}`)),
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("invalid status code: %d", w.Code)
+		}
+
+		var e routing.MatchExplanation
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+
+		if e.RouteID != "api" || e.Params["id"] != "42" {
+			t.Errorf("invalid explanation: %v", e)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", routing.ExplainPath+"?header=invalid", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid status code: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", routing.ExplainPath, nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("invalid status code: %d", w.Code)
+	}
+}
+
+func TestDoWithEvaluations(t *testing.T) {
+	tr := newExplainRouting(t)
+	defer tr.close()
+
+	for _, test := range []struct {
+		value   string
+		routeID string
+		matched bool
+	}{
+		{"foo", "custom", true},
+		{"bar", "catchAll", false},
+	} {
+		req := httptest.NewRequest("GET", "/hello", nil)
+		req.Header.Set("X-Custom-Predicate", test.value)
+		r, _, evals := tr.routing.Get().DoWithEvaluations(req)
+		if r == nil || r.Id != test.routeID {
+			t.Fatalf("invalid route, expected: %s, got: %v", test.routeID, r)
+		}
+
+		if len(evals) != 1 || evals[0].RouteID != "custom" || evals[0].Matched != test.matched || evals[0].Predicate == nil {
+			t.Errorf("invalid predicate evaluations: %+v", evals)
+		}
+	}
+}
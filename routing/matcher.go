@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/dimfeld/httppath"
 	"github.com/zalando/skipper/pathmux"
@@ -16,6 +17,36 @@ type leafRequestMatcher struct {
 	r         *http.Request
 	path      string
 	exactPath string
+	rec       leafRecorder
+}
+
+// leafCondition identifies a condition of a leaf matcher
+type leafCondition int
+
+const (
+	leafMatched leafCondition = iota
+	leafExactPath
+	leafMethod
+	leafHost
+	leafPathRegexp
+	leafHeader
+	leafHeaderRegexp
+	leafPredicate
+)
+
+// leafRejection tells which condition of a leaf matcher rejected a request. The index identifies the host or
+// path regexp, the regexp of the header, or the custom predicate.
+type leafRejection struct {
+	condition leafCondition
+	index     int
+	header    string
+}
+
+// leafRecorder receives the leaves evaluated during the lookup, and the condition that rejected the request,
+// or leafMatched, and the evaluations of the custom predicates. It is used to explain and to trace the lookup.
+type leafRecorder interface {
+	leaf(l *leafMatcher, rj leafRejection)
+	predicate(l *leafMatcher, index int, matched bool, d time.Duration)
 }
 
 func (m *leafRequestMatcher) Match(value interface{}) (bool, interface{}) {
@@ -24,7 +55,7 @@ func (m *leafRequestMatcher) Match(value interface{}) (bool, interface{}) {
 		return false, nil
 	}
 
-	l := matchLeaves(v.leaves, m.r, m.path, m.exactPath)
+	l := matchLeaves(v.leaves, m.r, m.path, m.exactPath, m.rec)
 	return l != nil, l
 }
 
@@ -357,7 +388,7 @@ func newMatcher(rs []*Route, o MatchingOptions) (*matcher, []*definitionError) {
 }
 
 // matches a path in the path trie structure.
-func matchPathTree(tree *pathmux.Tree, path string, lrm *leafRequestMatcher) (map[string]string, *leafMatcher) {
+func matchPathTree(tree *pathmux.Tree, path string, lrm pathmux.Matcher) (map[string]string, *leafMatcher) {
 	v, params, value := tree.LookupMatcher(path, lrm)
 	if v == nil {
 		return nil, nil
@@ -386,15 +417,20 @@ func matchPathTree(tree *pathmux.Tree, path string, lrm *leafRequestMatcher) (ma
 	return paramsMap, lm
 }
 
-// matches the path regexp conditions in a leaf matcher.
-func matchRegexps(rxs []*regexp.Regexp, s string) bool {
-	for _, rx := range rxs {
+// returns the index of the first regexp not matching the string, or -1 when all match
+func unmatchedRegexp(rxs []*regexp.Regexp, s string) int {
+	for i, rx := range rxs {
 		if !rx.MatchString(s) {
-			return false
+			return i
 		}
 	}
 
-	return true
+	return -1
+}
+
+// matches the path regexp conditions in a leaf matcher.
+func matchRegexps(rxs []*regexp.Regexp, s string) bool {
+	return unmatchedRegexp(rxs, s) < 0
 }
 
 // matches a set of request headers to a fix and regexp header condition
@@ -413,69 +449,95 @@ func matchHeader(h http.Header, key string, check func(string) bool) bool {
 	return false
 }
 
-// matches a set of request headers to the fix and regexp header conditions
-func matchHeaders(exact map[string]string, hrxs map[string][]*regexp.Regexp, h http.Header) bool {
+// returns the first fix or regexp header condition not matching the request headers
+func unmatchedHeader(exact map[string]string, hrxs map[string][]*regexp.Regexp, h http.Header) leafRejection {
 	for k, v := range exact {
 		if !matchHeader(h, k, func(val string) bool { return val == v }) {
-			return false
+			return leafRejection{condition: leafHeader, header: k}
 		}
 	}
 
 	for k, rxs := range hrxs {
-		for _, rx := range rxs {
+		for i, rx := range rxs {
 			if !matchHeader(h, k, rx.MatchString) {
-				return false
+				return leafRejection{condition: leafHeaderRegexp, header: k, index: i}
 			}
 		}
 	}
 
-	return true
+	return leafRejection{}
+}
+
+// matches a set of request headers to the fix and regexp header conditions
+func matchHeaders(exact map[string]string, hrxs map[string][]*regexp.Regexp, h http.Header) bool {
+	return unmatchedHeader(exact, hrxs, h).condition == leafMatched
 }
 
-// check if all defined custom predicates are matched
-func matchPredicates(cps []Predicate, req *http.Request) bool {
-	for _, cp := range cps {
-		if !cp.Match(req) {
-			return false
+// returns the index of the first custom predicate not matching the request, or -1 when all match
+func unmatchedPredicate(l *leafMatcher, req *http.Request, rec leafRecorder) int {
+	for i, cp := range l.predicates {
+		if rec == nil {
+			if !cp.Match(req) {
+				return i
+			}
+
+			continue
+		}
+
+		start := time.Now()
+		matched := cp.Match(req)
+		rec.predicate(l, i, matched, time.Since(start))
+		if !matched {
+			return i
 		}
 	}
 
-	return true
+	return -1
 }
 
-// matches a request to the conditions in a leaf matcher
-func matchLeaf(l *leafMatcher, req *http.Request, path, exactPath string) bool {
+// evaluates the conditions of a leaf matcher, and returns the first one rejecting the request
+func evalLeaf(l *leafMatcher, req *http.Request, path, exactPath string, rec leafRecorder) leafRejection {
 	if l.exactPath != "" && l.exactPath != path {
-		return false
+		return leafRejection{condition: leafExactPath}
 	}
 
 	if l.method != "" && l.method != req.Method {
-		return false
+		return leafRejection{condition: leafMethod}
 	}
 
-	if !matchRegexps(l.hostRxs, req.Host) {
-		return false
+	if i := unmatchedRegexp(l.hostRxs, req.Host); i >= 0 {
+		return leafRejection{condition: leafHost, index: i}
 	}
 
-	if !matchRegexps(l.pathRxs, exactPath) {
-		return false
+	if i := unmatchedRegexp(l.pathRxs, exactPath); i >= 0 {
+		return leafRejection{condition: leafPathRegexp, index: i}
 	}
 
-	if !matchHeaders(l.headersExact, l.headersRegexp, req.Header) {
-		return false
+	if rj := unmatchedHeader(l.headersExact, l.headersRegexp, req.Header); rj.condition != leafMatched {
+		return rj
 	}
 
-	if !matchPredicates(l.predicates, req) {
-		return false
+	if i := unmatchedPredicate(l, req, rec); i >= 0 {
+		return leafRejection{condition: leafPredicate, index: i}
 	}
 
-	return true
+	return leafRejection{}
+}
+
+// matches a request to the conditions in a leaf matcher
+func matchLeaf(l *leafMatcher, req *http.Request, path, exactPath string) bool {
+	return evalLeaf(l, req, path, exactPath, nil).condition == leafMatched
 }
 
-// matches a request to a set of leaf matchers
-func matchLeaves(leaves leafMatchers, req *http.Request, path, exactPath string) *leafMatcher {
+// matches a request to a set of leaf matchers. The optional recorder receives the evaluated leaves.
+func matchLeaves(leaves leafMatchers, req *http.Request, path, exactPath string, rec leafRecorder) *leafMatcher {
 	for _, l := range leaves {
-		if matchLeaf(l, req, path, exactPath) {
+		rj := evalLeaf(l, req, path, exactPath, rec)
+		if rec != nil {
+			rec.leaf(l, rj)
+		}
+
+		if rj.condition == leafMatched {
 			return l
 		}
 	}
@@ -487,6 +549,11 @@ func matchLeaves(leaves leafMatchers, req *http.Request, path, exactPath string)
 // returns the associated value, and the wildcard parameters from the path definition,
 // if any.
 func (m *matcher) match(r *http.Request) (*Route, map[string]string) {
+	return m.matchWith(r, nil)
+}
+
+// matchWith matches the request like match, and reports the evaluated leaves to the optional recorder.
+func (m *matcher) matchWith(r *http.Request, rec leafRecorder) (*Route, map[string]string) {
 	// normalize path before matching
 	// in case ignoring trailing slashes, match without the trailing slash
 	path := httppath.Clean(r.URL.Path)
@@ -494,7 +561,7 @@ func (m *matcher) match(r *http.Request) (*Route, map[string]string) {
 	if m.matchingOptions.ignoreTrailingSlash() {
 		path = trimTrailingSlash(path)
 	}
-	lrm := &leafRequestMatcher{r: r, path: path, exactPath: exact}
+	lrm := &leafRequestMatcher{r: r, path: path, exactPath: exact, rec: rec}
 
 	// first match fixed and wildcard paths
 	params, l := matchPathTree(m.paths, path, lrm)
@@ -504,7 +571,7 @@ func (m *matcher) match(r *http.Request) (*Route, map[string]string) {
 	}
 
 	// if no path match, match root leaves for other conditions
-	l = matchLeaves(m.rootLeaves, r, path, exact)
+	l = matchLeaves(m.rootLeaves, r, path, exact, rec)
 	if l != nil {
 		return l.route, nil
 	}
@@ -620,7 +620,7 @@ func TestMatchLeavesFalse(t *testing.T) {
 	l0 := &leafMatcher{method: "PUT"}
 	l1 := &leafMatcher{method: "POST"}
 	req := &http.Request{Method: "GET"}
-	if matchLeaves([]*leafMatcher{l0, l1}, req, "/some/path", "/some/path") != nil {
+	if matchLeaves([]*leafMatcher{l0, l1}, req, "/some/path", "/some/path", nil) != nil {
 		t.Error("failed not to match leaves")
 	}
 }
@@ -629,7 +629,7 @@ func TestMatchLeavesTrue(t *testing.T) {
 	l0 := &leafMatcher{method: "PUT"}
 	l1 := &leafMatcher{method: "POST"}
 	req := &http.Request{URL: &url.URL{Path: "/some/path"}, Method: "PUT"}
-	if matchLeaves([]*leafMatcher{l0, l1}, req, "/some/path", "/some/path") != l0 {
+	if matchLeaves([]*leafMatcher{l0, l1}, req, "/some/path", "/some/path", nil) != l0 {
 		t.Error("failed not to match leaves")
 	}
 }
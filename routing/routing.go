@@ -410,6 +410,39 @@ func (rl *RouteLookup) Do(req *http.Request) (*Route, map[string]string) {
 	return rl.matcher.match(req)
 }
 
+// PredicateEvaluation is a custom predicate evaluated during the route
+// lookup.
+type PredicateEvaluation struct {
+	RouteID   string
+	Predicate Predicate
+	Matched   bool
+	Duration  time.Duration
+}
+
+type evaluationRecorder struct {
+	evaluations []PredicateEvaluation
+}
+
+func (*evaluationRecorder) leaf(*leafMatcher, leafRejection) {}
+
+func (rec *evaluationRecorder) predicate(l *leafMatcher, index int, matched bool, d time.Duration) {
+	rec.evaluations = append(rec.evaluations, PredicateEvaluation{
+		RouteID:   l.route.Id,
+		Predicate: l.predicates[index],
+		Matched:   matched,
+		Duration:  d,
+	})
+}
+
+// DoWithEvaluations executes the lookup like Do, and returns the custom
+// predicates evaluated during the lookup, in the order of the
+// evaluation.
+func (rl *RouteLookup) DoWithEvaluations(req *http.Request) (*Route, map[string]string, []PredicateEvaluation) {
+	rec := &evaluationRecorder{}
+	r, params := rl.matcher.matchWith(req, rec)
+	return r, params, rec.evaluations
+}
+
 // Get returns a captured generation of the lookup table. This feature is
 // experimental. See the description of the RouteLookup type.
 func (r *Routing) Get() *RouteLookup {
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package scheduler
+
+import (
+	"syscall"
+	"time"
+)
+
+// returns the total user and system CPU time consumed by the process
+func processCPUTime() (time.Duration, bool) {
+	var u syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &u); err != nil {
+		return 0, false
+	}
+
+	return time.Duration(u.Utime.Nano() + u.Stime.Nano()), true
+}
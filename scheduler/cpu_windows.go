@@ -0,0 +1,8 @@
+package scheduler
+
+import "time"
+
+// measuring the CPU usage is not supported on Windows
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -2,4 +2,5 @@ package scheduler
 
 var (
 	ExportQueueCloseDelay = &queueCloseDelay
+	ExportSetCPU          = (*LoadShedder).setCPU
 )
@@ -0,0 +1,234 @@
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando/skipper/metrics"
+)
+
+const (
+	// Key used during routing to pass the priority class of the request from the filters to the proxy.
+	PriorityKey = "scheduler:priority"
+
+	// DefaultLoadSheddingRetryAfter is the default value of the Retry-After header sent with the rejected
+	// requests.
+	DefaultLoadSheddingRetryAfter = time.Second
+
+	// DefaultCPUUpdateInterval is the default frequency of sampling the CPU usage of the process.
+	DefaultCPUUpdateInterval = time.Second
+)
+
+// Priority is the class of a request used by the load shedding. When the proxy is overloaded, the requests
+// with lower priority classes are rejected first.
+type Priority int
+
+const (
+	// PriorityLow is rejected first, when the load reaches 60% of the limits.
+	PriorityLow Priority = iota
+
+	// PriorityNormal is the priority of the requests without a class. It is rejected when the load reaches
+	// 80% of the limits.
+	PriorityNormal
+
+	// PriorityHigh is rejected when the load reaches the limits.
+	PriorityHigh
+
+	// PriorityCritical is never rejected by the load shedding.
+	PriorityCritical
+)
+
+// the fraction of the limits at which the requests of a priority class are rejected
+var priorityLoadLimits = map[Priority]float64{
+	PriorityLow:    .6,
+	PriorityNormal: .8,
+	PriorityHigh:   1,
+}
+
+var priorityNames = map[Priority]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+// ParsePriority parses the name of a priority class: low, normal, high or critical.
+func ParsePriority(s string) (Priority, error) {
+	for p, n := range priorityNames {
+		if strings.EqualFold(s, n) {
+			return p, nil
+		}
+	}
+
+	return PriorityNormal, fmt.Errorf("invalid priority class: %s", s)
+}
+
+func (p Priority) String() string {
+	if n, ok := priorityNames[p]; ok {
+		return n
+	}
+
+	return fmt.Sprintf("priority(%d)", int(p))
+}
+
+// LoadSheddingOptions defines the limits of the load shedding. At least one of MaxInflight and MaxCPU needs
+// to be set to enable it.
+type LoadSheddingOptions struct {
+
+	// MaxInflight defines the number of the concurrently proxied requests considered as full load.
+	MaxInflight int
+
+	// MaxCPU defines the CPU usage of the process, as a percentage of the available CPUs, considered as full
+	// load.
+	MaxCPU float64
+
+	// RetryAfter is sent in the Retry-After header of the rejected requests. Defaults to 1s.
+	RetryAfter time.Duration
+
+	// CPUUpdateInterval defines how often the CPU usage is sampled, and the load gauges are updated. Defaults
+	// to 1s.
+	CPUUpdateInterval time.Duration
+}
+
+// LoadShedder admits or rejects requests based on their priority class and the current load of the proxy.
+// The load is the higher of the in-flight requests relative to MaxInflight, and the CPU usage relative to
+// MaxCPU. The admitted and rejected requests are counted per priority class in the
+// loadshedding.admitted.<class> and loadshedding.rejected.<class> metrics.
+type LoadShedder struct {
+	options  LoadSheddingOptions
+	metrics  metrics.Metrics
+	inflight int64
+	cpu      uint64
+	quit     chan struct{}
+	once     sync.Once
+}
+
+// Enabled tells whether any of the load shedding limits is set.
+func (o LoadSheddingOptions) Enabled() bool {
+	return o.MaxInflight > 0 || o.MaxCPU > 0
+}
+
+// NewLoadShedder creates a load shedder. It starts a background goroutine updating the loadshedding.inflight
+// gauge, and, when the MaxCPU is set, sampling the CPU usage and updating the loadshedding.cpu gauge. The
+// goroutine needs to be stopped by calling Close.
+func NewLoadShedder(o LoadSheddingOptions, m metrics.Metrics) *LoadShedder {
+	if o.RetryAfter <= 0 {
+		o.RetryAfter = DefaultLoadSheddingRetryAfter
+	}
+
+	if o.CPUUpdateInterval <= 0 {
+		o.CPUUpdateInterval = DefaultCPUUpdateInterval
+	}
+
+	if m == nil {
+		m = metrics.Void
+	}
+
+	ls := &LoadShedder{
+		options: o,
+		metrics: m,
+		quit:    make(chan struct{}),
+	}
+
+	var sampleCPU bool
+	if o.MaxCPU > 0 {
+		if _, ok := processCPUTime(); ok {
+			sampleCPU = true
+		} else {
+			log.Warn("Load shedding: measuring the CPU usage is not supported on this platform")
+		}
+	}
+
+	go ls.updateLoad(sampleCPU)
+	return ls
+}
+
+func (ls *LoadShedder) updateLoad(sampleCPU bool) {
+	last, _ := processCPUTime()
+	lastSample := time.Now()
+	for {
+		select {
+		case <-time.After(ls.options.CPUUpdateInterval):
+			if sampleCPU {
+				current, _ := processCPUTime()
+				now := time.Now()
+				elapsed := now.Sub(lastSample) * time.Duration(runtime.GOMAXPROCS(0))
+				if elapsed > 0 {
+					ls.setCPU(100 * float64(current-last) / float64(elapsed))
+				}
+
+				last, lastSample = current, now
+				ls.metrics.UpdateGauge("loadshedding.cpu", ls.loadCPU())
+			}
+
+			ls.metrics.UpdateGauge("loadshedding.inflight", float64(atomic.LoadInt64(&ls.inflight)))
+		case <-ls.quit:
+			return
+		}
+	}
+}
+
+func (ls *LoadShedder) setCPU(v float64) {
+	atomic.StoreUint64(&ls.cpu, math.Float64bits(v))
+}
+
+func (ls *LoadShedder) loadCPU() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&ls.cpu))
+}
+
+func (ls *LoadShedder) overloaded(p Priority, inflight int64) bool {
+	if p >= PriorityCritical {
+		return false
+	}
+
+	if p < PriorityLow {
+		p = PriorityLow
+	}
+
+	limit := priorityLoadLimits[p]
+	if ls.options.MaxInflight > 0 && float64(inflight) >= limit*float64(ls.options.MaxInflight) {
+		return true
+	}
+
+	return ls.options.MaxCPU > 0 && ls.loadCPU() >= limit*ls.options.MaxCPU
+}
+
+// Admit decides whether a request of the given priority class can be proxied. When the request is admitted,
+// the returned function needs to be called when the request was handled.
+func (ls *LoadShedder) Admit(p Priority) (done func(), ok bool) {
+	inflight := atomic.AddInt64(&ls.inflight, 1)
+	if ls.overloaded(p, inflight-1) {
+		atomic.AddInt64(&ls.inflight, -1)
+		ls.metrics.IncCounter("loadshedding.rejected." + p.String())
+		return nil, false
+	}
+
+	ls.metrics.IncCounter("loadshedding.admitted." + p.String())
+	return func() { atomic.AddInt64(&ls.inflight, -1) }, true
+}
+
+// Inflight returns the number of the currently admitted requests.
+func (ls *LoadShedder) Inflight() int {
+	return int(atomic.LoadInt64(&ls.inflight))
+}
+
+// RetryAfter returns the value of the Retry-After header for the rejected requests, in seconds.
+func (ls *LoadShedder) RetryAfter() int {
+	s := int(math.Ceil(ls.options.RetryAfter.Seconds()))
+	if s < 1 {
+		s = 1
+	}
+
+	return s
+}
+
+// Close stops updating the load gauges and sampling the CPU usage.
+func (ls *LoadShedder) Close() {
+	ls.once.Do(func() { close(ls.quit) })
+}
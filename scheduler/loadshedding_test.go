@@ -0,0 +1,206 @@
+package scheduler_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/metrics/metricstest"
+	"github.com/zalando/skipper/scheduler"
+)
+
+func TestParsePriority(t *testing.T) {
+	for _, p := range []scheduler.Priority{
+		scheduler.PriorityLow,
+		scheduler.PriorityNormal,
+		scheduler.PriorityHigh,
+		scheduler.PriorityCritical,
+	} {
+		parsed, err := scheduler.ParsePriority(p.String())
+		if err != nil || parsed != p {
+			t.Errorf("failed to parse priority: %v, %v", p, err)
+		}
+	}
+
+	if p, err := scheduler.ParsePriority("HIGH"); err != nil || p != scheduler.PriorityHigh {
+		t.Errorf("failed to parse priority case insensitive: %v, %v", p, err)
+	}
+
+	if _, err := scheduler.ParsePriority("urgent"); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func admitN(t *testing.T, ls *scheduler.LoadShedder, p scheduler.Priority, n int) []func() {
+	t.Helper()
+	var done []func()
+	for i := 0; i < n; i++ {
+		d, ok := ls.Admit(p)
+		if !ok {
+			t.Fatalf("failed to admit %v request: %d", p, i)
+		}
+
+		done = append(done, d)
+	}
+
+	return done
+}
+
+func TestLoadSheddingInflight(t *testing.T) {
+	m := &metricstest.MockMetrics{}
+	ls := scheduler.NewLoadShedder(scheduler.LoadSheddingOptions{MaxInflight: 10}, m)
+	defer ls.Close()
+
+	done := admitN(t, ls, scheduler.PriorityLow, 6)
+	if _, ok := ls.Admit(scheduler.PriorityLow); ok {
+		t.Error("failed to reject low priority request")
+	}
+
+	done = append(done, admitN(t, ls, scheduler.PriorityNormal, 2)...)
+	if _, ok := ls.Admit(scheduler.PriorityNormal); ok {
+		t.Error("failed to reject normal priority request")
+	}
+
+	done = append(done, admitN(t, ls, scheduler.PriorityHigh, 2)...)
+	if _, ok := ls.Admit(scheduler.PriorityHigh); ok {
+		t.Error("failed to reject high priority request")
+	}
+
+	done = append(done, admitN(t, ls, scheduler.PriorityCritical, 3)...)
+	if n := ls.Inflight(); n != 13 {
+		t.Errorf("invalid number of inflight requests: %d", n)
+	}
+
+	for _, d := range done {
+		d()
+	}
+
+	if n := ls.Inflight(); n != 0 {
+		t.Errorf("invalid number of inflight requests after done: %d", n)
+	}
+
+	admitN(t, ls, scheduler.PriorityLow, 1)
+
+	m.WithCounters(func(c map[string]int64) {
+		if c["loadshedding.admitted.low"] != 7 || c["loadshedding.rejected.low"] != 1 ||
+			c["loadshedding.admitted.normal"] != 2 || c["loadshedding.rejected.normal"] != 1 ||
+			c["loadshedding.admitted.high"] != 2 || c["loadshedding.rejected.high"] != 1 ||
+			c["loadshedding.admitted.critical"] != 3 || c["loadshedding.rejected.critical"] != 0 {
+			t.Errorf("invalid counters: %v", c)
+		}
+	})
+}
+
+func TestLoadSheddingInflightGauge(t *testing.T) {
+	m := &metricstest.MockMetrics{}
+	ls := scheduler.NewLoadShedder(scheduler.LoadSheddingOptions{MaxInflight: 10, CPUUpdateInterval: 10 * time.Millisecond}, m)
+	defer ls.Close()
+
+	admitN(t, ls, scheduler.PriorityNormal, 2)
+
+	timeout := time.After(time.Second)
+	for {
+		if v, ok := m.Gauge("loadshedding.inflight"); ok && v == 2 {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("failed to update the inflight gauge without the CPU limit")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if _, ok := m.Gauge("loadshedding.cpu"); ok {
+		t.Error("unexpected CPU gauge without the CPU limit")
+	}
+}
+
+func TestLoadSheddingCPU(t *testing.T) {
+	ls := scheduler.NewLoadShedder(scheduler.LoadSheddingOptions{MaxCPU: 50, CPUUpdateInterval: time.Hour}, nil)
+	defer ls.Close()
+
+	for _, tt := range []struct {
+		cpu    float64
+		expect []bool
+	}{
+		{cpu: 10, expect: []bool{true, true, true, true}},
+		{cpu: 35, expect: []bool{false, true, true, true}},
+		{cpu: 45, expect: []bool{false, false, true, true}},
+		{cpu: 80, expect: []bool{false, false, false, true}},
+	} {
+		scheduler.ExportSetCPU(ls, tt.cpu)
+		for p, expect := range tt.expect {
+			done, ok := ls.Admit(scheduler.Priority(p))
+			if ok != expect {
+				t.Errorf("invalid admission of %v at %v%% CPU, expected: %v", scheduler.Priority(p), tt.cpu, expect)
+			}
+
+			if ok {
+				done()
+			}
+		}
+	}
+}
+
+func TestLoadSheddingRetryAfter(t *testing.T) {
+	for _, tt := range []struct {
+		retryAfter time.Duration
+		expect     int
+	}{
+		{0, 1},
+		{100 * time.Millisecond, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	} {
+		ls := scheduler.NewLoadShedder(scheduler.LoadSheddingOptions{MaxInflight: 1, RetryAfter: tt.retryAfter}, nil)
+		if s := ls.RetryAfter(); s != tt.expect {
+			t.Errorf("invalid retry after for %v, expected: %d, got: %d", tt.retryAfter, tt.expect, s)
+		}
+
+		ls.Close()
+	}
+}
+
+func TestRegistryLoadShedder(t *testing.T) {
+	r := scheduler.NewRegistry()
+	if r.LoadShedder() != nil {
+		t.Error("unexpected load shedder")
+	}
+
+	r.Close()
+
+	r = scheduler.RegistryWith(scheduler.Options{LoadShedding: scheduler.LoadSheddingOptions{MaxCPU: 80}})
+	if r.LoadShedder() == nil {
+		t.Error("failed to create load shedder")
+	}
+
+	r.Close()
+}
+
+func TestLoadSheddingCPUSampling(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("measuring the CPU usage is not supported on windows")
+	}
+
+	m := &metricstest.MockMetrics{}
+	ls := scheduler.NewLoadShedder(scheduler.LoadSheddingOptions{MaxCPU: 80, CPUUpdateInterval: 10 * time.Millisecond}, m)
+	defer ls.Close()
+
+	timeout := time.After(time.Second)
+	for {
+		if _, ok := m.Gauge("loadshedding.cpu"); ok {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("failed to sample the CPU usage")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if _, ok := m.Gauge("loadshedding.inflight"); !ok {
+		t.Error("failed to update the inflight gauge")
+	}
+}
@@ -1,5 +1,5 @@
 // Package scheduler provides a registry to be used as a postprocessor for the routes
-// that use a LIFO filter.
+// that use a LIFO filter, and the priority aware load shedding of the proxy.
 package scheduler
 
 import (
@@ -81,6 +81,9 @@ type Options struct {
 
 	// Metrics must be provided to the registry in order to collect the LIFO metrics.
 	Metrics metrics.Metrics
+
+	// LoadShedding enables the priority aware load shedding, when any of its limits is set.
+	LoadShedding LoadSheddingOptions
 }
 
 // Registry maintains a set of LIFO queues. It is used to preserve LIFO queue instances
@@ -92,11 +95,11 @@ type Options struct {
 // metrics. This goroutine is started when the first lifo filter is detected and returns
 // when the registry is closed. Individual metrics objects (keys) are used for each
 // lifo filter, and one for each lifo group defined by the lifoGroup filter.
-//
 type Registry struct {
-	options   Options
-	measuring bool
-	quit      chan struct{}
+	options     Options
+	measuring   bool
+	quit        chan struct{}
+	loadShedder *LoadShedder
 
 	mu      sync.Mutex
 	queues  map[queueId]*Queue
@@ -193,12 +196,18 @@ func RegistryWith(o Options) *Registry {
 		o.MetricsUpdateTimeout = time.Second
 	}
 
-	return &Registry{
+	r := &Registry{
 		options: o,
 		quit:    make(chan struct{}),
 		queues:  make(map[queueId]*Queue),
 		deleted: make(map[*Queue]time.Time),
 	}
+
+	if o.LoadShedding.Enabled() {
+		r.loadShedder = NewLoadShedder(o.LoadShedding, o.Metrics)
+	}
+
+	return r
 }
 
 // NewRegistry creates a registry with the default options.
@@ -430,5 +439,14 @@ func (r *Registry) Close() {
 		q.close()
 	}
 
+	if r.loadShedder != nil {
+		r.loadShedder.Close()
+	}
+
 	close(r.quit)
 }
+
+// LoadShedder returns the load shedder of the registry, or nil, when the load shedding is not enabled.
+func (r *Registry) LoadShedder() *LoadShedder {
+	return r.loadShedder
+}
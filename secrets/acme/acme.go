@@ -0,0 +1,413 @@
+/*
+Package acme implements obtaining and renewing TLS certificates from an
+ACME server (RFC 8555), e.g. Let's Encrypt, for the hosts found in the
+routing table.
+
+Certificates are only requested for hosts that are both matched by the
+Host predicate of at least one route, and allowed by the configured
+allowlist. The obtained certificates are stored in a pluggable Store,
+which makes them available to every instance sharing the same store, and
+they are fed into a certregistry.CertRegistry, from where the TLS
+listener serves them.
+
+Domain ownership is verified either with the HTTP-01 challenge, served
+by an internal priority route, or with the TLS-ALPN-01 challenge, served
+during the TLS handshake. To enable the latter, the TLS config of the
+listener needs to use the GetCertificate method of the Manager and to
+advertise the "acme-tls/1" protocol, see TLSConfig.
+*/
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/secrets/certregistry"
+)
+
+const (
+	// LetsEncryptURL is the directory URL of the Let's Encrypt production ACME server.
+	LetsEncryptURL = acme.LetsEncryptURL
+
+	// ChallengeRouteID is the route ID of the internal priority route serving the
+	// HTTP-01 challenge responses.
+	ChallengeRouteID = "__acme_http01_challenge"
+
+	challengePathPrefix = "/.well-known/acme-challenge/"
+
+	defaultRenewBefore   = 30 * 24 * time.Hour
+	defaultCheckInterval = time.Hour
+)
+
+var (
+	errHostNotAllowed = errors.New("host not allowed")
+	errHostNotRouted  = errors.New("host not found in the routing table")
+)
+
+// Options configure the ACME certificate manager.
+type Options struct {
+
+	// DirectoryURL is the ACME directory endpoint. Defaults to
+	// LetsEncryptURL.
+	DirectoryURL string
+
+	// Email is the optional contact address used when registering the
+	// ACME account.
+	Email string
+
+	// AllowedHosts restricts the hosts for which certificates may be
+	// requested. An entry is either an exact host name, or a wildcard
+	// entry of the form '*.example.org', allowing any subdomain of
+	// example.org. Exact entries are obtained and renewed proactively,
+	// while hosts matching only a wildcard entry are obtained on demand
+	// during the first TLS handshake, and renewed proactively from then
+	// on, as long as they are routed. Required.
+	AllowedHosts []string
+
+	// Store persists the ACME account key, the certificates and the
+	// challenge tokens. When multiple instances share the same store,
+	// the certificates are requested only once. Required.
+	Store Store
+
+	// CertRegistry receives the obtained certificates. Required.
+	CertRegistry *certregistry.CertRegistry
+
+	// RenewBefore defines how early the certificates are renewed before
+	// they expire. Defaults to 30 days.
+	RenewBefore time.Duration
+
+	// CheckInterval defines how often the allowed and routed hosts are
+	// checked for missing or expiring certificates. Defaults to one
+	// hour.
+	CheckInterval time.Duration
+
+	// HTTPClient is used to communicate with the ACME server. Optional.
+	HTTPClient *http.Client
+}
+
+// Manager obtains and renews certificates from an ACME server. It
+// implements routing.PostProcessor to track the hosts in the routing
+// table, and proxy.PriorityRoute to serve the HTTP-01 challenges.
+type Manager struct {
+	autocert      *autocert.Manager
+	registry      *certregistry.CertRegistry
+	exact         map[string]bool
+	wildcard      []string
+	checkInterval time.Duration
+	challenge     *routing.Route
+
+	mu       sync.RWMutex
+	hosts    []*regexp.Regexp
+	onDemand map[string]bool
+
+	sync chan struct{}
+	quit chan struct{}
+	once sync.Once
+}
+
+// New creates a Manager and starts obtaining and renewing the
+// certificates in the background. The returned Manager needs to be
+// closed on teardown.
+func New(o Options) (*Manager, error) {
+	if len(o.AllowedHosts) == 0 {
+		return nil, errors.New("acme: at least one allowed host is required")
+	}
+
+	if o.Store == nil {
+		return nil, errors.New("acme: store is required")
+	}
+
+	if o.CertRegistry == nil {
+		return nil, errors.New("acme: certificate registry is required")
+	}
+
+	if o.DirectoryURL == "" {
+		o.DirectoryURL = LetsEncryptURL
+	}
+
+	if o.RenewBefore <= 0 {
+		o.RenewBefore = defaultRenewBefore
+	}
+
+	if o.CheckInterval <= 0 {
+		o.CheckInterval = defaultCheckInterval
+	}
+
+	m := &Manager{
+		registry:      o.CertRegistry,
+		exact:         make(map[string]bool),
+		onDemand:      make(map[string]bool),
+		checkInterval: o.CheckInterval,
+		sync:          make(chan struct{}, 1),
+		quit:          make(chan struct{}),
+	}
+
+	for _, h := range o.AllowedHosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		switch {
+		case h == "":
+		case strings.HasPrefix(h, "*."):
+			m.wildcard = append(m.wildcard, h[1:])
+		case strings.Contains(h, "*"):
+			return nil, fmt.Errorf("acme: invalid allowed host: %s", h)
+		default:
+			m.exact[h] = true
+		}
+	}
+
+	m.autocert = &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Cache:       o.Store,
+		HostPolicy:  m.hostPolicy,
+		RenewBefore: o.RenewBefore,
+		Email:       o.Email,
+		Client: &acme.Client{
+			DirectoryURL: o.DirectoryURL,
+			HTTPClient:   o.HTTPClient,
+		},
+	}
+
+	// calling HTTPHandler enables the HTTP-01 challenge type in
+	// addition to TLS-ALPN-01
+	m.challenge = &routing.Route{
+		Filters: []*routing.RouteFilter{{
+			Filter: &challengeFilter{handler: m.autocert.HTTPHandler(http.NotFoundHandler())},
+			Name:   "acmeChallenge",
+		}},
+	}
+	m.challenge.Id = ChallengeRouteID
+	m.challenge.Shunt = true
+
+	go m.run()
+	return m, nil
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return host
+}
+
+func (m *Manager) allowed(host string) bool {
+	if m.exact[host] {
+		return true
+	}
+
+	for _, w := range m.wildcard {
+		// only a single label is allowed in place of the wildcard
+		if strings.HasSuffix(host, w) && !strings.Contains(strings.TrimSuffix(host, w), ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Manager) routed(host string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, rx := range m.hosts {
+		if rx.MatchString(host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Manager) hostPolicy(_ context.Context, host string) error {
+	host = strings.ToLower(stripPort(host))
+	if !m.allowed(host) {
+		return errHostNotAllowed
+	}
+
+	if !m.routed(host) {
+		return errHostNotRouted
+	}
+
+	return nil
+}
+
+// Do implements routing.PostProcessor. It records the Host predicates
+// of the routes, and it doesn't modify the routes.
+func (m *Manager) Do(routes []*routing.Route) []*routing.Route {
+	var hosts []*regexp.Regexp
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		for _, h := range r.HostRegexps {
+			if seen[h] {
+				continue
+			}
+
+			seen[h] = true
+			rx, err := regexp.Compile(h)
+			if err != nil {
+				// invalid routes are already filtered out at this point
+				continue
+			}
+
+			hosts = append(hosts, rx)
+		}
+	}
+
+	m.mu.Lock()
+	m.hosts = hosts
+	m.mu.Unlock()
+
+	// trigger obtaining the certificates of the new hosts without
+	// blocking the route processing
+	select {
+	case m.sync <- struct{}{}:
+	default:
+	}
+
+	return routes
+}
+
+// Match implements proxy.PriorityRoute. It matches the HTTP-01 challenge
+// requests of the ACME server.
+func (m *Manager) Match(r *http.Request) (*routing.Route, map[string]string) {
+	if !strings.HasPrefix(r.URL.Path, challengePathPrefix) {
+		return nil, nil
+	}
+
+	return m.challenge, nil
+}
+
+// GetCertificate can be used as the GetCertificate function of a
+// tls.Config. It answers the TLS-ALPN-01 challenges, returns the
+// certificates from the registry, and obtains missing certificates on
+// demand for the allowed and routed hosts.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto {
+		return m.autocert.GetCertificate(hello)
+	}
+
+	if cert, err := m.registry.GetCertFromHello(hello); cert != nil || err != nil {
+		return cert, err
+	}
+
+	host := strings.ToLower(hello.ServerName)
+	if host == "" || m.hostPolicy(context.Background(), host) != nil {
+		return nil, nil
+	}
+
+	cert, err := m.obtain(host)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.exact[host] {
+		m.mu.Lock()
+		m.onDemand[host] = true
+		m.mu.Unlock()
+	}
+
+	return cert, nil
+}
+
+// TLSConfig returns a tls.Config using the certificates managed by the
+// Manager, and enabling the TLS-ALPN-01 challenge.
+func (m *Manager) TLSConfig(minVersion uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1", acme.ALPNProto},
+	}
+}
+
+func (m *Manager) obtain(host string) (*tls.Certificate, error) {
+	cert, err := m.autocert.GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.registry.ConfigureCertificate(host, cert); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// syncHosts returns the routed exact hosts, and the hosts obtained on
+// demand. The hosts obtained on demand that are not routed anymore are
+// dropped.
+func (m *Manager) syncHosts() []string {
+	var hosts []string
+	for host := range m.exact {
+		if m.routed(host) {
+			hosts = append(hosts, host)
+		}
+	}
+
+	m.mu.RLock()
+	onDemand := make([]string, 0, len(m.onDemand))
+	for host := range m.onDemand {
+		onDemand = append(onDemand, host)
+	}
+	m.mu.RUnlock()
+
+	for _, host := range onDemand {
+		if m.routed(host) {
+			hosts = append(hosts, host)
+			continue
+		}
+
+		m.mu.Lock()
+		delete(m.onDemand, host)
+		m.mu.Unlock()
+	}
+
+	return hosts
+}
+
+func (m *Manager) syncCertificates() {
+	for _, host := range m.syncHosts() {
+		select {
+		case <-m.quit:
+			return
+		default:
+		}
+
+		if _, err := m.obtain(host); err != nil {
+			log.Errorf("Failed to obtain ACME certificate for %s: %v", host, err)
+		}
+	}
+}
+
+func (m *Manager) run() {
+	ticker := time.NewTicker(m.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.sync:
+			m.syncCertificates()
+		case <-ticker.C:
+			m.syncCertificates()
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// Close stops obtaining and renewing the certificates.
+func (m *Manager) Close() {
+	m.once.Do(func() {
+		close(m.quit)
+	})
+}
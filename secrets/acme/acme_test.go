@@ -0,0 +1,278 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/secrets/certregistry"
+)
+
+func newTestManager(t *testing.T, store Store, allowed ...string) *Manager {
+	m, err := New(Options{
+		AllowedHosts:  allowed,
+		Store:         store,
+		CertRegistry:  certregistry.NewCertRegistry(),
+		CheckInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func routesWithHosts(hosts ...string) []*routing.Route {
+	var routes []*routing.Route
+	for _, h := range hosts {
+		routes = append(routes, &routing.Route{Route: eskip.Route{HostRegexps: []string{h}}})
+	}
+
+	return routes
+}
+
+func createCertificate(t *testing.T, host string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	registry := certregistry.NewCertRegistry()
+	store := NewFileStore(t.TempDir())
+
+	for _, o := range []Options{
+		{Store: store, CertRegistry: registry},
+		{AllowedHosts: []string{"www.example.org"}, CertRegistry: registry},
+		{AllowedHosts: []string{"www.example.org"}, Store: store},
+		{AllowedHosts: []string{"www.*.org"}, Store: store, CertRegistry: registry},
+	} {
+		_, err := New(o)
+		assert.Error(t, err)
+	}
+}
+
+func TestHostPolicy(t *testing.T) {
+	m := newTestManager(t, NewFileStore(t.TempDir()), "www.example.org", "*.example.com")
+	m.Do(routesWithHosts(`^www[.]example[.]org$`, `^[a-z]+[.]example[.]com$`, `^api[.]example[.]net$`))
+
+	for _, tc := range []struct {
+		host    string
+		allowed bool
+	}{
+		{"www.example.org", true},
+		{"www.example.org:443", true},
+		{"WWW.example.org", true},
+		{"api.example.com", true},
+		{"foo.api.example.com", false},
+		{"example.com", false},
+		{"api.example.net", false},
+		{"other.example.org", false},
+	} {
+		t.Run(tc.host, func(t *testing.T) {
+			err := m.hostPolicy(context.Background(), tc.host)
+			assert.Equal(t, tc.allowed, err == nil, "%v", err)
+		})
+	}
+
+	m.Do(nil)
+	assert.Equal(t, errHostNotRouted, m.hostPolicy(context.Background(), "www.example.org"))
+}
+
+func TestSyncHosts(t *testing.T) {
+	m := newTestManager(t, NewFileStore(t.TempDir()), "www.example.org", "api.example.org", "*.example.com")
+	m.Do(routesWithHosts(`^www[.]example[.]org$`, `^[a-z]+[.]example[.]com$`))
+
+	// set by GetCertificate after obtaining the certificates on demand
+	m.onDemand["foo.example.com"] = true
+	m.onDemand["bar.example.com"] = true
+
+	assert.ElementsMatch(t, []string{"www.example.org", "foo.example.com", "bar.example.com"}, m.syncHosts())
+
+	m.Do(routesWithHosts(`^www[.]example[.]org$`, `^foo[.]example[.]com$`))
+	assert.ElementsMatch(t, []string{"www.example.org", "foo.example.com"}, m.syncHosts())
+	assert.Equal(t, map[string]bool{"foo.example.com": true}, m.onDemand)
+}
+
+func TestHTTP01Challenge(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	m := newTestManager(t, store, "www.example.org")
+	m.Do(routesWithHosts(`^www[.]example[.]org$`))
+
+	require.NoError(t, store.Put(context.Background(), "test-token+http-01", []byte("test-key-authorization")))
+
+	req, err := http.NewRequest("GET", "http://www.example.org/index.html", nil)
+	require.NoError(t, err)
+
+	r, _ := m.Match(req)
+	assert.Nil(t, r)
+
+	for _, tc := range []struct {
+		host   string
+		status int
+		body   string
+	}{
+		{"www.example.org", http.StatusOK, "test-key-authorization"},
+		{"www.example.com", http.StatusForbidden, ""},
+	} {
+		t.Run(tc.host, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "http://"+tc.host+"/.well-known/acme-challenge/test-token", nil)
+			require.NoError(t, err)
+
+			r, _ := m.Match(req)
+			require.NotNil(t, r)
+			assert.Equal(t, ChallengeRouteID, r.Id)
+			require.Len(t, r.Filters, 1)
+
+			ctx := &filtertest.Context{FRequest: req}
+			r.Filters[0].Request(ctx)
+			require.True(t, ctx.FServed)
+			assert.Equal(t, tc.status, ctx.FResponse.StatusCode)
+
+			if tc.body != "" {
+				b, err := io.ReadAll(ctx.FResponse.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tc.body, string(b))
+			}
+		})
+	}
+}
+
+func TestGetCertificateFromRegistry(t *testing.T) {
+	registry := certregistry.NewCertRegistry()
+	m, err := New(Options{
+		AllowedHosts: []string{"www.example.org"},
+		Store:        NewFileStore(t.TempDir()),
+		CertRegistry: registry,
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	cert := createCertificate(t, "www.example.org")
+	require.NoError(t, registry.ConfigureCertificate("www.example.org", cert))
+
+	got, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.org"})
+	require.NoError(t, err)
+	assert.Equal(t, cert, got)
+
+	// not allowed hosts are not obtained on demand
+	got, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+// TestPebble runs against a local Pebble ACME test server, started e.g. with:
+//
+//	docker run -e PEBBLE_VA_ALWAYS_VALID=1 -p 14000:14000 letsencrypt/pebble
+//	SKIPPER_ACME_PEBBLE_URL=https://localhost:14000/dir go test ./secrets/acme -run TestPebble
+func TestPebble(t *testing.T) {
+	directoryURL := os.Getenv("SKIPPER_ACME_PEBBLE_URL")
+	if directoryURL == "" {
+		t.Skip("SKIPPER_ACME_PEBBLE_URL not set")
+	}
+
+	registry := certregistry.NewCertRegistry()
+	m, err := New(Options{
+		DirectoryURL: directoryURL,
+		AllowedHosts: []string{"www.example.org"},
+		Store:        NewFileStore(t.TempDir()),
+		CertRegistry: registry,
+		HTTPClient: &http.Client{Transport: &http.Transport{
+			// Pebble uses a self-signed certificate for its API
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}},
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	m.Do(routesWithHosts(`^www[.]example[.]org$`))
+	m.syncCertificates()
+
+	cert, err := registry.GetCertFromHello(&tls.ClientHelloInfo{ServerName: "www.example.org"})
+	require.NoError(t, err)
+	require.NotNil(t, cert)
+	assert.Equal(t, []string{"www.example.org"}, cert.Leaf.DNSNames)
+}
+
+func TestKubernetesSecretStore(t *testing.T) {
+	secrets := make(map[string][]byte)
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const base = "/api/v1/namespaces/skipper/secrets"
+		switch {
+		case r.Method == "GET":
+			b, ok := secrets[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.Write(b)
+		case r.Method == "POST" && r.URL.Path == base:
+			b, _ := io.ReadAll(r.Body)
+			secrets[base+"/skipper-acme-www.example.org-rsa"] = b
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "PUT":
+			if _, ok := secrets[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			secrets[r.URL.Path], _ = io.ReadAll(r.Body)
+		case r.Method == "DELETE":
+			delete(secrets, r.URL.Path)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer apiServer.Close()
+
+	store, err := NewKubernetesSecretStore(KubernetesSecretStoreOptions{
+		APIURL:    apiServer.URL,
+		Namespace: "skipper",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = store.Get(ctx, "www.example.org+rsa")
+	assert.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, store.Put(ctx, "www.example.org+rsa", []byte("foo")))
+	data, err := store.Get(ctx, "www.example.org+rsa")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(data))
+
+	require.NoError(t, store.Put(ctx, "www.example.org+rsa", []byte("bar")))
+	data, err = store.Get(ctx, "www.example.org+rsa")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(data))
+
+	require.NoError(t, store.Delete(ctx, "www.example.org+rsa"))
+	_, err = store.Get(ctx, "www.example.org+rsa")
+	assert.Equal(t, ErrNotFound, err)
+}
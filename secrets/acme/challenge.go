@@ -0,0 +1,53 @@
+package acme
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/zalando/skipper/filters"
+)
+
+// challengeFilter serves the HTTP-01 challenge responses from the
+// internal priority route.
+type challengeFilter struct {
+	handler http.Handler
+}
+
+type bufferedResponse struct {
+	header     http.Header
+	body       bytes.Buffer
+	statusCode int
+}
+
+func (r *bufferedResponse) Header() http.Header { return r.header }
+
+func (r *bufferedResponse) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+
+	return r.body.Write(b)
+}
+
+func (r *bufferedResponse) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+}
+
+func (f *challengeFilter) Request(ctx filters.FilterContext) {
+	rsp := &bufferedResponse{header: make(http.Header)}
+	f.handler.ServeHTTP(rsp, ctx.Request())
+	if rsp.statusCode == 0 {
+		rsp.statusCode = http.StatusOK
+	}
+
+	ctx.Serve(&http.Response{
+		StatusCode: rsp.statusCode,
+		Header:     rsp.header,
+		Body:       io.NopCloser(&rsp.body),
+	})
+}
+
+func (f *challengeFilter) Response(filters.FilterContext) {}
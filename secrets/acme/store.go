@@ -0,0 +1,289 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ErrNotFound is returned by the stores when the requested key doesn't
+// exist.
+var ErrNotFound = autocert.ErrCacheMiss
+
+// Store persists the ACME account key, the certificates and the
+// challenge tokens. Get returns ErrNotFound when the key doesn't exist.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFileStore returns a Store using the files in a local directory.
+// The directory is created if it doesn't exist.
+func NewFileStore(dir string) Store {
+	return autocert.DirCache(dir)
+}
+
+const (
+	serviceAccountDir      = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	serviceAccountTokenKey = "token"
+	serviceAccountCAKey    = "ca.crt"
+	defaultAPIURL          = "https://kubernetes.default.svc"
+	defaultSecretPrefix    = "skipper-acme-"
+	secretDataKey          = "data"
+	secretKeyAnnotation    = "skipper.zalando.org/acme-key"
+	secretsFmt             = "/api/v1/namespaces/%s/secrets"
+)
+
+var invalidSecretNameChars = regexp.MustCompile("[^a-z0-9.-]+")
+
+// KubernetesSecretStoreOptions configure the Kubernetes secret store.
+type KubernetesSecretStoreOptions struct {
+
+	// Namespace where the secrets are stored. Required.
+	Namespace string
+
+	// SecretPrefix is prepended to the names of the secrets. Defaults
+	// to "skipper-acme-".
+	SecretPrefix string
+
+	// InCluster uses the service account token and the cluster CA
+	// mounted into the pod.
+	InCluster bool
+
+	// APIURL of the Kubernetes API server. Defaults to
+	// https://kubernetes.default.svc when running in cluster.
+	APIURL string
+
+	// HTTPClient used when not running in cluster. Optional.
+	HTTPClient *http.Client
+}
+
+type kubernetesSecretStore struct {
+	apiURL    string
+	namespace string
+	prefix    string
+	tokenFile string
+	client    *http.Client
+}
+
+type secretMetadata struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type secretResource struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   secretMetadata    `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	Data       map[string][]byte `json:"data"`
+}
+
+// NewKubernetesSecretStore returns a Store keeping every entry in a
+// separate Kubernetes secret. It allows sharing the certificates
+// between all the instances running in the cluster.
+func NewKubernetesSecretStore(o KubernetesSecretStoreOptions) (Store, error) {
+	if o.Namespace == "" {
+		return nil, errors.New("acme: namespace is required for the kubernetes secret store")
+	}
+
+	s := &kubernetesSecretStore{
+		apiURL:    o.APIURL,
+		namespace: o.Namespace,
+		prefix:    o.SecretPrefix,
+		client:    o.HTTPClient,
+	}
+
+	if s.prefix == "" {
+		s.prefix = defaultSecretPrefix
+	}
+
+	if o.InCluster {
+		rootCA, err := os.ReadFile(serviceAccountDir + serviceAccountCAKey)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(rootCA) {
+			return nil, errors.New("acme: invalid kubernetes CA")
+		}
+
+		s.tokenFile = serviceAccountDir + serviceAccountTokenKey
+		s.client = &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					MinVersion: tls.VersionTLS12,
+					RootCAs:    pool,
+				},
+			},
+		}
+
+		if s.apiURL == "" {
+			s.apiURL = defaultAPIURL
+		}
+	}
+
+	if s.apiURL == "" {
+		return nil, errors.New("acme: API URL is required for the kubernetes secret store")
+	}
+
+	if s.client == nil {
+		s.client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return s, nil
+}
+
+func (s *kubernetesSecretStore) secretName(key string) string {
+	name := invalidSecretNameChars.ReplaceAllString(strings.ToLower(key), "-")
+	name = strings.Trim(s.prefix+name, ".-")
+	if len(name) > 253 {
+		name = name[:253]
+	}
+
+	return name
+}
+
+func (s *kubernetesSecretStore) do(ctx context.Context, method, uri string, body interface{}) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		r = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.apiURL+uri, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if s.tokenFile != "" {
+		token, err := os.ReadFile(s.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	return s.client.Do(req)
+}
+
+func (s *kubernetesSecretStore) get(ctx context.Context, name string) (*secretResource, error) {
+	rsp, err := s.do(ctx, "GET", fmt.Sprintf(secretsFmt, s.namespace)+"/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rsp.Body.Close()
+	if rsp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("acme: failed to get secret %s, status: %d", name, rsp.StatusCode)
+	}
+
+	var secret secretResource
+	if err := json.NewDecoder(rsp.Body).Decode(&secret); err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// Get implements Store.
+func (s *kubernetesSecretStore) Get(ctx context.Context, key string) ([]byte, error) {
+	secret, err := s.get(ctx, s.secretName(key))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := secret.Data[secretDataKey]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+// Put implements Store.
+func (s *kubernetesSecretStore) Put(ctx context.Context, key string, data []byte) error {
+	name := s.secretName(key)
+	secret := &secretResource{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: secretMetadata{
+			Name:        name,
+			Namespace:   s.namespace,
+			Annotations: map[string]string{secretKeyAnnotation: key},
+		},
+		Type: "Opaque",
+		Data: map[string][]byte{secretDataKey: data},
+	}
+
+	current, err := s.get(ctx, name)
+	switch {
+	case err == ErrNotFound:
+		return s.write(ctx, "POST", fmt.Sprintf(secretsFmt, s.namespace), secret)
+	case err != nil:
+		return err
+	default:
+		secret.Metadata.ResourceVersion = current.Metadata.ResourceVersion
+		return s.write(ctx, "PUT", fmt.Sprintf(secretsFmt, s.namespace)+"/"+name, secret)
+	}
+}
+
+func (s *kubernetesSecretStore) write(ctx context.Context, method, uri string, secret *secretResource) error {
+	rsp, err := s.do(ctx, method, uri, secret)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK && rsp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("acme: failed to store secret %s, status: %d", secret.Metadata.Name, rsp.StatusCode)
+	}
+
+	return nil
+}
+
+// Delete implements Store.
+func (s *kubernetesSecretStore) Delete(ctx context.Context, key string) error {
+	name := s.secretName(key)
+	rsp, err := s.do(ctx, "DELETE", fmt.Sprintf(secretsFmt, s.namespace)+"/"+name, nil)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK && rsp.StatusCode != http.StatusAccepted && rsp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("acme: failed to delete secret %s, status: %d", name, rsp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Parser caches the value parsed from the content of secrets, e.g.
+// keys or certificates, read with a secrets reader. The content of the
+// secrets is compared on every use, and parsed again only after it was
+// rotated. When the rotated content is invalid, the error is logged, and
+// the last valid value is used until the next rotation.
+type Parser struct {
+	secrets SecretsReader
+	paths   []string
+	parse   func(content ...[]byte) (interface{}, error)
+
+	mu    sync.Mutex
+	raw   [][]byte
+	value interface{}
+	valid bool
+	err   error
+}
+
+// NewParser creates a Parser for the secrets at the provided
+// paths. The parse function receives the content of the secrets in the
+// order of the paths. The content of an empty path is nil.
+func NewParser(sr SecretsReader, parse func(content ...[]byte) (interface{}, error), paths ...string) *Parser {
+	return &Parser{secrets: sr, paths: paths, parse: parse}
+}
+
+func (p *Parser) read() ([][]byte, error) {
+	raw := make([][]byte, len(p.paths))
+	for i, path := range p.paths {
+		if path == "" {
+			continue
+		}
+
+		b, ok := p.secrets.GetSecret(path)
+		if !ok {
+			return nil, fmt.Errorf("secret not found: %s", path)
+		}
+
+		raw[i] = b
+	}
+
+	return raw, nil
+}
+
+func (p *Parser) changed(raw [][]byte) bool {
+	for i := range raw {
+		if !bytes.Equal(raw[i], p.raw[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get returns the value parsed from the current content of the secrets.
+// It fails when a secret is missing, or when the secrets were not valid
+// since they were first read.
+func (p *Parser) Get() (interface{}, error) {
+	raw, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.raw == nil || p.changed(raw) {
+		p.raw = raw
+		if v, err := p.parse(raw...); err != nil {
+			err = fmt.Errorf("invalid secret %s: %w", strings.Join(p.paths, ", "), err)
+			if p.valid {
+				// keep using the last valid version
+				log.Errorf("%v", err)
+			} else {
+				p.err = err
+			}
+		} else {
+			p.value, p.valid, p.err = v, true, nil
+		}
+	}
+
+	return p.value, p.err
+}
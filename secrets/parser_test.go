@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type testSecrets map[string][]byte
+
+func (s testSecrets) GetSecret(path string) ([]byte, bool) {
+	b, ok := s[path]
+	return b, ok
+}
+
+func (testSecrets) Close() {}
+
+func TestParser(t *testing.T) {
+	var parsed int
+	sr := testSecrets{"/secrets/a": []byte("1")}
+	p := NewParser(sr, func(content ...[]byte) (interface{}, error) {
+		parsed++
+		if content[1] != nil {
+			return nil, errors.New("unexpected content of the empty path")
+		}
+
+		return strconv.Atoi(string(content[0]))
+	}, "/secrets/a", "")
+
+	get := func(expected int) {
+		t.Helper()
+		if v, err := p.Get(); err != nil || v != expected {
+			t.Errorf("expected %d, got: %v, %v", expected, v, err)
+		}
+	}
+
+	get(1)
+	get(1)
+	if parsed != 1 {
+		t.Errorf("expected parsing once, got: %d", parsed)
+	}
+
+	sr["/secrets/a"] = []byte("2")
+	get(2)
+
+	// the last valid version is kept after an invalid rotation
+	sr["/secrets/a"] = []byte("invalid")
+	get(2)
+	get(2)
+	if parsed != 3 {
+		t.Errorf("expected parsing the invalid version once, got: %d", parsed)
+	}
+
+	delete(sr, "/secrets/a")
+	if _, err := p.Get(); err == nil {
+		t.Error("expected error with missing secret")
+	}
+
+	// fails while never valid
+	p = NewParser(testSecrets{"/secrets/a": []byte("invalid")}, p.parse, "/secrets/a", "")
+	if _, err := p.Get(); err == nil {
+		t.Error("expected error with invalid secret")
+	}
+}
@@ -1,8 +1,10 @@
 package skipper
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
@@ -34,13 +36,16 @@ import (
 	"github.com/zalando/skipper/filters/builtin"
 	"github.com/zalando/skipper/filters/fadein"
 	logfilter "github.com/zalando/skipper/filters/log"
+	"github.com/zalando/skipper/filters/openpolicyagent"
 	ratelimitfilters "github.com/zalando/skipper/filters/ratelimit"
+	tlsfilters "github.com/zalando/skipper/filters/tls"
 	"github.com/zalando/skipper/innkeeper"
 	"github.com/zalando/skipper/loadbalancer"
 	"github.com/zalando/skipper/logging"
 	"github.com/zalando/skipper/metrics"
 	skpnet "github.com/zalando/skipper/net"
 	pauth "github.com/zalando/skipper/predicates/auth"
+	"github.com/zalando/skipper/predicates/clientcert"
 	"github.com/zalando/skipper/predicates/cookie"
 	"github.com/zalando/skipper/predicates/cron"
 	"github.com/zalando/skipper/predicates/forwarded"
@@ -58,6 +63,7 @@ import (
 	"github.com/zalando/skipper/routing"
 	"github.com/zalando/skipper/scheduler"
 	"github.com/zalando/skipper/secrets"
+	"github.com/zalando/skipper/secrets/acme"
 	"github.com/zalando/skipper/secrets/certregistry"
 	"github.com/zalando/skipper/swarm"
 	"github.com/zalando/skipper/tracing"
@@ -545,8 +551,59 @@ type Options struct {
 	// Logrus logger for access logs. To enable structured logging, use AccessLogJSONEnabled.
 	AccessLogJsonFormatter *log.JSONFormatter
 
+	// AccessLogFormat, when set, is the template of the access log
+	// entries, see logging.ParseAccessLogFormat. It can be overridden
+	// for specific routes with the accessLogFormat filter.
+	AccessLogFormat string
+
+	// AccessLogSampleRate, when between 0 and 1, sets the ratio of
+	// the requests logged in the access log.
+	AccessLogSampleRate float64
+
+	// AccessLogRedactHeaders lists the headers whose values are
+	// redacted in the access log. When not set,
+	// logging.DefaultRedactedHeaders are used.
+	AccessLogRedactHeaders []string
+
+	// AccessLogRedactQueryParams lists the query params whose values
+	// are redacted in the access log.
+	AccessLogRedactQueryParams []string
+
+	// ApplicationLogSinks lists the URLs of the sinks receiving the
+	// application log entries in addition to ApplicationLogOutput,
+	// e.g. syslog+tcp://localhost:514 or otlp+http://localhost:4318.
+	// See logging.NewLogSink for the supported sinks.
+	ApplicationLogSinks []string
+
+	// AccessLogSinks lists the URLs of the sinks receiving the access
+	// log entries in addition to AccessLogOutput.
+	AccessLogSinks []string
+
+	// LogSinkBufferSize sets the number of log entries buffered for
+	// each sink. When the buffer is full, the new entries are dropped,
+	// and counted in the logsink.<name>.dropped metric.
+	LogSinkBufferSize int
+
 	DebugListener string
 
+	// DebugTraceHeader sets the name of the request header triggering
+	// the per-request debug traces. Defaults to X-Skipper-Debug.
+	DebugTraceHeader string
+
+	// DebugTraceTokens lists the values of the debug trace header that
+	// trigger the per-request debug trace.
+	DebugTraceTokens []string
+
+	// DebugTraceSigningKeyFile is the path of the file containing the
+	// key used to verify the signed values of the debug trace header.
+	DebugTraceSigningKeyFile string
+
+	// DebugTraceStoreSize, when greater than zero, enables storing the
+	// last debug traces, and serving them on the support listener
+	// under /debug/traces/, instead of returning them in a response
+	// header.
+	DebugTraceStoreSize int
+
 	// Path of certificate(s) when using TLS, mutiple may be given comma separated
 	CertPathTLS string
 	// Path of key(s) when using TLS, multiple may be given comma separated. For
@@ -562,6 +619,15 @@ type Options struct {
 	// TLSMinVersion to set the minimal TLS version for all TLS configurations
 	TLSMinVersion uint16
 
+	// TLSClientAuth sets the client certificate verification policy of
+	// the TLS listener.
+	TLSClientAuth tls.ClientAuthType
+
+	// TLSClientCAFile is the path of the PEM encoded CA certificate(s)
+	// used to verify the client certificates, multiple may be given
+	// comma separated.
+	TLSClientCAFile string
+
 	// Flush interval for upgraded Proxy connections
 	BackendFlushInterval time.Duration
 
@@ -586,6 +652,22 @@ type Options struct {
 	// BreakerSettings contain global and host specific settings for the circuit breakers.
 	BreakerSettings []circuit.BreakerSettings
 
+	// EnableSharedBreakers enables sharing the failure counts and the open state of the circuit breakers across
+	// the skipper instances, via the redis or the swim based swarm. Requires EnableSwarm.
+	EnableSharedBreakers bool
+
+	// BreakerSyncInterval is the interval of synchronizing the shared circuit breakers. Defaults to
+	// circuit.DefaultSyncInterval.
+	BreakerSyncInterval time.Duration
+
+	// EnableBreakerAdminWrite enables tripping and resetting the circuit breakers on the support listener.
+	// The support listener doesn't authenticate the requests.
+	EnableBreakerAdminWrite bool
+
+	// EnableQuotaAdminReset enables resetting the usage of the quotas on the support listener. The support
+	// listener doesn't authenticate the requests.
+	EnableQuotaAdminReset bool
+
 	// EnableRatelimiters enables the usage of the ratelimiter in the route definitions without initializing any
 	// by default. It is a shortcut for setting the RatelimitSettings to:
 	//
@@ -599,6 +681,19 @@ type Options struct {
 	// EnableRouteLIFOMetrics enables metrics for the individual route LIFO queues, if any.
 	EnableRouteLIFOMetrics bool
 
+	// LoadSheddingMaxInflight enables the load shedding, and sets the number of the concurrent backend
+	// requests considered as full load. When the load increases, the requests with lower priority classes,
+	// set by the priorityClass filters, are rejected first.
+	LoadSheddingMaxInflight int
+
+	// LoadSheddingMaxCPU enables the load shedding, and sets the CPU usage of the process, in percent of the
+	// available CPUs, considered as full load.
+	LoadSheddingMaxCPU float64
+
+	// LoadSheddingRetryAfter sets the Retry-After header of the requests rejected by the load shedding.
+	// Defaults to 1s.
+	LoadSheddingRetryAfter time.Duration
+
 	// OpenTracing enables opentracing
 	OpenTracing []string
 
@@ -774,6 +869,18 @@ type Options struct {
 	// WebhookTimeout sets timeout duration while calling a custom webhook auth service
 	WebhookTimeout time.Duration
 
+	// OpenPolicyAgentBundleSource enables the opaAuthorizeRequest
+	// filter, and sets the directory or the URL of the HTTP bundle
+	// server, where the policy bundles are loaded from.
+	OpenPolicyAgentBundleSource string
+
+	// OpenPolicyAgentReloadInterval sets how often the policy bundles
+	// are checked for changes.
+	OpenPolicyAgentReloadInterval time.Duration
+
+	// OpenPolicyAgentDecisionLogs enables logging the policy decisions.
+	OpenPolicyAgentDecisionLogs bool
+
 	// MaxAuditBody sets the maximum read size of the body read by the audit log filter
 	MaxAuditBody int
 
@@ -790,6 +897,38 @@ type Options struct {
 	SwarmRedisPoolTimeout   time.Duration
 	SwarmRedisMinIdleConns  int
 	SwarmRedisMaxIdleConns  int
+	// SwarmRedisSRV enables the discovery of the redis shards from the
+	// DNS SRV records of this name, instead of SwarmRedisURLs.
+	SwarmRedisSRV string
+	// SwarmRedisRemote enables the discovery of the redis shards from
+	// this URL, instead of SwarmRedisURLs.
+	SwarmRedisRemote string
+	// SwarmRedisKubernetesNamespace and SwarmRedisKubernetesService
+	// enable the discovery of the redis shards from the endpoints of a
+	// Kubernetes service, using the Kubernetes data client, instead of
+	// SwarmRedisURLs.
+	SwarmRedisKubernetesNamespace string
+	SwarmRedisKubernetesService   string
+	// SwarmRedisUpdateInterval is the interval of the redis shard
+	// discovery.
+	SwarmRedisUpdateInterval time.Duration
+	// SwarmRedisUsername is the ACL username of redis
+	SwarmRedisUsername string
+	// SwarmRedisTLS enables TLS for the connections to redis
+	SwarmRedisTLS bool
+	// SwarmRedisTLSCAFile is the file of the CA certificates to verify
+	// the redis servers, the system CAs are used when not set
+	SwarmRedisTLSCAFile string
+	// SwarmRedisMode is one of ring, sentinel or cluster, defaults to
+	// ring. In sentinel mode, SwarmRedisURLs are the addresses of the
+	// sentinels, and in cluster mode, the seed addresses of the
+	// cluster.
+	SwarmRedisMode string
+	// SwarmRedisSentinelMasterName is the name of the master in
+	// sentinel mode
+	SwarmRedisSentinelMasterName string
+	// SwarmRedisSentinelPassword is the password of the sentinels
+	SwarmRedisSentinelPassword string
 	// swim based swarm
 	SwarmKubernetesNamespace          string
 	SwarmKubernetesLabelSelectorKey   string
@@ -811,6 +950,36 @@ type Options struct {
 	// KubernetesEnableTLS enables kubernetes to use resources to terminate tls
 	KubernetesEnableTLS bool
 
+	// ACMEAllowedHosts enables obtaining TLS certificates from an ACME
+	// server for the listed hosts, when they are also found in the
+	// routing table. Entries of the form '*.example.org' allow any
+	// subdomain of example.org.
+	ACMEAllowedHosts []string
+
+	// ACMEDirectoryURL is the directory endpoint of the ACME server,
+	// defaults to Let's Encrypt.
+	ACMEDirectoryURL string
+
+	// ACMEEmail is the contact address used for the ACME account.
+	ACMEEmail string
+
+	// ACMECacheDir is the local directory used to store the ACME
+	// account and the certificates.
+	ACMECacheDir string
+
+	// ACMEKubernetesSecretsNamespace, when set, the ACME account and the
+	// certificates are stored as Kubernetes secrets in this namespace,
+	// shared by all the instances, instead of ACMECacheDir.
+	ACMEKubernetesSecretsNamespace string
+
+	// ACMERenewBefore defines how early the ACME certificates are
+	// renewed before they expire.
+	ACMERenewBefore time.Duration
+
+	// ACMECheckInterval defines how often the ACME certificates are
+	// checked for renewal.
+	ACMECheckInterval time.Duration
+
 	testOptions
 }
 
@@ -832,6 +1001,40 @@ func newServerErrorLog() *stdlog.Logger {
 	return stdlog.New(&serverErrorLogWriter{}, "", 0)
 }
 
+// returns the redis shard discovery function, when configured
+func getRedisAddrUpdater(o Options, dataClients []routing.DataClient) (func() ([]string, error), error) {
+	switch {
+	case o.SwarmRedisKubernetesService != "":
+		var kdc *kubernetes.Client
+		for _, dc := range dataClients {
+			if c, ok := dc.(*kubernetes.Client); ok {
+				kdc = c
+				break
+			}
+		}
+
+		if kdc == nil {
+			return nil, fmt.Errorf("redis shard discovery from Kubernetes requires the Kubernetes data client")
+		}
+
+		namespace, name := o.SwarmRedisKubernetesNamespace, o.SwarmRedisKubernetesService
+		return func() ([]string, error) {
+			addrs := kdc.GetEndpointAddresses(namespace, name)
+			if len(addrs) == 0 {
+				return nil, fmt.Errorf("no endpoints found for the redis service %s/%s", namespace, name)
+			}
+
+			return addrs, nil
+		}, nil
+	case o.SwarmRedisSRV != "":
+		return skpnet.NewRedisSRVUpdater(o.SwarmRedisSRV), nil
+	case o.SwarmRedisRemote != "":
+		return skpnet.NewRedisRemoteUpdater(o.SwarmRedisRemote), nil
+	default:
+		return nil, nil
+	}
+}
+
 func createDataClients(o Options, auth innkeeper.Authentication, cr *certregistry.CertRegistry) ([]routing.DataClient, error) {
 	var clients []routing.DataClient
 
@@ -914,6 +1117,11 @@ func createDataClients(o Options, auth innkeeper.Authentication, cr *certregistr
 	}
 
 	if o.Kubernetes {
+		// the certificate registry may exist only for ACME
+		if !o.KubernetesEnableTLS {
+			cr = nil
+		}
+
 		kubernetesClient, err := kubernetes.New(kubernetes.Options{
 			KubernetesIngressV1:               o.KubernetesIngressV1,
 			AllowedExternalNames:              o.KubernetesAllowedExternalNames,
@@ -961,6 +1169,24 @@ func getLogOutput(name string) (io.Writer, error) {
 	return os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
 }
 
+func newLogSinks(name string, urls []string) ([]logging.LogSink, error) {
+	var sinks []logging.LogSink
+	for _, u := range urls {
+		s, err := logging.NewLogSink(u, logging.LogSinkOptions{Name: name})
+		if err != nil {
+			for _, si := range sinks {
+				si.Close()
+			}
+
+			return nil, err
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	return sinks, nil
+}
+
 func initLog(o Options) error {
 	var (
 		logOutput       io.Writer
@@ -982,6 +1208,27 @@ func initLog(o Options) error {
 		}
 	}
 
+	var accessLogFormat *logging.AccessLogFormat
+	if o.AccessLogFormat != "" {
+		accessLogFormat, err = logging.ParseAccessLogFormat(o.AccessLogFormat)
+		if err != nil {
+			return err
+		}
+	}
+
+	applicationLogSinks, err := newLogSinks("application", o.ApplicationLogSinks)
+	if err != nil {
+		return err
+	}
+
+	var accessLogSinks []logging.LogSink
+	if !o.AccessLogDisabled {
+		accessLogSinks, err = newLogSinks("access", o.AccessLogSinks)
+		if err != nil {
+			return err
+		}
+	}
+
 	logging.Init(logging.Options{
 		ApplicationLogPrefix:        o.ApplicationLogPrefix,
 		ApplicationLogOutput:        logOutput,
@@ -991,26 +1238,48 @@ func initLog(o Options) error {
 		AccessLogJSONEnabled:        o.AccessLogJSONEnabled,
 		AccessLogStripQuery:         o.AccessLogStripQuery,
 		AccessLogJsonFormatter:      o.AccessLogJsonFormatter,
+		AccessLogFormat:             accessLogFormat,
+		AccessLogSampleRate:         o.AccessLogSampleRate,
+		AccessLogRedactHeaders:      o.AccessLogRedactHeaders,
+		AccessLogRedactQueryParams:  o.AccessLogRedactQueryParams,
+		ApplicationLogSinks:         applicationLogSinks,
+		AccessLogSinks:              accessLogSinks,
+		LogSinkBufferSize:           o.LogSinkBufferSize,
 	})
 
 	return nil
 }
 
-func (o *Options) tlsConfig(cr *certregistry.CertRegistry) (*tls.Config, error) {
+func (o *Options) tlsConfig(cr *certregistry.CertRegistry, am *acme.Manager) (*tls.Config, error) {
 
 	if o.ProxyTLS != nil {
 		return o.ProxyTLS, nil
 	}
 
-	if o.CertPathTLS == "" && o.KeyPathTLS == "" && !o.KubernetesEnableTLS {
+	if o.CertPathTLS == "" && o.KeyPathTLS == "" && !o.KubernetesEnableTLS && am == nil {
 		return nil, nil
 	}
 
-	if o.CertPathTLS == "" && o.KeyPathTLS == "" {
-		config := &tls.Config{
+	var config *tls.Config
+	switch {
+	case am != nil:
+		config = am.TLSConfig(o.TLSMinVersion)
+	case o.KubernetesEnableTLS:
+		config = &tls.Config{
 			MinVersion:     o.TLSMinVersion,
 			GetCertificate: cr.GetCertFromHello,
 		}
+	default:
+		config = &tls.Config{
+			MinVersion: o.TLSMinVersion,
+		}
+	}
+
+	if err := o.configureClientAuth(config); err != nil {
+		return nil, err
+	}
+
+	if o.CertPathTLS == "" && o.KeyPathTLS == "" {
 		return config, nil
 	}
 
@@ -1021,14 +1290,6 @@ func (o *Options) tlsConfig(cr *certregistry.CertRegistry) (*tls.Config, error)
 		return nil, fmt.Errorf("number of certificates does not match number of keys")
 	}
 
-	config := &tls.Config{
-		MinVersion: o.TLSMinVersion,
-	}
-
-	if o.KubernetesEnableTLS {
-		config.GetCertificate = cr.GetCertFromHello
-	}
-
 	for i := 0; i < len(crts); i++ {
 		crt, key := crts[i], keys[i]
 		keypair, err := tls.LoadX509KeyPair(crt, key)
@@ -1040,6 +1301,89 @@ func (o *Options) tlsConfig(cr *certregistry.CertRegistry) (*tls.Config, error)
 	return config, nil
 }
 
+func (o *Options) configureClientAuth(config *tls.Config) error {
+	config.ClientAuth = o.TLSClientAuth
+	if o.TLSClientCAFile == "" {
+		if o.TLSClientAuth >= tls.VerifyClientCertIfGiven {
+			return fmt.Errorf("client certificate verification requires a client CA file")
+		}
+
+		return nil
+	}
+
+	pool := x509.NewCertPool()
+	for _, f := range strings.Split(o.TLSClientCAFile, ",") {
+		pem, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file %s: %w", f, err)
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("failed to load client CA certificates from %s", f)
+		}
+	}
+
+	config.ClientCAs = pool
+	return nil
+}
+
+func (o *Options) redisTLSConfig() (*tls.Config, error) {
+	if !o.SwarmRedisTLS {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.SwarmRedisTLSCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(o.SwarmRedisTLSCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis CA file %s: %w", o.SwarmRedisTLSCAFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("failed to load redis CA certificates from %s", o.SwarmRedisTLSCAFile)
+	}
+
+	config.RootCAs = pool
+	return config, nil
+}
+
+func (o *Options) acmeManager(cr *certregistry.CertRegistry) (*acme.Manager, error) {
+	var (
+		store acme.Store
+		err   error
+	)
+
+	switch {
+	case o.ACMEKubernetesSecretsNamespace != "":
+		store, err = acme.NewKubernetesSecretStore(acme.KubernetesSecretStoreOptions{
+			Namespace: o.ACMEKubernetesSecretsNamespace,
+			InCluster: o.KubernetesInCluster,
+			APIURL:    o.KubernetesURL,
+		})
+		if err != nil {
+			return nil, err
+		}
+	case o.ACMECacheDir != "":
+		store = acme.NewFileStore(o.ACMECacheDir)
+	default:
+		return nil, fmt.Errorf("ACME requires a cache directory or a kubernetes secrets namespace")
+	}
+
+	return acme.New(acme.Options{
+		DirectoryURL:  o.ACMEDirectoryURL,
+		Email:         o.ACMEEmail,
+		AllowedHosts:  o.ACMEAllowedHosts,
+		Store:         store,
+		CertRegistry:  cr,
+		RenewBefore:   o.ACMERenewBefore,
+		CheckInterval: o.ACMECheckInterval,
+	})
+}
+
 func listen(o *Options, mtr metrics.Metrics) (net.Listener, error) {
 	if o.Address == "" {
 		o.Address = ":http"
@@ -1097,8 +1441,9 @@ func listenAndServeQuit(
 	idleConnsCH chan struct{},
 	mtr metrics.Metrics,
 	cr *certregistry.CertRegistry,
+	am *acme.Manager,
 ) error {
-	tlsConfig, err := o.tlsConfig(cr)
+	tlsConfig, err := o.tlsConfig(cr, am)
 	if err != nil {
 		return err
 	}
@@ -1174,7 +1519,7 @@ func listenAndServeQuit(
 }
 
 func listenAndServe(proxy http.Handler, o *Options) error {
-	return listenAndServeQuit(proxy, o, nil, nil, nil, nil)
+	return listenAndServeQuit(proxy, o, nil, nil, nil, nil, nil)
 }
 
 func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
@@ -1184,6 +1529,8 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		return err
 	}
 
+	defer logging.CloseSinks()
+
 	if o.EnablePrometheusMetrics {
 		o.MetricsFlavours = append(o.MetricsFlavours, "prometheus")
 	}
@@ -1260,10 +1607,19 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 	}
 
 	var cr *certregistry.CertRegistry
-	if o.KubernetesEnableTLS {
+	if o.KubernetesEnableTLS || len(o.ACMEAllowedHosts) > 0 {
 		cr = certregistry.NewCertRegistry()
 	}
 
+	var acmeManager *acme.Manager
+	if len(o.ACMEAllowedHosts) > 0 {
+		acmeManager, err = o.acmeManager(cr)
+		if err != nil {
+			return err
+		}
+		defer acmeManager.Close()
+	}
+
 	// *DEPRECATED* innkeeper - create data clients
 	dataClients, err := createDataClients(o, inkeeperAuth, cr)
 	if err != nil {
@@ -1341,7 +1697,9 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 	o.CustomFilters = append(o.CustomFilters,
 		logfilter.NewAuditLog(o.MaxAuditBody),
 		auth.NewBearerInjector(sp),
-		auth.NewJwtValidationWithOptions(tio),
+		auth.NewSignJwt(sp),
+		tlsfilters.NewBackendTLS(sp),
+		auth.NewJwtValidation(auth.JwtValidationOptions{TokenintrospectionOptions: tio, Secrets: sp}),
 		auth.TokenintrospectionWithOptions(auth.NewOAuthTokenintrospectionAnyClaims, tio),
 		auth.TokenintrospectionWithOptions(auth.NewOAuthTokenintrospectionAllClaims, tio),
 		auth.TokenintrospectionWithOptions(auth.NewOAuthTokenintrospectionAnyKV, tio),
@@ -1351,6 +1709,8 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		auth.TokenintrospectionWithOptions(auth.NewSecureOAuthTokenintrospectionAnyKV, tio),
 		auth.TokenintrospectionWithOptions(auth.NewSecureOAuthTokenintrospectionAllKV, tio),
 		auth.WebhookWithOptions(who),
+		auth.NewExternalAuthz(auth.ExternalAuthzOptions(who)),
+		auth.NewTokenExchange(auth.TokenExchangeOptions{TokenintrospectionOptions: tio, Secrets: sp}),
 		auth.NewOAuthOidcUserInfosWithOptions(o.OIDCSecretsFile, o.SecretsRegistry, oo),
 		auth.NewOAuthOidcAnyClaimsWithOptions(o.OIDCSecretsFile, o.SecretsRegistry, oo),
 		auth.NewOAuthOidcAllClaimsWithOptions(o.OIDCSecretsFile, o.SecretsRegistry, oo),
@@ -1363,14 +1723,45 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		),
 	)
 
+	if o.OpenPolicyAgentBundleSource != "" {
+		opaRegistry := openpolicyagent.NewRegistry(openpolicyagent.Options{
+			BundleSource:   o.OpenPolicyAgentBundleSource,
+			ReloadInterval: o.OpenPolicyAgentReloadInterval,
+			DecisionLogs:   o.OpenPolicyAgentDecisionLogs,
+		})
+		defer opaRegistry.Close()
+
+		o.CustomFilters = append(o.CustomFilters, openpolicyagent.NewOpaAuthorizeRequestSpec(opaRegistry))
+	}
+
 	var swarmer ratelimit.Swarmer
 	var redisOptions *skpnet.RedisOptions
 	if o.EnableSwarm {
-		if len(o.SwarmRedisURLs) > 0 {
-			log.Infof("Redis based swarm with %d shards", len(o.SwarmRedisURLs))
+		redisAddrUpdater, err := getRedisAddrUpdater(o, dataClients)
+		if err != nil {
+			return err
+		}
+
+		if len(o.SwarmRedisURLs) > 0 || redisAddrUpdater != nil {
+			if redisAddrUpdater != nil {
+				log.Infof("Redis based swarm with shard discovery")
+			} else {
+				log.Infof("Redis based swarm with %d shards", len(o.SwarmRedisURLs))
+			}
+
+			redisTLSConfig, err := o.redisTLSConfig()
+			if err != nil {
+				return err
+			}
+
 			redisOptions = &skpnet.RedisOptions{
 				Addrs:               o.SwarmRedisURLs,
+				Username:            o.SwarmRedisUsername,
 				Password:            o.SwarmRedisPassword,
+				TLSConfig:           redisTLSConfig,
+				Mode:                o.SwarmRedisMode,
+				SentinelMasterName:  o.SwarmRedisSentinelMasterName,
+				SentinelPassword:    o.SwarmRedisSentinelPassword,
 				HashAlgorithm:       o.SwarmRedisHashAlgorithm,
 				DialTimeout:         o.SwarmRedisDialTimeout,
 				ReadTimeout:         o.SwarmRedisReadTimeout,
@@ -1380,6 +1771,8 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 				MaxIdleConns:        o.SwarmRedisMaxIdleConns,
 				ConnMetricsInterval: o.redisConnMetricsInterval,
 				Tracer:              tracer,
+				AddrUpdater:         redisAddrUpdater,
+				UpdateInterval:      o.SwarmRedisUpdateInterval,
 			}
 		} else {
 			log.Infof("Start swim based swarm")
@@ -1454,10 +1847,34 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		)
 
 		if redisOptions != nil {
-			o.CustomFilters = append(o.CustomFilters, ratelimitfilters.NewClusterLeakyBucketRatelimit(ratelimitRegistry))
+			o.CustomFilters = append(o.CustomFilters,
+				ratelimitfilters.NewClusterLeakyBucketRatelimit(ratelimitRegistry),
+				ratelimitfilters.NewClusterClientQuota(ratelimitRegistry),
+			)
 		}
 	}
 
+	// the Redis ring client of the swarm, shared by the features keeping their state in Redis
+	var swarmRedis *skpnet.RedisRingClient
+	if redisOptions != nil {
+		if ratelimitRegistry != nil {
+			swarmRedis = ratelimitRegistry.RedisRing()
+		} else {
+			swarmRedis = skpnet.NewRedisRingClient(redisOptions)
+			defer swarmRedis.Close()
+		}
+	}
+
+	apiKeyOptions := auth.ApiKeyOptions{
+		Secrets:          sp,
+		Ratelimits:       ratelimitRegistry,
+		ClusterRatelimit: swarmer != nil || redisOptions != nil,
+	}
+	if swarmRedis != nil {
+		apiKeyOptions.Redis = swarmRedis
+	}
+	o.CustomFilters = append(o.CustomFilters, auth.NewApiKey(apiKeyOptions))
+
 	if o.TLSMinVersion == 0 {
 		o.TLSMinVersion = tls.VersionTLS12
 	}
@@ -1558,6 +1975,8 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		forwarded.NewForwardedHost(),
 		forwarded.NewForwardedProto(),
 		host.NewAny(),
+		clientcert.NewSubject(),
+		clientcert.NewSAN(),
 	)
 
 	// provide default value for wrapper if not defined
@@ -1570,6 +1989,11 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 	schedulerRegistry := scheduler.RegistryWith(scheduler.Options{
 		Metrics:                mtr,
 		EnableRouteLIFOMetrics: o.EnableRouteLIFOMetrics,
+		LoadShedding: scheduler.LoadSheddingOptions{
+			MaxInflight: o.LoadSheddingMaxInflight,
+			MaxCPU:      o.LoadSheddingMaxCPU,
+			RetryAfter:  o.LoadSheddingRetryAfter,
+		},
 	})
 	defer schedulerRegistry.Close()
 
@@ -1592,6 +2016,10 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		SignalFirstLoad: o.WaitFirstRouteLoad,
 	}
 
+	if acmeManager != nil {
+		ro.PostProcessors = append(ro.PostProcessors, acmeManager)
+	}
+
 	if o.DefaultFilters != nil {
 		ro.PreProcessors = append(ro.PreProcessors, o.DefaultFilters)
 	}
@@ -1638,14 +2066,59 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		ClientTLS:                  o.ClientTLS,
 		CustomHttpRoundTripperWrap: o.CustomHttpRoundTripperWrap,
 		RateLimiters:               ratelimitRegistry,
+		LoadShedder:                schedulerRegistry.LoadShedder(),
+	}
+
+	if acmeManager != nil {
+		proxyParams.PriorityRoutes = append(proxyParams.PriorityRoutes, acmeManager)
 	}
 
 	if o.EnableBreakers || len(o.BreakerSettings) > 0 {
-		proxyParams.CircuitBreakers = circuit.NewRegistry(o.BreakerSettings...)
+		breakerOptions := circuit.Options{
+			Settings:     o.BreakerSettings,
+			SyncInterval: o.BreakerSyncInterval,
+		}
+
+		if o.EnableSharedBreakers {
+			switch {
+			case swarmRedis != nil:
+				breakerOptions.SharedStore = circuit.NewRedisStore(swarmRedis)
+			case swarmer != nil:
+				breakerOptions.SharedStore = circuit.NewSwarmStore(swarmer)
+			default:
+				log.Warn("Shared circuit breakers require the swarm, using local circuit breakers")
+			}
+		}
+
+		breakers := circuit.NewRegistryWithOptions(breakerOptions)
+		defer breakers.Close()
+		breakers.StartMetricsCollection()
+		proxyParams.CircuitBreakers = breakers
+	}
+
+	if len(o.DebugTraceTokens) > 0 || o.DebugTraceSigningKeyFile != "" {
+		proxyParams.DebugTrace = &proxy.DebugTraceParams{
+			Header: o.DebugTraceHeader,
+			Tokens: o.DebugTraceTokens,
+		}
+
+		if o.DebugTraceSigningKeyFile != "" {
+			key, err := os.ReadFile(o.DebugTraceSigningKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read debug trace signing key: %w", err)
+			}
+
+			proxyParams.DebugTrace.SigningKey = bytes.TrimSpace(key)
+		}
+
+		if o.DebugTraceStoreSize > 0 {
+			proxyParams.DebugTrace.Store = proxy.NewDebugTraceStore(o.DebugTraceStoreSize)
+		}
 	}
 
 	if o.DebugListener != "" {
 		do := proxyParams
+		do.DebugTrace = nil
 		do.Flags |= proxy.Debug
 		dbg := proxy.WithParams(do)
 		log.Infof("debug listener on %v", o.DebugListener)
@@ -1664,6 +2137,7 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		mux := http.NewServeMux()
 		mux.Handle("/routes", routing)
 		mux.Handle("/routes/", routing)
+		mux.Handle("/routes/explain", routing.ExplainHandler())
 
 		metricsHandler := metrics.NewHandler(mtrOpts, mtr)
 		mux.Handle("/metrics", metricsHandler)
@@ -1671,6 +2145,26 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 		mux.Handle("/debug/pprof", metricsHandler)
 		mux.Handle("/debug/pprof/", metricsHandler)
 
+		if ratelimitRegistry != nil && redisOptions != nil {
+			mux.Handle(ratelimit.QuotaHandlerPrefix, ratelimit.NewQuotaHandlerWithOptions(
+				ratelimitRegistry,
+				ratelimit.QuotaHandlerOptions{EnableReset: o.EnableQuotaAdminReset},
+			))
+		}
+
+		if proxyParams.CircuitBreakers != nil {
+			breakerHandler := circuit.NewBreakerHandlerWithOptions(
+				proxyParams.CircuitBreakers,
+				circuit.BreakerHandlerOptions{EnableWrite: o.EnableBreakerAdminWrite},
+			)
+			mux.Handle(circuit.BreakerHandlerPrefix, breakerHandler)
+			mux.Handle(circuit.BreakerHandlerPrefix+"/", breakerHandler)
+		}
+
+		if proxyParams.DebugTrace != nil && proxyParams.DebugTrace.Store != nil {
+			mux.Handle(proxy.DebugTraceHandlerPrefix, proxyParams.DebugTrace.Store)
+		}
+
 		log.Infof("support listener on %s", supportListener)
 		go func() {
 			if err := http.ListenAndServe(supportListener, mux); err != nil {
@@ -1716,7 +2210,7 @@ func run(o Options, sig chan os.Signal, idleConnsCH chan struct{}) error {
 	// wait for the first route configuration to be loaded if enabled:
 	<-routing.FirstLoad()
 
-	return listenAndServeQuit(o.CustomHttpHandlerWrap(proxy), &o, sig, idleConnsCH, mtr, cr)
+	return listenAndServeQuit(o.CustomHttpHandlerWrap(proxy), &o, sig, idleConnsCH, mtr, cr, acmeManager)
 }
 
 // Run skipper.
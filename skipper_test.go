@@ -85,41 +85,48 @@ func TestOptionsTLSConfig(t *testing.T) {
 
 	// empty
 	o := &Options{}
-	c, err := o.tlsConfig(cr)
+	c, err := o.tlsConfig(cr, nil)
 	require.NoError(t, err)
 	require.Nil(t, c)
 
 	// enable kubernetes tls
 	o = &Options{KubernetesEnableTLS: true}
-	c, err = o.tlsConfig(cr)
+	c, err = o.tlsConfig(cr, nil)
 	require.NoError(t, err)
 	require.NotNil(t, c.GetCertificate)
 
 	// proxy tls config
 	o = &Options{ProxyTLS: &tls.Config{}}
-	c, err = o.tlsConfig(cr)
+	c, err = o.tlsConfig(cr, nil)
 	require.NoError(t, err)
 	require.Equal(t, &tls.Config{}, c)
 
 	// proxy tls config priority
 	o = &Options{ProxyTLS: &tls.Config{}, CertPathTLS: "fixtures/test.crt", KeyPathTLS: "fixtures/test.key"}
-	c, err = o.tlsConfig(cr)
+	c, err = o.tlsConfig(cr, nil)
 	require.NoError(t, err)
 	require.Equal(t, &tls.Config{}, c)
 
 	// cert key path
 	o = &Options{TLSMinVersion: tls.VersionTLS12, CertPathTLS: "fixtures/test.crt", KeyPathTLS: "fixtures/test.key"}
-	c, err = o.tlsConfig(cr)
+	c, err = o.tlsConfig(cr, nil)
 	require.NoError(t, err)
 	require.Equal(t, uint16(tls.VersionTLS12), c.MinVersion)
 	require.Equal(t, []tls.Certificate{cert}, c.Certificates)
 
 	// multiple cert key paths
 	o = &Options{TLSMinVersion: tls.VersionTLS13, CertPathTLS: "fixtures/test.crt,fixtures/test2.crt", KeyPathTLS: "fixtures/test.key,fixtures/test2.key"}
-	c, err = o.tlsConfig(cr)
+	c, err = o.tlsConfig(cr, nil)
 	require.NoError(t, err)
 	require.Equal(t, uint16(tls.VersionTLS13), c.MinVersion)
 	require.Equal(t, []tls.Certificate{cert, cert2}, c.Certificates)
+
+	// client certificate verification
+	o = &Options{CertPathTLS: "fixtures/test.crt", KeyPathTLS: "fixtures/test.key", TLSClientAuth: tls.RequireAndVerifyClientCert, TLSClientCAFile: "fixtures/test.crt,fixtures/test2.crt"}
+	c, err = o.tlsConfig(cr, nil)
+	require.NoError(t, err)
+	require.Equal(t, tls.RequireAndVerifyClientCert, c.ClientAuth)
+	require.NotNil(t, c.ClientCAs)
 }
 
 func TestOptionsTLSConfigInvalidPaths(t *testing.T) {
@@ -136,9 +143,12 @@ func TestOptionsTLSConfigInvalidPaths(t *testing.T) {
 		{"cert key mismatch", &Options{CertPathTLS: "fixtures/test.crt", KeyPathTLS: "fixtures/test2.key"}},
 		{"multiple cert key count mismatch", &Options{CertPathTLS: "fixtures/test.crt,fixtures/test2.crt", KeyPathTLS: "fixtures/test.key"}},
 		{"multiple cert key mismatch", &Options{CertPathTLS: "fixtures/test.crt,fixtures/test2.crt", KeyPathTLS: "fixtures/test2.key,fixtures/test.key"}},
+		{"client verification without CA", &Options{CertPathTLS: "fixtures/test.crt", KeyPathTLS: "fixtures/test.key", TLSClientAuth: tls.VerifyClientCertIfGiven}},
+		{"wrong client CA path", &Options{CertPathTLS: "fixtures/test.crt", KeyPathTLS: "fixtures/test.key", TLSClientCAFile: "fixtures/notFound.crt"}},
+		{"invalid client CA", &Options{CertPathTLS: "fixtures/test.crt", KeyPathTLS: "fixtures/test.key", TLSClientCAFile: "fixtures/test.key"}},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := tt.options.tlsConfig(cr)
+			_, err := tt.options.tlsConfig(cr, nil)
 			t.Logf("tlsConfig error: %v", err)
 			require.Error(t, err)
 		})
@@ -263,7 +273,7 @@ func testServerShutdown(t *testing.T, o *Options, scheme string) {
 
 	sigs := make(chan os.Signal, 1)
 	go func() {
-		err := listenAndServeQuit(proxy, o, sigs, nil, nil, nil)
+		err := listenAndServeQuit(proxy, o, sigs, nil, nil, nil, nil)
 		require.NoError(t, err)
 	}()
 